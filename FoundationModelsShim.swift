@@ -7,7 +7,48 @@ import FoundationModels
 public class SessionWrapper {
   private var _session: LanguageModelSession?
   var tools: [any Tool] = []
-  let instructions: String?
+  var instructions: String?
+  // In-flight generation task, cancelled by CancelResponse. Every generation starts a new
+  // task, which also starts a new tool call budget. Guarded by activeTaskLock because
+  // CancelResponse runs on the caller's thread while generations assign it.
+  var activeTask: Task<Void, Never>? {
+    get {
+      activeTaskLock.lock()
+      defer { activeTaskLock.unlock() }
+      return _activeTask
+    }
+    set {
+      resetToolCalls()
+      activeTaskLock.lock()
+      _activeTask = newValue
+      activeTaskLock.unlock()
+    }
+  }
+  private var _activeTask: Task<Void, Never>?
+  private let activeTaskLock = NSLock()
+
+  // Cancels and clears the in-flight task in one step, so a concurrent assignment can't be lost
+  func cancelActiveTask() {
+    activeTaskLock.lock()
+    let task = _activeTask
+    _activeTask = nil
+    activeTaskLock.unlock()
+    task?.cancel()
+  }
+
+  // Tool calls allowed in each generation (0 = unlimited) and those made in the current one
+  var maxToolCalls = 0
+  private var toolCallCount = 0
+  private let toolCallLock = NSLock()
+  // Context added without a generation, presented to the model as instructions entries
+  var addedContext: [String] = []
+  // Entries of an exported conversation, replayed when the session is created
+  var restoredEntries: [Transcript.Entry] = []
+  // Model the session runs on, built from the use case or adapter and the guardrails
+  var model: SystemLanguageModel = .default
+  var useCase: SystemLanguageModel.UseCase = .general
+  var adapter: SystemLanguageModel.Adapter?
+  var guardrails: SystemLanguageModel.Guardrails = .default
   
   init(instructions: String? = nil) {
     self.instructions = instructions
@@ -22,17 +63,26 @@ public class SessionWrapper {
     
     // Create new session with tools and instructions
     let newSession: LanguageModelSession
-    if tools.isEmpty {
+    if !addedContext.isEmpty || !restoredEntries.isEmpty {
+      var entries: [Transcript.Entry] = []
+      if let instructions = instructions {
+        entries.append(SessionWrapper.instructionsEntry(instructions))
+      }
+      entries += restoredEntries
+      entries += addedContext.map(SessionWrapper.instructionsEntry)
+      newSession = LanguageModelSession(
+        model: model, tools: tools, transcript: Transcript(entries: entries))
+    } else if tools.isEmpty {
       if let instructions = instructions {
-        newSession = LanguageModelSession(instructions: instructions)
+        newSession = LanguageModelSession(model: model, instructions: instructions)
       } else {
-        newSession = LanguageModelSession()
+        newSession = LanguageModelSession(model: model)
       }
     } else {
       if let instructions = instructions {
-        newSession = LanguageModelSession(tools: tools, instructions: instructions)
+        newSession = LanguageModelSession(model: model, tools: tools, instructions: instructions)
       } else {
-        newSession = LanguageModelSession(tools: tools)
+        newSession = LanguageModelSession(model: model, tools: tools)
       }
     }
     
@@ -40,22 +90,163 @@ public class SessionWrapper {
     return newSession
   }
   
+  // Whether the framework session is generating; false before it has been created
+  var isResponding: Bool {
+    return _session?.isResponding ?? false
+  }
+
+  // Count a tool call against the budget, returning false if it would exceed it
+  func spendToolCall() -> Bool {
+    toolCallLock.lock()
+    defer { toolCallLock.unlock() }
+    if maxToolCalls > 0 && toolCallCount >= maxToolCalls {
+      return false
+    }
+    toolCallCount += 1
+    return true
+  }
+
+  private func resetToolCalls() {
+    toolCallLock.lock()
+    toolCallCount = 0
+    toolCallLock.unlock()
+  }
+
   // Force recreation of session when tools change
   func invalidateSession() {
     _session = nil
   }
+
+  // Append context to the transcript without generating a response
+  func addContext(_ text: String) {
+    addedContext.append(text)
+    guard let existingSession = _session else {
+      return // Included when the session is created
+    }
+    let entries = Array(existingSession.transcript) + [SessionWrapper.instructionsEntry(text)]
+    _session = LanguageModelSession(
+      model: model, tools: tools, transcript: Transcript(entries: entries))
+  }
+
+  // Rebuild the model after the use case, adapter or guardrails changed, keeping the
+  // conversation so far
+  func updateModel() {
+    if let adapter = adapter {
+      model = SystemLanguageModel(adapter: adapter, guardrails: guardrails)
+    } else {
+      model = SystemLanguageModel(useCase: useCase, guardrails: guardrails)
+    }
+    guard let existingSession = _session else {
+      return // Used when the session is created
+    }
+    _session = LanguageModelSession(
+      model: model, tools: tools, transcript: existingSession.transcript)
+  }
+
+  // Replace the transcript, e.g. with a trimmed one. The entries include any instructions.
+  func replaceTranscript(_ entries: [Transcript.Entry]) {
+    instructions = nil
+    addedContext = []
+    restoredEntries = entries
+    _session = nil
+  }
+
+  static func instructionsEntry(_ text: String) -> Transcript.Entry {
+    return .instructions(
+      Transcript.Instructions(
+        segments: [.text(Transcript.TextSegment(content: text))],
+        toolDefinitions: []))
+  }
 }
 
 private var logs: [String] = []
+// Guards logs, which tool calls running in parallel append to
+private let logsLock = NSLock()
 
 private func log(_ message: String) {
+    logsLock.lock()
     logs.append(message)
+    logsLock.unlock()
+}
+
+// Token count reported in a context window error, or 0 if there is none. The message may
+// also quote the limit ("exceeds the 4096 token limit (4210 tokens)"), which is always the
+// smaller number, so the largest count wins.
+private func exceededTokenCount(_ description: String) -> Int {
+  guard let regex = try? NSRegularExpression(pattern: #"(\d+)\s*tokens?\b"#) else {
+    return 0
+  }
+  let range = NSRange(description.startIndex..., in: description)
+  return regex.matches(in: description, range: range)
+    .compactMap { match in
+      Range(match.range(at: 1), in: description).flatMap { Int(description[$0]) }
+    }
+    .max() ?? 0
+}
+
+// Format an error for return to Go. Every error starts with a \u{1} marker so Go can't
+// mistake model output beginning with "Error:" for a failure. Framework errors that Go
+// needs to tell apart are tagged with a bracketed code after the "Error:" prefix.
+private func errorMessage(_ error: Error) -> String {
+  if error is CancellationError {
+    return "\u{1}Error: [cancelled] generation was cancelled"
+  }
+  if let budgetError = toolBudgetError(error) {
+    log("Swift: Tool call budget of \(budgetError.limit) exceeded")
+    return "\u{1}Error: [tool_budget_exceeded] \(budgetError.limit)"
+  }
+  if let genError = error as? LanguageModelSession.GenerationError {
+    switch genError {
+    case .exceededContextWindowSize(let context):
+      log("Swift: Context window exceeded: \(context.debugDescription)")
+      let tokens = exceededTokenCount(context.debugDescription)
+      return "\u{1}Error: [context_exceeded] \(tokens) \(context.debugDescription)"
+    case .assetsUnavailable(let context):
+      // Raised when Apple Intelligence is disabled or the model is removed mid-session
+      log("Swift: Model assets unavailable: \(context.debugDescription)")
+      return "\u{1}Error: [model_unavailable] \(context.debugDescription)"
+    case .rateLimited(let context), .concurrentRequests(let context):
+      log("Swift: Rate limited: \(context.debugDescription)")
+      return "\u{1}Error: [rate_limited] \(context.debugDescription)"
+    case .guardrailViolation(let context):
+      log("Swift: Guardrail violation: \(context.debugDescription)")
+      return "\u{1}Error: [guardrail_violation] \(context.debugDescription)"
+    default:
+      break
+    }
+  }
+  if let assetError = error as? SystemLanguageModel.Adapter.AssetError {
+    switch assetError {
+    case .compatibleAdapterNotFound(let context):
+      return "\u{1}Error: [adapter_incompatible] \(context.debugDescription)"
+    case .invalidAsset(let context), .invalidAdapterName(let context):
+      return "\u{1}Error: [adapter_invalid] \(context.debugDescription)"
+    @unknown default:
+      return "\u{1}Error: [adapter_invalid] \(assetError)"
+    }
+  }
+  return "\u{1}Error: \(error)"
+}
+
+// MARK: - Versioning
+
+// Version of the C interface this library exports. Bump it whenever an export is removed or
+// changes signature, together with shimVersion in the Go package, which refuses to load a
+// library reporting a different version. Added exports are optional on the Go side and
+// don't need a bump.
+let shimABIVersion: Int32 = 1
+
+@_cdecl("ShimVersion")
+public func ShimVersion() -> Int32 {
+  return shimABIVersion
 }
 
 @_cdecl("GetLogs")
 public func GetLogs() -> UnsafeMutablePointer<CChar> {
+    logsLock.lock()
     let logString = logs.joined(separator: "\n")
     logs.removeAll()
+    logsLock.unlock()
     return strdup(logString)
 }
 
@@ -75,6 +266,148 @@ public func CreateSessionWithInstructions(
   return Unmanaged.passRetained(wrapper).toOpaque()
 }
 
+// Create a session that resumes an exported conversation. The transcript is JSON in the
+// format returned by GetTranscript, and already includes any instructions.
+@_cdecl("CreateSessionWithTranscript")
+public func CreateSessionWithTranscript(
+  _ cTranscript: UnsafePointer<CChar>
+) -> UnsafeMutableRawPointer? {
+  let json = String(cString: cTranscript)
+  guard let data = json.data(using: .utf8),
+    let decoded = try? JSONDecoder().decode([TranscriptEntryJSON].self, from: data)
+  else {
+    log("Swift: Failed to decode transcript: \(json)")
+    return nil
+  }
+  let wrapper = SessionWrapper(instructions: nil)
+  wrapper.restoredEntries = transcriptEntries(decoded)
+  log("Swift: Restored \(wrapper.restoredEntries.count) transcript entries")
+  return Unmanaged.passRetained(wrapper).toOpaque()
+}
+
+// Replace a session's transcript with JSON in the format returned by GetTranscript
+@_cdecl("SetTranscript")
+public func SetTranscript(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cTranscript: UnsafePointer<CChar>
+) -> Bool {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  let json = String(cString: cTranscript)
+  guard let data = json.data(using: .utf8),
+    let decoded = try? JSONDecoder().decode([TranscriptEntryJSON].self, from: data)
+  else {
+    log("Swift: Failed to decode transcript: \(json)")
+    return false
+  }
+  wrapper.replaceTranscript(transcriptEntries(decoded))
+  log("Swift: Replaced transcript with \(decoded.count) entries")
+  return true
+}
+
+// Run a session on the model for a use case: 0 is general, 1 is content tagging
+@_cdecl("SetSessionUseCase")
+public func SetSessionUseCase(_ sessionPtr: UnsafeMutableRawPointer, _ useCase: Int32) -> Bool {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  switch useCase {
+  case 0:
+    wrapper.useCase = .general
+  case 1:
+    wrapper.useCase = .contentTagging
+  default:
+    log("Swift: Unknown use case \(useCase)")
+    return false
+  }
+  wrapper.updateModel()
+  log("Swift: Session use case set to \(useCase)")
+  return true
+}
+
+// Run a session on the base model with a trained adapter (.fmadapter) applied. Returns
+// an empty string on success, or an error if the adapter can't be loaded or was trained
+// for a different base model version.
+@_cdecl("SetSessionAdapter")
+public func SetSessionAdapter(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cPath: UnsafePointer<CChar>
+) -> UnsafeMutablePointer<CChar> {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  let path = String(cString: cPath)
+  do {
+    let adapter = try SystemLanguageModel.Adapter(fileURL: URL(fileURLWithPath: path))
+    wrapper.adapter = adapter
+    wrapper.updateModel()
+    log("Swift: Session adapter set to \(path)")
+    return strdup("")
+  } catch {
+    log("Swift: Failed to load adapter \(path): \(error)")
+    if error is SystemLanguageModel.Adapter.AssetError {
+      return strdup(errorMessage(error))
+    }
+    return strdup("\u{1}Error: [adapter_invalid] \(error)")
+  }
+}
+
+// Set a session's guardrails: 0 is the default, 1 permits content transformations
+// (e.g. summarizing or rewriting user-provided text on sensitive topics)
+@_cdecl("SetSessionGuardrails")
+public func SetSessionGuardrails(_ sessionPtr: UnsafeMutableRawPointer, _ guardrails: Int32) -> Bool {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  switch guardrails {
+  case 0:
+    wrapper.guardrails = .default
+  case 1:
+    wrapper.guardrails = .permissiveContentTransformations
+  default:
+    log("Swift: Unknown guardrails \(guardrails)")
+    return false
+  }
+  wrapper.updateModel()
+  log("Swift: Session guardrails set to \(guardrails)")
+  return true
+}
+
+// Limit the tool calls of each generation on a session. A call beyond the limit ends the
+// generation with a tool_budget_exceeded error; 0 removes the limit.
+@_cdecl("SetSessionMaxToolCalls")
+public func SetSessionMaxToolCalls(_ sessionPtr: UnsafeMutableRawPointer, _ maxToolCalls: Int32) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  wrapper.maxToolCalls = max(0, Int(maxToolCalls))
+  log("Swift: Session tool call budget set to \(wrapper.maxToolCalls)")
+}
+
+// Check whether an adapter can run on the installed base model without creating a
+// session. Returns an empty string if it can, or an error explaining why not.
+@_cdecl("CheckAdapterCompatibility")
+public func CheckAdapterCompatibility(_ cPath: UnsafePointer<CChar>) -> UnsafeMutablePointer<CChar> {
+  let path = String(cString: cPath)
+  do {
+    let adapter = try SystemLanguageModel.Adapter(fileURL: URL(fileURLWithPath: path))
+    let model = SystemLanguageModel(adapter: adapter)
+    if case .unavailable(let reason) = model.availability,
+      SystemLanguageModel.default.isAvailable
+    {
+      // The base model works, so the adapter is what makes the model unavailable
+      return strdup("\u{1}Error: [adapter_incompatible] \(reason)")
+    }
+    return strdup("")
+  } catch {
+    if error is SystemLanguageModel.Adapter.AssetError {
+      return strdup(errorMessage(error))
+    }
+    return strdup("\u{1}Error: [adapter_invalid] \(error)")
+  }
+}
+
 @_cdecl("ReleaseSession")
 public func ReleaseSession(_ sessionPtr: UnsafeMutableRawPointer) {
   Unmanaged<SessionWrapper>.fromOpaque(sessionPtr).release()
@@ -82,6 +415,50 @@ public func ReleaseSession(_ sessionPtr: UnsafeMutableRawPointer) {
 
 // MARK: - System Model Availability
 
+struct AvailabilityJSON: Encodable {
+  let status: Int32
+  let reason: String
+}
+
+// Report the availability status with a human-readable reason, as JSON
+@_cdecl("GetModelAvailability")
+public func GetModelAvailability() -> UnsafeMutablePointer<CChar> {
+  guard let data = try? JSONEncoder().encode(currentAvailability()) else {
+    return strdup("{\"status\":-1,\"reason\":\"failed to encode availability\"}")
+  }
+  return strdup(String(data: data, encoding: .utf8) ?? "")
+}
+
+private func currentAvailability() -> AvailabilityJSON {
+  let availability = SystemLanguageModel.default.availability
+  let reason: String
+  switch availability {
+  case .available:
+    reason = ""
+  case .unavailable(.appleIntelligenceNotEnabled):
+    #if os(macOS) && !targetEnvironment(macCatalyst)
+    reason = "Apple Intelligence is turned off in System Settings"
+    #else
+    reason = "Apple Intelligence is turned off in Settings > Apple Intelligence & Siri"
+    #endif
+  case .unavailable(.modelNotReady):
+    if ProcessInfo.processInfo.isLowPowerModeEnabled {
+      reason = "the model is not ready yet; Low Power Mode can delay downloading its assets"
+    } else {
+      reason = "the model is not ready yet, e.g. because its assets are still downloading"
+    }
+  case .unavailable(.deviceNotEligible):
+    #if targetEnvironment(simulator)
+    reason = "the simulator can only use the model when the host Mac supports Apple Intelligence"
+    #else
+    reason = "this device does not support Apple Intelligence"
+    #endif
+  @unknown default:
+    reason = String(describing: availability)
+  }
+  return AvailabilityJSON(status: CheckModelAvailability(), reason: reason)
+}
+
 @_cdecl("CheckModelAvailability")
 public func CheckModelAvailability() -> Int32 {
   switch SystemLanguageModel.default.availability {
@@ -112,12 +489,12 @@ public func RespondSync(
   var out: String = ""
   let sema = DispatchSemaphore(value: 0)
 
-  Task {
+  wrapper.activeTask = Task {
     do {
       let resp = try await wrapper.session.respond(to: prompt)
       out = resp.content
     } catch {
-      out = "Error: \(error)"
+      out = errorMessage(error)
     }
     sema.signal()
   }
@@ -151,7 +528,7 @@ public func RespondWithStructuredOutput(
   var out: String = ""
   let sema = DispatchSemaphore(value: 0)
 
-  Task {
+  wrapper.activeTask = Task {
     do {
       // Note: Structured output may not be available in the current API
       // For now, use basic respond and format the output
@@ -162,7 +539,129 @@ public func RespondWithStructuredOutput(
       let jsonData = try encoder.encode(jsonOutput)
       out = String(data: jsonData, encoding: .utf8) ?? "Failed to encode JSON"
     } catch {
-      out = "Error: \(error)"
+      out = errorMessage(error)
+    }
+    sema.signal()
+  }
+  sema.wait()
+  return strdup(out)
+}
+
+// MARK: - Schema-Constrained Output
+
+// Schema node as sent by Go (a JSON-encoded schemaNode)
+final class SchemaNode: Decodable {
+  let type: String
+  let name: String?
+  let description: String?
+  let properties: [SchemaProperty]?
+  let items: SchemaNode?
+  let minItems: Int?
+  let maxItems: Int?
+  let choices: [String]?
+  let pattern: String?
+  let minimum: Double?
+  let maximum: Double?
+
+  enum CodingKeys: String, CodingKey {
+    case type, name, description, properties, items, minItems, maxItems, pattern, minimum, maximum
+    case choices = "enum"
+  }
+}
+
+struct SchemaProperty: Decodable {
+  let name: String
+  let description: String?
+  let optional: Bool?
+  let schema: SchemaNode
+}
+
+enum SchemaError: Error {
+  case invalid(String)
+}
+
+// Build a DynamicGenerationSchema from a Go schema node
+private func dynamicSchema(_ node: SchemaNode) throws -> DynamicGenerationSchema {
+  switch node.type {
+  case "object":
+    let properties = try (node.properties ?? []).map { property in
+      DynamicGenerationSchema.Property(
+        name: property.name,
+        description: property.description,
+        schema: try dynamicSchema(property.schema),
+        isOptional: property.optional ?? false)
+    }
+    return DynamicGenerationSchema(
+      name: node.name ?? "Object", description: node.description, properties: properties)
+  case "array":
+    guard let items = node.items else {
+      throw SchemaError.invalid("array schema without items")
+    }
+    return DynamicGenerationSchema(
+      arrayOf: try dynamicSchema(items),
+      minimumElements: node.minItems,
+      maximumElements: node.maxItems)
+  case "string":
+    if let choices = node.choices, !choices.isEmpty {
+      return DynamicGenerationSchema(
+        name: node.name ?? "Choice", description: node.description, anyOf: choices)
+    }
+    var guides: [GenerationGuide<String>] = []
+    if let pattern = node.pattern {
+      guides.append(.pattern(try Regex(pattern)))
+    }
+    return DynamicGenerationSchema(type: String.self, guides: guides)
+  case "integer":
+    var guides: [GenerationGuide<Int>] = []
+    if let minimum = node.minimum {
+      guides.append(.minimum(Int(minimum)))
+    }
+    if let maximum = node.maximum {
+      guides.append(.maximum(Int(maximum)))
+    }
+    return DynamicGenerationSchema(type: Int.self, guides: guides)
+  case "number":
+    var guides: [GenerationGuide<Double>] = []
+    if let minimum = node.minimum {
+      guides.append(.minimum(minimum))
+    }
+    if let maximum = node.maximum {
+      guides.append(.maximum(maximum))
+    }
+    return DynamicGenerationSchema(type: Double.self, guides: guides)
+  case "boolean":
+    return DynamicGenerationSchema(type: Bool.self)
+  default:
+    throw SchemaError.invalid("unknown schema type '\(node.type)'")
+  }
+}
+
+// Decode a Go schema node into a GenerationSchema
+private func generationSchema(_ cSchema: UnsafePointer<CChar>) throws -> GenerationSchema {
+  let node = try JSONDecoder().decode(SchemaNode.self, from: Data(String(cString: cSchema).utf8))
+  return try GenerationSchema(root: dynamicSchema(node), dependencies: [])
+}
+
+@_cdecl("RespondWithSchema")
+public func RespondWithSchema(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cPrompt: UnsafePointer<CChar>,
+  _ cSchema: UnsafePointer<CChar>
+) -> UnsafeMutablePointer<CChar> {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  let prompt = String(cString: cPrompt)
+  var out: String = ""
+  let sema = DispatchSemaphore(value: 0)
+
+  wrapper.activeTask = Task {
+    do {
+      let schema = try generationSchema(cSchema)
+      let resp = try await wrapper.session.respond(to: prompt, schema: schema)
+      out = resp.content.jsonString
+    } catch {
+      out = errorMessage(error)
     }
     sema.signal()
   }
@@ -192,14 +691,29 @@ public struct ParameterDefinition: Codable, Sendable {
 }
 
 
-// Global storage for dynamic tools
-private var registeredTools: [String: DynamicTool] = [:]
+// Thrown by a tool call beyond the session's tool call budget, which ends the generation
+struct ToolBudgetExceededError: Error {
+  let limit: Int
+}
 
-// Dynamic tool that calls back to Go
+// Find a budget error, which reaches the generation wrapped in the framework's ToolCallError
+private func toolBudgetError(_ error: Error) -> ToolBudgetExceededError? {
+  if let budgetError = error as? ToolBudgetExceededError {
+    return budgetError
+  }
+  if let toolError = error as? LanguageModelSession.ToolCallError {
+    return toolError.underlyingError as? ToolBudgetExceededError
+  }
+  return nil
+}
+
+// Dynamic tool that calls back to Go. Tools are owned by a session, and calls are routed
+// back to that session's registry in Go by sessionID.
 public final class DynamicTool: Tool {
   public let name: String
   public let description: String
   public let parameters: GenerationSchema
+  let sessionID: UInt
   
   // The Arguments type must conform to 'Generable' for the Tool protocol.
   // We can define a struct that can hold the expected arguments.
@@ -209,7 +723,8 @@ public final class DynamicTool: Tool {
     public var arguments: String
   }
 
-  init(name: String, description: String, parameters: GenerationSchema) {
+  init(sessionID: UInt, name: String, description: String, parameters: GenerationSchema) {
+    self.sessionID = sessionID
     self.name = name
     self.description = description
     self.parameters = parameters
@@ -219,13 +734,29 @@ public final class DynamicTool: Tool {
     log("Swift: DynamicTool.call invoked for tool '\(name)'")
     log("Swift: Raw arguments JSON: \(arguments.arguments)")
 
+    // Stop runaway tool loops before the call reaches Go
+    if let sessionPtr = UnsafeRawPointer(bitPattern: sessionID) {
+      let wrapper = Unmanaged<SessionWrapper>.fromOpaque(sessionPtr).takeUnretainedValue()
+      if !wrapper.spendToolCall() {
+        throw ToolBudgetExceededError(limit: wrapper.maxToolCalls)
+      }
+    }
+
     // The arguments are already in JSON format, so we can pass them directly to Go.
     let argsJSON = arguments.arguments
     
     log("Swift: Calling Go callback with JSON: \(argsJSON)")
 
-    // Call back to Go to execute the tool
-    let result = executeGoTool(name, argsJSON)
+    // Call back to Go to execute the tool off Swift's cooperative thread pool, so tool
+    // calls the framework makes in parallel run concurrently. Go bounds how many run at
+    // once per session; each result is returned to the call that asked for it.
+    let sessionID = self.sessionID
+    let name = self.name
+    let result = await withCheckedContinuation { continuation in
+      toolQueue.async {
+        continuation.resume(returning: executeGoTool(sessionID, name, argsJSON))
+      }
+    }
 
     log("Swift: Tool execution result: \(result)")
 
@@ -238,18 +769,22 @@ public final class DynamicTool: Tool {
 }
 
 
-// Function pointer for calling back to Go
-private var goToolCallback: (@convention(c) (UnsafePointer<CChar>, UnsafePointer<CChar>) -> UnsafeMutablePointer<CChar>)?
+// Queue running Go tool calls, which block until the tool finishes
+private let toolQueue = DispatchQueue(label: "FoundationModelsShim.tools", attributes: .concurrent)
+
+// Function pointer for calling back to Go. The first argument identifies the session
+// that owns the tool (the session pointer handed to Go).
+private var goToolCallback: (@convention(c) (UInt, UnsafePointer<CChar>, UnsafePointer<CChar>) -> UnsafeMutablePointer<CChar>)?
 
-@_cdecl("SetToolCallback")
-public func SetToolCallback(
-  _ callback: @escaping @convention(c) (UnsafePointer<CChar>, UnsafePointer<CChar>) -> UnsafeMutablePointer<CChar>
+@_cdecl("SetSessionToolCallback")
+public func SetSessionToolCallback(
+  _ callback: @escaping @convention(c) (UInt, UnsafePointer<CChar>, UnsafePointer<CChar>) -> UnsafeMutablePointer<CChar>
 ) {
   goToolCallback = callback
 }
 
 // Function to call Go tool execution
-private func executeGoTool(_ toolName: String, _ argsJSON: String) -> String {
+private func executeGoTool(_ sessionID: UInt, _ toolName: String, _ argsJSON: String) -> String {
   guard let callback = goToolCallback else {
     return "Error: No Go callback set"
   }
@@ -257,7 +792,7 @@ private func executeGoTool(_ toolName: String, _ argsJSON: String) -> String {
   let cToolName = strdup(toolName)
   let cArgsJSON = strdup(argsJSON)
   
-  let result = callback(cToolName!, cArgsJSON!)
+  let result = callback(sessionID, cToolName!, cArgsJSON!)
   let resultString = String(cString: result)
   
   free(cToolName)
@@ -298,10 +833,11 @@ public func RegisterTool(
     let schema = try GenerationSchema(root: rootSchema, dependencies: [])
 
     // Create dynamic tool with the new schema.
-    let dynamicTool = DynamicTool(name: toolDef.name, description: toolDef.description, parameters: schema)
-    
-    // Store in global registry
-    registeredTools[toolDef.name] = dynamicTool
+    let dynamicTool = DynamicTool(
+      sessionID: UInt(bitPattern: sessionPtr),
+      name: toolDef.name,
+      description: toolDef.description,
+      parameters: schema)
     
     // Add to session's tools
     wrapper.tools.append(dynamicTool)
@@ -346,7 +882,7 @@ public func RespondWithTools(
   var out: String = ""
   let sema = DispatchSemaphore(value: 0)
 
-  Task {
+  wrapper.activeTask = Task {
     do {
       log("Swift: RespondWithTools called with prompt: \(prompt)")
       log("Swift: Using session with \(wrapper.tools.count) tools")
@@ -356,7 +892,7 @@ public func RespondWithTools(
       out = resp.content
       log("Swift: Received response: \(out)")
     } catch {
-      out = "Error: \(error)"
+      out = errorMessage(error)
     }
     sema.signal()
   }
@@ -382,7 +918,7 @@ public func RespondWithStreaming(
   
   log("Swift: Starting streaming response for prompt: \(prompt)")
   
-  Task {
+  wrapper.activeTask = Task {
     do {
       let session = wrapper.session
       log("Swift: Attempting to use streaming API")
@@ -422,7 +958,7 @@ public func RespondWithStreaming(
         free(cString)
       }
     } catch {
-      let errorMsg = "Error: \(error)"
+      let errorMsg = errorMessage(error)
       let cString = strdup(errorMsg)
       callback(cString!, true) // true = isLast (error ends stream)
       free(cString)
@@ -445,7 +981,7 @@ public func RespondWithToolsStreaming(
   
   log("Swift: Starting streaming response with tools for prompt: \(prompt)")
   
-  Task {
+  wrapper.activeTask = Task {
     do {
       log("Swift: Using session with \(wrapper.tools.count) tools for streaming")
       
@@ -471,7 +1007,7 @@ public func RespondWithToolsStreaming(
       
       log("Swift: Tool streaming completed")
     } catch {
-      let errorMsg = "Error: \(error)"
+      let errorMsg = errorMessage(error)
       let cString = strdup(errorMsg)
       callback(cString!, true) // true = isLast (error ends stream)
       free(cString)
@@ -480,30 +1016,229 @@ public func RespondWithToolsStreaming(
   }
 }
 
+// Stream a response using the framework's native async sequence. Every chunk is tagged
+// with the caller's stream ID so Go can route it without a callback per stream.
+@_cdecl("RespondStreamingWithID")
+public func RespondStreamingWithID(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cPrompt: UnsafePointer<CChar>,
+  _ streamID: Int64,
+  _ callback: @escaping @convention(c) (Int64, UnsafePointer<CChar>, Bool) -> Void
+) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  startStream(wrapper, String(cString: cPrompt), GenerationOptions(), streamID, callback)
+}
+
+// Generation options as sent by Go (a JSON-encoded GenerationOptions). Penalties and
+// stop sequences have no FoundationModels equivalent and are not decoded.
+struct GoGenerationOptions: Decodable {
+  let samplingMode: String?
+  let maxTokens: Int?
+  let temperature: Double?
+  let topP: Double?
+  let topK: Int?
+  let seed: UInt64?
+
+  // Map the sampling mode, top-k, top-p and seed onto a sampling mode (nil keeps the
+  // framework default)
+  var sampling: GenerationOptions.SamplingMode? {
+    if samplingMode == "greedy" {
+      return .greedy
+    }
+    if let topK = topK {
+      return .random(top: topK, seed: seed)
+    }
+    if let topP = topP {
+      return .random(probabilityThreshold: topP, seed: seed)
+    }
+    if let seed = seed {
+      return .random(probabilityThreshold: 1.0, seed: seed)
+    }
+    return nil
+  }
+}
+
+// Decode Go generation options, falling back to the defaults if they can't be parsed
+private func decodeGenerationOptions(_ cOptions: UnsafePointer<CChar>) -> GenerationOptions {
+  let json = String(cString: cOptions)
+  guard let data = json.data(using: .utf8),
+    let options = try? JSONDecoder().decode(GoGenerationOptions.self, from: data)
+  else {
+    log("Swift: Failed to decode generation options: \(json)")
+    return GenerationOptions()
+  }
+  return GenerationOptions(
+    sampling: options.sampling,
+    temperature: options.temperature,
+    maximumResponseTokens: options.maxTokens)
+}
+
+@_cdecl("RespondStreamingWithOptions")
+public func RespondStreamingWithOptions(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cPrompt: UnsafePointer<CChar>,
+  _ cOptions: UnsafePointer<CChar>,
+  _ streamID: Int64,
+  _ callback: @escaping @convention(c) (Int64, UnsafePointer<CChar>, Bool) -> Void
+) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  startStream(wrapper, String(cString: cPrompt), decodeGenerationOptions(cOptions), streamID, callback)
+}
+
+// Stream a response, delivering each newly generated suffix to callback under streamID
+private func startStream(
+  _ wrapper: SessionWrapper,
+  _ prompt: String,
+  _ options: GenerationOptions,
+  _ streamID: Int64,
+  _ callback: @escaping @convention(c) (Int64, UnsafePointer<CChar>, Bool) -> Void
+) {
+  log("Swift: Starting stream \(streamID) for prompt: \(prompt)")
+
+  wrapper.activeTask = Task {
+    do {
+      var previous = ""
+      for try await snapshot in wrapper.session.streamResponse(to: prompt, options: options) {
+        // Snapshots are cumulative, so only forward the newly generated suffix
+        let content = snapshot.content
+        let delta = content.hasPrefix(previous) ? String(content.dropFirst(previous.count)) : content
+        previous = content
+        if delta.isEmpty {
+          continue
+        }
+        let cChunk = strdup(delta)
+        callback(streamID, cChunk!, false)
+        free(cChunk)
+      }
+      let cEnd = strdup("")
+      callback(streamID, cEnd!, true)
+      free(cEnd)
+      log("Swift: Stream \(streamID) completed")
+    } catch {
+      let cError = strdup(errorMessage(error))
+      callback(streamID, cError!, true)
+      free(cError)
+      log("Swift: Stream \(streamID) error: \(error)")
+    }
+  }
+}
+
+// Stream a schema-constrained response. Unlike startStream, each chunk is a complete
+// snapshot of the partially generated value as JSON, since the snapshots of a structured
+// value are not cumulative text.
+@_cdecl("RespondWithSchemaStreaming")
+public func RespondWithSchemaStreaming(
+  _ sessionPtr: UnsafeMutableRawPointer,
+  _ cPrompt: UnsafePointer<CChar>,
+  _ cSchema: UnsafePointer<CChar>,
+  _ streamID: Int64,
+  _ callback: @escaping @convention(c) (Int64, UnsafePointer<CChar>, Bool) -> Void
+) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  let prompt = String(cString: cPrompt)
+  let schemaResult = Result { try generationSchema(cSchema) }
+  log("Swift: Starting schema stream \(streamID) for prompt: \(prompt)")
+
+  wrapper.activeTask = Task {
+    do {
+      let schema = try schemaResult.get()
+      var previous = ""
+      for try await snapshot in wrapper.session.streamResponse(to: prompt, schema: schema) {
+        let json = snapshot.content.jsonString
+        if json == previous {
+          continue
+        }
+        previous = json
+        let cChunk = strdup(json)
+        callback(streamID, cChunk!, false)
+        free(cChunk)
+      }
+      let cEnd = strdup("")
+      callback(streamID, cEnd!, true)
+      free(cEnd)
+      log("Swift: Schema stream \(streamID) completed")
+    } catch {
+      let cError = strdup(errorMessage(error))
+      callback(streamID, cError!, true)
+      free(cError)
+      log("Swift: Schema stream \(streamID) error: \(error)")
+    }
+  }
+}
+
+// MARK: - Cancellation
+
+@_cdecl("CancelResponse")
+public func CancelResponse(_ sessionPtr: UnsafeMutableRawPointer) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  wrapper.cancelActiveTask()
+  log("Swift: Cancelled active response")
+}
+
+@_cdecl("IsResponding")
+public func IsResponding(_ sessionPtr: UnsafeMutableRawPointer) -> Bool {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  return wrapper.isResponding
+}
+
+// MARK: - Prewarm
+
+// Load the model resources for the session ahead of its first request. Tools registered
+// afterwards recreate the session, which then needs prewarming again.
+@_cdecl("PrewarmSession")
+public func PrewarmSession(_ sessionPtr: UnsafeMutableRawPointer) {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  wrapper.session.prewarm()
+  log("Swift: Prewarmed session")
+}
+
+// MARK: - Context
+
+@_cdecl("AddContext")
+public func AddContext(_ sessionPtr: UnsafeMutableRawPointer, _ text: UnsafePointer<CChar>) -> Bool {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+  let contextText = String(cString: text)
+  wrapper.addContext(contextText)
+  log("Swift: Added \(contextText.count) characters of context")
+  return true
+}
+
 // MARK: - Advanced Request Options
 
-@_cdecl("RespondWithOptions")
-public func RespondWithOptions(
+@_cdecl("RespondWithGenerationOptions")
+public func RespondWithGenerationOptions(
   _ sessionPtr: UnsafeMutableRawPointer,
   _ cPrompt: UnsafePointer<CChar>,
-  _ maxTokens: Int32,
-  _ temperature: Float
+  _ cOptions: UnsafePointer<CChar>
 ) -> UnsafeMutablePointer<CChar> {
   let wrapper = Unmanaged<SessionWrapper>
     .fromOpaque(sessionPtr)
     .takeUnretainedValue()
   let prompt = String(cString: cPrompt)
+  let options = decodeGenerationOptions(cOptions)
   var out: String = ""
   let sema = DispatchSemaphore(value: 0)
 
-  Task {
+  wrapper.activeTask = Task {
     do {
-      // Note: Advanced options may not be available in the current FoundationModels API
-      // For now, use basic respond method
-      let resp = try await wrapper.session.respond(to: prompt)
+      let resp = try await wrapper.session.respond(to: prompt, options: options)
       out = resp.content
     } catch {
-      out = "Error: \(error)"
+      out = errorMessage(error)
     }
     sema.signal()
   }
@@ -511,8 +1246,184 @@ public func RespondWithOptions(
   return strdup(out)
 }
 
+// MARK: - Transcript
+
+// Transcript entry as seen by Go
+public struct TranscriptEntryJSON: Codable {
+  let role: String
+  let content: String
+  let toolName: String?
+}
+
+// Rebuild framework transcript entries from the GetTranscript format. Consecutive tool
+// calls are grouped, and each tool output is paired with the earliest unanswered call to
+// the same tool.
+private func transcriptEntries(_ decoded: [TranscriptEntryJSON]) -> [Transcript.Entry] {
+  var entries: [Transcript.Entry] = []
+  var calls: [Transcript.ToolCall] = []
+  var pendingCallIDs: [String: [String]] = [:]
+
+  func flushCalls() {
+    if !calls.isEmpty {
+      entries.append(.toolCalls(Transcript.ToolCalls(calls)))
+      calls = []
+    }
+  }
+
+  for entry in decoded {
+    let segments: [Transcript.Segment] = [.text(Transcript.TextSegment(content: entry.content))]
+    let toolName = entry.toolName ?? ""
+    if entry.role != "toolCall" {
+      flushCalls()
+    }
+    switch entry.role {
+    case "instructions":
+      entries.append(.instructions(Transcript.Instructions(segments: segments, toolDefinitions: [])))
+    case "prompt":
+      entries.append(.prompt(Transcript.Prompt(segments: segments)))
+    case "response":
+      entries.append(.response(Transcript.Response(assetIDs: [], segments: segments)))
+    case "toolCall":
+      guard let arguments = try? GeneratedContent(json: entry.content) else {
+        log("Swift: Skipping tool call with invalid arguments: \(entry.content)")
+        continue
+      }
+      let id = UUID().uuidString
+      pendingCallIDs[toolName, default: []].append(id)
+      calls.append(Transcript.ToolCall(id: id, toolName: toolName, arguments: arguments))
+    case "toolOutput":
+      var id = UUID().uuidString
+      if var pending = pendingCallIDs[toolName], !pending.isEmpty {
+        id = pending.removeFirst()
+        pendingCallIDs[toolName] = pending
+      }
+      entries.append(.toolOutput(Transcript.ToolOutput(id: id, toolName: toolName, segments: segments)))
+    default:
+      log("Swift: Skipping transcript entry with role \(entry.role)")
+    }
+  }
+  flushCalls()
+  return entries
+}
+
+private func segmentsText(_ segments: [Transcript.Segment]) -> String {
+  return segments.map { segment -> String in
+    switch segment {
+    case .text(let text):
+      return text.content
+    case .structure(let structured):
+      return structured.content.jsonString
+    @unknown default:
+      return "\(segment)"
+    }
+  }.joined(separator: "\n")
+}
+
+@_cdecl("GetTranscript")
+public func GetTranscript(_ sessionPtr: UnsafeMutableRawPointer) -> UnsafeMutablePointer<CChar> {
+  let wrapper = Unmanaged<SessionWrapper>
+    .fromOpaque(sessionPtr)
+    .takeUnretainedValue()
+
+  var entries: [TranscriptEntryJSON] = []
+  for entry in wrapper.session.transcript {
+    switch entry {
+    case .instructions(let instructions):
+      entries.append(TranscriptEntryJSON(role: "instructions", content: segmentsText(instructions.segments), toolName: nil))
+    case .prompt(let prompt):
+      entries.append(TranscriptEntryJSON(role: "prompt", content: segmentsText(prompt.segments), toolName: nil))
+    case .toolCalls(let calls):
+      for call in calls {
+        entries.append(TranscriptEntryJSON(role: "toolCall", content: call.arguments.jsonString, toolName: call.toolName))
+      }
+    case .toolOutput(let output):
+      entries.append(TranscriptEntryJSON(role: "toolOutput", content: segmentsText(output.segments), toolName: output.toolName))
+    case .response(let response):
+      entries.append(TranscriptEntryJSON(role: "response", content: segmentsText(response.segments), toolName: nil))
+    @unknown default:
+      entries.append(TranscriptEntryJSON(role: "unknown", content: "\(entry)", toolName: nil))
+    }
+  }
+
+  do {
+    let data = try JSONEncoder().encode(entries)
+    return strdup(String(data: data, encoding: .utf8) ?? "[]")
+  } catch {
+    return strdup(errorMessage(error))
+  }
+}
+
+// MARK: - Token Counting
+
+// Count tokens with the model's real tokenizer. Returns -1 if counting failed, or -2 if
+// the system doesn't support it.
+@_cdecl("CountTokens")
+public func CountTokens(_ cText: UnsafePointer<CChar>) -> Int32 {
+  let text = String(cString: cText)
+  var count: Int32 = -1
+  let sema = DispatchSemaphore(value: 0)
+
+  Task {
+    if #available(macOS 26.4, *) {
+      do {
+        count = Int32(try await SystemLanguageModel.default.tokenCount(for: text))
+      } catch {
+        log("Swift: Failed to count tokens: \(error)")
+      }
+    } else {
+      // -2 tells Go that counting will never work on this system
+      log("Swift: Token counting requires macOS 26.4 or later")
+      count = -2
+    }
+    sema.signal()
+  }
+  sema.wait()
+  return count
+}
+
 // MARK: - Utility Functions
 
+struct ModelDetailsJSON: Encodable {
+  let osVersion: String
+  let modelIdentifier: String
+  let adapterIdentifier: String?
+  let useCase: String
+  let supportedLanguages: [String]
+  let contextSize: Int
+  let availability: AvailabilityJSON
+}
+
+// Describe the default model as JSON. The context size comes from the framework on
+// macOS 26.4 and later, and is 0 (unknown) before.
+@_cdecl("GetModelDetails")
+public func GetModelDetails() -> UnsafeMutablePointer<CChar> {
+  let model = SystemLanguageModel.default
+  var contextSize = 0
+  let sema = DispatchSemaphore(value: 0)
+  Task {
+    if #available(macOS 26.4, *) {
+      contextSize = (try? await model.contextSize) ?? 0
+    }
+    sema.signal()
+  }
+  sema.wait()
+
+  let details = ModelDetailsJSON(
+    osVersion: ProcessInfo.processInfo.operatingSystemVersionString,
+    modelIdentifier: "SystemLanguageModel.default",
+    adapterIdentifier: nil,
+    useCase: "general",
+    supportedLanguages: model.supportedLanguages.map { $0.minimalIdentifier }.sorted(),
+    contextSize: contextSize,
+    availability: currentAvailability())
+  do {
+    let data = try JSONEncoder().encode(details)
+    return strdup(String(data: data, encoding: .utf8) ?? "{}")
+  } catch {
+    return strdup(errorMessage(error))
+  }
+}
+
 @_cdecl("GetModelInfo")
 public func GetModelInfo() -> UnsafeMutablePointer<CChar> {
   let model = SystemLanguageModel.default
@@ -0,0 +1,206 @@
+package fm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ebitengine/purego"
+)
+
+// adapterExt is the extension of adapter bundles produced by Apple's adapter training toolkit
+const adapterExt = ".fmadapter"
+
+// adapterMetadataFile is the file in an adapter bundle describing the adapter
+const adapterMetadataFile = "metadata.json"
+
+// AdapterMetadata describes an adapter bundle, as returned by AdapterInfo and ListAdapters
+type AdapterMetadata struct {
+	// Name is the bundle's file name without the .fmadapter extension
+	Name string `json:"name"`
+	// Path is the absolute path of the bundle
+	Path string `json:"path"`
+	// Identifier is the adapter identifier chosen when it was trained
+	Identifier string `json:"adapterIdentifier,omitempty"`
+	// Description, Author and License are free-form fields set when the adapter was exported
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+	License     string `json:"license,omitempty"`
+	// BaseModelSignature identifies the base model version the adapter was trained for
+	BaseModelSignature string `json:"baseModelSignature,omitempty"`
+	// LoRARank is the rank of the adapter's low-rank weights
+	LoRARank int `json:"loraRank,omitempty"`
+	// CreatorDefined holds any extra metadata added when the adapter was exported
+	CreatorDefined map[string]any `json:"creatorDefined,omitempty"`
+	// CreatedAt is when the bundle's metadata was written
+	CreatedAt time.Time `json:"createdAt"`
+	// Compatible reports whether the adapter runs on the installed base model
+	Compatible bool `json:"compatible"`
+	// Incompatibility explains why the adapter is not compatible, or is empty
+	Incompatibility string `json:"incompatibility,omitempty"`
+}
+
+// AdapterInfo reads the metadata of the adapter bundle at path and checks whether it
+// runs on the installed base model, so an app can fail fast on a version mismatch
+// instead of when creating a session. The compatibility check needs the shim; without it
+// Compatible is false and Incompatibility says why.
+func AdapterInfo(path string) (*AdapterMetadata, error) {
+	if err := validateAdapterPath(path); err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAdapterInvalid, err)
+	}
+
+	metadataPath := filepath.Join(path, adapterMetadataFile)
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAdapterInvalid, err)
+	}
+	info := &AdapterMetadata{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s: %w", ErrAdapterInvalid, adapterMetadataFile, err)
+	}
+	info.Name = strings.TrimSuffix(filepath.Base(path), adapterExt)
+	info.Path = path
+	if stat, err := os.Stat(metadataPath); err == nil {
+		info.CreatedAt = stat.ModTime()
+	}
+
+	if err := checkAdapter(path); err != nil {
+		info.Incompatibility = err.Error()
+	} else {
+		info.Compatible = true
+	}
+	return info, nil
+}
+
+// ListAdapters returns the metadata of the adapter bundles in dir, sorted by name.
+// Bundles that can't be read are skipped; the returned error joins their errors.
+func ListAdapters(dir string) ([]*AdapterMetadata, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adapters: %w", err)
+	}
+
+	var adapters []*AdapterMetadata
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() || filepath.Ext(entry.Name()) != adapterExt {
+			continue
+		}
+		info, err := AdapterInfo(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		adapters = append(adapters, info)
+	}
+	sort.Slice(adapters, func(i, j int) bool {
+		return adapters[i].Name < adapters[j].Name
+	})
+	return adapters, errors.Join(errs...)
+}
+
+// checkAdapter asks the shim whether the adapter at path runs on the installed base model
+func checkAdapter(path string) error {
+	if Init() != nil {
+		return fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if err := shimSymbol(checkAdapterCompatibility, "CheckAdapterCompatibility"); err != nil {
+		return err
+	}
+
+	cPath := cString(path)
+	defer freePtr(cPath)
+
+	respPtr, _, _ := purego.SyscallN(checkAdapterCompatibility, uintptr(cPath))
+	if respPtr == 0 {
+		return ErrNoResponse
+	}
+	response := takeString(respPtr)
+
+	return shimError(response)
+}
+
+// NewSessionWithAdapter creates a new session running on the base model with the trained
+// adapter at path (a .fmadapter bundle) applied, configured by opts. It returns an error
+// matching ErrAdapterInvalid if the adapter can't be loaded, or ErrAdapterIncompatible if
+// it was trained for a different version of the base model than the one installed.
+func NewSessionWithAdapter(path string, opts ...SessionOption) (*Session, error) {
+	if err := validateAdapterPath(path); err != nil {
+		return nil, err
+	}
+	if Init() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+
+	session := NewSession()
+	if session == nil {
+		return nil, fmt.Errorf("failed to create LanguageModelSession")
+	}
+	if err := session.setAdapter(path); err != nil {
+		session.Release()
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(session)
+	}
+	return session, nil
+}
+
+// Adapter returns the path of the adapter the session runs with, or "" if none
+func (s *Session) Adapter() string {
+	return s.adapterPath
+}
+
+// validateAdapterPath checks that path looks like an adapter bundle before handing it
+// to the framework, whose errors for a wrong path are less helpful
+func validateAdapterPath(path string) error {
+	if filepath.Ext(path) != adapterExt {
+		return fmt.Errorf("%w: %s is not a %s bundle", ErrAdapterInvalid, path, adapterExt)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAdapterInvalid, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrAdapterInvalid, path)
+	}
+	return nil
+}
+
+// setAdapter switches the shim session to the base model with the adapter at path
+func (s *Session) setAdapter(path string) error {
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := shimSymbol(setSessionAdapter, "SetSessionAdapter"); err != nil {
+		return err
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAdapterInvalid, err)
+	}
+
+	cPath := cString(path)
+	defer freePtr(cPath)
+
+	respPtr, _, _ := purego.SyscallN(setSessionAdapter, uintptr(s.ptr), uintptr(cPath))
+	if respPtr == 0 {
+		return ErrNoResponse
+	}
+	response := takeString(respPtr)
+
+	if err := shimError(response); err != nil {
+		return fmt.Errorf("failed to load adapter %s: %w", path, err)
+	}
+	s.adapterPath = path
+	return nil
+}
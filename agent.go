@@ -0,0 +1,304 @@
+package fm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/purego"
+)
+
+// AgentEvent is a single event from RespondAgentStream. It is one of TextChunkEvent,
+// ToolCallEvent, ToolResultEvent or DoneEvent.
+type AgentEvent interface {
+	agentEvent()
+}
+
+// TextChunkEvent carries newly generated response text
+type TextChunkEvent struct {
+	Text string
+}
+
+// ToolCallEvent is sent when the model calls a tool, before the tool runs
+type ToolCallEvent struct {
+	Name      string
+	Arguments map[string]any
+}
+
+// ToolResultEvent is sent when a tool call finishes. Calls made in the same turn run
+// concurrently, so results may arrive in a different order than their ToolCallEvents;
+// Arguments identify the call.
+type ToolResultEvent struct {
+	Name      string
+	Arguments map[string]any
+	Result    ToolResult
+	Duration  time.Duration
+}
+
+// DoneEvent is always the last event. Text is the complete response text and Err is
+// set if generation failed or the context was done.
+type DoneEvent struct {
+	Text string
+	Err  error
+	// Duration is the time from the start of generation to the end of the stream
+	Duration time.Duration
+	// ToolCalls is the number of tool calls that finished during generation
+	ToolCalls int
+}
+
+// Event is an AgentEvent, under the name used by Events
+type Event = AgentEvent
+
+// Names of the events for Events. The tool result event keeps the name ToolResultEvent,
+// since ToolResult is the result a tool returns.
+type (
+	// TextDelta is a TextChunkEvent
+	TextDelta = TextChunkEvent
+	// ToolCallStarted is a ToolCallEvent
+	ToolCallStarted = ToolCallEvent
+	// GenerationFinished is a DoneEvent
+	GenerationFinished = DoneEvent
+)
+
+func (TextChunkEvent) agentEvent()  {}
+func (ToolCallEvent) agentEvent()   {}
+func (ToolResultEvent) agentEvent() {}
+func (DoneEvent) agentEvent()       {}
+
+// agentStream serializes events from the shim's stream and tool callbacks into one channel
+type agentStream struct {
+	mu        sync.Mutex
+	events    chan AgentEvent
+	done      chan struct{} // Closed when the consumer's context is done
+	closed    bool
+	response  strings.Builder
+	start     time.Time
+	toolCalls int
+}
+
+// send delivers an event unless the stream was abandoned. Callers must hold mu.
+func (a *agentStream) send(event AgentEvent) {
+	if a.closed {
+		return
+	}
+	select {
+	case a.events <- event:
+	case <-a.done:
+	}
+}
+
+// finish sends the final event, filling in its metadata, and closes the channel.
+// Callers must hold mu.
+func (a *agentStream) finish(event DoneEvent) {
+	if a.closed {
+		return
+	}
+	event.Duration = time.Since(a.start)
+	event.ToolCalls = a.toolCalls
+	select {
+	case <-a.done:
+		// Abandoned: discard undelivered events to make room for the final one
+		for len(a.events) > 0 {
+			<-a.events
+		}
+		a.events <- event
+	default:
+		a.send(event)
+	}
+	close(a.events)
+	a.closed = true
+}
+
+// RespondAgentStream generates a response with tool calling, delivering live text and
+// tool activity as a single ordered stream of events:
+//
+//   - TextChunkEvent values arrive in generation order
+//   - when the model calls a tool, a ToolCallEvent is sent before the tool runs and a
+//     ToolResultEvent after it finishes, both after any text generated before the call
+//   - a DoneEvent is always sent last, after which the channel is closed
+//
+// If ctx is done, generation is cancelled and the DoneEvent carries ctx.Err().
+func (s *Session) RespondAgentStream(ctx context.Context, prompt string) (<-chan AgentEvent, error) {
+	if err := agentShim.ready(); err != nil {
+		return nil, err
+	}
+	if s.ptr == nil {
+		return nil, ErrInvalidSession
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The lock is held until the DoneEvent is sent
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	prompt = s.withTimeContext(prompt)
+	if err := s.ensureContextRoom(prompt); err != nil {
+		s.unlock()
+		return nil, err
+	}
+	if err := s.verifyAvailability(); err != nil {
+		s.unlock()
+		return nil, err
+	}
+	release, err := s.waitToStart(ctx)
+	if err != nil {
+		s.unlock()
+		return nil, err
+	}
+
+	agent := &agentStream{
+		events: make(chan AgentEvent, streamBufferSize),
+		done:   make(chan struct{}),
+		start:  time.Now(),
+	}
+	id := nextStreamID.Add(1)
+	finished := make(chan struct{})
+
+	removeHook := addToolHook(func(sess *Session, invocation ToolInvocation, done bool) {
+		if sess != s {
+			return
+		}
+		agent.mu.Lock()
+		defer agent.mu.Unlock()
+		if done {
+			agent.toolCalls++
+			agent.send(ToolResultEvent{
+				Name:      invocation.Name,
+				Arguments: invocation.Arguments,
+				Result:    invocation.Result,
+				Duration:  invocation.Duration,
+			})
+		} else {
+			agent.send(ToolCallEvent{Name: invocation.Name, Arguments: invocation.Arguments})
+		}
+	})
+
+	var cleanupOnce sync.Once
+	cleanup := func() {
+		cleanupOnce.Do(func() {
+			removeHook()
+			streamsMu.Lock()
+			delete(streams, id)
+			streamsMu.Unlock()
+			close(finished)
+			release()
+			s.unlock()
+		})
+	}
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			close(agent.done)
+			s.Cancel()
+		})
+	}
+
+	stream := &activeStream{
+		done: agent.done,
+		stop: stop,
+		deliver: func(chunk StreamChunk) {
+			agent.mu.Lock()
+			if chunk.Text != "" {
+				agent.response.WriteString(chunk.Text)
+				agent.send(TextChunkEvent{Text: chunk.Text})
+			}
+			if !chunk.Done {
+				agent.mu.Unlock()
+				return
+			}
+			response := agent.response.String()
+			agent.finish(DoneEvent{Text: response, Err: s.withPartialTranscript(chunk.Err)})
+			agent.mu.Unlock()
+
+			if chunk.Err == nil {
+				s.addToContext(response)
+			}
+			cleanup()
+		},
+	}
+
+	streamsMu.Lock()
+	streams[id] = stream
+	streamsMu.Unlock()
+
+	// Cancel generation if the caller's context is done first
+	go func() {
+		select {
+		case <-ctx.Done():
+			logger().Debug("Agent stream context done, cancelling", "stream_id", id)
+			stop()
+			agent.mu.Lock()
+			agent.finish(DoneEvent{Text: agent.response.String(), Err: ctx.Err()})
+			agent.mu.Unlock()
+			if cancelResponse == 0 {
+				// The shim can't cancel, so the session is held until the final chunk
+				return
+			}
+			cleanup()
+		case <-finished:
+		}
+	}()
+
+	s.addToContext(prompt)
+
+	agentShim.respond(s, prompt, id)
+
+	return agent.events, nil
+}
+
+// agentShim holds the shim calls RespondAgentStream makes, replaceable so tests can
+// drive the stream and tool callbacks without the shim
+var agentShim = struct {
+	ready   func() error                              // Checks that the shim can stream
+	respond func(s *Session, prompt string, id int64) // Starts generating into stream id
+}{
+	ready: func() error {
+		if Init() != nil {
+			return shimInitError
+		}
+		return shimSymbol(respondStreamingWithID, "RespondStreamingWithID")
+	},
+	respond: func(s *Session, prompt string, id int64) {
+		cPrompt := cString(prompt)
+		logger().Debug("Calling Swift RespondStreamingWithID for agent stream", "stream_id", id)
+		purego.SyscallN(respondStreamingWithID,
+			uintptr(s.ptr),
+			uintptr(cPrompt),
+			uintptr(id),
+			streamCallback)
+		freePtr(cPrompt)
+	},
+}
+
+// Events is RespondAgentStream for callers that handle every outcome in their event
+// loop: if generation can't start, the error is delivered as the GenerationFinished
+// event instead of being returned.
+//
+//	for event := range sess.Events(ctx, prompt) {
+//		switch e := event.(type) {
+//		case fm.TextDelta:
+//			fmt.Print(e.Text)
+//		case fm.ToolCallStarted:
+//			fmt.Printf("calling %s…\n", e.Name)
+//		case fm.ToolResultEvent:
+//			fmt.Printf("%s returned %q\n", e.Name, e.Result.Content)
+//		case fm.GenerationFinished:
+//			if e.Err != nil {
+//				log.Print(e.Err)
+//			}
+//		}
+//	}
+func (s *Session) Events(ctx context.Context, prompt string) <-chan Event {
+	events, err := s.RespondAgentStream(ctx, prompt)
+	if err != nil {
+		failed := make(chan Event, 1)
+		failed <- GenerationFinished{Err: err}
+		close(failed)
+		return failed
+	}
+	return events
+}
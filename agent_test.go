@@ -0,0 +1,195 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// drainAgentEvents reads events until the channel is closed
+func drainAgentEvents(t *testing.T, events <-chan AgentEvent) []AgentEvent {
+	t.Helper()
+	var got []AgentEvent
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		case <-timeout:
+			t.Fatal("event channel was not closed")
+		}
+	}
+}
+
+func TestAgentStreamEvents(t *testing.T) {
+	args := map[string]any{"city": "Paris"}
+	errFailed := errors.New("generation failed")
+
+	tests := []struct {
+		name    string
+		abandon bool // Abandon the stream before it finishes
+		events  []AgentEvent
+		done    DoneEvent
+		want    []AgentEvent
+	}{
+		{
+			name: "text and tools in order",
+			events: []AgentEvent{
+				TextChunkEvent{Text: "Let me check. "},
+				ToolCallEvent{Name: "weather", Arguments: args},
+				ToolResultEvent{Name: "weather", Arguments: args, Result: ToolResult{Content: "sunny"}},
+				TextChunkEvent{Text: "It's sunny."},
+			},
+			done: DoneEvent{Text: "Let me check. It's sunny."},
+			want: []AgentEvent{
+				TextChunkEvent{Text: "Let me check. "},
+				ToolCallEvent{Name: "weather", Arguments: args},
+				ToolResultEvent{Name: "weather", Arguments: args, Result: ToolResult{Content: "sunny"}},
+				TextChunkEvent{Text: "It's sunny."},
+				DoneEvent{Text: "Let me check. It's sunny.", ToolCalls: 1},
+			},
+		},
+		{
+			name:   "failed",
+			events: []AgentEvent{TextChunkEvent{Text: "Partial"}},
+			done:   DoneEvent{Text: "Partial", Err: errFailed},
+			want:   []AgentEvent{TextChunkEvent{Text: "Partial"}, DoneEvent{Text: "Partial", Err: errFailed}},
+		},
+		{
+			name:    "abandoned",
+			abandon: true,
+			events:  []AgentEvent{TextChunkEvent{Text: "Nobody"}, TextChunkEvent{Text: " reads this"}},
+			done:    DoneEvent{Text: "Nobody reads this", Err: context.Canceled},
+			want:    []AgentEvent{DoneEvent{Text: "Nobody reads this", Err: context.Canceled}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &agentStream{
+				events: make(chan AgentEvent, streamBufferSize),
+				done:   make(chan struct{}),
+				start:  time.Now(),
+			}
+			if tt.abandon {
+				close(a.done)
+			}
+
+			a.mu.Lock()
+			for _, event := range tt.events {
+				if _, ok := event.(ToolResultEvent); ok {
+					a.toolCalls++
+				}
+				a.send(event)
+			}
+			a.finish(tt.done)
+			// Events after the DoneEvent are dropped
+			a.send(TextChunkEvent{Text: "late"})
+			a.finish(DoneEvent{Text: "again"})
+			a.mu.Unlock()
+
+			got := drainAgentEvents(t, a.events)
+			if len(got) == 0 {
+				t.Fatal("no events")
+			}
+			done, ok := got[len(got)-1].(DoneEvent)
+			if !ok {
+				t.Fatalf("last event = %T, want DoneEvent", got[len(got)-1])
+			}
+			if done.Duration <= 0 {
+				t.Errorf("DoneEvent.Duration = %v, want > 0", done.Duration)
+			}
+			done.Duration = 0
+			got[len(got)-1] = done
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsStartFailure(t *testing.T) {
+	sess := newTestSession()
+
+	got := drainAgentEvents(t, sess.Events(context.Background(), "What's the weather?"))
+	if len(got) != 1 {
+		t.Fatalf("Events() sent %d events, want 1", len(got))
+	}
+	done, ok := got[0].(GenerationFinished)
+	if !ok || done.Err == nil {
+		t.Errorf("Events() sent %#v, want a GenerationFinished with an error", got[0])
+	}
+}
+
+func TestRespondAgentStreamWithTool(t *testing.T) {
+	calculator := &testTool{name: "calculator", fn: func(args map[string]any) (ToolResult, error) {
+		a, _ := args["a"].(float64)
+		b, _ := args["b"].(float64)
+		return ToolResult{Content: fmt.Sprint(a + b)}, nil
+	}}
+	sess := newTestSession(calculator)
+	sess.ptr = unsafe.Pointer(new(byte))
+	trackSession(sess)
+	t.Cleanup(func() { untrackSession(sess) })
+
+	// The fake shim streams text around a tool call, as the model would
+	oldShim := agentShim
+	t.Cleanup(func() { agentShim = oldShim })
+	agentShim.ready = func() error { return nil }
+	agentShim.respond = func(s *Session, prompt string, id int64) {
+		go func() {
+			routeStreamChunk(id, "Let me add that. ", false)
+			executeTool(lookupSession(uintptr(s.ptr)), "calculator", `{"a":2,"b":2}`)
+			routeStreamChunk(id, "It's 4.", false)
+			routeStreamChunk(id, "", true)
+		}()
+	}
+
+	events, err := sess.RespondAgentStream(context.Background(), "What's 2 + 2?")
+	if err != nil {
+		t.Fatalf("RespondAgentStream() error = %v", err)
+	}
+	got := drainAgentEvents(t, events)
+
+	args := map[string]any{"a": 2.0, "b": 2.0}
+	want := []AgentEvent{
+		TextChunkEvent{Text: "Let me add that. "},
+		ToolCallEvent{Name: "calculator", Arguments: args},
+		ToolResultEvent{Name: "calculator", Arguments: args, Result: ToolResult{Content: "4"}},
+		TextChunkEvent{Text: "It's 4."},
+		DoneEvent{Text: "Let me add that. It's 4.", ToolCalls: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %#v, want %#v", got, want)
+	}
+	// Durations vary, so only their presence is checked
+	if result, ok := got[2].(ToolResultEvent); ok {
+		result.Duration = 0
+		got[2] = result
+	}
+	if done, ok := got[4].(DoneEvent); ok {
+		if done.Duration <= 0 {
+			t.Errorf("DoneEvent.Duration = %v, want > 0", done.Duration)
+		}
+		done.Duration = 0
+		got[4] = done
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %#v, want %#v", got, want)
+	}
+
+	// The session is released for the next request once the stream is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := sess.lock(ctx); err != nil {
+		t.Errorf("session still busy after the stream finished: %v", err)
+	} else {
+		sess.unlock()
+	}
+}
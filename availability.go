@@ -0,0 +1,132 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ebitengine/purego"
+)
+
+// Availability describes whether the model can be used and, if not, why
+type Availability struct {
+	// Status is the availability status
+	Status ModelAvailability `json:"status"`
+	// Reason is a human-readable explanation of why the model is unavailable (empty when
+	// it is available)
+	Reason string `json:"reason"`
+}
+
+// GetModelAvailability returns the model's availability with the framework's reason
+func GetModelAvailability() Availability {
+	if err := Init(); err != nil {
+		if errors.Is(err, ErrUnsupportedPlatform) {
+			return Availability{Status: ModelUnavailableUnsupportedPlatform, Reason: err.Error()}
+		}
+		return Availability{
+			Status: ModelUnavailableUnknown,
+			Reason: fmt.Sprintf("Foundation Models shim not initialized: %v", shimInitError),
+		}
+	}
+
+	if getModelAvailability == 0 {
+		// Older shims only report the status
+		return Availability{Status: CheckModelAvailability()}
+	}
+
+	respPtr, _, _ := purego.SyscallN(getModelAvailability)
+	if respPtr == 0 {
+		return Availability{Status: ModelUnavailableUnknown, Reason: "no availability from FoundationModels"}
+	}
+	response := takeString(respPtr)
+
+	var availability Availability
+	if err := json.Unmarshal([]byte(response), &availability); err != nil {
+		return Availability{Status: ModelUnavailableUnknown, Reason: fmt.Sprintf("failed to parse availability: %v", err)}
+	}
+	return availability
+}
+
+// Available reports whether the model can be used
+func (a Availability) Available() bool {
+	return a.Status == ModelAvailable
+}
+
+// Err returns nil if the model is available, and otherwise an error carrying the reason
+// that matches ErrModelUnavailable and the sentinel for the status (such as
+// ErrModelNotReady) with errors.Is
+func (a Availability) Err() error {
+	var sentinel error
+	switch a.Status {
+	case ModelAvailable:
+		return nil
+	case ModelUnavailableAINotEnabled:
+		sentinel = ErrAppleIntelligenceNotEnabled
+	case ModelUnavailableNotReady:
+		sentinel = ErrModelNotReady
+	case ModelUnavailableDeviceNotEligible:
+		sentinel = ErrDeviceNotEligible
+	case ModelUnavailableUnsupportedPlatform:
+		return ErrUnsupportedPlatform
+	default:
+		sentinel = ErrModelUnavailable
+	}
+	if a.Reason == "" {
+		return sentinel
+	}
+	return fmt.Errorf("%w (%s)", sentinel, a.Reason)
+}
+
+// String returns the status, with the reason if the model is unavailable
+func (a Availability) String() string {
+	if a.Reason == "" {
+		return a.Status.String()
+	}
+	return fmt.Sprintf("%s: %s", a.Status, a.Reason)
+}
+
+// DefaultAvailabilityPollInterval is how often WatchModelAvailability checks availability
+const DefaultAvailabilityPollInterval = 5 * time.Second
+
+// WatchModelAvailability reports changes in model availability, e.g. from
+// ModelUnavailableNotReady to ModelAvailable once the model assets have downloaded, so
+// long-running programs can react without restarting. The current availability is sent
+// first, then each transition, polling every DefaultAvailabilityPollInterval. The channel
+// is closed once ctx is done.
+func WatchModelAvailability(ctx context.Context) <-chan ModelAvailability {
+	out := make(chan ModelAvailability, 1)
+
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(DefaultAvailabilityPollInterval)
+		defer ticker.Stop()
+
+		last := ModelAvailability(-3) // Not a real status, so the first check is always sent
+		for {
+			current := ModelAvailability(ModelUnavailableUnknown)
+			if err := Init(); err == nil || errors.Is(err, ErrUnsupportedPlatform) {
+				current = CheckModelAvailability()
+			}
+			if current != last {
+				logger().Debug("Model availability changed", "from", last, "to", current)
+				select {
+				case out <- current:
+				case <-ctx.Done():
+					return
+				}
+				last = current
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
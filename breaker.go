@@ -0,0 +1,103 @@
+package fm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// Global circuit breaker on model unavailability (zero cooldown = disabled)
+	breakerMu       sync.Mutex
+	breakerCooldown time.Duration
+	breakerOpen     bool
+	breakerReason   error // Why the breaker opened
+	breakerRetry    time.Time
+	breakerEpoch    int // Incremented whenever the breaker is reconfigured, to stop stale probes
+
+	// breakerAvailability, if set, replaces GetModelAvailability in the breaker's probes
+	// for deterministic probing
+	breakerAvailability func() Availability
+)
+
+// SetGlobalCircuitBreaker makes generations fail fast once the model is found to be
+// unavailable, e.g. while its assets update, instead of each one failing slowly. When a
+// generation fails because the model became unavailable, the breaker opens: generations
+// on every session then fail immediately with an error matching ErrCircuitOpen and
+// ErrModelUnavailable. Availability is probed in the background every cooldown, and the
+// breaker closes as soon as the model is available again. Zero or a negative cooldown
+// disables the breaker.
+func SetGlobalCircuitBreaker(cooldown time.Duration) {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+
+	breakerEpoch++
+	breakerOpen = false
+	breakerReason = nil
+	if cooldown <= 0 {
+		logger().Debug("Disabling circuit breaker")
+		breakerCooldown = 0
+		return
+	}
+	logger().Debug("Enabling circuit breaker", "cooldown", cooldown)
+	breakerCooldown = cooldown
+}
+
+// checkCircuitBreaker returns an error matching ErrCircuitOpen if the breaker is open
+func checkCircuitBreaker() error {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	if !breakerOpen {
+		return nil
+	}
+	return fmt.Errorf("%w (next probe in %v): %w", ErrCircuitOpen,
+		time.Until(breakerRetry).Round(time.Second), breakerReason)
+}
+
+// tripCircuitBreaker opens the breaker, if enabled, because reason showed the model to be
+// unavailable, and starts probing availability in the background
+func tripCircuitBreaker(reason error) {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	if breakerCooldown <= 0 || breakerOpen {
+		return
+	}
+
+	logger().Warn("Model unavailable, opening circuit breaker", "cooldown", breakerCooldown, "reason", reason)
+	breakerOpen = true
+	breakerReason = reason
+	breakerRetry = time.Now().Add(breakerCooldown)
+	check := breakerAvailability
+	if check == nil {
+		check = GetModelAvailability
+	}
+	go probeAvailability(breakerEpoch, breakerCooldown, check)
+}
+
+// probeAvailability checks availability with check every cooldown while the breaker is
+// open, and closes it once the model is available. It stops if the breaker is
+// reconfigured.
+func probeAvailability(epoch int, cooldown time.Duration, check func() Availability) {
+	ticker := time.NewTicker(cooldown)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		availability := check()
+
+		breakerMu.Lock()
+		if epoch != breakerEpoch {
+			breakerMu.Unlock()
+			return
+		}
+		if availability.Available() {
+			logger().Info("Model available again, closing circuit breaker")
+			breakerOpen = false
+			breakerReason = nil
+			breakerMu.Unlock()
+			return
+		}
+		breakerRetry = time.Now().Add(cooldown)
+		breakerMu.Unlock()
+		logger().Debug("Model still unavailable, circuit breaker stays open", "availability", availability.String())
+	}
+}
@@ -0,0 +1,93 @@
+package fm
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBreakerAvailability makes the breaker's probes report the model available once
+// available is set, resetting the breaker when the test ends
+func fakeBreakerAvailability(t *testing.T) *atomic.Bool {
+	t.Helper()
+	var available atomic.Bool
+	saved := breakerAvailability
+	breakerAvailability = func() Availability {
+		if available.Load() {
+			return Availability{Status: ModelAvailable}
+		}
+		return Availability{Status: ModelUnavailableNotReady}
+	}
+	t.Cleanup(func() {
+		SetGlobalCircuitBreaker(0)
+		breakerAvailability = saved
+	})
+	return &available
+}
+
+func TestCircuitBreakerTrip(t *testing.T) {
+	reason := ErrModelBecameUnavailable
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		wantOpen bool
+	}{
+		{name: "disabled", cooldown: 0, wantOpen: false},
+		{name: "enabled", cooldown: time.Hour, wantOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeBreakerAvailability(t)
+			SetGlobalCircuitBreaker(tt.cooldown)
+			if err := checkCircuitBreaker(); err != nil {
+				t.Fatalf("checkCircuitBreaker() before tripping = %v, want nil", err)
+			}
+
+			tripCircuitBreaker(reason)
+			// A second trip while open keeps the first reason
+			tripCircuitBreaker(errors.New("other reason"))
+
+			err := checkCircuitBreaker()
+			if !tt.wantOpen {
+				if err != nil {
+					t.Fatalf("checkCircuitBreaker() = %v, want nil", err)
+				}
+				return
+			}
+			for _, target := range []error{ErrCircuitOpen, ErrModelUnavailable, reason} {
+				if !errors.Is(err, target) {
+					t.Errorf("checkCircuitBreaker() = %v, want it to match %v", err, target)
+				}
+			}
+
+			// Reconfiguring closes the breaker
+			SetGlobalCircuitBreaker(tt.cooldown)
+			if err := checkCircuitBreaker(); err != nil {
+				t.Errorf("checkCircuitBreaker() after reconfiguring = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerProbeCloses(t *testing.T) {
+	available := fakeBreakerAvailability(t)
+	const cooldown = 5 * time.Millisecond
+	SetGlobalCircuitBreaker(cooldown)
+	tripCircuitBreaker(ErrModelBecameUnavailable)
+
+	// Probes while the model is still unavailable keep the breaker open
+	time.Sleep(4 * cooldown)
+	if err := checkCircuitBreaker(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("checkCircuitBreaker() while unavailable = %v, want ErrCircuitOpen", err)
+	}
+
+	available.Store(true)
+	deadline := time.Now().Add(time.Second)
+	for checkCircuitBreaker() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("breaker did not close once the model was available")
+		}
+		time.Sleep(cooldown)
+	}
+}
@@ -0,0 +1,156 @@
+package fm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
+
+	"github.com/ebitengine/purego"
+)
+
+var (
+	// Registry of live sessions, held weakly so forgotten sessions can still be collected
+	sessionsMu   sync.Mutex
+	liveSessions = make(map[uintptr]weak.Pointer[Session])
+)
+
+// trackSession adds a newly created session to the live session registry, releasing it
+// if it is collected before Release is called
+func trackSession(s *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	liveSessions[uintptr(s.ptr)] = weak.Make(s)
+	trackAlloc(uintptr(s.ptr), "session")
+	addSessionCleanup(s)
+}
+
+// untrackSession removes a session from the live session registry
+func untrackSession(s *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	delete(liveSessions, uintptr(s.ptr))
+	trackFree(uintptr(s.ptr))
+	s.cleanup.Stop()
+}
+
+// lookupSession returns the live session with the given shim pointer, or nil
+func lookupSession(ptr uintptr) *Session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if ref, ok := liveSessions[ptr]; ok {
+		return ref.Value()
+	}
+	return nil
+}
+
+// Cancel cancels the session's in-flight generation, if any. The pending call returns
+// ErrGenerationCancelled (or an "Error:" chunk from the callback streaming methods).
+// The session remains usable afterwards.
+func (s *Session) Cancel() {
+	if s.ptr == nil {
+		return
+	}
+	generationShim.cancel(s)
+}
+
+// shimCancel cancels the session's in-flight generation in the shim
+func shimCancel(s *Session) {
+	if !shimInitialized {
+		return
+	}
+	if err := shimSymbol(cancelResponse, "CancelResponse"); err != nil {
+		logger().Warn("Cannot cancel generation", "error", err)
+		return
+	}
+	logger().Debug("Cancelling in-flight generation", "ptr", s.ptr)
+	purego.SyscallN(cancelResponse, uintptr(s.ptr))
+}
+
+// CancelAll cancels every in-flight generation across all live sessions and stops all
+// channel-based streams, e.g. during application teardown. Sessions are not released;
+// callers should still Release them once their pending calls have returned.
+func CancelAll() {
+	sessionsMu.Lock()
+	var sessions []*Session
+	for key, ref := range liveSessions {
+		if s := ref.Value(); s != nil {
+			sessions = append(sessions, s)
+		} else {
+			delete(liveSessions, key)
+		}
+	}
+	sessionsMu.Unlock()
+
+	streamsMu.Lock()
+	var stops []func()
+	for _, stream := range streams {
+		stops = append(stops, stream.stop)
+	}
+	streamsMu.Unlock()
+
+	logger().Debug("Cancelling all generations", "sessions", len(sessions), "streams", len(stops))
+
+	for _, stop := range stops {
+		stop()
+	}
+	for _, s := range sessions {
+		s.Cancel()
+	}
+}
+
+// SetDefaultTimeout sets a timeout applied to every blocking generation call on the
+// session (Respond, RespondWithOptions, RespondWithTools and RespondWithStructuredOutput).
+// When it elapses the in-flight generation is cancelled and the call returns an error
+// matching ErrGenerationTimeout. Zero disables the timeout, which is the default.
+func (s *Session) SetDefaultTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultTimeout = d
+}
+
+// DefaultTimeout returns the session's default generation timeout, or 0 if it has none
+func (s *Session) DefaultTimeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultTimeout
+}
+
+// runGeneration runs call (a blocking generation in the shim) once the rate limits and
+// scheduler allow (see waitToStart), cancelling the generation if the session's default timeout
+// elapses first. It returns the timeout if it fired (0 otherwise), or ctx's error if ctx
+// was done before the generation could start.
+func (s *Session) runGeneration(ctx context.Context, call func()) (time.Duration, error) {
+	release, err := s.waitToStart(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	timeout := s.DefaultTimeout()
+	if timeout <= 0 {
+		call()
+		return 0, nil
+	}
+
+	var fired atomic.Bool
+	cancelled := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() {
+		defer close(cancelled)
+		fired.Store(true)
+		logger().Debug("Default timeout elapsed, cancelling generation", "timeout", timeout)
+		s.Cancel()
+	})
+	call()
+	if !timer.Stop() {
+		// The timeout fired as the call returned: wait for its cancel, so it can't reach
+		// the session's next generation
+		<-cancelled
+	}
+
+	if !fired.Load() {
+		return 0, nil
+	}
+	return timeout, nil
+}
@@ -0,0 +1,309 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// newTrackedTestSession returns a test session registered as live, under a pointer that
+// stands in for its shim-side session
+func newTrackedTestSession(t *testing.T) *Session {
+	t.Helper()
+	s := newTestSession()
+	s.ptr = unsafe.Pointer(new(byte))
+	trackSession(s)
+	t.Cleanup(func() { untrackSession(s) })
+	return s
+}
+
+// stubBlockingGeneration replaces the shim's generation calls with a generation that
+// blocks until its session is cancelled and then fails as the shim does. started
+// receives the session of each generation as it begins; cancels counts cancellations.
+func stubBlockingGeneration(t *testing.T) (started <-chan *Session, cancels *atomic.Int32) {
+	t.Helper()
+	oldShim := generationShim
+	t.Cleanup(func() { generationShim = oldShim })
+
+	begun := make(chan *Session, 1)
+	cancels = new(atomic.Int32)
+	var mu sync.Mutex
+	cancelled := make(map[*Session]chan struct{})
+	waitFor := func(s *Session) chan struct{} {
+		mu.Lock()
+		defer mu.Unlock()
+		if cancelled[s] == nil {
+			cancelled[s] = make(chan struct{})
+		}
+		return cancelled[s]
+	}
+
+	generationShim.ready = func() error { return nil }
+	generationShim.respond = func(s *Session, _ generationKind, _, _, _ string) (string, bool) {
+		wait := waitFor(s)
+		begun <- s
+		<-wait
+		return shimErrorMarker + shimErrorPrefix + shimCodeCancelled + " generation was cancelled", true
+	}
+	generationShim.cancel = func(s *Session) {
+		cancels.Add(1)
+		wait := waitFor(s)
+		mu.Lock()
+		defer mu.Unlock()
+		select {
+		case <-wait:
+		default:
+			close(wait)
+		}
+	}
+	return begun, cancels
+}
+
+func TestSessionRegistry(t *testing.T) {
+	tracked := newTrackedTestSession(t)
+	untracked := newTrackedTestSession(t)
+	untrackSession(untracked)
+
+	tests := []struct {
+		name string
+		ptr  uintptr
+		want *Session
+	}{
+		{name: "tracked", ptr: uintptr(tracked.ptr), want: tracked},
+		{name: "untracked", ptr: uintptr(untracked.ptr), want: nil},
+		{name: "unknown", ptr: uintptr(unsafe.Pointer(new(byte))), want: nil},
+		{name: "zero", ptr: 0, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookupSession(tt.ptr); got != tt.want {
+				t.Errorf("lookupSession() = %p, want %p", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancelAllStopsStreams(t *testing.T) {
+	if Init() == nil {
+		t.Skip("would cancel fake pointers in the shim")
+	}
+	newTrackedTestSession(t)
+
+	const count = 3
+	stopped := make([]int, count)
+	var ids []int64
+	streamsMu.Lock()
+	for i := range count {
+		id := nextStreamID.Add(1)
+		ids = append(ids, id)
+		streams[id] = &activeStream{stop: func() { stopped[i]++ }}
+	}
+	streamsMu.Unlock()
+	t.Cleanup(func() {
+		streamsMu.Lock()
+		defer streamsMu.Unlock()
+		for _, id := range ids {
+			delete(streams, id)
+		}
+	})
+
+	CancelAll()
+
+	for i, n := range stopped {
+		if n != 1 {
+			t.Errorf("stream %d stopped %d times, want 1", i, n)
+		}
+	}
+}
+
+func TestCancelAllUnblocksRespond(t *testing.T) {
+	started, cancels := stubBlockingGeneration(t)
+	sess := newTrackedTestSession(t)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := sess.Respond("Write a long story", nil)
+		result <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("generation did not start")
+	}
+	CancelAll()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrGenerationCancelled) {
+			t.Errorf("Respond() error = %v, want ErrGenerationCancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Respond() still blocked after CancelAll")
+	}
+	if cancels.Load() == 0 {
+		t.Error("CancelAll() did not cancel the generation in the shim")
+	}
+}
+
+func TestDefaultTimeoutCancelsInShim(t *testing.T) {
+	started, cancels := stubBlockingGeneration(t)
+	sess := newTrackedTestSession(t)
+	sess.SetDefaultTimeout(20 * time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := sess.Respond("Write a long story", nil)
+		result <- err
+	}()
+	<-started
+	// Changing the timeout mid-generation only affects later calls
+	sess.SetDefaultTimeout(time.Minute)
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrGenerationTimeout) || !strings.Contains(err.Error(), "20ms") {
+			t.Errorf("Respond() error = %v, want ErrGenerationTimeout after 20ms", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Respond() still blocked after the default timeout")
+	}
+	if got := cancels.Load(); got != 1 {
+		t.Errorf("shim cancelled %d times, want 1", got)
+	}
+}
+
+func TestRunGenerationTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		call      time.Duration // How long the generation takes
+		wantFired bool
+	}{
+		{name: "no timeout", timeout: 0, call: 10 * time.Millisecond},
+		{name: "finishes in time", timeout: time.Second, call: 0},
+		{name: "times out", timeout: 5 * time.Millisecond, call: 50 * time.Millisecond, wantFired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			sess.SetDefaultTimeout(tt.timeout)
+
+			called := false
+			fired, err := sess.runGeneration(context.Background(), func() {
+				called = true
+				time.Sleep(tt.call)
+			})
+			if err != nil {
+				t.Fatalf("runGeneration() error = %v", err)
+			}
+			if !called {
+				t.Fatal("runGeneration() did not run the generation")
+			}
+			if (fired > 0) != tt.wantFired {
+				t.Errorf("runGeneration() fired = %v, want fired %v", fired, tt.wantFired)
+			}
+		})
+	}
+}
+
+// blockingLimiter is a rate limiter that never lets a generation start
+type blockingLimiter struct{}
+
+func (blockingLimiter) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunGenerationContextDone(t *testing.T) {
+	sess := newTestSession()
+	sess.limiter = blockingLimiter{}
+	sess.SetDefaultTimeout(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	fired, err := sess.runGeneration(ctx, func() { t.Error("generation ran after ctx was done") })
+	if !errors.Is(err, context.DeadlineExceeded) || fired != 0 {
+		t.Errorf("runGeneration() = %v, %v, want 0, context.DeadlineExceeded", fired, err)
+	}
+}
+
+func TestSessionSettingsConcurrentAccess(t *testing.T) {
+	sess := newTestSession()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			sess.SetDefaultTimeout(time.Duration(i%2) * time.Second)
+			sess.SetContextValidation(i%2 == 0)
+			sess.SetCheckAvailabilityBeforeEachCall(false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			if _, err := sess.runGeneration(context.Background(), func() {}); err != nil {
+				t.Errorf("runGeneration() error = %v", err)
+			}
+			_ = sess.validateContextSize("hello")
+			if err := sess.verifyAvailability(); err != nil {
+				t.Errorf("verifyAvailability() error = %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestSessionRegistryConcurrentAccess(t *testing.T) {
+	if Init() == nil {
+		t.Skip("would cancel fake pointers in the shim")
+	}
+	const workers = 8
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				s := newTestSession()
+				s.ptr = unsafe.Pointer(new(byte))
+				trackSession(s)
+				if got := lookupSession(uintptr(s.ptr)); got != s {
+					t.Errorf("lookupSession() = %p, want %p", got, s)
+				}
+
+				streamsMu.Lock()
+				id := nextStreamID.Add(1)
+				streams[id] = &activeStream{stop: func() {}}
+				streamsMu.Unlock()
+
+				CancelAll()
+
+				streamsMu.Lock()
+				delete(streams, id)
+				streamsMu.Unlock()
+				untrackSession(s)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReleaseCollectedSession(t *testing.T) {
+	if Init() == nil {
+		t.Skip("would release a fake pointer in the shim")
+	}
+	sess := newTrackedTestSession(t)
+
+	releaseCollectedSession(collectedSession{ptr: uintptr(sess.ptr)})
+
+	if got := lookupSession(uintptr(sess.ptr)); got != nil {
+		t.Errorf("lookupSession() = %p after cleanup, want nil", got)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that Foundation Models is ready for use",
+	Long: `Run a self-test that verifies the Swift shim loaded, the model is available,
+and a trivial generation succeeds. Exits non-zero on the first failing step.`,
+	Example: `  # Check readiness
+  found doctor
+
+  # Allow more time for a cold model
+  found doctor --timeout 1m`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		SetupSlog(verbose)
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		err := fm.SelfTest(ctx)
+		if err != nil {
+			var stErr *fm.SelfTestError
+			if errors.As(err, &stErr) {
+				fmt.Fprintf(cmd.OutOrStdout(), "❌ %s: %v\n", stErr.Step, stErr.Err)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "❌ %v\n", err)
+			}
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ Foundation Models is ready (self-test took %v)\n", time.Since(start).Round(time.Millisecond))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().Duration("timeout", 30*time.Second, "Maximum time to wait for the test generation")
+}
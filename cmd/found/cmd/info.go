@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	fm "github.com/blacktop/go-foundationmodels"
 	"github.com/spf13/cobra"
@@ -14,40 +16,60 @@ var infoCmd = &cobra.Command{
 	Long: `Display information about Foundation Models availability on this device,
 including model status, capabilities, and system requirements.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("=== Foundation Models Information ===")
+		out := cmd.OutOrStdout()
+
+		fmt.Fprintln(out, "=== Foundation Models Information ===")
 
 		// Check model availability
-		availability := fm.CheckModelAvailability()
-		fmt.Printf("Model Availability: ")
+		availability := fm.GetModelAvailability()
+		fmt.Fprintf(out, "Model Availability: ")
 
-		switch availability {
+		switch availability.Status {
 		case fm.ModelAvailable:
-			fmt.Println("✅ Available")
+			fmt.Fprintln(out, "✅ Available")
 		case fm.ModelUnavailableAINotEnabled:
-			fmt.Println("❌ Apple Intelligence not enabled")
+			fmt.Fprintln(out, "❌ Apple Intelligence not enabled")
 		case fm.ModelUnavailableNotReady:
-			fmt.Println("⏳ Model not ready")
+			fmt.Fprintln(out, "⏳ Model not ready")
 		case fm.ModelUnavailableDeviceNotEligible:
-			fmt.Println("❌ Device not eligible")
+			fmt.Fprintln(out, "❌ Device not eligible")
 		default:
-			fmt.Printf("❓ Unknown status (%d)\n", availability)
+			fmt.Fprintf(out, "❓ Unknown status (%d)\n", availability.Status)
+		}
+		if availability.Reason != "" {
+			fmt.Fprintf(out, "Reason: %s\n", availability.Reason)
 		}
 
 		// Get detailed model info
-		fmt.Println("\n=== Model Details ===")
-		info := fm.GetModelInfo()
-		fmt.Print(info)
+		fmt.Fprintln(out, "\n=== Model Details ===")
+		if details, err := fm.GetModelDetails(); err != nil {
+			fmt.Fprint(out, fm.GetModelInfo())
+		} else {
+			fmt.Fprintf(out, "OS Version: %s\n", details.OSVersion)
+			fmt.Fprintf(out, "Model: %s\n", details.ModelIdentifier)
+			if details.AdapterIdentifier != "" {
+				fmt.Fprintf(out, "Adapter: %s\n", details.AdapterIdentifier)
+			}
+			fmt.Fprintf(out, "Use Case: %s\n", details.UseCase)
+			fmt.Fprintf(out, "Context Window: %d tokens\n", details.ContextSize)
+			fmt.Fprintf(out, "Supported Languages: %s\n", strings.Join(details.SupportedLanguages, ", "))
+			if locale := os.Getenv("LANG"); locale != "" {
+				if ok, err := fm.SupportsLanguage(locale); err == nil {
+					fmt.Fprintf(out, "Current Locale (%s) Supported: %t\n", locale, ok)
+				}
+			}
+		}
 
 		// System requirements
-		fmt.Println("\n=== System Requirements ===")
-		fmt.Println("• macOS 26 Tahoe or later")
-		fmt.Println("• Apple Intelligence enabled")
-		fmt.Println("• Compatible Apple Silicon device")
-		fmt.Println("• Context window: 4096 tokens")
-
-		if availability != fm.ModelAvailable {
-			fmt.Println("\n⚠️  Foundation Models is not available on this device.")
-			fmt.Println("Please check your macOS version and Apple Intelligence settings.")
+		fmt.Fprintln(out, "\n=== System Requirements ===")
+		fmt.Fprintln(out, "• macOS 26 Tahoe or later")
+		fmt.Fprintln(out, "• Apple Intelligence enabled")
+		fmt.Fprintln(out, "• Compatible Apple Silicon device")
+		fmt.Fprintln(out, "• Context window: 4096 tokens")
+
+		if !availability.Available() {
+			fmt.Fprintln(out, "\n⚠️  Foundation Models is not available on this device.")
+			fmt.Fprintln(out, "Please check your macOS version and Apple Intelligence settings.")
 		}
 	},
 }
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/blacktop/go-foundationmodels/fmmcp"
+	"github.com/spf13/cobra"
+)
+
+// mcpCmd represents the mcp command
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run as an MCP server so MCP clients can use Foundation Models",
+	Long: `Run a Model Context Protocol server that lets MCP clients, such as desktop
+assistants and editors, delegate work to the on-device model. It offers a "generate"
+tool that responds to a prompt and a "summarize" tool that summarizes text, plus the
+calculator and weather tools with --tools.
+
+By default the server speaks MCP over standard input and output, as clients expect of
+local servers they start themselves. With --http it serves MCP's streamable HTTP
+transport instead.`,
+	Example: `  # Add to an MCP client's configuration
+  {"mcpServers": {"foundation-models": {"command": "found", "args": ["mcp"]}}}
+
+  # Serve over HTTP with the calculator and weather tools
+  found mcp --http 127.0.0.1:8090 --tools`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		SetupSlog(verbose)
+
+		httpAddr, _ := cmd.Flags().GetString("http")
+		useTools, _ := cmd.Flags().GetBool("tools")
+
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
+		}
+
+		var opts []fmmcp.ServerOption
+		if useTools {
+			opts = append(opts, fmmcp.WithServerTools(&CalculatorTool{}, &WeatherTool{}))
+		}
+		server := fmmcp.NewServer(opts...)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if httpAddr == "" {
+			if err := server.ServeStdio(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("MCP server failed: %v", err)
+			}
+			return
+		}
+
+		httpServer := &http.Server{
+			Addr:              httpAddr,
+			Handler:           server.HTTPHandler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		slog.Info("Serving MCP over HTTP", "addr", httpAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("MCP server failed: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+
+	mcpCmd.Flags().String("http", "", "Serve streamable HTTP on this address instead of stdio")
+	mcpCmd.Flags().BoolP("tools", "t", false, "Offer the calculator and weather tools")
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/spf13/cobra"
+)
+
+// openAIMessage is a message in the OpenAI chat completions "messages" format
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a function call requested by an assistant message
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall holds the name and JSON arguments of a function call
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// toOpenAIMessages converts transcript entries to OpenAI messages. Tool calls become
+// assistant messages with tool_calls, and tool outputs become tool messages answering
+// the oldest unanswered call to the same tool.
+func toOpenAIMessages(entries []fm.TranscriptEntry) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(entries))
+	pending := make(map[string][]string) // tool name -> unanswered call IDs
+	nextCallID := 1
+
+	for _, entry := range entries {
+		content := entry.Content
+		switch entry.Role {
+		case fm.TranscriptRoleInstructions:
+			messages = append(messages, openAIMessage{Role: "system", Content: &content})
+		case fm.TranscriptRolePrompt:
+			messages = append(messages, openAIMessage{Role: "user", Content: &content})
+		case fm.TranscriptRoleResponse:
+			messages = append(messages, openAIMessage{Role: "assistant", Content: &content})
+		case fm.TranscriptRoleToolCall:
+			id := fmt.Sprintf("call_%d", nextCallID)
+			nextCallID++
+			pending[entry.ToolName] = append(pending[entry.ToolName], id)
+			messages = append(messages, openAIMessage{
+				Role: "assistant",
+				ToolCalls: []openAIToolCall{{
+					ID:       id,
+					Type:     "function",
+					Function: openAIFunctionCall{Name: entry.ToolName, Arguments: content},
+				}},
+			})
+		case fm.TranscriptRoleToolOutput:
+			var id string
+			if ids := pending[entry.ToolName]; len(ids) > 0 {
+				id, pending[entry.ToolName] = ids[0], ids[1:]
+			}
+			messages = append(messages, openAIMessage{
+				Role:       "tool",
+				Name:       entry.ToolName,
+				ToolCallID: id,
+				Content:    &content,
+			})
+		}
+	}
+	return messages
+}
+
+// readOpenAIMessages reads a messages array, either bare or as a request's "messages" member
+func readOpenAIMessages(path string) ([]openAIMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var messages []openAIMessage
+	if err := json.Unmarshal(data, &messages); err == nil {
+		return messages, nil
+	}
+	var request struct {
+		Messages []openAIMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %v", err)
+	}
+	return request.Messages, nil
+}
+
+// seedFromOpenAIMessages splits messages into system instructions and a rendering of the
+// remaining conversation, including tool calls and tool results, as plain text
+func seedFromOpenAIMessages(messages []openAIMessage) (instructions, conversation string) {
+	var system []string
+	var sb strings.Builder
+	for _, msg := range messages {
+		content := ""
+		if msg.Content != nil {
+			content = *msg.Content
+		}
+		switch msg.Role {
+		case "system", "developer":
+			system = append(system, content)
+		case "user":
+			fmt.Fprintf(&sb, "User: %s\n", content)
+		case "assistant":
+			if content != "" {
+				fmt.Fprintf(&sb, "Assistant: %s\n", content)
+			}
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&sb, "Assistant called tool %s with %s\n", call.Function.Name, call.Function.Arguments)
+			}
+		case "tool", "function":
+			fmt.Fprintf(&sb, "Tool result (%s): %s\n", msg.Name, content)
+		}
+	}
+	return strings.Join(system, "\n\n"), sb.String()
+}
+
+// exportOpenAICmd represents the export-openai command
+var exportOpenAICmd = &cobra.Command{
+	Use:   "export-openai <conversation.json>",
+	Short: "Convert a saved conversation to OpenAI messages JSON",
+	Long: `Convert a conversation saved with --save-transcript to an OpenAI-style "messages"
+array. Instructions become system messages, prompts user messages and responses
+assistant messages. Tool calls become assistant messages with tool_calls, and tool
+outputs become tool messages with matching tool_call_id values.`,
+	Example: `  # Save a conversation, then convert it
+  found quest --save-transcript conversation.json "What is Go?"
+  found export-openai conversation.json > messages.json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read conversation: %v", err)
+		}
+		var entries []fm.TranscriptEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			log.Fatalf("Failed to parse conversation: %v", err)
+		}
+
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(toOpenAIMessages(entries)); err != nil {
+			log.Fatalf("Failed to encode messages: %v", err)
+		}
+	},
+}
+
+// importOpenAICmd represents the import-openai command
+var importOpenAICmd = &cobra.Command{
+	Use:   "import-openai <messages.json> <prompt>",
+	Short: "Seed a session from OpenAI messages JSON and continue the conversation",
+	Long: `Seed a session from an OpenAI-style "messages" array (bare or inside a request
+object) and answer a follow-up prompt. System messages become the session's
+instructions; the rest of the conversation, including tool calls and tool results,
+is added as context without generating a response.`,
+	Example: `  found import-openai messages.json "Can you summarize what we discussed?"`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		SetupSlog(verbose)
+
+		messages, err := readOpenAIMessages(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read messages: %v", err)
+		}
+		prompt := args[1]
+
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
+		}
+
+		instructions, conversation := seedFromOpenAIMessages(messages)
+		var sess *fm.Session
+		if instructions != "" {
+			sess = fm.NewSessionWithInstructions(instructions)
+		} else {
+			sess = fm.NewSession()
+		}
+		if sess == nil {
+			log.Fatal("Failed to create session")
+		}
+		defer sess.Release()
+
+		if conversation != "" {
+			if err := sess.AddContext("Previous conversation:\n" + conversation); err != nil {
+				log.Fatalf("Failed to seed conversation: %v", err)
+			}
+		}
+
+		chatUI := NewChatUI(cmd)
+		chatUI.Statusf("📥 Imported %d messages\n", len(messages))
+		chatUI.PrintUserMessage(prompt)
+		chatUI.ShowTypingIndicator()
+		response, err := sess.Respond(prompt, nil)
+		chatUI.HideTypingIndicator()
+		if err != nil {
+			log.Fatalf("Failed to generate response: %v", err)
+		}
+		chatUI.PrintAssistantMessage(response)
+		chatUI.PrintContextUsage(sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
+
+		PrintTranscript(cmd, sess)
+		SaveTranscript(cmd, sess)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportOpenAICmd)
+	rootCmd.AddCommand(importOpenAICmd)
+}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	fm "github.com/blacktop/go-foundationmodels"
 	"github.com/spf13/cobra"
@@ -11,8 +15,11 @@ import (
 var (
 	systemInstructions string
 	jsonOutput         bool
+	jsonIndent         int
 	temperature        float32
 	streamOutput       bool
+	rawOutput          bool
+	sessionID          string
 )
 
 // questCmd represents the quest command
@@ -40,7 +47,15 @@ Supports system instructions and structured JSON output.`,
 
   # Real-time streaming output
   found quest --stream "Write a short story about robots"
-  found quest --stream --json "Analyze this in JSON: 'Hello world'"`,
+  found quest --stream --json "Analyze this in JSON: 'Hello world'"
+
+  # Continue a conversation across invocations
+  found quest --session trip "Plan a weekend in Lisbon"
+  found quest --session trip "Make it cheaper"
+
+  # Only the answer, for scripting
+  found quest --raw "Name a color" > answer.txt
+  found quest --raw --json "List three fruits" | jq .`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		prompt := args[0]
@@ -50,18 +65,41 @@ Supports system instructions and structured JSON output.`,
 		SetupSlog(verbose)
 
 		// Check model availability
-		availability := fm.CheckModelAvailability()
-		if availability != fm.ModelAvailable {
-			log.Fatalf("Foundation Models not available on this device (status: %d)", availability)
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
 		}
 
-		// Create session with or without system instructions
+		// Create chat UI
+		chatUI := NewChatUI(cmd)
+
+		// Resume the saved session, or create one with or without system instructions
 		var sess *fm.Session
-		if systemInstructions != "" {
-			fmt.Printf("System Instructions: %s\n", systemInstructions)
-			sess = fm.NewSessionWithInstructions(systemInstructions)
-		} else {
-			sess = fm.NewSession()
+		var store *fm.SessionStore
+		if sessionID != "" {
+			var err error
+			if store, err = OpenSessionStore(); err != nil {
+				log.Fatalf("Failed to open session store: %v", err)
+			}
+			sess, err = store.Load(sessionID)
+			switch {
+			case err == nil:
+				chatUI.Statusf("Resuming session: %s\n", sessionID)
+				if systemInstructions != "" {
+					chatUI.Statusf("⚠️  Ignoring --system, the saved session keeps its instructions\n")
+				}
+			case errors.Is(err, fm.ErrSessionNotFound):
+				chatUI.Statusf("Starting session: %s\n", sessionID)
+			default:
+				log.Fatalf("Failed to load session %s: %v", sessionID, err)
+			}
+		}
+		if sess == nil {
+			if systemInstructions != "" {
+				chatUI.Statusf("System Instructions: %s\n", systemInstructions)
+				sess = fm.NewSessionWithInstructions(systemInstructions)
+			} else {
+				sess = fm.NewSession()
+			}
 		}
 
 		if sess == nil {
@@ -69,44 +107,58 @@ Supports system instructions and structured JSON output.`,
 		}
 		defer sess.Release()
 
+		if jsonOutput && jsonIndent > 0 {
+			sess.SetJSONIndent(strings.Repeat(" ", jsonIndent))
+		}
+
 		// Show initial context if using system instructions
 		if systemInstructions != "" {
-			fmt.Printf("Initial Context: %d/%d tokens\n", sess.GetContextSize(), sess.GetMaxContextSize())
+			chatUI.Statusf("Initial Context: %d/%d tokens\n", sess.GetContextSize(), sess.GetMaxContextSize())
 		}
 
-		fmt.Printf("\nPrompt: %s\n", prompt)
+		chatUI.Statusf("\nPrompt: %s\n", prompt)
 
 		// Prepare generation options
 		var options *fm.GenerationOptions
 		if temperature > 0 {
-			fmt.Printf("Temperature: %.2f\n", temperature)
+			chatUI.Statusf("Temperature: %.2f\n", temperature)
 			options = &fm.GenerationOptions{
 				Temperature: &temperature,
 			}
 		}
 
-		// Create chat UI
-		chatUI := NewChatUI()
-
 		// Display user question
 		chatUI.PrintUserMessage(prompt)
 
 		// Generate response
 		if streamOutput {
-			fmt.Println("Mode: Real-time streaming")
+			chatUI.Statusf("Mode: Real-time streaming\n")
 
 			// Use streaming for real-time output
 			callback := func(chunk string, isLast bool) {
 				if chunk != "" {
-					fmt.Print(chunk)
+					fmt.Fprint(chatUI.Out(), chunk)
 				}
 				if isLast {
-					fmt.Println() // Final newline
+					fmt.Fprintln(chatUI.Out()) // Final newline
 				}
 			}
 
 			if jsonOutput {
-				sess.RespondWithStreaming(prompt+" (respond in structured JSON format)", callback)
+				// Render partial JSON live as UI, then print the validated (repaired) object
+				chunks, stop := sess.StreamStructuredOutput(prompt)
+				defer stop()
+				for chunk := range chunks {
+					chatUI.Statusf("%s", chunk.Text)
+					if !chunk.Done {
+						continue
+					}
+					chatUI.Statusf("\n")
+					if chunk.Err != nil {
+						log.Fatalf("Structured streaming failed: %v", chunk.Err)
+					}
+					fmt.Fprintln(chatUI.Out(), chunk.JSON)
+				}
 			} else {
 				sess.RespondWithStreaming(prompt, callback)
 			}
@@ -116,23 +168,59 @@ Supports system instructions and structured JSON output.`,
 
 			// Use traditional blocking response (which uses streaming internally)
 			var response string
+			var detailed *fm.Response
+			var err error
 			if jsonOutput {
-				fmt.Println("Output Format: JSON")
-				response = sess.RespondWithStructuredOutput(prompt)
+				chatUI.Statusf("Output Format: JSON\n")
+				response, err = sess.RespondWithStructuredOutput(prompt)
+			} else if verbose {
+				// Report token counts and timing after the response
+				detailed, err = sess.RespondDetailed(context.Background(), prompt, options)
+				if err == nil {
+					response = detailed.Text
+				}
 			} else {
-				response = sess.Respond(prompt, options)
+				response, err = sess.Respond(prompt, options)
 			}
 
 			// Hide typing indicator and display assistant response
 			chatUI.HideTypingIndicator()
-			chatUI.PrintAssistantMessage(response)
+			if err != nil {
+				log.Fatalf("Failed to generate response: %v", err)
+			}
+			if jsonOutput {
+				// Print JSON as-is so the chat bubble doesn't re-wrap the indentation
+				fmt.Fprintln(chatUI.Out(), response)
+			} else {
+				chatUI.PrintAssistantMessage(response)
+			}
+			if detailed != nil {
+				chatUI.Statusf("Tokens: %d prompt + %d completion, first token %v, total %v, finish reason: %s\n",
+					detailed.PromptTokens, detailed.CompletionTokens,
+					detailed.FirstTokenLatency.Round(time.Millisecond), detailed.Duration.Round(time.Millisecond),
+					detailed.FinishReason)
+			}
 		}
 
 		// Show final context usage
 		chatUI.PrintContextUsage(sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
 
 		if sess.IsContextNearLimit() {
-			fmt.Println("⚠️  Context is near the limit - consider shorter prompts")
+			chatUI.Statusf("⚠️  Context is near the limit - consider shorter prompts\n")
+		}
+
+		if verbose {
+			PrintContextDrift(cmd, sess)
+		}
+
+		// Print the model's view of the session if --transcript flag is set
+		PrintTranscript(cmd, sess)
+		SaveTranscript(cmd, sess)
+
+		if store != nil {
+			if err := store.Save(sessionID, sess); err != nil {
+				log.Fatalf("Failed to save session %s: %v", sessionID, err)
+			}
 		}
 	},
 }
@@ -143,6 +231,9 @@ func init() {
 	// Add flags
 	questCmd.Flags().StringVarP(&systemInstructions, "system", "s", "", "System instructions for the model")
 	questCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output structured JSON response")
+	questCmd.Flags().IntVar(&jsonIndent, "indent", 2, "Spaces to indent --json output (0 prints the model output as-is)")
 	questCmd.Flags().Float32VarP(&temperature, "temp", "t", 0, "Temperature for generation (0.0=deterministic, 1.0=creative)")
 	questCmd.Flags().BoolVarP(&streamOutput, "stream", "", false, "Show real-time streaming output")
+	questCmd.Flags().BoolVar(&rawOutput, "raw", false, "Print only the response text (no chat bubbles, emoji or context usage)")
+	questCmd.Flags().StringVar(&sessionID, "session", "", "Continue the conversation saved under this ID, saving it again afterwards")
 }
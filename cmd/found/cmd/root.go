@@ -35,6 +35,8 @@ func init() {
 
 	// Add global flags that all subcommands can inherit
 	rootCmd.PersistentFlags().BoolP("verbose", "V", false, "Show debug logs (both Go and Swift)")
+	rootCmd.PersistentFlags().Bool("transcript", false, "Print the model's transcript of the session after generation")
+	rootCmd.PersistentFlags().String("save-transcript", "", "Save the session's transcript as a JSON conversation file")
 
 	// Settings
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/blacktop/go-foundationmodels/fmprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+// serveModelID is the model name reported and accepted by the server
+const serveModelID = "apple-foundation-model"
+
+// maxRequestBytes bounds the size of a request body
+const maxRequestBytes = 10 << 20
+
+// sessionPool hands out sessions to requests, limiting how many generate at once.
+// Sessions are created on first use and reused; each request replaces the transcript
+// and tools of the session it gets.
+type sessionPool struct {
+	sessions chan *fm.Session // nil entries are slots without a session yet
+}
+
+// newSessionPool creates a pool of size sessions
+func newSessionPool(size int) *sessionPool {
+	p := &sessionPool{sessions: make(chan *fm.Session, size)}
+	for range size {
+		p.sessions <- nil
+	}
+	return p
+}
+
+// acquire waits for a free session, creating one if needed
+func (p *sessionPool) acquire(ctx context.Context) (*fm.Session, error) {
+	select {
+	case sess := <-p.sessions:
+		if sess != nil {
+			return sess, nil
+		}
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			p.sessions <- nil
+			return nil, err
+		}
+		if sess = fm.NewSession(); sess == nil {
+			p.sessions <- nil
+			return nil, fm.ErrInvalidSession
+		}
+		return sess, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release returns sess to the pool without the tools of the request that used it
+func (p *sessionPool) release(sess *fm.Session) {
+	if err := sess.ClearTools(); err != nil {
+		slog.Warn("Discarding pooled session", "error", err)
+		sess.Release()
+		sess = nil
+	}
+	p.sessions <- sess
+}
+
+// close releases the pooled sessions, waiting for those in use to be returned
+func (p *sessionPool) close() {
+	for range cap(p.sessions) {
+		if sess := <-p.sessions; sess != nil {
+			sess.Release()
+		}
+	}
+}
+
+// server serves the model over HTTP
+type server struct {
+	pool    *sessionPool
+	tools   bool // Offer the calculator and weather tools in WebSocket chats
+	metrics bool // Serve Prometheus metrics on /metrics
+}
+
+// handler returns the server's routes
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+	mux.HandleFunc("GET /{$}", s.handleOllamaRoot)
+	mux.HandleFunc("GET /api/version", s.handleOllamaVersion)
+	mux.HandleFunc("GET /api/tags", s.handleOllamaTags)
+	mux.HandleFunc("POST /api/show", s.handleOllamaShow)
+	mux.HandleFunc("POST /api/generate", s.handleOllamaGenerate)
+	mux.HandleFunc("POST /api/chat", s.handleOllamaChat)
+	if s.metrics {
+		mux.Handle("GET /metrics", promhttp.Handler())
+	}
+	return mux
+}
+
+// decodeRequest decodes a JSON request body into v
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v)
+}
+
+// writeJSON writes v as a JSON response with status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+	}
+}
+
+// errorStatus returns the HTTP status for a generation error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, fm.ErrModelUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, fm.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, fm.ErrContextLimit), errors.Is(err, fm.ErrContextExceeded):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// newID returns a random identifier with prefix, such as "chatcmpl-3f2a..."
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Foundation Models over OpenAI- and Ollama-compatible HTTP APIs or gRPC",
+	Long: `Serve the on-device model over HTTP with OpenAI-compatible /v1/chat/completions
+and /v1/models endpoints, so OpenAI SDKs and apps can use it by changing their base URL.
+
+Each request is answered from a pool of sessions: system messages become the
+instructions and earlier messages the conversation. Tools in the request are offered
+to the model; when it calls one, the response has finish_reason "tool_calls" for the
+client to run the tool and send the result, as with OpenAI. Requests with
+"stream": true are answered with server-sent events as the model generates.
+
+Ollama's /api/generate and /api/chat, with /api/tags, /api/show and /api/version, are
+served in Ollama's wire format for editors and chat frontends that speak it. As in
+Ollama, responses are streamed as newline-delimited JSON unless "stream" is false.
+
+The /ws WebSocket endpoint keeps one session per connection for multi-turn chat UIs.
+Send {"type": "prompt", "content": "..."} to ask and {"type": "cancel"} to stop; the
+server replies with typed JSON messages: "delta" text, "tool_call" and "tool_result"
+events, and "done" when the response is complete. The "system" query parameter sets
+the session's instructions.
+
+With --metrics, Prometheus metrics from the fmprom module are served on /metrics:
+requests, tokens, latency, tool invocations, guardrail refusals and scheduler queue
+depth.
+
+With --grpc the model is served over gRPC instead, with the foundationmodels.v1 service
+from the fmgrpc module: clients create sessions, generate responses whole or streamed,
+and register tools they implement themselves. The address may be a Unix socket such as
+unix:///tmp/found.sock for local IPC.`,
+	Example: `  # Serve on the default address
+  found serve
+
+  # Use it from any OpenAI client
+  curl http://localhost:8080/v1/chat/completions -d '{
+    "model": "apple-foundation-model",
+    "messages": [{"role": "user", "content": "Hello!"}]
+  }'
+
+  # Stream the response token by token
+  curl -N http://localhost:8080/v1/chat/completions -d '{
+    "model": "apple-foundation-model",
+    "stream": true,
+    "messages": [{"role": "user", "content": "Write a haiku"}]
+  }'
+
+  # Use it from Ollama clients (set OLLAMA_HOST=http://localhost:8080)
+  curl http://localhost:8080/api/chat -d '{
+    "model": "apple-foundation-model",
+    "messages": [{"role": "user", "content": "Why is the sky blue?"}]
+  }'
+
+  # Chat over a WebSocket with the calculator and weather tools
+  found serve --tools
+  websocat 'ws://localhost:8080/ws?system=Be%20brief'
+
+  # Expose Prometheus metrics
+  found serve --metrics
+  curl http://localhost:8080/metrics
+
+  # Serve gRPC on a Unix socket
+  found serve --grpc --addr unix:///tmp/found.sock
+
+  # Allow two generations at once
+  found serve --addr :9000 --sessions 2`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		SetupSlog(verbose)
+
+		addr, _ := cmd.Flags().GetString("addr")
+		sessions, _ := cmd.Flags().GetInt("sessions")
+		useTools, _ := cmd.Flags().GetBool("tools")
+		useGRPC, _ := cmd.Flags().GetBool("grpc")
+		useMetrics, _ := cmd.Flags().GetBool("metrics")
+		if sessions < 1 {
+			log.Fatal("--sessions must be at least 1")
+		}
+
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if useGRPC {
+			slog.Info("Serving Foundation Models over gRPC", "addr", addr)
+			if err := serveGRPC(ctx, addr); err != nil {
+				log.Fatalf("Server failed: %v", err)
+			}
+			return
+		}
+
+		if useMetrics {
+			metrics, err := fmprom.New(prometheus.DefaultRegisterer)
+			if err != nil {
+				log.Fatalf("Failed to register metrics: %v", err)
+			}
+			fm.SetGlobalTelemetry(metrics)
+		}
+
+		s := &server{pool: newSessionPool(sessions), tools: useTools, metrics: useMetrics}
+		defer s.pool.close()
+
+		httpServer := &http.Server{
+			Addr:              addr,
+			Handler:           s.handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		slog.Info("Serving Foundation Models", "addr", addr, "sessions", sessions)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().Int("sessions", 1, "Number of pooled sessions, i.e. how many requests generate at once")
+	serveCmd.Flags().BoolP("tools", "t", false, "Enable calculator and weather tools in WebSocket chats")
+	serveCmd.Flags().Bool("grpc", false, "Serve the foundationmodels.v1 gRPC service instead of HTTP")
+	serveCmd.Flags().Bool("metrics", false, "Serve Prometheus metrics on /metrics")
+	serveCmd.MarkFlagsMutuallyExclusive("grpc", "metrics")
+}
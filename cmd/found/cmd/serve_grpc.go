@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/blacktop/go-foundationmodels/fmgrpc"
+	"google.golang.org/grpc"
+)
+
+// serveGRPC serves the foundationmodels.v1 gRPC service on addr until ctx is done
+func serveGRPC(ctx context.Context, addr string) error {
+	lis, err := listen(addr)
+	if err != nil {
+		return err
+	}
+
+	srv := fmgrpc.NewServer()
+	defer srv.Close()
+
+	grpcServer := grpc.NewServer()
+	srv.Register(grpcServer)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+	return grpcServer.Serve(lis)
+}
+
+// listen listens on a TCP address, or on a Unix socket for addresses such as
+// "unix:///tmp/found.sock", removing a stale socket file first
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	path = strings.TrimPrefix(path, "//")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,544 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+)
+
+// ollamaVersion is the Ollama version reported to clients, some of which check it
+// before using newer API features
+const ollamaVersion = "0.9.0"
+
+// ollamaModelName is the model as listed by /api/tags, with Ollama's default tag
+const ollamaModelName = serveModelID + ":latest"
+
+// ollamaOptions are the model parameters of an Ollama request
+type ollamaOptions struct {
+	Temperature      *float32 `json:"temperature"`
+	TopP             *float32 `json:"top_p"`
+	TopK             *int     `json:"top_k"`
+	NumPredict       *int     `json:"num_predict"`
+	Stop             []string `json:"stop"`
+	Seed             *int     `json:"seed"`
+	PresencePenalty  *float32 `json:"presence_penalty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty"`
+}
+
+// ollamaGenerateRequest is an Ollama /api/generate request
+type ollamaGenerateRequest struct {
+	Model   string          `json:"model"`
+	Prompt  string          `json:"prompt"`
+	System  string          `json:"system"`
+	Images  []string        `json:"images"`
+	Format  json.RawMessage `json:"format"`
+	Stream  *bool           `json:"stream"`
+	Options ollamaOptions   `json:"options"`
+}
+
+// ollamaChatRequest is an Ollama /api/chat request
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools"`
+	Format   json.RawMessage `json:"format"`
+	Stream   *bool           `json:"stream"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaMessage is a chat message
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall is a function call requested by an assistant message
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+// ollamaFunctionCall holds the name and arguments of a function call. Unlike OpenAI's,
+// the arguments are an object rather than a JSON string.
+type ollamaFunctionCall struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// ollamaMetrics are the statistics on the final value of a response, with durations
+// in nanoseconds
+type ollamaMetrics struct {
+	DoneReason         string `json:"done_reason,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+}
+
+// ollamaGenerateResponse is an /api/generate response, or one value of a streamed one
+type ollamaGenerateResponse struct {
+	Model     string `json:"model"`
+	CreatedAt string `json:"created_at"`
+	Response  string `json:"response"`
+	Done      bool   `json:"done"`
+	ollamaMetrics
+}
+
+// ollamaChatResponse is an /api/chat response, or one value of a streamed one
+type ollamaChatResponse struct {
+	Model     string        `json:"model"`
+	CreatedAt string        `json:"created_at"`
+	Message   ollamaMessage `json:"message"`
+	Done      bool          `json:"done"`
+	ollamaMetrics
+}
+
+// ollamaModel is an entry of the /api/tags list
+type ollamaModel struct {
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	ModifiedAt string             `json:"modified_at"`
+	Size       int64              `json:"size"`
+	Digest     string             `json:"digest"`
+	Details    ollamaModelDetails `json:"details"`
+}
+
+// ollamaModelDetails describes a model
+type ollamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ollamaFormat is a request's output format: any JSON value, or one matching a schema
+type ollamaFormat struct {
+	json   bool
+	schema *fm.GenerationSchema
+}
+
+// parseOllamaFormat parses the "format" member, which is "json" or a JSON Schema
+func parseOllamaFormat(raw json.RawMessage) (ollamaFormat, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == `""` {
+		return ollamaFormat{}, nil
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		if name != "json" {
+			return ollamaFormat{}, fmt.Errorf("unsupported format %q", name)
+		}
+		return ollamaFormat{json: true}, nil
+	}
+	schema, err := fm.SchemaFromJSON(raw)
+	if err != nil {
+		return ollamaFormat{}, fmt.Errorf("unsupported format schema: %w", err)
+	}
+	return ollamaFormat{json: true, schema: schema}, nil
+}
+
+// ndjsonWriter writes newline-delimited JSON, flushing each value so clients see it
+// immediately. The response starts with the first value.
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+// send writes v as a line
+func (n *ndjsonWriter) send(v any) {
+	if !n.started {
+		n.w.Header().Set("Content-Type", "application/x-ndjson")
+		n.w.WriteHeader(http.StatusOK)
+		n.started = true
+	}
+	if err := json.NewEncoder(n.w).Encode(v); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+		return
+	}
+	if flusher, ok := n.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeOllamaError writes an Ollama-format error response
+func writeOllamaError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// handleOllamaRoot answers the liveness check clients make before using the API
+func (s *server) handleOllamaRoot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("Ollama is running"))
+}
+
+// handleOllamaVersion reports the Ollama API version
+func (s *server) handleOllamaVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": ollamaVersion})
+}
+
+// handleOllamaTags lists the on-device model
+func (s *server) handleOllamaTags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models": []ollamaModel{{
+			Name:       ollamaModelName,
+			Model:      ollamaModelName,
+			ModifiedAt: time.Now().UTC().Format(time.RFC3339),
+			Details:    ollamaModelDetails{Family: serveModelID, Families: []string{serveModelID}},
+		}},
+	})
+}
+
+// handleOllamaShow describes the on-device model, including its context length
+func (s *server) handleOllamaShow(w http.ResponseWriter, r *http.Request) {
+	contextSize := fm.MAX_CONTEXT_SIZE
+	if info, err := fm.GetModelDetails(); err == nil {
+		contextSize = info.ContextSize
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"details":      ollamaModelDetails{Family: serveModelID, Families: []string{serveModelID}},
+		"model_info":   map[string]any{"general.architecture": serveModelID, serveModelID + ".context_length": contextSize},
+		"capabilities": []string{"completion", "tools"},
+		"modified_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// handleOllamaGenerate answers an /api/generate request. An empty prompt only loads the
+// model in Ollama, so it is answered with done_reason "load".
+func (s *server) handleOllamaGenerate(w http.ResponseWriter, r *http.Request) {
+	var req ollamaGenerateRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	model := ollamaResponseModel(req.Model)
+	if req.Prompt == "" {
+		writeJSON(w, http.StatusOK, ollamaGenerateResponse{
+			Model:         model,
+			CreatedAt:     ollamaTimestamp(),
+			Done:          true,
+			ollamaMetrics: ollamaMetrics{DoneReason: "load"},
+		})
+		return
+	}
+	if len(req.Images) > 0 {
+		writeOllamaError(w, http.StatusBadRequest, errors.New("images are not supported"))
+		return
+	}
+	format, err := parseOllamaFormat(req.Format)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var entries []fm.TranscriptEntry
+	if req.System != "" {
+		entries = []fm.TranscriptEntry{{Role: fm.TranscriptRoleInstructions, Content: req.System}}
+	}
+
+	sess, err := s.pool.acquire(r.Context())
+	if err != nil {
+		writeOllamaError(w, errorStatus(err), err)
+		return
+	}
+	defer s.pool.release(sess)
+	if err := sess.SetTranscript(entries); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	out := &ndjsonWriter{w: w}
+	stream := ollamaStreaming(req.Stream)
+	emit := func(text string) {
+		if stream {
+			out.send(ollamaGenerateResponse{Model: model, CreatedAt: ollamaTimestamp(), Response: text})
+		}
+	}
+	text, metrics, err := ollamaGenerate(r.Context(), sess, req.Prompt, req.Options.generationOptions(), format, emit)
+	if err != nil {
+		writeOllamaStreamError(w, out, err)
+		return
+	}
+
+	final := ollamaGenerateResponse{Model: model, CreatedAt: ollamaTimestamp(), Done: true, ollamaMetrics: metrics}
+	if !stream {
+		final.Response = text
+		writeJSON(w, http.StatusOK, final)
+		return
+	}
+	out.send(final)
+}
+
+// handleOllamaChat answers an /api/chat request. Tools in the request are offered to
+// the model; when it calls one, the response has the calls for the client to run, as
+// with Ollama.
+func (s *server) handleOllamaChat(w http.ResponseWriter, r *http.Request) {
+	var req ollamaChatRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	model := ollamaResponseModel(req.Model)
+	if len(req.Messages) == 0 {
+		writeJSON(w, http.StatusOK, ollamaChatResponse{
+			Model:         model,
+			CreatedAt:     ollamaTimestamp(),
+			Message:       ollamaMessage{Role: "assistant"},
+			Done:          true,
+			ollamaMetrics: ollamaMetrics{DoneReason: "load"},
+		})
+		return
+	}
+	messages, err := openAIMessagesFromOllama(req.Messages)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, prompt, err := transcriptFromOpenAIMessages(messages)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+	format, err := parseOllamaFormat(req.Format)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sess, err := s.pool.acquire(r.Context())
+	if err != nil {
+		writeOllamaError(w, errorStatus(err), err)
+		return
+	}
+	defer s.pool.release(sess)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	calls := &toolCallRecorder{cancel: cancel}
+	if err := prepareSession(sess, entries, req.Tools, calls); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	out := &ndjsonWriter{w: w}
+	stream := ollamaStreaming(req.Stream)
+	emit := func(text string) {
+		if stream {
+			out.send(ollamaChatResponse{
+				Model:     model,
+				CreatedAt: ollamaTimestamp(),
+				Message:   ollamaMessage{Role: "assistant", Content: text},
+			})
+		}
+	}
+	text, metrics, err := ollamaGenerate(ctx, sess, prompt, req.Options.generationOptions(), format, emit)
+
+	final := ollamaChatResponse{
+		Model:     model,
+		CreatedAt: ollamaTimestamp(),
+		Message:   ollamaMessage{Role: "assistant"},
+		Done:      true,
+	}
+	switch {
+	case calls.len() > 0:
+		final.Message.ToolCalls = ollamaToolCalls(calls.list())
+		final.DoneReason = "stop"
+	case err != nil:
+		writeOllamaStreamError(w, out, err)
+		return
+	default:
+		final.ollamaMetrics = metrics
+	}
+	if !stream {
+		final.Message.Content = text
+		writeJSON(w, http.StatusOK, final)
+		return
+	}
+	out.send(final)
+}
+
+// writeOllamaStreamError reports a generation error: as an error response if nothing
+// was streamed yet, and otherwise as a final {"error": ...} line, as Ollama does
+func writeOllamaStreamError(w http.ResponseWriter, out *ndjsonWriter, err error) {
+	if !out.started {
+		writeOllamaError(w, errorStatus(err), err)
+		return
+	}
+	out.send(map[string]string{"error": err.Error()})
+}
+
+// ollamaGenerate generates the response to prompt, passing text to emit as it is
+// generated, and returns it with its statistics. Requests with a format generate the
+// whole JSON value, which is emitted at once.
+func ollamaGenerate(ctx context.Context, sess *fm.Session, prompt string, options *fm.GenerationOptions, format ollamaFormat, emit func(string)) (string, ollamaMetrics, error) {
+	start := time.Now()
+	var firstText time.Time
+	var text strings.Builder
+	add := func(chunk string) {
+		if chunk == "" {
+			return
+		}
+		if firstText.IsZero() {
+			firstText = time.Now()
+		}
+		text.WriteString(chunk)
+		emit(chunk)
+	}
+
+	reason := fm.FinishStop
+	if format.json {
+		response, err := respondOllamaJSON(ctx, sess, prompt, format)
+		if err != nil {
+			return "", ollamaMetrics{}, err
+		}
+		add(response)
+	} else {
+		stream, err := sess.RespondStream(ctx, prompt, options)
+		if err != nil {
+			return "", ollamaMetrics{}, err
+		}
+		for chunk := range stream {
+			add(chunk.Text)
+			if chunk.Done {
+				if chunk.Err != nil {
+					return text.String(), ollamaMetrics{}, chunk.Err
+				}
+				reason = chunk.FinishReason
+			}
+		}
+	}
+
+	end := time.Now()
+	if firstText.IsZero() {
+		firstText = end
+	}
+	doneReason := "stop"
+	if reason == fm.FinishLength {
+		doneReason = "length"
+	}
+	return text.String(), ollamaMetrics{
+		DoneReason:         doneReason,
+		TotalDuration:      end.Sub(start).Nanoseconds(),
+		PromptEvalCount:    ollamaTokenCount(prompt),
+		PromptEvalDuration: firstText.Sub(start).Nanoseconds(),
+		EvalCount:          ollamaTokenCount(text.String()),
+		EvalDuration:       end.Sub(firstText).Nanoseconds(),
+	}, nil
+}
+
+// respondOllamaJSON generates a JSON response, constrained to the format's schema if it
+// has one
+func respondOllamaJSON(ctx context.Context, sess *fm.Session, prompt string, format ollamaFormat) (string, error) {
+	if format.schema == nil {
+		return sess.RespondWithStructuredOutputContext(ctx, prompt)
+	}
+	stream, err := sess.RespondWithSchemaStream(ctx, prompt, format.schema)
+	if err != nil {
+		return "", err
+	}
+	var response string
+	for chunk := range stream {
+		if chunk.Done {
+			if chunk.Err != nil {
+				return "", chunk.Err
+			}
+			response = chunk.JSON
+		}
+	}
+	return response, nil
+}
+
+// generationOptions maps the request's model parameters to generation options. A
+// negative num_predict means no limit, as in Ollama.
+func (o *ollamaOptions) generationOptions() *fm.GenerationOptions {
+	options := &fm.GenerationOptions{
+		Temperature:      o.Temperature,
+		TopP:             o.TopP,
+		TopK:             o.TopK,
+		PresencePenalty:  o.PresencePenalty,
+		FrequencyPenalty: o.FrequencyPenalty,
+		StopSequences:    o.Stop,
+		Seed:             o.Seed,
+	}
+	if o.NumPredict != nil && *o.NumPredict >= 0 {
+		options.MaxTokens = o.NumPredict
+	}
+	return options
+}
+
+// openAIMessagesFromOllama converts Ollama chat messages to OpenAI's, which the
+// transcript is built from
+func openAIMessagesFromOllama(messages []ollamaMessage) ([]openAIMessage, error) {
+	converted := make([]openAIMessage, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.Images) > 0 {
+			return nil, errors.New("images are not supported")
+		}
+		content := msg.Content
+		m := openAIMessage{Role: msg.Role, Content: &content, Name: msg.ToolName}
+		for _, call := range msg.ToolCalls {
+			args, err := json.Marshal(call.Function.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %w", call.Function.Name, err)
+			}
+			m.ToolCalls = append(m.ToolCalls, openAIToolCall{
+				ID:       newID("call_"),
+				Type:     "function",
+				Function: openAIFunctionCall{Name: call.Function.Name, Arguments: string(args)},
+			})
+		}
+		converted = append(converted, m)
+	}
+	return converted, nil
+}
+
+// ollamaToolCalls converts recorded tool calls to Ollama's, with object arguments
+func ollamaToolCalls(calls []openAIToolCall) []ollamaToolCall {
+	converted := make([]ollamaToolCall, 0, len(calls))
+	for _, call := range calls {
+		var args map[string]any
+		json.Unmarshal([]byte(call.Function.Arguments), &args)
+		converted = append(converted, ollamaToolCall{
+			Function: ollamaFunctionCall{Name: call.Function.Name, Arguments: args},
+		})
+	}
+	return converted
+}
+
+// ollamaStreaming reports whether a response is streamed; Ollama streams by default
+func ollamaStreaming(stream *bool) bool {
+	return stream == nil || *stream
+}
+
+// ollamaResponseModel returns the model name to echo in responses
+func ollamaResponseModel(requested string) string {
+	if requested == "" {
+		return ollamaModelName
+	}
+	return requested
+}
+
+// ollamaTimestamp returns the current time in the format of "created_at"
+func ollamaTimestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// ollamaTokenCount counts the tokens in text, estimating if the tokenizer is unavailable
+func ollamaTokenCount(text string) int {
+	count, err := fm.CountTokens(text)
+	if err != nil {
+		return len(text) / 4
+	}
+	return count
+}
@@ -0,0 +1,506 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+)
+
+// toolResultsPrompt asks the model to continue when a conversation ends with tool results
+// rather than a user message
+const toolResultsPrompt = "Answer using the results of the tool calls above."
+
+// chatCompletionRequest is an OpenAI chat completions request
+type chatCompletionRequest struct {
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	Stream              bool            `json:"stream"`
+	MaxTokens           *int            `json:"max_tokens"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens"`
+	Temperature         *float32        `json:"temperature"`
+	TopP                *float32        `json:"top_p"`
+	PresencePenalty     *float32        `json:"presence_penalty"`
+	FrequencyPenalty    *float32        `json:"frequency_penalty"`
+	Stop                stopSequences   `json:"stop"`
+	Seed                *int            `json:"seed"`
+	Tools               []openAITool    `json:"tools"`
+}
+
+// stopSequences is the "stop" member, which is either a string or an array of them
+type stopSequences []string
+
+// UnmarshalJSON accepts a single stop sequence or an array of them
+func (s *stopSequences) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = stopSequences{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
+
+// openAITool is a tool the client offers the model
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIFunctionSpec `json:"function"`
+}
+
+// openAIFunctionSpec defines a function tool
+type openAIFunctionSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// chatCompletionResponse is an OpenAI chat completions response
+type chatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []chatCompletionChoice `json:"choices"`
+	Usage   *chatCompletionUsage   `json:"usage,omitempty"`
+}
+
+// chatCompletionChoice is a generated message
+type chatCompletionChoice struct {
+	Index        int           `json:"index"`
+	Message      openAIMessage `json:"message"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+// chatCompletionChunk is one server-sent event of a streamed chat completion
+type chatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []chatCompletionChunkChoice `json:"choices"`
+}
+
+// chatCompletionChunkChoice is the change to a message in a chunk
+type chatCompletionChunkChoice struct {
+	Index        int                 `json:"index"`
+	Delta        chatCompletionDelta `json:"delta"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+// chatCompletionDelta is the text or tool calls added to a message by a chunk
+type chatCompletionDelta struct {
+	Role      string                `json:"role,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	ToolCalls []openAIToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// openAIToolCallDelta is a tool call in a chunk, identified by its position
+type openAIToolCallDelta struct {
+	Index int `json:"index"`
+	openAIToolCall
+}
+
+// chatCompletionUsage reports estimated token counts
+type chatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// openAIModel is an entry of the /v1/models list
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// openAIError is the body of an error response
+type openAIError struct {
+	Error openAIErrorDetail `json:"error"`
+}
+
+// openAIErrorDetail describes an error
+type openAIErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// writeOpenAIError writes an OpenAI-format error response
+func writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	detail := openAIErrorDetail{Message: err.Error(), Type: "server_error"}
+	switch {
+	case status == http.StatusBadRequest:
+		detail.Type = "invalid_request_error"
+	case status == http.StatusTooManyRequests:
+		detail.Type = "rate_limit_error"
+	}
+	if errors.Is(err, fm.ErrContextLimit) || errors.Is(err, fm.ErrContextExceeded) {
+		detail.Code = "context_length_exceeded"
+	}
+	writeJSON(w, status, openAIError{Error: detail})
+}
+
+// handleModels lists the on-device model
+func (s *server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data":   []openAIModel{{ID: serveModelID, Object: "model", OwnedBy: "apple"}},
+	})
+}
+
+// handleChatCompletions answers a chat completions request
+func (s *server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	entries, prompt, err := transcriptFromOpenAIMessages(req.Messages)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sess, err := s.pool.acquire(r.Context())
+	if err != nil {
+		writeOpenAIError(w, errorStatus(err), err)
+		return
+	}
+	defer s.pool.release(sess)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	calls := &toolCallRecorder{cancel: cancel}
+	if err := prepareSession(sess, entries, req.Tools, calls); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Stream {
+		streamChatCompletion(ctx, w, sess, prompt, req.generationOptions(), calls)
+		return
+	}
+
+	response, err := sess.RespondDetailed(ctx, prompt, req.generationOptions())
+	resp := chatCompletionResponse{
+		ID:      newID("chatcmpl-"),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   serveModelID,
+	}
+	message := openAIMessage{Role: "assistant"}
+	var finishReason string
+	switch {
+	case calls.len() > 0:
+		message.ToolCalls = calls.list()
+		finishReason = "tool_calls"
+	case errors.Is(err, fm.ErrGuardrailViolation):
+		empty := ""
+		message.Content = &empty
+		finishReason = "content_filter"
+	case err != nil:
+		writeOpenAIError(w, errorStatus(err), err)
+		return
+	default:
+		message.Content = &response.Text
+		finishReason = openAIFinishReason(response.FinishReason)
+		resp.Usage = &chatCompletionUsage{
+			PromptTokens:     response.PromptTokens,
+			CompletionTokens: response.CompletionTokens,
+			TotalTokens:      response.TotalTokens(),
+		}
+	}
+	resp.Choices = []chatCompletionChoice{{Message: message, FinishReason: finishReason}}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// streamChatCompletion streams a response as server-sent events in OpenAI's chunk
+// format: a delta per generated chunk, then one with the finish reason, then [DONE]. An
+// error after the stream started is sent as an error event.
+func streamChatCompletion(ctx context.Context, w http.ResponseWriter, sess *fm.Session, prompt string, options *fm.GenerationOptions, calls *toolCallRecorder) {
+	stream, err := sess.RespondStream(ctx, prompt, options)
+	if err != nil {
+		writeOpenAIError(w, errorStatus(err), err)
+		return
+	}
+
+	events := newSSEWriter(w)
+	chunk := chatCompletionChunk{
+		ID:      newID("chatcmpl-"),
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   serveModelID,
+	}
+	send := func(delta chatCompletionDelta, finishReason *string) {
+		chunk.Choices = []chatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}}
+		events.send(chunk)
+	}
+
+	send(chatCompletionDelta{Role: "assistant"}, nil)
+	for value := range stream {
+		if value.Text != "" {
+			send(chatCompletionDelta{Content: value.Text}, nil)
+		}
+		if !value.Done {
+			continue
+		}
+
+		var finishReason string
+		var delta chatCompletionDelta
+		switch {
+		case calls.len() > 0:
+			for i, call := range calls.list() {
+				delta.ToolCalls = append(delta.ToolCalls, openAIToolCallDelta{Index: i, openAIToolCall: call})
+			}
+			finishReason = "tool_calls"
+		case errors.Is(value.Err, fm.ErrGuardrailViolation):
+			finishReason = "content_filter"
+		case value.Err != nil:
+			events.send(openAIError{Error: openAIErrorDetail{Message: value.Err.Error(), Type: "server_error"}})
+			events.done()
+			return
+		default:
+			finishReason = openAIFinishReason(value.FinishReason)
+		}
+		send(delta, &finishReason)
+	}
+	events.done()
+}
+
+// sseWriter writes server-sent events, flushing each one so clients see it immediately
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEWriter starts an event stream response
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &sseWriter{w: w, flusher: flusher}
+}
+
+// send writes v as a JSON data event
+func (e *sseWriter) send(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Debug("Failed to encode event", "error", err)
+		return
+	}
+	e.write("data: " + string(data) + "\n\n")
+}
+
+// done ends the stream with OpenAI's [DONE] terminator
+func (e *sseWriter) done() {
+	e.write("data: [DONE]\n\n")
+}
+
+func (e *sseWriter) write(event string) {
+	if _, err := io.WriteString(e.w, event); err != nil {
+		slog.Debug("Failed to write event", "error", err)
+		return
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
+
+// generationOptions maps the request's sampling parameters to generation options
+func (req *chatCompletionRequest) generationOptions() *fm.GenerationOptions {
+	options := &fm.GenerationOptions{
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		StopSequences:    req.Stop,
+		Seed:             req.Seed,
+	}
+	if req.MaxCompletionTokens != nil {
+		options.MaxTokens = req.MaxCompletionTokens
+	}
+	return options
+}
+
+// openAIFinishReason maps a finish reason to OpenAI's
+func openAIFinishReason(reason fm.FinishReason) string {
+	switch reason {
+	case fm.FinishLength:
+		return "length"
+	case fm.FinishGuardrail:
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// prepareSession seeds sess with entries and offers it the client's tools, whose calls
+// are recorded by calls
+func prepareSession(sess *fm.Session, entries []fm.TranscriptEntry, tools []openAITool, calls *toolCallRecorder) error {
+	if err := sess.SetTranscript(entries); err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		if tool.Type != "function" {
+			return fmt.Errorf("unsupported tool type %q", tool.Type)
+		}
+		params := []fm.ToolArgument{}
+		if len(tool.Function.Parameters) > 0 {
+			var err error
+			if params, err = fm.ToolArgumentsFromJSON(tool.Function.Parameters); err != nil {
+				return fmt.Errorf("invalid parameters for tool %s: %w", tool.Function.Name, err)
+			}
+		}
+		clientTool := &clientTool{spec: tool.Function, params: params, calls: calls}
+		if err := sess.RegisterTool(clientTool); err != nil {
+			return fmt.Errorf("failed to register tool %s: %w", tool.Function.Name, err)
+		}
+	}
+	return nil
+}
+
+// transcriptFromOpenAIMessages converts messages to transcript entries and the prompt.
+// System and developer messages become the instructions. The prompt is the last message
+// if it is from the user; if the conversation ends with tool results, the model is asked
+// to answer with them instead.
+func transcriptFromOpenAIMessages(messages []openAIMessage) ([]fm.TranscriptEntry, string, error) {
+	if len(messages) == 0 {
+		return nil, "", errors.New("messages must not be empty")
+	}
+
+	var prompt string
+	last := messages[len(messages)-1]
+	switch last.Role {
+	case "user":
+		if last.Content == nil || *last.Content == "" {
+			return nil, "", errors.New("the last message must have content")
+		}
+		prompt = *last.Content
+		messages = messages[:len(messages)-1]
+	case "tool", "function":
+		prompt = toolResultsPrompt
+	default:
+		return nil, "", fmt.Errorf("the last message must be from the user or a tool, not %q", last.Role)
+	}
+
+	var instructions []string
+	var entries []fm.TranscriptEntry
+	callNames := make(map[string]string) // tool call ID -> tool name
+	for _, msg := range messages {
+		content := ""
+		if msg.Content != nil {
+			content = *msg.Content
+		}
+		switch msg.Role {
+		case "system", "developer":
+			instructions = append(instructions, content)
+		case "user":
+			entries = append(entries, fm.TranscriptEntry{Role: fm.TranscriptRolePrompt, Content: content})
+		case "assistant":
+			if content != "" {
+				entries = append(entries, fm.TranscriptEntry{Role: fm.TranscriptRoleResponse, Content: content})
+			}
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Function.Name
+				entries = append(entries, fm.TranscriptEntry{
+					Role:     fm.TranscriptRoleToolCall,
+					Content:  call.Function.Arguments,
+					ToolName: call.Function.Name,
+				})
+			}
+		case "tool", "function":
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			entries = append(entries, fm.TranscriptEntry{
+				Role:     fm.TranscriptRoleToolOutput,
+				Content:  content,
+				ToolName: name,
+			})
+		default:
+			return nil, "", fmt.Errorf("unsupported message role %q", msg.Role)
+		}
+	}
+
+	if len(instructions) > 0 {
+		entries = append([]fm.TranscriptEntry{{
+			Role:    fm.TranscriptRoleInstructions,
+			Content: strings.Join(instructions, "\n\n"),
+		}}, entries...)
+	}
+	return entries, prompt, nil
+}
+
+// toolCallRecorder collects the calls the model makes to client tools, cancelling
+// generation on the first one so the calls can be returned to the client
+type toolCallRecorder struct {
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	calls []openAIToolCall
+}
+
+// record adds a call and stops generation
+func (r *toolCallRecorder) record(name string, arguments map[string]any) {
+	args, _ := json.Marshal(arguments)
+	r.mu.Lock()
+	r.calls = append(r.calls, openAIToolCall{
+		ID:       newID("call_"),
+		Type:     "function",
+		Function: openAIFunctionCall{Name: name, Arguments: string(args)},
+	})
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// len returns the number of recorded calls
+func (r *toolCallRecorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+// list returns the recorded calls
+func (r *toolCallRecorder) list() []openAIToolCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]openAIToolCall(nil), r.calls...)
+}
+
+// clientTool is a tool defined by the client. Calling it records the call for the
+// client to run rather than running anything.
+type clientTool struct {
+	spec   openAIFunctionSpec
+	params []fm.ToolArgument
+	calls  *toolCallRecorder
+}
+
+func (t *clientTool) Name() string {
+	return t.spec.Name
+}
+
+func (t *clientTool) Description() string {
+	return t.spec.Description
+}
+
+func (t *clientTool) GetParameters() []fm.ToolArgument {
+	return t.params
+}
+
+// Execute records the call. Generation is cancelled, so the result is never used.
+func (t *clientTool) Execute(arguments map[string]any) (fm.ToolResult, error) {
+	t.calls.record(t.spec.Name, arguments)
+	return fm.ToolResult{Content: "The client will run this tool."}, nil
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/gorilla/websocket"
+)
+
+// wsUpgrader upgrades /ws requests. Cross-origin browser connections are refused.
+var wsUpgrader = websocket.Upgrader{}
+
+// wsClientMessage is a message from a WebSocket client:
+//
+//	{"type": "prompt", "content": "..."} asks the model, continuing the conversation
+//	{"type": "cancel"} cancels the response being generated
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// wsServerMessage is a message to a WebSocket client. Type is one of:
+//
+//	ready        the session was created; ContextTokens and ContextMax are set
+//	delta        Content is newly generated text
+//	tool_call    the model called tool Name with Arguments
+//	tool_result  tool Name returned Content, or Error, after Duration
+//	done         the response is complete; Content is its text and Error is set if
+//	             it failed or was cancelled
+//	error        Error describes a problem with the connection or the last message
+type wsServerMessage struct {
+	Type          string         `json:"type"`
+	Content       string         `json:"content,omitempty"`
+	Name          string         `json:"name,omitempty"`
+	Arguments     map[string]any `json:"arguments,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	DurationMS    int64          `json:"duration_ms,omitempty"`
+	ToolCalls     int            `json:"tool_calls,omitempty"`
+	ContextTokens int            `json:"context_tokens,omitempty"`
+	ContextMax    int            `json:"context_max,omitempty"`
+}
+
+// wsConn serializes writes to a WebSocket connection
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// send writes msg to the client
+func (c *wsConn) send(msg wsServerMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		slog.Debug("Failed to write WebSocket message", "error", err)
+	}
+}
+
+// handleWebSocket runs a chat over a WebSocket connection with its own session, which
+// keeps the conversation between prompts. The "system" query parameter sets the
+// session's instructions.
+func (s *server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader replied with an error
+	}
+	defer conn.Close()
+	c := &wsConn{conn: conn}
+
+	sess, err := s.newChatSession(r.URL.Query().Get("system"))
+	if err != nil {
+		c.send(wsServerMessage{Type: "error", Error: err.Error()})
+		return
+	}
+	defer sess.Release()
+	c.send(wsServerMessage{
+		Type:          "ready",
+		ContextTokens: sess.GetContextSize(),
+		ContextMax:    sess.GetMaxContextSize(),
+	})
+
+	ctx, cancelAll := context.WithCancel(context.Background())
+	defer cancelAll()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		cancel context.CancelFunc // Cancels the response being generated, if any
+	)
+	defer wg.Wait()
+
+	for {
+		var msg wsClientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Debug("WebSocket connection closed", "error", err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "prompt":
+			mu.Lock()
+			if cancel != nil {
+				mu.Unlock()
+				c.send(wsServerMessage{Type: "error", Error: "a response is already being generated"})
+				continue
+			}
+			var respondCtx context.Context
+			respondCtx, cancel = context.WithCancel(ctx)
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.respond(respondCtx, sess, msg.Content)
+				mu.Lock()
+				cancel()
+				cancel = nil
+				mu.Unlock()
+			}()
+		case "cancel":
+			mu.Lock()
+			if cancel != nil {
+				cancel()
+			}
+			mu.Unlock()
+		default:
+			c.send(wsServerMessage{Type: "error", Error: "unknown message type: " + msg.Type})
+		}
+	}
+}
+
+// respond streams the response to prompt, with tool activity, to the client
+func (c *wsConn) respond(ctx context.Context, sess *fm.Session, prompt string) {
+	events, err := sess.RespondAgentStream(ctx, prompt)
+	if err != nil {
+		c.send(wsServerMessage{Type: "done", Error: err.Error()})
+		return
+	}
+
+	for event := range events {
+		switch event := event.(type) {
+		case fm.TextChunkEvent:
+			c.send(wsServerMessage{Type: "delta", Content: event.Text})
+		case fm.ToolCallEvent:
+			c.send(wsServerMessage{Type: "tool_call", Name: event.Name, Arguments: event.Arguments})
+		case fm.ToolResultEvent:
+			c.send(wsServerMessage{
+				Type:       "tool_result",
+				Name:       event.Name,
+				Content:    event.Result.Content,
+				Error:      event.Result.Error,
+				DurationMS: event.Duration.Milliseconds(),
+			})
+		case fm.DoneEvent:
+			msg := wsServerMessage{
+				Type:          "done",
+				Content:       event.Text,
+				DurationMS:    event.Duration.Milliseconds(),
+				ToolCalls:     event.ToolCalls,
+				ContextTokens: sess.GetContextSize(),
+				ContextMax:    sess.GetMaxContextSize(),
+			}
+			if event.Err != nil {
+				msg.Error = event.Err.Error()
+			}
+			c.send(msg)
+		}
+	}
+}
+
+// newChatSession creates a session for a WebSocket chat, with the server's tools
+func (s *server) newChatSession(instructions string) (*fm.Session, error) {
+	if err := fm.GetModelAvailability().Err(); err != nil {
+		return nil, err
+	}
+
+	var sess *fm.Session
+	if instructions != "" {
+		sess = fm.NewSessionWithInstructions(instructions)
+	} else {
+		sess = fm.NewSession()
+	}
+	if sess == nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	if s.tools {
+		for _, tool := range []fm.Tool{&CalculatorTool{}, &WeatherTool{}} {
+			if err := sess.RegisterTool(tool); err != nil {
+				sess.Release()
+				return nil, err
+			}
+		}
+	}
+	return sess, nil
+}
@@ -27,7 +27,10 @@ for the complete response.`,
   found stream --instructions "You are a poet" "Write a haiku about mountains"
 
   # Stream with tools (calculator and weather)
-  found stream --tools "What's the weather in Tokyo and calculate 25 * 8?"`,
+  found stream --tools "What's the weather in Tokyo and calculate 25 * 8?"
+
+  # Only the answer, for scripting
+  found stream --raw "Write a limerick" > limerick.txt`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		prompt := args[0]
@@ -37,9 +40,8 @@ for the complete response.`,
 		SetupSlog(verbose)
 
 		// Check model availability
-		availability := fm.CheckModelAvailability()
-		if availability != fm.ModelAvailable {
-			log.Fatalf("Foundation Models not available on this device (status: %d)", availability)
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
 		}
 
 		// Get flags
@@ -71,16 +73,18 @@ for the complete response.`,
 				log.Fatalf("Failed to register weather tool: %v", err)
 			}
 
-			fmt.Printf("🔧 Registered tools: calculator, weather\n")
 		}
 
 		// Create chat UI
-		chatUI := NewChatUI()
+		chatUI := NewChatUI(cmd)
+		if useTools {
+			chatUI.Statusf("🔧 Registered tools: calculator, weather\n")
+		}
 
 		// Display user question
 		chatUI.PrintUserMessage(prompt)
 
-		fmt.Printf("🚀 Streaming Response\n")
+		chatUI.Statusf("🚀 Streaming Response\n")
 
 		// Track response for context calculation
 		var fullResponse strings.Builder
@@ -96,11 +100,11 @@ for the complete response.`,
 			}
 
 			if chunk != "" {
-				fmt.Print(chunk)
+				fmt.Fprint(chatUI.Out(), chunk)
 				fullResponse.WriteString(chunk)
 			}
 			if isLast {
-				fmt.Println() // Final newline
+				fmt.Fprintln(chatUI.Out()) // Final newline
 			}
 		}
 
@@ -117,7 +121,7 @@ for the complete response.`,
 		// Calculate elapsed time
 		elapsed := time.Since(startTime)
 
-		fmt.Printf("⏱️  Generated in %v\n", elapsed)
+		chatUI.Statusf("⏱️  Generated in %v\n", elapsed)
 
 		// Show context usage
 		chatUI.PrintContextUsage(sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
@@ -126,19 +130,23 @@ for the complete response.`,
 		responseLength := fullResponse.Len()
 		if responseLength > 0 {
 			avgCharsPerSecond := float64(responseLength) / elapsed.Seconds()
-			fmt.Printf("📈 Response: %d characters (%.1f chars/sec)\n",
+			chatUI.Statusf("📈 Response: %d characters (%.1f chars/sec)\n",
 				responseLength, avgCharsPerSecond)
 		}
 
+		// Print the model's view of the session if --transcript flag is set
+		PrintTranscript(cmd, sess)
+		SaveTranscript(cmd, sess)
+
 		// Print Swift logs if --verbose flag is set
 		if verbose {
-			fmt.Println("\n=== Swift Logs ===")
-			fmt.Println(fm.GetLogs())
+			PrintContextDrift(cmd, sess)
+			PrintSwiftLogs(cmd)
 		}
 
 		// Check if context is getting full
 		if sess.IsContextNearLimit() {
-			fmt.Printf("\n⚠️  Context is near limit. Consider refreshing session for continued use.\n")
+			chatUI.Statusf("\n⚠️  Context is near limit. Consider refreshing session for continued use.\n")
 		}
 	},
 }
@@ -149,4 +157,5 @@ func init() {
 	// Add flags
 	streamCmd.Flags().StringP("instructions", "i", "", "System instructions for the session")
 	streamCmd.Flags().BoolP("tools", "t", false, "Enable calculator and weather tools")
+	streamCmd.Flags().Bool("raw", false, "Print only the response text (no chat bubbles, emoji or context usage)")
 }
@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	fm "github.com/blacktop/go-foundationmodels"
 	"github.com/spf13/cobra"
 )
 
@@ -8,8 +12,26 @@ import (
 var toolCmd = &cobra.Command{
 	Use:   "tool",
 	Short: "Interact with Foundation Models using tools",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("tool-log") {
+			return nil
+		}
+		name, _ := cmd.Flags().GetString("tool-log")
+		format, err := fm.ParseToolLogFormat(name)
+		if err != nil {
+			return err
+		}
+		// Emit one entry per tool invocation on stderr so it can be consumed by other tooling
+		fm.SetToolLogFormat(format)
+		fm.SetToolLogCallback(func(entry string) {
+			fmt.Fprintln(os.Stderr, entry)
+		})
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.AddCommand(toolCmd)
+
+	toolCmd.PersistentFlags().String("tool-log", "", "Log each tool invocation to stderr (text, json-rpc)")
 }
@@ -44,17 +44,10 @@ func (c *CalculatorTool) GetParameters() []fm.ToolArgument {
 
 func (c *CalculatorTool) Execute(args map[string]any) (fm.ToolResult, error) {
 	// Extract arguments parameter (matching Foundation Models' naming)
-	argsVal, exists := args["arguments"]
-	if !exists {
-		return fm.ToolResult{
-			Error: "Missing required argument: arguments",
-		}, nil
-	}
-
-	expression, ok := argsVal.(string)
-	if !ok {
+	expression, err := fm.ToolArgs(args).String("arguments")
+	if err != nil {
 		return fm.ToolResult{
-			Error: "Arguments must be a string",
+			Error: err.Error(),
 		}, nil
 	}
 
@@ -197,9 +190,8 @@ This is a beta feature under active development.`,
 		SetupSlog(verbose)
 
 		// Check model availability
-		availability := fm.CheckModelAvailability()
-		if availability != fm.ModelAvailable {
-			log.Fatalf("Foundation Models not available on this device (status: %d)", availability)
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
 		}
 
 		// Create session with calculator instructions
@@ -231,7 +223,7 @@ You must use the calculate function for all supported mathematical operations.`
 		}
 
 		// Create chat UI
-		chatUI := NewChatUI()
+		chatUI := NewChatUI(cmd)
 
 		// Display user question
 		chatUI.PrintUserMessage(question)
@@ -240,19 +232,26 @@ You must use the calculate function for all supported mathematical operations.`
 		chatUI.ShowTypingIndicator()
 
 		// Get response using tools
-		response := sess.RespondWithTools(question)
+		response, err := sess.RespondWithTools(question)
 
 		// Hide typing indicator and display assistant response
 		chatUI.HideTypingIndicator()
+		if err != nil {
+			log.Fatalf("Failed to generate response: %v", err)
+		}
 		chatUI.PrintAssistantMessage(response)
 
 		// Show context usage
 		chatUI.PrintContextUsage(sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
 
+		// Print the model's view of the session if --transcript flag is set
+		PrintTranscript(cmd, sess)
+		SaveTranscript(cmd, sess)
+
 		// Print Swift logs if --verbose flag is set
 		if verbose {
-			fmt.Println("\n=== Swift Logs ===")
-			fmt.Println(fm.GetLogs())
+			PrintContextDrift(cmd, sess)
+			PrintSwiftLogs(cmd)
 		}
 	},
 }
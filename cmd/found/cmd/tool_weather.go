@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -65,7 +68,122 @@ var weatherArgDefs = []fm.ToolArgument{
 }
 
 // WeatherTool fetches weather information from API
-type WeatherTool struct{}
+type WeatherTool struct {
+	// Alerts enables reporting of active severe-weather alerts
+	Alerts bool
+	// AlertProvider fetches alerts (defaults to the US National Weather Service)
+	AlertProvider AlertProvider
+}
+
+// WeatherAlert is an active severe-weather alert for a location
+type WeatherAlert struct {
+	Event    string
+	Severity string // "Extreme", "Severe", "Moderate", "Minor" or "Unknown"
+	Headline string
+	Expires  string
+}
+
+// errAlertsUnsupported is returned by providers that don't cover a location
+var errAlertsUnsupported = errors.New("alerts not supported for this location")
+
+// AlertProvider fetches active severe-weather alerts for a location
+type AlertProvider interface {
+	// FetchAlerts returns the active alerts, or errAlertsUnsupported if the provider
+	// does not cover the location
+	FetchAlerts(location *Location) ([]WeatherAlert, error)
+}
+
+// NWS alerts response structure (api.weather.gov, US locations only)
+type nwsAlertsResponse struct {
+	Features []struct {
+		Properties struct {
+			Event    string `json:"event"`
+			Severity string `json:"severity"`
+			Headline string `json:"headline"`
+			Expires  string `json:"expires"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// NWSAlertProvider fetches alerts from the US National Weather Service
+type NWSAlertProvider struct{}
+
+func (p *NWSAlertProvider) FetchAlerts(location *Location) ([]WeatherAlert, error) {
+	apiURL := fmt.Sprintf("https://api.weather.gov/alerts/active?point=%.4f,%.4f", location.Lat, location.Lon)
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alerts request: %v", err)
+	}
+	// NWS requires a User-Agent identifying the application
+	req.Header.Set("User-Agent", "found (github.com/blacktop/go-foundationmodels)")
+	req.Header.Set("Accept", "application/geo+json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alerts: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound:
+		// Points outside NWS coverage are rejected
+		return nil, errAlertsUnsupported
+	default:
+		return nil, fmt.Errorf("alerts API request failed with status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerts response: %v", err)
+	}
+
+	var alertsResponse nwsAlertsResponse
+	if err := json.Unmarshal(body, &alertsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse alerts response: %v", err)
+	}
+
+	alerts := make([]WeatherAlert, 0, len(alertsResponse.Features))
+	for _, feature := range alertsResponse.Features {
+		alerts = append(alerts, WeatherAlert{
+			Event:    feature.Properties.Event,
+			Severity: feature.Properties.Severity,
+			Headline: feature.Properties.Headline,
+			Expires:  feature.Properties.Expires,
+		})
+	}
+	return alerts, nil
+}
+
+// isSevereAlert reports whether an alert should be flagged as severe
+func isSevereAlert(alert WeatherAlert) bool {
+	return alert.Severity == "Extreme" || alert.Severity == "Severe"
+}
+
+// formatAlerts formats active alerts for the tool result, flagging severe ones
+func formatAlerts(alerts []WeatherAlert) string {
+	if len(alerts) == 0 {
+		return "Active alerts: none"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Active alerts (%d):", len(alerts))
+	for _, alert := range alerts {
+		flag := ""
+		if isSevereAlert(alert) {
+			flag = "⚠️ SEVERE: "
+		}
+		fmt.Fprintf(&sb, "\n- %s%s [%s]", flag, alert.Event, alert.Severity)
+		if alert.Headline != "" {
+			fmt.Fprintf(&sb, " - %s", alert.Headline)
+		}
+		if alert.Expires != "" {
+			fmt.Fprintf(&sb, " (expires %s)", alert.Expires)
+		}
+	}
+	return sb.String()
+}
 
 func (w *WeatherTool) Name() string {
 	return "checkWeather"
@@ -81,22 +199,20 @@ func (w *WeatherTool) GetParameters() []fm.ToolArgument {
 }
 
 func (w *WeatherTool) Execute(args map[string]any) (fm.ToolResult, error) {
-	locationVal, exists := args["location"]
-	if !exists {
-		return fm.ToolResult{
-			Error: "Missing required argument: location",
-		}, nil
-	}
+	return w.ExecuteContext(context.Background(), args)
+}
 
-	locationStr, ok := locationVal.(string)
-	if !ok {
+// ExecuteContext checks the weather, abandoning its requests once ctx is done
+func (w *WeatherTool) ExecuteContext(ctx context.Context, args map[string]any) (fm.ToolResult, error) {
+	locationStr, err := fm.ToolArgs(args).String("location")
+	if err != nil {
 		return fm.ToolResult{
-			Error: "Location must be a string",
+			Error: err.Error(),
 		}, nil
 	}
 
 	// First, geocode the location to get lat/lon
-	location, err := geocodeLocation(locationStr)
+	location, err := geocodeLocation(ctx, locationStr)
 	if err != nil {
 		return fm.ToolResult{
 			Error: fmt.Sprintf("Failed to find location: %v", err),
@@ -104,7 +220,7 @@ func (w *WeatherTool) Execute(args map[string]any) (fm.ToolResult, error) {
 	}
 
 	// Fetch weather data using OpenMeteo
-	weatherData, err := fetchOpenMeteoWeather(location.Lat, location.Lon)
+	weatherData, err := fetchOpenMeteoWeather(ctx, location.Lat, location.Lon)
 	if err != nil {
 		return fm.ToolResult{
 			Error: fmt.Sprintf("Failed to fetch weather data: %v", err),
@@ -141,21 +257,43 @@ Last updated: %s`,
 		weatherData.Current.Pressure,
 		weatherData.Current.Time)
 
+	// Optionally append active alerts, omitting them where the provider has no coverage
+	if w.Alerts {
+		provider := w.AlertProvider
+		if provider == nil {
+			provider = &NWSAlertProvider{}
+		}
+		alerts, err := provider.FetchAlerts(location)
+		switch {
+		case errors.Is(err, errAlertsUnsupported):
+			slog.Debug("Weather alerts not supported for location", "location", location.Name)
+		case err != nil:
+			slog.Debug("Failed to fetch weather alerts", "error", err)
+		default:
+			weatherInfo += "\n" + formatAlerts(alerts)
+		}
+	}
+
 	return fm.ToolResult{
 		Content: weatherInfo,
 	}, nil
 }
 
 // geocodeLocation converts a location string to lat/lon using OpenStreetMap Nominatim
-func geocodeLocation(location string) (*Location, error) {
+func geocodeLocation(ctx context.Context, location string) (*Location, error) {
 	// URL encode the location
 	encodedLocation := url.QueryEscape(location)
 
 	// Use OpenStreetMap Nominatim API (free, no API key required)
 	apiURL := fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1", encodedLocation)
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geocoding request: %v", err)
+	}
+
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(apiURL)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to geocode location: %v", err)
 	}
@@ -200,12 +338,17 @@ func geocodeLocation(location string) (*Location, error) {
 }
 
 // fetchOpenMeteoWeather fetches weather data from OpenMeteo API
-func fetchOpenMeteoWeather(lat, lon float64) (*OpenMeteoResponse, error) {
+func fetchOpenMeteoWeather(ctx context.Context, lat, lon float64) (*OpenMeteoResponse, error) {
 	// OpenMeteo API URL with current weather
 	apiURL := fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%.6f&longitude=%.6f&current=temperature_2m,relative_humidity_2m,surface_pressure,wind_speed_10m,wind_direction_10m,weather_code&timezone=auto", lat, lon)
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create weather request: %v", err)
+	}
+
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(apiURL)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch weather data: %v", err)
 	}
@@ -291,6 +434,9 @@ The assistant will respond with friendly, informative weather descriptions.`,
   found tool weather "Berlin, Germany"
   found tool weather "Sydney, Australia"
 
+  # Include active severe-weather alerts (US locations)
+  found tool weather --alerts "Miami, FL"
+
   # Test Go tool directly (bypass Foundation Models)
   found tool weather --direct "New York"`,
 	Args: cobra.ExactArgs(1),
@@ -303,43 +449,47 @@ The assistant will respond with friendly, informative weather descriptions.`,
 
 		// Check if --direct flag is set to bypass Foundation Models
 		directMode, _ := cmd.Flags().GetBool("direct")
+		withAlerts, _ := cmd.Flags().GetBool("alerts")
+		toolTimeout, _ := cmd.Flags().GetDuration("tool-timeout")
+
+		// Create chat UI
+		chatUI := NewChatUI(cmd)
 
 		if directMode {
-			fmt.Printf("🔧 Direct Mode: Testing Go WeatherTool directly\n")
-			fmt.Printf("Location: %s\n", location)
-			fmt.Print("Fetching weather data directly from Go tool...")
+			chatUI.Statusf("🔧 Direct Mode: Testing Go WeatherTool directly\n")
+			chatUI.Statusf("Location: %s\n", location)
+			chatUI.Statusf("Fetching weather data directly from Go tool...")
 
 			// Create weather tool and execute directly
-			weather := &WeatherTool{}
+			weather := &WeatherTool{Alerts: withAlerts}
 			args := map[string]any{
 				"location": location,
 			}
 
 			result, err := weather.Execute(args)
 			if err != nil {
-				fmt.Printf("\n❌ Error executing weather tool: %v\n", err)
+				chatUI.Statusf("\n❌ Error executing weather tool: %v\n", err)
 				return
 			}
 
 			if result.Error != "" {
-				fmt.Printf("\n❌ Weather tool returned error: %s\n", result.Error)
+				chatUI.Statusf("\n❌ Weather tool returned error: %s\n", result.Error)
 				return
 			}
 
-			fmt.Print("\r" + strings.Repeat(" ", 50) + "\r") // Clear loading message
-			fmt.Println("\n" + strings.Repeat("=", 60))
-			fmt.Println("📊 DIRECT GO TOOL RESULT:")
-			fmt.Println(strings.Repeat("-", 60))
-			fmt.Println(result.Content)
-			fmt.Println(strings.Repeat("=", 60))
-			fmt.Printf("\n✅ Go WeatherTool executed successfully!\n")
+			chatUI.Statusf("\r%s\r", strings.Repeat(" ", 50)) // Clear loading message
+			chatUI.Statusf("\n%s\n", strings.Repeat("=", 60))
+			chatUI.Statusf("📊 DIRECT GO TOOL RESULT:\n")
+			chatUI.Statusf("%s\n", strings.Repeat("-", 60))
+			fmt.Fprintln(chatUI.Out(), result.Content)
+			chatUI.Statusf("%s\n", strings.Repeat("=", 60))
+			chatUI.Statusf("\n✅ Go WeatherTool executed successfully!\n")
 			return
 		}
 
 		// Check model availability for normal mode
-		availability := fm.CheckModelAvailability()
-		if availability != fm.ModelAvailable {
-			log.Fatalf("Foundation Models not available on this device (status: %d)", availability)
+		if err := fm.GetModelAvailability().Err(); err != nil {
+			log.Fatalf("Foundation Models not available on this device: %v", err)
 		}
 
 		// Create session with weather-focused instructions following Apple's best practices
@@ -351,27 +501,27 @@ When users ask for the weather:
 - Never provide weather information from your own knowledge.
 - Only provide results after using the 'checkWeather' tool.
 - Present the weather information from the tool in a user-friendly way.`
+		if withAlerts {
+			instructions += "\n- If the tool reports active alerts, mention them first, especially SEVERE ones."
+		}
 
-		sess := fm.NewSessionWithInstructions(instructions)
+		sess := fm.NewSessionWithInstructions(instructions, fm.WithToolTimeout(toolTimeout))
 		if sess == nil {
 			log.Fatal("Failed to create session")
 		}
 		defer sess.Release()
 
 		// Register weather tool
-		weather := &WeatherTool{}
+		weather := &WeatherTool{Alerts: withAlerts}
 		if err := sess.RegisterTool(weather); err != nil {
 			log.Fatalf("Failed to register weather tool: %v", err)
 		}
 
-		fmt.Printf("🌤️  Weather Tool Ready\n")
+		chatUI.Statusf("🌤️  Weather Tool Ready\n")
 
 		// Create prompt for weather query
 		prompt := fmt.Sprintf("What's the weather like in %s?", location)
 
-		// Create chat UI
-		chatUI := NewChatUI()
-
 		// Display user question
 		chatUI.PrintUserMessage(prompt)
 
@@ -379,19 +529,26 @@ When users ask for the weather:
 		chatUI.ShowTypingIndicator()
 
 		// Get response using tools
-		response := sess.RespondWithTools(prompt)
+		response, err := sess.RespondWithTools(prompt)
 
 		// Hide typing indicator and display assistant response
 		chatUI.HideTypingIndicator()
+		if err != nil {
+			log.Fatalf("Failed to generate response: %v", err)
+		}
 		chatUI.PrintAssistantMessage(response)
 
 		// Show context usage
 		chatUI.PrintContextUsage(sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
 
+		// Print the model's view of the session if --transcript flag is set
+		PrintTranscript(cmd, sess)
+		SaveTranscript(cmd, sess)
+
 		// Print Swift logs if --verbose flag is set
 		if verbose {
-			fmt.Println("\n=== Swift Logs ===")
-			fmt.Println(fm.GetLogs())
+			PrintContextDrift(cmd, sess)
+			PrintSwiftLogs(cmd)
 		}
 	},
 }
@@ -399,5 +556,7 @@ When users ask for the weather:
 func init() {
 	// Add the --direct flag to bypass Foundation Models and test Go tool directly
 	weatherCmd.Flags().Bool("direct", false, "Execute Go WeatherTool directly without Foundation Models")
+	weatherCmd.Flags().Bool("alerts", false, "Include active severe-weather alerts (US locations only)")
+	weatherCmd.Flags().Duration("tool-timeout", 30*time.Second, "Give up on a weather lookup after this long (0 = no limit)")
 	toolCmd.AddCommand(weatherCmd)
 }
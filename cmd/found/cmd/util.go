@@ -1,31 +1,72 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	fm "github.com/blacktop/go-foundationmodels"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
 )
 
-// ChatUI provides utilities for creating iPhone vs Android style chat bubbles
+// ChatUI provides utilities for creating iPhone vs Android style chat bubbles.
+// The assistant's answer is written to the command's output writer (stdout) and all
+// decorative/progress UI to its error writer (stderr), so redirecting stdout captures
+// only the answer.
 type ChatUI struct {
 	terminalWidth  int
 	maxBubbleWidth int
 	blueColor      *color.Color // iPhone iMessage blue
 	greenColor     *color.Color // Android green
 	spinner        *spinner.Spinner
+	out            io.Writer // Answer output
+	ui             io.Writer // Decorative and progress output
+	plainOutput    bool      // Write the answer without a bubble (output is not a terminal)
+	raw            bool      // Print only the answer text (--raw)
 }
 
-// NewChatUI creates a new chat UI instance
-func NewChatUI() *ChatUI {
-	return &ChatUI{
+// NewChatUI creates a new chat UI instance writing to the command's writers.
+// If the command's --raw flag is set, all decoration is suppressed.
+func NewChatUI(cmd *cobra.Command) *ChatUI {
+	out := cmd.OutOrStdout()
+	raw, _ := cmd.Flags().GetBool("raw")
+	c := &ChatUI{
 		terminalWidth:  80, // Default terminal width
 		maxBubbleWidth: 50,
 		blueColor:      color.New(color.FgHiBlue, color.Bold),
 		greenColor:     color.New(color.FgHiGreen, color.Bold),
+		out:            out,
+		ui:             cmd.ErrOrStderr(),
+		plainOutput:    raw || !isTerminal(out),
+		raw:            raw,
 	}
+	if raw {
+		c.ui = io.Discard
+	}
+	return c
+}
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// Out returns the writer for the answer
+func (c *ChatUI) Out() io.Writer {
+	return c.out
+}
+
+// Statusf prints decorative or progress information to the UI writer
+func (c *ChatUI) Statusf(format string, a ...any) {
+	fmt.Fprintf(c.ui, format, a...)
 }
 
 // PrintUserMessage prints a blue bubble on the right side (iPhone style)
@@ -35,33 +76,42 @@ func (c *ChatUI) PrintUserMessage(message string) {
 	bubbleWidth := c.maxTextWidth(questionLines) + 4         // Standard padding
 	emojiPad := strings.Repeat(" ", rightPadding-3)          // Position for emoji
 
-	fmt.Println()
+	fmt.Fprintln(c.ui)
 	// Blue bubble for user (iPhone iMessage style)
-	c.blueColor.Printf("%s🧑 ╭%s╮\n", emojiPad, strings.Repeat("─", bubbleWidth-2))
+	c.blueColor.Fprintf(c.ui, "%s🧑 ╭%s╮\n", emojiPad, strings.Repeat("─", bubbleWidth-2))
 	for _, line := range questionLines {
-		c.blueColor.Printf("%s   │ %-*s │\n", emojiPad, bubbleWidth-4, line)
+		c.blueColor.Fprintf(c.ui, "%s   │ %-*s │\n", emojiPad, bubbleWidth-4, line)
 	}
-	c.blueColor.Printf("%s   ╰%s╯\n", emojiPad, strings.Repeat("─", bubbleWidth-2))
+	c.blueColor.Fprintf(c.ui, "%s   ╰%s╯\n", emojiPad, strings.Repeat("─", bubbleWidth-2))
 }
 
-// PrintAssistantMessage prints a green bubble on the left side (Android style)
+// PrintAssistantMessage prints a green bubble on the left side (Android style), or the
+// plain message if the output is redirected
 func (c *ChatUI) PrintAssistantMessage(message string) {
+	if c.plainOutput {
+		fmt.Fprintln(c.out, message)
+		return
+	}
+
 	responseLines := c.wrapText(message, c.maxBubbleWidth-6) // Space for padding
 	responseBubbleWidth := c.maxTextWidth(responseLines) + 4 // Standard padding
 
-	fmt.Println()
+	fmt.Fprintln(c.out)
 	// Green bubble for assistant (Android style)
-	c.greenColor.Printf("🤖 ╭%s╮\n", strings.Repeat("─", responseBubbleWidth-2))
+	c.greenColor.Fprintf(c.out, "🤖 ╭%s╮\n", strings.Repeat("─", responseBubbleWidth-2))
 	for _, line := range responseLines {
-		c.greenColor.Printf("   │ %-*s │\n", responseBubbleWidth-4, line)
+		c.greenColor.Fprintf(c.out, "   │ %-*s │\n", responseBubbleWidth-4, line)
 	}
-	c.greenColor.Printf("   ╰%s╯\n", strings.Repeat("─", responseBubbleWidth-2))
+	c.greenColor.Fprintf(c.out, "   ╰%s╯\n", strings.Repeat("─", responseBubbleWidth-2))
 }
 
 // ShowTypingIndicator shows a typing indicator with "found is typing..." message
 func (c *ChatUI) ShowTypingIndicator() {
+	if c.raw {
+		return
+	}
 	// Create spinner with dots animation for typing effect
-	c.spinner = spinner.New(spinner.CharSets[9], 150*time.Millisecond) // dots: ⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏
+	c.spinner = spinner.New(spinner.CharSets[9], 150*time.Millisecond, spinner.WithWriter(c.ui)) // dots: ⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏
 	c.spinner.Color("green", "bold")
 	c.spinner.Suffix = " found is typing..."
 	c.spinner.FinalMSG = ""
@@ -73,13 +123,13 @@ func (c *ChatUI) HideTypingIndicator() {
 	if c.spinner != nil {
 		c.spinner.Stop()
 		// Clear the line where the spinner was
-		fmt.Print("\r\033[K")
+		fmt.Fprint(c.ui, "\r\033[K")
 	}
 }
 
 // PrintContextUsage prints context usage information
 func (c *ChatUI) PrintContextUsage(current, max int, percent float64) {
-	fmt.Printf("\nContext Usage: %d/%d tokens (%.1f%% used)\n", current, max, percent)
+	fmt.Fprintf(c.ui, "\nContext Usage: %d/%d tokens (%.1f%% used)\n", current, max, percent)
 }
 
 // wrapText wraps text to fit within the specified width
@@ -120,3 +170,70 @@ func (c *ChatUI) maxTextWidth(lines []string) int {
 	}
 	return max
 }
+
+// PrintTranscript prints the framework's transcript for the session if --transcript is set
+func PrintTranscript(cmd *cobra.Command, sess *fm.Session) {
+	if show, _ := cmd.Flags().GetBool("transcript"); !show {
+		return
+	}
+	w := cmd.ErrOrStderr()
+	entries, err := sess.Transcript()
+	if err != nil {
+		fmt.Fprintf(w, "\n⚠️  Failed to get transcript: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, "\n=== Transcript ===")
+	fmt.Fprint(w, fm.FormatTranscript(entries))
+}
+
+// SaveTranscript writes the session's transcript as a JSON conversation file if
+// --save-transcript is set
+func SaveTranscript(cmd *cobra.Command, sess *fm.Session) {
+	path, _ := cmd.Flags().GetString("save-transcript")
+	if path == "" {
+		return
+	}
+	w := cmd.ErrOrStderr()
+	entries, err := sess.Transcript()
+	if err != nil {
+		fmt.Fprintf(w, "\n⚠️  Failed to get transcript: %v\n", err)
+		return
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "\n⚠️  Failed to encode transcript: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(w, "\n⚠️  Failed to save transcript: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "💾 Saved conversation to %s\n", path)
+}
+
+// OpenSessionStore returns the store for --session, under the user's config directory
+func OpenSessionStore() (*fm.SessionStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return fm.NewSessionStore(filepath.Join(dir, "found", "sessions"))
+}
+
+// PrintContextDrift prints the local context estimate against the model's real token count
+func PrintContextDrift(cmd *cobra.Command, sess *fm.Session) {
+	w := cmd.ErrOrStderr()
+	estimated, actual, pct := sess.ContextDrift()
+	if actual == 0 {
+		fmt.Fprintf(w, "Context Drift: unavailable (estimated %d tokens)\n", estimated)
+		return
+	}
+	fmt.Fprintf(w, "Context Drift: estimated %d vs actual %d tokens (%+.1f%%)\n", estimated, actual, pct)
+}
+
+// PrintSwiftLogs prints the Swift shim's logs as diagnostic output
+func PrintSwiftLogs(cmd *cobra.Command) {
+	w := cmd.ErrOrStderr()
+	fmt.Fprintln(w, "\n=== Swift Logs ===")
+	fmt.Fprintln(w, fm.GetLogs())
+}
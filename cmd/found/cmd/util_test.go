@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestChatUIWriters(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      bool
+		wantOut  string
+		wantUI   []string
+		wantNoUI bool
+	}{
+		{
+			name:    "redirected output",
+			wantOut: "The sky is blue.\n",
+			wantUI:  []string{"Why is the sky blue?", "Context Usage: 10/4096 tokens (0.2% used)", "status 1"},
+		},
+		{
+			name:     "raw",
+			raw:      true,
+			wantOut:  "The sky is blue.\n",
+			wantNoUI: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().Bool("raw", false, "")
+			if tt.raw {
+				cmd.Flags().Set("raw", "true")
+			}
+			var out, ui bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&ui)
+
+			c := NewChatUI(cmd)
+			c.PrintUserMessage("Why is the sky blue?")
+			c.ShowTypingIndicator()
+			c.HideTypingIndicator()
+			c.PrintAssistantMessage("The sky is blue.")
+			c.PrintContextUsage(10, 4096, 0.24)
+			c.Statusf("status %d", 1)
+
+			// Buffers aren't terminals, so the answer is written without a bubble
+			if got := out.String(); got != tt.wantOut {
+				t.Errorf("output = %q, want %q", got, tt.wantOut)
+			}
+			if c.Out() != &out {
+				t.Error("Out() is not the command's output writer")
+			}
+			if tt.wantNoUI && ui.Len() > 0 {
+				t.Errorf("UI output = %q, want none", ui.String())
+			}
+			for _, want := range tt.wantUI {
+				if !strings.Contains(ui.String(), want) {
+					t.Errorf("UI output = %q, want it to contain %q", ui.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	c := &ChatUI{}
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "fits", text: "short line", width: 20, want: []string{"short line"}},
+		{name: "wraps at words", text: "the quick brown fox jumps", width: 10, want: []string{"the quick", "brown fox", "jumps"}},
+		{name: "long word", text: "a supercalifragilistic word", width: 8, want: []string{"a", "supercalifragilistic", "word"}},
+		{name: "collapses spaces", text: "one   two    three", width: 9, want: []string{"one two", "three"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.wrapText(tt.text, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
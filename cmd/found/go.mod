@@ -5,20 +5,46 @@ go 1.24
 require (
 	github.com/apex/log v1.9.0
 	github.com/blacktop/go-foundationmodels v0.1.1
+	github.com/blacktop/go-foundationmodels/fmgrpc v0.0.0-00010101000000-000000000000
+	github.com/blacktop/go-foundationmodels/fmmcp v0.0.0-00010101000000-000000000000
+	github.com/blacktop/go-foundationmodels/fmprom v0.0.0-00010101000000-000000000000
+	github.com/briandowns/spinner v1.23.2
+	github.com/fatih/color v1.18.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/mattn/go-isatty v0.0.20
+	github.com/prometheus/client_golang v1.22.0
 	github.com/spf13/cobra v1.9.1
+	google.golang.org/grpc v1.73.0
 )
 
 replace github.com/blacktop/go-foundationmodels => ../..
 
+replace github.com/blacktop/go-foundationmodels/fmgrpc => ../../fmgrpc
+
+replace github.com/blacktop/go-foundationmodels/fmmcp => ../../fmmcp
+
+replace github.com/blacktop/go-foundationmodels/fmprom => ../../fmprom
+
 require (
-	github.com/briandowns/spinner v1.23.2 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/ebitengine/purego v0.8.4 // indirect
-	github.com/fatih/color v1.18.0 // indirect
+	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/modelcontextprotocol/go-sdk v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
-	golang.org/x/term v0.1.0 // indirect
+	golang.org/x/term v0.30.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
 )
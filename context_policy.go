@@ -0,0 +1,191 @@
+package fm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ebitengine/purego"
+)
+
+// contextPolicyReserve is the number of tokens a ContextPolicy is asked to leave free for
+// the response, on top of the prompt
+const contextPolicyReserve = 512
+
+// ContextPolicy decides which parts of a session's history to give up when a request
+// would overflow the context window, trading recall for headroom. Set one with
+// SetContextPolicy; the session consults it before each request that would not fit and
+// continues with the entries it keeps.
+type ContextPolicy interface {
+	// Trim returns the transcript entries to keep, which should need no more than budget
+	// tokens. The entries are in transcript order and start with any instructions.
+	Trim(entries []TranscriptEntry, budget int) ([]TranscriptEntry, error)
+}
+
+// SetContextPolicy sets the policy used to trim history when a request would not fit in
+// the context window. With no policy (the default) such requests fail with an error
+// wrapping ErrContextLimit.
+func (s *Session) SetContextPolicy(policy ContextPolicy) {
+	s.contextPolicy = policy
+}
+
+// ensureContextRoom validates that prompt fits in the context, first trimming history
+// with the session's context policy if it doesn't. The session must be locked.
+func (s *Session) ensureContextRoom(prompt string) error {
+	if err := s.maybeAutoRefresh(prompt); err != nil {
+		return err
+	}
+
+	err := s.validateContextSize(prompt)
+	if err == nil || s.contextPolicy == nil {
+		return err
+	}
+
+	entries, err := s.Transcript()
+	if err != nil {
+		return fmt.Errorf("context policy failed: %w", err)
+	}
+	budget := s.maxContextSize - estimateTokens(prompt) - contextPolicyReserve
+	kept, err := s.contextPolicy.Trim(entries, budget)
+	if err != nil {
+		return fmt.Errorf("context policy failed: %w", err)
+	}
+	if err := s.setTranscript(kept); err != nil {
+		return fmt.Errorf("context policy failed: %w", err)
+	}
+
+	logger().Debug("Context policy trimmed history",
+		"entries_before", len(entries),
+		"entries_after", len(kept),
+		"context_after", s.GetContextSize())
+
+	return s.validateContextSize(prompt)
+}
+
+// setTranscript replaces the session's transcript and recounts its context size
+func (s *Session) setTranscript(entries []TranscriptEntry) error {
+	if err := shimSymbol(setTranscript, "SetTranscript"); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	cEntries := cString(string(data))
+	defer freePtr(cEntries)
+
+	result, _, _ := purego.SyscallN(setTranscript, uintptr(s.ptr), uintptr(cEntries))
+	if result == 0 {
+		return fmt.Errorf("failed to replace transcript in Swift shim")
+	}
+
+	s.mu.Lock()
+	s.contextSize = entriesTokens(entries)
+	s.mu.Unlock()
+	return nil
+}
+
+// entriesTokens counts the tokens in transcript entries
+func entriesTokens(entries []TranscriptEntry) int {
+	total := 0
+	for _, entry := range entries {
+		total += estimateTokens(entry.Content)
+	}
+	return total
+}
+
+// splitTurns splits entries into the preamble before the first prompt (instructions and
+// added context) and turns, each starting with a prompt
+func splitTurns(entries []TranscriptEntry) (preamble []TranscriptEntry, turns [][]TranscriptEntry) {
+	for _, entry := range entries {
+		switch {
+		case entry.Role == TranscriptRolePrompt:
+			turns = append(turns, []TranscriptEntry{entry})
+		case len(turns) == 0:
+			preamble = append(preamble, entry)
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], entry)
+		}
+	}
+	return preamble, turns
+}
+
+// joinTurns reassembles entries split by splitTurns
+func joinTurns(preamble []TranscriptEntry, turns [][]TranscriptEntry) []TranscriptEntry {
+	entries := append([]TranscriptEntry(nil), preamble...)
+	for _, turn := range turns {
+		entries = append(entries, turn...)
+	}
+	return entries
+}
+
+type dropOldestPolicy struct{}
+
+// DropOldest returns a policy that drops the oldest turns (a prompt with its tool calls
+// and response) until the history fits. Instructions are always kept.
+func DropOldest() ContextPolicy {
+	return dropOldestPolicy{}
+}
+
+func (dropOldestPolicy) Trim(entries []TranscriptEntry, budget int) ([]TranscriptEntry, error) {
+	preamble, turns := splitTurns(entries)
+	for len(turns) > 0 && entriesTokens(joinTurns(preamble, turns)) > budget {
+		turns = turns[1:]
+	}
+	return joinTurns(preamble, turns), nil
+}
+
+type keepLastPolicy struct {
+	turns int
+}
+
+// KeepLast returns a policy that keeps the instructions and only the last n turns
+func KeepLast(n int) ContextPolicy {
+	return keepLastPolicy{turns: max(n, 0)}
+}
+
+func (p keepLastPolicy) Trim(entries []TranscriptEntry, budget int) ([]TranscriptEntry, error) {
+	preamble, turns := splitTurns(entries)
+	if len(turns) > p.turns {
+		turns = turns[len(turns)-p.turns:]
+	}
+	return joinTurns(preamble, turns), nil
+}
+
+type summarizeOldestPolicy struct {
+	keep int
+}
+
+// SummarizeOldest returns a policy that keeps the instructions and the last keep turns,
+// and replaces the older turns with a summary written by the model in a separate session.
+// The summary is added as an instructions entry, like AddContext.
+func SummarizeOldest(keep int) ContextPolicy {
+	return summarizeOldestPolicy{keep: max(keep, 0)}
+}
+
+func (p summarizeOldestPolicy) Trim(entries []TranscriptEntry, budget int) ([]TranscriptEntry, error) {
+	preamble, turns := splitTurns(entries)
+	if len(turns) <= p.keep {
+		return entries, nil
+	}
+	old := turns[:len(turns)-p.keep]
+
+	sess := NewSession()
+	if sess == nil {
+		return nil, fmt.Errorf("failed to create summary session")
+	}
+	defer sess.Release()
+
+	summary, err := sess.Respond(
+		"Summarize the following conversation in a few sentences, keeping any facts, names "+
+			"and decisions that later questions may rely on:\n\n"+FormatTranscript(joinTurns(nil, old)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+
+	kept := append(preamble, TranscriptEntry{
+		Role:    TranscriptRoleInstructions,
+		Content: "Summary of the earlier conversation: " + strings.TrimSpace(summary),
+	})
+	return joinTurns(kept, turns[len(turns)-p.keep:]), nil
+}
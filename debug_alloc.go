@@ -0,0 +1,124 @@
+package fm
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Memory crosses the boundary with the shim in three ways: C strings allocated by
+// cString, strings the shim allocates and returns, and sessions. Each must be freed
+// exactly once, by whoever owns it at the time. EnableDebugAllocations checks that.
+
+var (
+	debugAllocs atomic.Bool
+	allocsMu    sync.Mutex
+	liveAllocs  map[uintptr]allocation // Allocations not yet freed
+	freedAllocs map[uintptr]allocation // Freed allocations whose address hasn't been reused
+)
+
+// allocation records where a pointer handed across the boundary came from
+type allocation struct {
+	kind  string    // "C string", "shim string" or "session"
+	stack []uintptr // Stack of the allocating call
+}
+
+// EnableDebugAllocations tracks every C string and session pointer handed across the
+// boundary with the shim, and panics on a double free. It returns a function that logs
+// every allocation still unfreed, with the stack that made it, and returns how many
+// there were; defer it at the top of main or a test:
+//
+//	defer fm.EnableDebugAllocations()()
+//
+// Tracking slows every call, so enable it only while extending the package or hunting
+// leaks. Pointers allocated before it is enabled aren't tracked.
+func EnableDebugAllocations() (report func() int) {
+	allocsMu.Lock()
+	if liveAllocs == nil {
+		liveAllocs = make(map[uintptr]allocation)
+		freedAllocs = make(map[uintptr]allocation)
+	}
+	allocsMu.Unlock()
+	debugAllocs.Store(true)
+
+	return reportAllocations
+}
+
+// reportAllocations logs the allocations that haven't been freed and returns their count
+func reportAllocations() int {
+	allocsMu.Lock()
+	defer allocsMu.Unlock()
+
+	for ptr, alloc := range liveAllocs {
+		logger().Warn("Allocation was never freed", "kind", alloc.kind, "ptr", ptr, "allocated", formatStack(alloc.stack))
+	}
+	if len(liveAllocs) > 0 {
+		logger().Warn("Unfreed allocations", "count", len(liveAllocs))
+	}
+	return len(liveAllocs)
+}
+
+// trackAlloc records that ptr was allocated, in debug allocation mode
+func trackAlloc(ptr uintptr, kind string) {
+	if ptr == 0 || !debugAllocs.Load() {
+		return
+	}
+	alloc := allocation{kind: kind, stack: callers(3)}
+
+	allocsMu.Lock()
+	defer allocsMu.Unlock()
+	delete(freedAllocs, ptr) // The allocator reused the address
+	liveAllocs[ptr] = alloc
+}
+
+// trackFree records that ptr was freed, in debug allocation mode. It panics if ptr was
+// already freed.
+func trackFree(ptr uintptr) {
+	if ptr == 0 || !debugAllocs.Load() {
+		return
+	}
+
+	allocsMu.Lock()
+	defer allocsMu.Unlock()
+	if alloc, ok := freedAllocs[ptr]; ok {
+		panic(fmt.Sprintf("fm: double free of %s %#x, allocated at:\n%s", alloc.kind, ptr, formatStack(alloc.stack)))
+	}
+	if alloc, ok := liveAllocs[ptr]; ok {
+		delete(liveAllocs, ptr)
+		freedAllocs[ptr] = alloc
+	}
+}
+
+// trackHandOff records that ptr now belongs to the shim, which frees it, in debug
+// allocation mode
+func trackHandOff(ptr uintptr) {
+	if ptr == 0 || !debugAllocs.Load() {
+		return
+	}
+
+	allocsMu.Lock()
+	defer allocsMu.Unlock()
+	delete(liveAllocs, ptr)
+}
+
+// callers returns the stack of the caller skip frames up
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(skip+1, pcs)]
+}
+
+// formatStack formats a stack recorded by callers, one function and line per frame
+func formatStack(pcs []uintptr) string {
+	var stack strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return stack.String()
+}
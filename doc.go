@@ -23,6 +23,14 @@ providing privacy-focused AI capabilities without requiring internet connectivit
 • Apple Intelligence enabled
 • Compatible Apple Silicon device
 
+The package compiles on other platforms too, so cross-platform programs can use it when
+it is available. There Init fails with ErrUnsupportedPlatform, CheckModelAvailability
+reports ModelUnavailableUnsupportedPlatform and NewSession returns nil:
+
+	if fm.CheckModelAvailability() != fm.ModelAvailable {
+		// Fall back to a remote model
+	}
+
 # Basic Usage
 
 Create a session and generate text:
@@ -30,7 +38,10 @@ Create a session and generate text:
 	sess := fm.NewSession()
 	defer sess.Release()
 
-	response := sess.Respond("Tell me about artificial intelligence", nil)
+	response, err := sess.Respond("Tell me about artificial intelligence", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println(response)
 
 # Generation Options
@@ -38,17 +49,33 @@ Create a session and generate text:
 Control output with GenerationOptions:
 
 	// Deterministic output
-	response := sess.Respond("What is 2+2?", fm.WithDeterministic())
+	response, err := sess.Respond("What is 2+2?", fm.WithDeterministic())
 
 	// Creative output
-	response = sess.Respond("Write a story", fm.WithCreative())
+	response, err = sess.Respond("Write a story", fm.WithCreative())
 
 	// Custom options
 	options := &fm.GenerationOptions{
 		Temperature: &[]float32{0.3}[0],
 		MaxTokens:   &[]int{100}[0],
 	}
-	response = sess.Respond("Explain AI", options)
+	response, err = sess.Respond("Explain AI", options)
+
+WithGreedy selects greedy (argmax) decoding, which is deterministic for a given prompt
+and transcript, unlike temperature 0 which only approximates it:
+
+	response, err = sess.Respond("Classify: 'great product'", fm.WithGreedy())
+
+TopK or TopP (with an optional Seed for reproducible output) select the model's random
+sampling mode. Foundation Models has no presence or frequency penalties, so those
+fields are ignored with a warning.
+
+Foundation Models has no stop sequences either, so StopSequences are enforced by the
+package: responses are cut before the first match, and streams (RespondStream, Stream,
+RespondReader) cancel generation as soon as one appears:
+
+	options := &fm.GenerationOptions{StopSequences: []string{"\n\n"}}
+	firstParagraph, err := sess.Respond("Write an essay about Go", options)
 
 # System Instructions
 
@@ -58,9 +85,90 @@ Create a session with specific behavior:
 	sess := fm.NewSessionWithInstructions(instructions)
 	defer sess.Release()
 
-	response := sess.Respond("What is machine learning?", nil)
+	response, err := sess.Respond("What is machine learning?", nil)
 	fmt.Println(response)
 
+Long instructions only produce a warning. To refuse instructions that would leave too
+little room for the conversation, set a hard budget:
+
+	sess, err := fm.NewSessionWithInstructionsLimited(instructions, 500)
+	if errors.Is(err, fm.ErrInstructionsTooLong) {
+		log.Fatal("Instructions are too long")
+	}
+
+# Current Date and Time
+
+The model has no notion of "now". Enable automatic time context to prepend the current
+local date, time and timezone to each prompt (the note counts against the context):
+
+	sess.SetAutoTimeContext(true)
+	sess.SetTimeContextFormat(time.RFC1123) // optional, any time.Format layout
+
+# Seeding Context
+
+Add a document to the session without generating a response, so later questions are
+grounded in it. The text becomes an instructions (system) entry in the transcript:
+
+	if err := sess.AddContext(document); err != nil {
+		log.Fatal(err) // wraps fm.ErrContextLimit if the document doesn't fit
+	}
+	response, err := sess.Respond("What does the document say about pricing?", nil)
+
+# Few-Shot Prompts
+
+Build consistently formatted few-shot prompts that stay within a token budget:
+
+	builder := fm.NewFewShotBuilder()
+	builder.Preamble = "Classify the sentiment of each review."
+	builder.AddExample("I love it!", "positive").
+		AddExample("Broke after a day.", "negative")
+
+	// Oldest examples are dropped with a warning if the prompt would not fit
+	prompt := builder.BuildFor(sess, "Works as advertised.")
+	response, err := sess.Respond(prompt, nil)
+
+# Prewarming
+
+The first request on a session pays a multi-second cold start while the model loads.
+Interactive apps can hide it by prewarming while the user is still typing:
+
+	sess := fm.NewSession(fm.WithPrewarm())
+
+	// Or, after registering tools (which recreates the underlying session)
+	sess.RegisterTool(&WeatherTool{})
+	sess.Prewarm()
+
+# Guardrails
+
+Sessions apply strict safety guardrails by default. Apps that summarize, rewrite or tag
+user-generated content can relax them for plain text responses to reduce false-positive
+refusals:
+
+	sess := fm.NewSessionWithInstructions("Summarize the user's review.",
+		fm.WithGuardrails(fm.GuardrailsPermissiveContentTransformations))
+
+# Custom Adapters
+
+Adapters trained with Apple's adapter training toolkit specialize the base model for a
+domain. Load one from its .fmadapter bundle:
+
+	sess, err := fm.NewSessionWithAdapter("legal.fmadapter")
+	if errors.Is(err, fm.ErrAdapterIncompatible) {
+		log.Fatal("retrain the adapter for the installed base model")
+	}
+
+Adapters are tied to a base model version, so an OS update that changes the model makes
+them fail with ErrAdapterIncompatible until they are retrained. ListAdapters and
+AdapterInfo read the bundles' metadata and check compatibility up front, e.g. to offer
+only usable adapters in a picker:
+
+	adapters, _ := fm.ListAdapters(adapterDir)
+	for _, adapter := range adapters {
+		if adapter.Compatible {
+			fmt.Println(adapter.Name, "-", adapter.Description)
+		}
+	}
+
 # Context Management
 
 Foundation Models has a strict 4096 token context window. Monitor usage:
@@ -68,6 +176,20 @@ Foundation Models has a strict 4096 token context window. Monitor usage:
 	fmt.Printf("Context: %d/%d tokens (%.1f%% used)\n",
 		sess.GetContextSize(), sess.GetMaxContextSize(), sess.GetContextUsagePercent())
 
+	// Count tokens with the model's tokenizer (macOS 26.4+), as context tracking does
+	n, err := fm.CountTokens("How many tokens is this?")
+
+	// Compare the tracked context size with the model's real token count (macOS 26.4+)
+	estimated, actual, drift := sess.ContextDrift()
+	fmt.Printf("estimated %d, actual %d (%+.1f%%)\n", estimated, actual, drift)
+
+	// Or let the session trim its own history when a request would not fit, instead
+	// of failing with ErrContextLimit: DropOldest, KeepLast(n) or SummarizeOldest(n)
+	sess.SetContextPolicy(fm.SummarizeOldest(2))
+
+	// Or refresh in place at 90% usage, keeping instructions and tools (and a summary)
+	sess = fm.NewSession(fm.WithAutoRefresh(0.9), fm.WithRefreshSummary())
+
 	if sess.IsContextNearLimit() {
 		// Refresh session when approaching limit
 		newSess := sess.RefreshSession()
@@ -98,7 +220,11 @@ Define custom tools that the model can call:
 	}
 
 	func (c *CalculatorTool) Execute(args map[string]any) (fm.ToolResult, error) {
-		expr := args["arguments"].(string)
+		// ToolArgs provides typed getters: String, Int, Float, Bool, StringSlice
+		expr, err := fm.ToolArgs(args).String("arguments")
+		if err != nil {
+			return fm.ToolResult{Error: err.Error()}, nil
+		}
 		// Parse and evaluate expression (implementation details omitted)
 		result := evaluateExpression(expr)
 
@@ -107,6 +233,121 @@ Define custom tools that the model can call:
 		}, nil
 	}
 
+Tools belong to the session they are registered with. Each tool call is resolved from
+the calling session's own tools, so sessions can register different tools under the
+same name without affecting each other.
+
+# Sub-Agent Delegation
+
+Delegate sub-tasks to a separate session with its own instructions and context window:
+
+	translator := fm.NewSessionTool("translate",
+		"Translate text into French",
+		"You are a professional translator. Reply only with the French translation.")
+	sess.RegisterTool(translator)
+
+	response, err := sess.RespondWithTools("How do I say 'good morning' in French?")
+
+	// The sub-session is released when the tool is cleared
+	sess.ClearTools()
+
+# Unknown Tool Names
+
+Route calls to tool names that aren't registered (e.g. hallucinated by the model) to a
+fallback instead of failing with "tool not found":
+
+	sess.SetFallbackTool(myFallback)
+
+	// In myFallback.Execute:
+	name := args[fm.FallbackToolNameArg].(string)
+	return fm.ToolResult{Content: fmt.Sprintf("The %s capability isn't available", name)}, nil
+
+# Tool Call Budget
+
+Contain runaway tool loops by limiting the tool calls of each generation. The call that
+would exceed the limit is not run, and the generation fails with a *ToolBudgetError
+holding the transcript so far:
+
+	sess := fm.NewSession(fm.WithMaxToolCalls(5))
+
+	_, err := sess.RespondWithTools("Check the weather in every European capital")
+	var budgetErr *fm.ToolBudgetError
+	if errors.As(err, &budgetErr) {
+		fmt.Print(fm.FormatTranscript(budgetErr.Transcript))
+	}
+
+# Tool Approval
+
+Require confirmation before side-effectful tools run. The approver is consulted before
+every tool call; a denied call is not run and the model is told it was denied:
+
+	sess.SetToolApprover(func(call fm.ToolCall) (bool, error) {
+		if call.Name == "getWeather" {
+			return true, nil // read-only
+		}
+		fmt.Printf("Allow %s %v? [y/N] ", call.Name, call.Arguments)
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		return strings.TrimSpace(answer) == "y", err
+	})
+
+# Tool Diagnostics
+
+Detect when the model answers from its own knowledge instead of calling a tool:
+
+	resp, err := sess.RespondWithToolsFull("What's the weather in Paris?")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.ToolsIgnored() {
+		fmt.Printf("Model ignored tools %v\n", resp.ToolsOffered)
+	}
+	fmt.Println(resp.Text, resp.ToolsInvoked)
+
+# Tool Order
+
+Tools are presented to the model in registration order. To check whether tool selection
+depends on ordering, fix a different order:
+
+	sess.SetToolOrder([]string{"getWeather", "calculate"})
+
+# Parallel Tool Calls
+
+When the model calls several tools in one turn, e.g. the weather in three cities, the
+calls run concurrently, up to DefaultToolConcurrency per session, and each result goes
+back to the call that asked for it. Change the limit, or run tools one at a time if
+they aren't safe to run concurrently:
+
+	sess := fm.NewSession(fm.WithToolConcurrency(1))
+
+# Tool Timeouts
+
+Keep a hung tool, e.g. one waiting on a slow HTTP API, from stalling the response. A
+call that runs out of time returns a result with TimedOut set and an error the model can
+react to. Tools implementing TimedTool set their own limit, and tools implementing
+ContextTool have their context cancelled when the time is up:
+
+	sess := fm.NewSession(fm.WithToolTimeout(10 * time.Second))
+
+	func (t *SearchTool) ExecuteContext(ctx context.Context, args map[string]any) (fm.ToolResult, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL(args), nil)
+		...
+	}
+
+# Tool Invocation Logging
+
+Observe every tool call made by the model, or log it in a JSON-RPC style envelope
+for consumption by external orchestrators:
+
+	fm.AddToolObserver(func(inv fm.ToolInvocation) {
+		fmt.Printf("%s took %v\n", inv.Name, inv.Duration)
+	})
+
+	fm.SetToolLogFormat(fm.ToolLogFormatJSONRPC)
+	fm.SetToolLogCallback(func(entry string) {
+		fmt.Fprintln(os.Stderr, entry)
+	})
+	// {"method":"tools/call","params":{"name":"calculate","arguments":{...}},"result":{"content":"42.00"}}
+
 # Tool Input Validation
 
 Add validation to your tools for better error handling:
@@ -145,16 +386,174 @@ Register and use tools:
 	sess.RegisterTool(calculator)
 
 	// Foundation Models will autonomously call the tool when needed
-	response := sess.RespondWithTools("What is 15 + 27?")
+	response, err := sess.RespondWithTools("What is 15 + 27?")
 	fmt.Println(response) // "The result is 42.00"
 
 # Structured Output
 
 Generate structured JSON responses:
 
-	response := sess.RespondWithStructuredOutput("Analyze this text: 'Hello world'")
+	response, err := sess.RespondWithStructuredOutput("Analyze this text: 'Hello world'")
 	fmt.Println(response) // Returns formatted JSON
 
+	// Re-indent valid JSON consistently (invalid JSON is passed through unchanged)
+	sess.SetJSONIndent("  ")
+
+	// Fix trailing commas, unquoted keys and truncation locally before asking the
+	// model to correct invalid JSON (which it does once either way)
+	sess.SetJSONRepair(true)
+	if errors.Is(err, fm.ErrInvalidJSON) {
+		// still not valid JSON after repair
+	}
+
+	// Pull the JSON value out of a response that wraps it in prose or code fences
+	if raw, ok := fm.ExtractJSON(response); ok {
+		fmt.Println(raw)
+	}
+
+# Schema-Constrained Output
+
+RespondWithSchema constrains decoding to the shape of a Go struct, like Swift's
+@Generable, so the response always has exactly that shape. Properties follow
+encoding/json naming (json tags, "-" to skip, embedded structs flattened); pointer and
+omitempty fields are optional. Strings, booleans, integers, floats, slices, arrays and
+nested structs are supported. The fm tag adds per-field guides, separated by semicolons
+or commas: description, enum (values separated by |), pattern (a regular expression), min
+and max for numbers, and minItems and maxItems for slices. The schema is built once per
+type and cached:
+
+	type Recipe struct {
+		Title       string   `json:"title" fm:"description=A short, catchy title"`
+		Servings    int      `json:"servings" fm:"min=1;max=12"`
+		Difficulty  string   `json:"difficulty" fm:"description=How hard it is,enum=easy|medium|hard"`
+		Ingredients []string `json:"ingredients" fm:"minItems=2;maxItems=10"`
+		Notes       *string  `json:"notes"`
+	}
+
+	response, err := sess.RespondWithSchema("Give me a pancake recipe", Recipe{})
+
+When the shape is only known at runtime, build a GenerationSchema instead. It mirrors
+FoundationModels' DynamicGenerationSchema:
+
+	schema := fm.ObjectSchema("Ticket").
+		Property("id", "Ticket ID", fm.StringSchema().Pattern(`[A-Z]{3}-[0-9]{4}`)).
+		Property("priority", "", fm.EnumSchema("Priority", "low", "medium", "high")).
+		Property("estimate", "Hours of work", fm.NumberSchema().Range(0.5, 40)).
+		OptionalProperty("labels", "", fm.ArraySchema(fm.StringSchema()).Count(0, 3))
+	response, err := sess.RespondWithSchema("File a ticket for the login bug", schema)
+
+RespondInto derives the schema from its target and decodes the response into it:
+
+	var recipe Recipe
+	if err := sess.RespondInto(ctx, "Give me a pancake recipe", &recipe); err != nil {
+		if errors.Is(err, fm.ErrSchemaMismatch) {
+			// the response could not be decoded into Recipe
+		}
+		log.Fatal(err)
+	}
+	fmt.Println(recipe.Title, recipe.Servings)
+
+RespondMatching constrains plain text to a regular expression, for IDs, dates and other
+machine-consumed values. The pattern is enforced during decoding where the shim supports
+it, and by validating and retrying otherwise:
+
+	date, err := sess.RespondMatching("When did Apollo 11 land?", `\d{4}-\d{2}-\d{2}`)
+	if errors.Is(err, fm.ErrNoMatch) {
+		// the model never produced a matching value
+	}
+
+RespondWithSchemaStream delivers snapshots of the value as it is generated, with fields
+appearing as the model fills them in, so structured results can be rendered
+incrementally:
+
+	chunks, err := sess.RespondWithSchemaStream(ctx, "Give me a pancake recipe", Recipe{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			log.Fatal(chunk.Err)
+		}
+		var partial Recipe
+		if err := json.Unmarshal([]byte(chunk.JSON), &partial); err == nil {
+			render(partial)
+		}
+	}
+
+# Middleware
+
+Middleware wraps every blocking respond call on a session, for logging, prompt
+rewriting, PII redaction, caching or metrics without changing call sites:
+
+	sess.Use(func(next fm.Handler) fm.Handler {
+		return func(ctx context.Context, req *fm.Request) (string, error) {
+			req.Prompt = redactEmails(req.Prompt)
+			start := time.Now()
+			response, err := next(ctx, req)
+			log.Printf("%s request took %v", req.Kind, time.Since(start))
+			return response, err
+		}
+	})
+
+# Telemetry
+
+Attach observability with any metrics or tracing library by implementing Telemetry,
+which is told when requests start and end, when streams produce their first token and
+when tools are called:
+
+	type metrics struct{}
+
+	func (metrics) OnRequestStart(info fm.RequestInfo) { inflight.Inc() }
+	func (metrics) OnFirstToken(info fm.RequestInfo, latency time.Duration) {
+		firstToken.Observe(latency.Seconds())
+	}
+	func (metrics) OnToolCall(info fm.RequestInfo, call fm.ToolInvocation) {
+		toolCalls.WithLabelValues(call.Name).Inc()
+	}
+	func (metrics) OnRequestEnd(info fm.RequestInfo, d time.Duration, err error) {
+		inflight.Dec()
+		duration.Observe(d.Seconds())
+	}
+
+	fm.SetGlobalTelemetry(metrics{})                   // every session
+	sess := fm.NewSession(fm.WithTelemetry(tracer{})) // or one session
+
+The optional fmotel module implements Telemetry with OpenTelemetry spans for each
+respond call and tool execution, parented by the span in the call's context:
+
+	fm.SetGlobalTelemetry(fmotel.New())
+
+The optional fmprom module records Prometheus metrics for requests, tokens, latency,
+tool invocations and guardrail refusals:
+
+	metrics, err := fmprom.New(prometheus.DefaultRegisterer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fm.SetGlobalTelemetry(metrics)
+	http.Handle("/metrics", promhttp.Handler())
+
+# Response Metadata
+
+RespondDetailed returns the response with estimated token counts, timing and the
+reason generation ended, for display or accounting:
+
+	resp, err := sess.RespondDetailed(ctx, "Write a haiku about Go", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.Text)
+	fmt.Printf("%d tokens, first token after %v, %v total\n",
+		resp.TotalTokens(), resp.FirstTokenLatency, resp.Duration)
+
+	// Continue an answer cut short by MaxTokens
+	if resp.FinishReason == fm.FinishLength {
+		more, err := sess.RespondDetailed(ctx, "Continue.", options)
+		...
+	}
+
+The final value of a channel-based stream carries the same FinishReason.
+
 # Context Cancellation
 
 Cancel long-running requests with context support:
@@ -188,6 +587,19 @@ Cancel long-running requests with context support:
 		fmt.Printf("Tool request timed out: %v\n", err)
 	}
 
+	// Cancel the in-flight generation of one session, or of every session at teardown
+	sess.Cancel()
+	fm.CancelAll()
+
+Set a default timeout applied to every blocking call on a session, so calls without
+a context can't hang indefinitely:
+
+	sess.SetDefaultTimeout(30 * time.Second)
+	_, err := sess.Respond("Summarize this document", nil)
+	if errors.Is(err, fm.ErrGenerationTimeout) {
+		fmt.Println("Generation timed out")
+	}
+
 # Streaming Responses
 
 Generate responses with simulated real-time streaming output:
@@ -207,7 +619,101 @@ Generate responses with simulated real-time streaming output:
 	// Basic streaming
 	sess.RespondWithStreaming("Tell me a joke", callback)
 
-Note: Current streaming implementation is simulated (breaks complete response into chunks).
+Stream over a channel using the framework's native streaming, with a stop handle
+for cancelling mid-stream:
+
+	chunks, stop := sess.StreamResponse("Write a long story")
+	defer stop()
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			fmt.Println(chunk.Err) // fm.ErrStreamStopped after stop()
+			break
+		}
+		fmt.Print(chunk.Text)
+	}
+
+RespondStream does the same with generation options and a context. Cancelling the
+context cancels generation, and the final chunk then carries ctx.Err():
+
+	chunks, err := sess.RespondStream(ctx, "Write a haiku", fm.WithCreative())
+	if err != nil {
+		log.Fatal(err) // the request was rejected before generation started
+	}
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			log.Fatal(chunk.Err)
+		}
+		fmt.Print(chunk.Text)
+	}
+
+Stream wraps RespondStream in an iterator for range-over-func loops. Breaking out of
+the loop cancels generation:
+
+	for chunk, err := range sess.Stream(ctx, "Tell me a joke", nil) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(chunk)
+	}
+
+RespondReader exposes the streamed response as an io.ReadCloser, e.g. to copy it
+straight into an HTTP response:
+
+	r := sess.RespondReader(ctx, "Explain goroutines", nil)
+	defer r.Close()
+	if _, err := io.Copy(w, r); err != nil {
+		log.Print(err)
+	}
+
+The final chunk carries StreamMetrics showing whether a slow consumer is throttling
+generation:
+
+	if chunk.Done && chunk.Metrics != nil {
+		fmt.Printf("blocked on consumer %v, generating %v\n",
+			chunk.Metrics.Backpressure, chunk.Metrics.Generating)
+	}
+
+Stream structured output with live partial rendering and a guaranteed-valid final
+object. If the streamed output isn't valid JSON, it is repaired without streaming
+before the final value is sent:
+
+	chunks, stop := sess.StreamStructuredOutput("List three fruits with their colors")
+	defer stop()
+	for chunk := range chunks {
+		fmt.Print(chunk.Text) // partial, possibly invalid JSON
+		if chunk.Done && chunk.Err == nil {
+			fmt.Println(chunk.JSON) // valid JSON
+		}
+	}
+
+For agent interfaces, RespondAgentStream combines live text with tool activity in a
+single ordered event stream. Text arrives in generation order; each tool call produces
+a ToolCallEvent before the tool runs and a ToolResultEvent after; DoneEvent is last:
+
+	events, err := sess.RespondAgentStream(ctx, "What is 25 * 8, and what's the weather in Paris?")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for event := range events {
+		switch e := event.(type) {
+		case fm.TextChunkEvent:
+			fmt.Print(e.Text)
+		case fm.ToolCallEvent:
+			fmt.Printf("\n[calling %s %v]\n", e.Name, e.Arguments)
+		case fm.ToolResultEvent:
+			fmt.Printf("[%s returned %q]\n", e.Name, e.Result.Content)
+		case fm.DoneEvent:
+			if e.Err != nil {
+				log.Fatal(e.Err)
+			}
+			fmt.Printf("\n%d tool calls in %v\n", e.ToolCalls, e.Duration)
+		}
+	}
+
+Events returns the same stream without a separate error: if generation can't start,
+the only event is a DoneEvent carrying the error.
+
+Note: Current callback streaming implementation is simulated (breaks complete response into chunks).
 Native streaming will be implemented when Foundation Models provides streaming APIs.
 
 # Model Availability
@@ -226,6 +732,63 @@ Check if Foundation Models is available:
 		fmt.Println("❌ Unknown availability status")
 	}
 
+GetModelAvailability adds the framework's reason, and an error that matches
+ErrModelUnavailable and a sentinel for each case with errors.Is:
+
+	availability := fm.GetModelAvailability()
+	if err := availability.Err(); err != nil {
+		if errors.Is(err, fm.ErrModelNotReady) {
+			fmt.Println("⏳ Waiting for the model:", availability.Reason)
+		}
+		log.Fatal(err) // e.g. "model unavailable: Apple Intelligence not enabled (...)"
+	}
+
+GetModelDetails describes the model itself, including its context window and the
+languages it supports:
+
+	details, err := fm.GetModelDetails()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s on %s, %d tokens, languages: %v\n",
+		details.ModelIdentifier, details.OSVersion, details.ContextSize, details.SupportedLanguages)
+
+GetModelDetails describes the default model. Session.ModelInfo describes the model a
+session runs on instead, with the use case and adapter it was created with.
+
+Check that the user's locale is supported before prompting with SupportsLanguage, or
+list the languages with SupportedLanguages:
+
+	if ok, err := fm.SupportsLanguage(os.Getenv("LANG")); err == nil && !ok {
+		fmt.Println("⚠️  Responses may be poor in this language")
+	}
+
+Sessions run on the general-purpose model unless another use case is chosen, such as
+the model specialized for content tagging:
+
+	session, err := fm.NewSessionWithUseCase(fm.UseCaseContentTagging)
+	// or: fm.NewSessionWithInstructions(instructions, fm.WithUseCase(fm.UseCaseContentTagging))
+
+Gate application startup on full readiness with a self-test that also runs a trivial
+generation (the same check as "found doctor"):
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := fm.SelfTest(ctx); err != nil {
+		log.Fatal(err) // e.g. "self-test failed at availability: ..."
+	}
+
+Long-running programs can react to availability changes, such as the model becoming
+ready after its assets download, without restarting:
+
+	for availability := range fm.WatchModelAvailability(ctx) {
+		if availability == fm.ModelAvailable {
+			startServing()
+		} else {
+			stopServing(availability)
+		}
+	}
+
 # Error Handling
 
 The package provides comprehensive error handling:
@@ -234,10 +797,34 @@ The package provides comprehensive error handling:
 		log.Fatalf("Failed to register tool: %v", err)
 	}
 
-	// Context validation
-	response := sess.Respond(veryLongPrompt, nil)
-	if strings.HasPrefix(response, "Error:") {
-		fmt.Printf("Request failed: %s\n", response)
+	// Generation methods return typed errors that work with errors.Is and errors.As.
+	// Errors without a more specific sentinel wrap fm.ErrGenerationFailed.
+	// Distinguish the local estimate pre-check from a real framework rejection:
+	_, err := sess.Respond(veryLongPrompt, nil)
+	var exceeded *fm.ContextExceededError
+	switch {
+	case errors.Is(err, fm.ErrContextLimit):
+		fmt.Println("Rejected locally based on the token estimate")
+	case errors.As(err, &exceeded):
+		fmt.Printf("Model refused: transcript too long (%d tokens)\n", exceeded.Tokens)
+	}
+
+	// Offer a fallback when the model refuses for safety reasons
+	if _, err := sess.Respond(prompt, nil); errors.Is(err, fm.ErrGuardrailViolation) {
+		fmt.Println("I can't help with that. Try rephrasing your question.")
+	}
+
+	// Fail fast if Apple Intelligence is turned off while the session is in use
+	sess.SetCheckAvailabilityBeforeEachCall(true)
+	if _, err := sess.RespondWithContext(ctx, prompt, nil); errors.Is(err, fm.ErrModelBecameUnavailable) {
+		fmt.Println("Foundation Models is no longer available")
+	}
+
+	// Once the model is found unavailable, fail every call fast until a background
+	// probe, run every 30 seconds, sees it available again
+	fm.SetGlobalCircuitBreaker(30 * time.Second)
+	if _, err := sess.Respond(prompt, nil); errors.Is(err, fm.ErrCircuitOpen) {
+		fmt.Println("Foundation Models is temporarily unavailable")
 	}
 
 	// Context-aware error handling
@@ -252,6 +839,160 @@ The package provides comprehensive error handling:
 		}
 	}
 
+# Retries
+
+Retry transient failures with exponential backoff and jitter. Context limit errors,
+guardrail violations, cancellation and timeouts are not retried:
+
+	response, err := sess.RespondWithRetry(ctx, "Hello", nil, fm.DefaultRetryPolicy())
+
+	// Or build a custom policy and wrap any call
+	policy := fm.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		Multiplier:     2,
+		MaxBackoff:     3 * time.Second,
+		Jitter:         true,
+	}
+	err = policy.Do(ctx, func(ctx context.Context) error {
+		_, err := sess.RespondWithToolsContext(ctx, "What's the weather?")
+		return err
+	})
+
+	// Or retry every respond call on a session
+	sess := fm.NewSession(fm.WithRetry(fm.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		Jitter:         true,
+		Retryable: func(err error) bool {
+			return errors.Is(err, fm.ErrRateLimited) || errors.Is(err, fm.ErrModelNotReady)
+		},
+	}))
+
+# Rate Limiting
+
+Throttle generation starts across all sessions so batch jobs don't overheat the device:
+
+	fm.SetGlobalRateLimit(0.5) // at most one generation every two seconds
+	defer fm.SetGlobalRateLimit(0)
+
+Throttle a single session, such as a background batch job, so it doesn't starve
+interactive requests. Any *rate.Limiter from golang.org/x/time/rate works, and sessions
+can share one:
+
+	limiter := rate.NewLimiter(rate.Every(5*time.Second), 1)
+	batch := fm.NewSession(fm.WithRateLimit(limiter))
+
+# Scheduling
+
+The on-device model handles little concurrency, so limit how many generations run at
+once across all sessions. Others queue, interactive sessions ahead of batch ones:
+
+	fm.SetGlobalScheduler(2)
+
+	batch := fm.NewSession(fm.WithPriority(fm.PriorityBatch))
+	defer batch.Release()
+
+GetSchedulerStats reports how many generations are running and queued; fmprom exports the
+queue depth.
+
+# Fallback Providers
+
+Code against the Provider interface to try Foundation Models first and fall back to
+another backend, such as an OpenAI-compatible endpoint, when the device is ineligible
+or the model is unavailable. Guardrail violations and cancellations don't fall back:
+
+	local := fm.NewSessionProvider("You are a helpful assistant.")
+	defer local.Release()
+
+	remote := fm.NewOpenAIProvider("https://api.openai.com/v1", os.Getenv("OPENAI_API_KEY"), "gpt-4o-mini")
+	remote.Instructions = "You are a helpful assistant."
+
+	provider := fm.Fallback(local, remote)
+	response, err := provider.RespondWithContext(ctx, "Hello", nil)
+
+	stream, err := provider.RespondStream(ctx, "Tell me a story", nil)
+
+# LangChainGo
+
+The optional fmlangchain module implements langchaingo's llms.Model, so existing chains
+and agents run on device. Each call replays its messages into a new session with
+SetTranscript; fm tools registered with fmlangchain.WithTools are called by the model:
+
+	llm := fmlangchain.New(fmlangchain.WithTools(&WeatherTool{}))
+	answer, err := llms.GenerateFromSinglePrompt(ctx, llm, "What's the weather in Paris?")
+
+# Genkit
+
+The optional fmgenkit module is a Genkit plugin providing Foundation Models as a model.
+Requested tools are looked up in the Genkit registry and run by the model, and JSON
+output with a schema is constrained to it:
+
+	g := genkit.Init(ctx, genkit.WithPlugins(&fmgenkit.FoundationModels{}))
+	resp, err := genkit.Generate(ctx, g, ai.WithModel(fmgenkit.Model(g)), ai.WithPrompt("Hello"))
+
+SchemaFromJSON converts a JSON Schema document, such as a Genkit output schema or an
+OpenAI response format, into a GenerationSchema:
+
+	schema, err := fm.SchemaFromJSON([]byte(`{"type": "object", "properties": {"city": {"type": "string"}}}`))
+	response, err := sess.RespondWithSchema("Where is the Eiffel Tower?", schema)
+
+# gRPC
+
+The optional fmgrpc module serves the model to other processes and languages with the
+foundationmodels.v1 gRPC service (fmgrpc/foundationmodels/v1/foundationmodels.proto).
+Clients create sessions, generate responses whole or streamed, and register tools they
+implement themselves, which the model calls over the RegisterTool stream:
+
+	srv := fmgrpc.NewServer()
+	defer srv.Close()
+	g := grpc.NewServer()
+	srv.Register(g)
+	g.Serve(lis)
+
+`found serve --grpc` runs it on a TCP address or a Unix socket.
+
+# MCP
+
+The optional fmmcp module connects to Model Context Protocol servers over stdio or HTTP
+and registers their tools with a session, forwarding the model's calls to the server:
+
+	client, err := fmmcp.ConnectCommand(ctx, exec.Command("mcp-server-git"), fmmcp.WithPrefix("git_"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.RegisterTools(ctx, sess); err != nil {
+		log.Fatal(err)
+	}
+
+Conversely, fmmcp.NewServer runs an MCP server that lets MCP clients delegate work to the
+model, with "generate" and "summarize" tools plus any Go tools given to it; `found mcp`
+runs it over stdio or HTTP:
+
+	server := fmmcp.NewServer(fmmcp.WithServerTools(&WeatherTool{}))
+	if err := server.ServeStdio(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+# Agent Loop
+
+A single tool-calling response handles tasks the model can finish in one go. The fmagent
+package runs multi-step tasks in a loop of steps on a session, each using tools and
+observing their results, until the model gives a final answer or the iteration limit is
+reached. Step hooks see each step's reply and tool calls:
+
+	agent := fmagent.New(sess,
+		fmagent.WithMaxIterations(5),
+		fmagent.WithStepHook(func(ctx context.Context, step fmagent.Step) error {
+			log.Printf("step %d: %d tool calls", step.Index, len(step.ToolCalls))
+			return nil
+		}))
+	result, err := agent.Run(ctx, "Get the weather in Paris, Tokyo and Lima and compare them")
+
+fmagent.WithMaxToolCalls limits the tool calls of a whole run in the same way, returning
+an error matching ErrToolBudgetExceeded.
+
 # Memory Management
 
 Always release sessions to prevent memory leaks:
@@ -261,21 +1002,57 @@ Always release sessions to prevent memory leaks:
 
 	// Use session...
 
+A session that is garbage collected without being released is released then, so a
+forgotten Release no longer leaks the Swift session for the life of the process, but
+collection may happen much later or not at all. To find sessions that are never
+released, log where each was created:
+
+	fm.SetSessionLeakDebug(true)
+
+When extending the package, check that every C string and session crossing the boundary
+with the shim is freed exactly once. Double frees panic, and the returned function logs
+whatever is still allocated:
+
+	defer fm.EnableDebugAllocations()()
+
 # Performance Considerations
 
 • Foundation Models runs entirely on-device
 • No internet connection required
 • Processing time depends on prompt complexity and device capabilities
 • Context window is limited to 4096 tokens
-• Token estimation is approximate (4 chars per token)
+• Token counts use the model's tokenizer on macOS 26.4+ and are approximate (4 chars per token) otherwise
 • Use context cancellation for long-running requests
 • Input validation prevents runtime errors and improves performance
 
 # Threading
 
-The package is not thread-safe. Use appropriate synchronization when accessing
-sessions from multiple goroutines. Context cancellation is goroutine-safe and can
-be used from any goroutine.
+A Session may be shared between goroutines. Generations on a session are serialized:
+each Respond*, streaming or AddContext call waits for the one in flight to finish
+(streams hold the session until their final value is sent or they are stopped), so
+concurrent calls never interleave inside the model. The context variants stop waiting
+when their context is done. Registering, clearing and reordering tools also wait, so the
+tool set never changes mid-generation; a tool must therefore not change the tools of the
+session that is calling it. Context accounting getters, IsResponding and Cancel never
+wait; use IsResponding to disable input in a UI while a generation is in flight.
+
+	sess := fm.NewSession()
+	defer sess.Release()
+
+	var wg sync.WaitGroup
+	for _, prompt := range prompts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := sess.RespondWithContext(ctx, prompt, nil)
+			// ...
+		}()
+	}
+	wg.Wait()
+
+Configuration setters (SetJSONIndent, SetDefaultTimeout, SetAutoTimeContext and the like)
+should be called before the session is shared, and Release only once no calls are in
+flight. Cancel, CancelAll and the package-level functions are goroutine-safe.
 
 # Swift Shim
 
@@ -284,10 +1061,43 @@ Foundation Models APIs to C functions callable from Go via purego.
 
 The library search strategy:
 1. Look for existing libFMShim.dylib in current directory and common paths
-2. If not found, automatically extract embedded library to temp directory
+2. If not found, extract the embedded library to ~/Library/Caches/go-foundationmodels/<sha256>/
 3. Load the library and initialize the Foundation Models interface
 
-No manual setup required - the package is fully self-contained!
+An extracted copy is reused only while its SHA-256 matches the embedded library, and is
+replaced otherwise, so upgrading the package never loads a stale or modified shim.
+
+Every library reports the version of the interface it exports, and Init fails with
+ErrShimVersionMismatch if a library found in step 1 was built for a different version of
+the package. Delete the stale libFMShim.dylib or rebuild it with make. A library older
+than the package still loads: the features needing exports it lacks return
+ErrShimFeatureUnavailable.
+
+No manual setup required - the package is fully self-contained! The library is loaded
+on first use rather than at import. Call Init at startup to get the load error directly
+and pay the loading cost up front:
+
+	if err := fm.Init(); err != nil {
+		log.Fatalf("Foundation Models unavailable: %v", err)
+	}
+
+Packaged apps that ship the library in their bundle, and must not write to the current or
+cache directory, can load it from a fixed path with WithShimPath or the FM_SHIM_PATH
+environment variable; no other location is tried:
+
+	err := fm.Init(fm.WithShimPath("/Applications/MyApp.app/Contents/Frameworks/libFMShim.dylib"))
+
+When developing the shim, set FM_DISABLE_EMBEDDED=1 to skip step 2: initialization
+then fails with an error listing the searched paths if no on-disk library is found,
+rather than silently using a stale embedded copy.
+
+Building with CGO_ENABLED=1 (make build-static) links a static shim into the binary
+instead of loading libFMShim.dylib. Both builds share one implementation, so every
+feature works the same in either; only loading the shim differs.
+
+iOS doesn't allow loading dynamic libraries, so iOS 26 and Mac Catalyst apps built with
+gomobile use the CGO build, which links a static shim for each target. Build them with
+make build-ios.
 
 # Limitations
 
@@ -314,7 +1124,8 @@ No manual setup required - the package is fully self-contained!
 
 # Debug Logging
 
-The package provides comprehensive debug logging through Go's slog package:
+The package never prints to stdout or stderr itself; all diagnostics go through Go's
+slog package, to slog.Default() unless a logger is set with SetLogger:
 
 	import "log/slog"
 
@@ -322,7 +1133,7 @@ The package provides comprehensive debug logging through Go's slog package:
 	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	})
-	slog.SetDefault(slog.New(handler))
+	fm.SetLogger(slog.New(handler))
 
 	// All fm operations will now log detailed debug information
 	sess := fm.NewSession()
@@ -335,6 +1146,64 @@ Debug logs include:
 • Context usage and memory management
 • Swift shim layer interaction details
 
+# Transcript
+
+Inspect the framework's actual transcript to see how instructions, prompts and tool calls
+are structured for the model (the CLI prints it with --transcript):
+
+	entries, err := sess.Transcript()
+	if err == nil {
+		fmt.Print(fm.FormatTranscript(entries))
+	}
+
+SetTranscript replaces a session's conversation, e.g. with chat history kept by the
+caller. Include an instructions entry to keep instructions:
+
+	err = sess.SetTranscript([]fm.TranscriptEntry{
+		{Role: fm.TranscriptRoleInstructions, Content: "You are a travel agent."},
+		{Role: fm.TranscriptRolePrompt, Content: "I'd like to visit Lisbon."},
+		{Role: fm.TranscriptRoleResponse, Content: "Great choice! When are you travelling?"},
+	})
+
+# Conversation History
+
+Save a multi-turn conversation and resume it later. The prior turns are replayed into the
+new session's transcript; tools must be registered again:
+
+	data, err := sess.ExportHistory()
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.WriteFile("conversation.json", data, 0o600)
+
+	// Later
+	data, _ = os.ReadFile("conversation.json")
+	sess, err = fm.NewSessionFromHistory(data)
+
+Clone branches a conversation: the clone starts with the same instructions, transcript,
+tools and settings, and asking it something new leaves the original untouched:
+
+	branch, err := sess.Clone()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer branch.Release()
+	alternative, err := branch.Respond("What if I asked about trains instead?", nil)
+
+A SessionStore saves sessions under an ID (the CLI's quest --session uses one) and
+rehydrates them, registering the tools passed to Load:
+
+	store, err := fm.NewSessionStore(filepath.Join(configDir, "sessions"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sess, err := store.Load("trip", &WeatherTool{})
+	if errors.Is(err, fm.ErrSessionNotFound) {
+		sess = fm.NewSession()
+	}
+	// ...
+	err = store.Save("trip", sess)
+
 # License
 
 See LICENSE file for details.
@@ -0,0 +1,221 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrShimNotInitialized is returned when the Swift shim library failed to load
+	ErrShimNotInitialized = errors.New("Foundation Models shim not initialized")
+
+	// ErrUnsupportedPlatform is returned by Init, and by everything that needs the model,
+	// when the program isn't running on an Apple silicon Mac. It matches
+	// ErrModelUnavailable with errors.Is.
+	ErrUnsupportedPlatform = fmt.Errorf("%w: Foundation Models requires macOS on Apple silicon", ErrModelUnavailable)
+
+	// ErrShimVersionMismatch is returned by Init when the shim library found on disk was
+	// built for a different version of this package, typically a stale libFMShim.dylib
+	// in the working directory
+	ErrShimVersionMismatch = errors.New("shim library version mismatch")
+
+	// ErrShimFeatureUnavailable is returned by features that need a shim export the
+	// loaded library lacks, typically an older libFMShim.dylib. Rebuild it with make.
+	ErrShimFeatureUnavailable = errors.New("shim library lacks the feature")
+
+	// ErrInvalidSession is returned when a session was never created or has been released
+	ErrInvalidSession = errors.New("invalid session")
+
+	// ErrNoResponse is returned when the shim returned no response at all
+	ErrNoResponse = errors.New("no response from FoundationModels")
+
+	// ErrGenerationFailed is returned when Foundation Models reported an error that has no
+	// more specific sentinel. The error message carries the framework's description.
+	ErrGenerationFailed = errors.New("generation failed")
+
+	// ErrContextLimit is returned when the local token estimate predicts that a request
+	// would overflow the context window. The request is never sent to the model.
+	ErrContextLimit = errors.New("context size would exceed limit")
+
+	// ErrContextExceeded is returned when Foundation Models itself rejected the request
+	// because the transcript no longer fits in the context window.
+	ErrContextExceeded = errors.New("model context window exceeded")
+
+	// ErrInstructionsTooLong is returned by NewSessionWithInstructionsLimited when the
+	// instructions exceed the requested token budget
+	ErrInstructionsTooLong = errors.New("instructions exceed token budget")
+
+	// ErrModelUnavailable is matched by the errors for every reason the model can be
+	// unavailable (see Availability.Err)
+	ErrModelUnavailable = errors.New("model unavailable")
+
+	// ErrAppleIntelligenceNotEnabled means Apple Intelligence is turned off
+	ErrAppleIntelligenceNotEnabled = fmt.Errorf("%w: Apple Intelligence not enabled", ErrModelUnavailable)
+
+	// ErrModelNotReady means the model can't be used yet, e.g. while its assets download
+	ErrModelNotReady = fmt.Errorf("%w: model not ready", ErrModelUnavailable)
+
+	// ErrDeviceNotEligible means the device does not support Apple Intelligence
+	ErrDeviceNotEligible = fmt.Errorf("%w: device not eligible", ErrModelUnavailable)
+
+	// ErrModelBecameUnavailable is returned when the model stops being available while a
+	// session is in use, e.g. because Apple Intelligence was turned off
+	ErrModelBecameUnavailable = errors.New("model became unavailable")
+
+	// ErrCircuitOpen is returned without starting a generation while the circuit breaker
+	// is open because the model was recently found to be unavailable (see
+	// SetGlobalCircuitBreaker). It matches ErrModelUnavailable with errors.Is.
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrModelUnavailable)
+
+	// ErrGenerationCancelled is returned when an in-flight generation was cancelled with
+	// Session.Cancel or CancelAll
+	ErrGenerationCancelled = errors.New("generation cancelled")
+
+	// ErrGenerationTimeout is returned when a generation was cancelled because the
+	// session's default timeout elapsed. It also matches context.DeadlineExceeded.
+	ErrGenerationTimeout = fmt.Errorf("generation timed out: %w", context.DeadlineExceeded)
+
+	// ErrSchemaMismatch is returned by RespondInto when the structured response can't be
+	// decoded into the target value
+	ErrSchemaMismatch = errors.New("response does not match target type")
+
+	// ErrNoMatch is returned by RespondMatching when no response matched the pattern
+	ErrNoMatch = errors.New("response does not match pattern")
+
+	// ErrTokenCountingUnavailable is returned by CountTokens when the model's tokenizer
+	// can't be used, which requires macOS 26.4 or later
+	ErrTokenCountingUnavailable = errors.New("token counting is not available on this system")
+
+	// ErrGuardrailViolation is returned when Foundation Models refused a prompt or response
+	// for safety reasons. The error message carries the framework's description. Asking
+	// the user to rephrase, or relaxing the session's guardrails (see WithGuardrails) for
+	// content transformations, may help.
+	ErrGuardrailViolation = errors.New("guardrail violation")
+
+	// ErrRateLimited is returned when Foundation Models refused a request because too many
+	// are in flight, e.g. from concurrent sessions or a background app. It is worth retrying.
+	ErrRateLimited = errors.New("rate limited by FoundationModels")
+
+	// ErrToolBudgetExceeded is matched by the error returned when a generation or agent run
+	// makes more tool calls than its budget allows (see WithMaxToolCalls and
+	// ToolBudgetError)
+	ErrToolBudgetExceeded = errors.New("tool call budget exceeded")
+
+	// ErrToolCallDenied starts the error reported to the model when a session's tool
+	// approver denies a tool call (see Session.SetToolApprover)
+	ErrToolCallDenied = errors.New("tool call denied")
+
+	// ErrToolTimeout starts the error reported to the model when a tool call runs out of
+	// time (see WithToolTimeout)
+	ErrToolTimeout = errors.New("tool call timed out")
+
+	// ErrAdapterInvalid is returned when an adapter file is missing or can't be loaded
+	ErrAdapterInvalid = errors.New("invalid adapter")
+
+	// ErrAdapterIncompatible is returned when an adapter was trained for a different
+	// version of the base model than the one installed
+	ErrAdapterIncompatible = errors.New("adapter is incompatible with the installed base model")
+)
+
+// ContextExceededError is returned when the framework refuses a request because the
+// transcript exceeds the context window. It matches ErrContextExceeded with errors.Is.
+type ContextExceededError struct {
+	// Tokens is the token count reported by the framework, or 0 if it did not report one
+	Tokens int
+	// Detail is the framework's description of the failure
+	Detail string
+}
+
+func (e *ContextExceededError) Error() string {
+	if e.Tokens > 0 {
+		return fmt.Sprintf("%v (%d tokens): %s", ErrContextExceeded, e.Tokens, e.Detail)
+	}
+	return fmt.Sprintf("%v: %s", ErrContextExceeded, e.Detail)
+}
+
+func (e *ContextExceededError) Unwrap() error {
+	return ErrContextExceeded
+}
+
+// ToolBudgetError is returned when the model tries to call more tools than allowed,
+// stopping a runaway tool loop. It matches ErrToolBudgetExceeded with errors.Is.
+type ToolBudgetError struct {
+	// Limit is the number of tool calls that were allowed
+	Limit int
+	// Transcript is the conversation when the budget ran out, including the tool calls
+	// and results so far, or nil if it couldn't be read
+	Transcript []TranscriptEntry
+}
+
+func (e *ToolBudgetError) Error() string {
+	return fmt.Sprintf("%v: more than %d tool calls", ErrToolBudgetExceeded, e.Limit)
+}
+
+func (e *ToolBudgetError) Unwrap() error {
+	return ErrToolBudgetExceeded
+}
+
+// Error codes tagged onto "Error:" responses by the Swift shim. Every shim error starts
+// with shimErrorMarker, so errors can't be confused with model output that happens to
+// start with "Error:". A context_exceeded error carries the token count the shim parsed
+// from the framework's message (0 if none) before the message itself, and a
+// tool_budget_exceeded error carries the limit.
+const (
+	shimErrorMarker          = "\x01"
+	shimErrorPrefix          = "Error: "
+	shimCodeContextExceeded  = "[context_exceeded]"
+	shimCodeModelUnavailable = "[model_unavailable]"
+	shimCodeCancelled        = "[cancelled]"
+	shimCodeGuardrail        = "[guardrail_violation]"
+	shimCodeRateLimited      = "[rate_limited]"
+	shimCodeAdapterInvalid   = "[adapter_invalid]"
+	shimCodeAdapterIncompat  = "[adapter_incompatible]"
+	shimCodeToolBudget       = "[tool_budget_exceeded]"
+)
+
+// shimError converts an error response from the Swift shim into a typed error.
+// It returns nil for regular responses.
+func shimError(response string) error {
+	detail, ok := strings.CutPrefix(response, shimErrorMarker+shimErrorPrefix)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(detail, shimCodeContextExceeded):
+		detail = strings.TrimSpace(strings.TrimPrefix(detail, shimCodeContextExceeded))
+		err := &ContextExceededError{Detail: detail}
+		count, message, _ := strings.Cut(detail, " ")
+		if tokens, convErr := strconv.Atoi(count); convErr == nil {
+			err.Tokens = tokens
+			err.Detail = strings.TrimSpace(message)
+		}
+		return err
+	case strings.HasPrefix(detail, shimCodeModelUnavailable):
+		detail = strings.TrimSpace(strings.TrimPrefix(detail, shimCodeModelUnavailable))
+		err := fmt.Errorf("%w: %s", ErrModelBecameUnavailable, detail)
+		if availability := GetModelAvailability(); !availability.Available() {
+			err = fmt.Errorf("%w: %w: %s", ErrModelBecameUnavailable, availability.Err(), detail)
+		}
+		tripCircuitBreaker(err)
+		return err
+	case strings.HasPrefix(detail, shimCodeCancelled):
+		return ErrGenerationCancelled
+	case strings.HasPrefix(detail, shimCodeGuardrail):
+		return fmt.Errorf("%w: %s", ErrGuardrailViolation, strings.TrimSpace(strings.TrimPrefix(detail, shimCodeGuardrail)))
+	case strings.HasPrefix(detail, shimCodeRateLimited):
+		return fmt.Errorf("%w: %s", ErrRateLimited, strings.TrimSpace(strings.TrimPrefix(detail, shimCodeRateLimited)))
+	case strings.HasPrefix(detail, shimCodeToolBudget):
+		limit, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(detail, shimCodeToolBudget)))
+		return &ToolBudgetError{Limit: limit}
+	case strings.HasPrefix(detail, shimCodeAdapterInvalid):
+		return fmt.Errorf("%w: %s", ErrAdapterInvalid, strings.TrimSpace(strings.TrimPrefix(detail, shimCodeAdapterInvalid)))
+	case strings.HasPrefix(detail, shimCodeAdapterIncompat):
+		return fmt.Errorf("%w: %s", ErrAdapterIncompatible, strings.TrimSpace(strings.TrimPrefix(detail, shimCodeAdapterIncompat)))
+	default:
+		return fmt.Errorf("%w: %s", ErrGenerationFailed, detail)
+	}
+}
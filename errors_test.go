@@ -0,0 +1,163 @@
+package fm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShimError(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     error
+		wantMsg  string
+	}{
+		{name: "response", response: "The sky is blue.", want: nil},
+		{name: "unmarked error text", response: "Error: [context_exceeded] looks like an error", want: nil},
+		{name: "marker only", response: shimErrorMarker + "Error", want: nil},
+		{
+			name:     "generic",
+			response: shimErrorMarker + "Error: something broke",
+			want:     ErrGenerationFailed,
+			wantMsg:  "generation failed: something broke",
+		},
+		{
+			name:     "cancelled",
+			response: shimErrorMarker + "Error: [cancelled] stopped",
+			want:     ErrGenerationCancelled,
+			wantMsg:  "generation cancelled",
+		},
+		{
+			name:     "guardrail",
+			response: shimErrorMarker + "Error: [guardrail_violation] unsafe content",
+			want:     ErrGuardrailViolation,
+			wantMsg:  "guardrail violation: unsafe content",
+		},
+		{
+			name:     "rate limited",
+			response: shimErrorMarker + "Error: [rate_limited] busy",
+			want:     ErrRateLimited,
+			wantMsg:  "rate limited by FoundationModels: busy",
+		},
+		{
+			name:     "adapter invalid",
+			response: shimErrorMarker + "Error: [adapter_invalid] missing file",
+			want:     ErrAdapterInvalid,
+			wantMsg:  "invalid adapter: missing file",
+		},
+		{
+			name:     "adapter incompatible",
+			response: shimErrorMarker + "Error: [adapter_incompatible] wrong base",
+			want:     ErrAdapterIncompatible,
+			wantMsg:  "adapter is incompatible with the installed base model: wrong base",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := shimError(tt.response)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("shimError(%q) = %v, want nil", tt.response, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("shimError(%q) = %v, want %v", tt.response, err, tt.want)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("shimError(%q) message = %q, want %q", tt.response, err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestShimErrorContextExceeded(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		wantTokens int
+		wantDetail string
+		wantMsg    string
+	}{
+		{
+			name:       "with token count",
+			response:   shimErrorMarker + "Error: [context_exceeded] 4153 Content contains 4153 tokens, which exceeds the maximum",
+			wantTokens: 4153,
+			wantDetail: "Content contains 4153 tokens, which exceeds the maximum",
+			wantMsg:    "model context window exceeded (4153 tokens): Content contains 4153 tokens, which exceeds the maximum",
+		},
+		{
+			name:       "limit and count",
+			response:   shimErrorMarker + "Error: [context_exceeded] 4210 exceeds the 4096 token limit (4210 tokens)",
+			wantTokens: 4210,
+			wantDetail: "exceeds the 4096 token limit (4210 tokens)",
+			wantMsg:    "model context window exceeded (4210 tokens): exceeds the 4096 token limit (4210 tokens)",
+		},
+		{
+			name:       "without token count",
+			response:   shimErrorMarker + "Error: [context_exceeded] 0 context window full",
+			wantDetail: "context window full",
+			wantMsg:    "model context window exceeded: context window full",
+		},
+		{
+			name:       "without count field",
+			response:   shimErrorMarker + "Error: [context_exceeded] context window full",
+			wantDetail: "context window full",
+			wantMsg:    "model context window exceeded: context window full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := shimError(tt.response)
+			var exceeded *ContextExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("shimError(%q) = %v, want *ContextExceededError", tt.response, err)
+			}
+			if !errors.Is(err, ErrContextExceeded) {
+				t.Errorf("shimError(%q) does not match ErrContextExceeded", tt.response)
+			}
+			if exceeded.Tokens != tt.wantTokens {
+				t.Errorf("Tokens = %d, want %d", exceeded.Tokens, tt.wantTokens)
+			}
+			if exceeded.Detail != tt.wantDetail {
+				t.Errorf("Detail = %q, want %q", exceeded.Detail, tt.wantDetail)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestShimErrorModelUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantMsg  string
+	}{
+		{
+			name:     "with detail",
+			response: shimErrorMarker + "Error: [model_unavailable] Apple Intelligence was turned off",
+			wantMsg:  "Apple Intelligence was turned off",
+		},
+		{
+			name:     "without detail",
+			response: shimErrorMarker + "Error: [model_unavailable]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := shimError(tt.response)
+			if !errors.Is(err, ErrModelBecameUnavailable) {
+				t.Fatalf("shimError(%q) = %v, want ErrModelBecameUnavailable", tt.response, err)
+			}
+			if availability := GetModelAvailability(); !availability.Available() && !errors.Is(err, availability.Err()) {
+				t.Errorf("shimError(%q) = %v, want it to carry the availability error %v", tt.response, err, availability.Err())
+			}
+			if !strings.HasSuffix(err.Error(), ": "+tt.wantMsg) {
+				t.Errorf("shimError(%q) = %q, want it to end with the detail %q", tt.response, err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -1,6 +1,3 @@
-//go:build !cgo
-// +build !cgo
-
 // Package fm provides a pure Go wrapper around macOS Foundation Models framework
 // using purego to call a Swift shim library that exports C functions.
 //
@@ -21,34 +18,62 @@ package fm
 
 import (
 	"context"
-	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log/slog"
-	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/ebitengine/purego"
+	"golang.org/x/text/language"
 )
 
 const MAX_CONTEXT_SIZE = 4096 // Foundation Models context limit
 
+// shimVersion is the version of the shim's C interface this package was built against. It
+// must match shimABIVersion in FoundationModelsShim.swift. Added exports are optional
+// symbols and don't change it; only removed or changed exports do.
+const shimVersion = 1
+
 var (
 	// Swift shim library handle and function pointers
 	shimLib                       uintptr
 	createSess                    uintptr
 	createSessionWithInstructions uintptr
+	createSessionWithTranscript   uintptr
+	setTranscript                 uintptr
+	prewarmSession                uintptr
+	isResponding                  uintptr
+	getModelAvailability          uintptr
+	getModelDetails               uintptr
+	setSessionUseCase             uintptr
+	setSessionAdapter             uintptr
+	checkAdapterCompatibility     uintptr
+	setSessionGuardrails          uintptr
+	setSessionMaxToolCalls        uintptr
 	releaseSession                uintptr
 	checkModelAvailability        uintptr
 	respondSync                   uintptr
 	respondWithStructuredOutput   uintptr
 	respondWithTools              uintptr
 	respondWithOptions            uintptr
+	respondWithSchema             uintptr
 	respondWithStreaming          uintptr
 	respondWithToolsStreaming     uintptr
+	respondStreamingWithID        uintptr
+	respondStreamingWithOptions   uintptr
+	respondWithSchemaStreaming    uintptr
+	cancelResponse                uintptr
+	getTranscript                 uintptr
+	countTokens                   uintptr
+	addContext                    uintptr
 	getModelInfo                  uintptr
 	registerTool                  uintptr
 	clearTools                    uintptr
@@ -59,133 +84,188 @@ var (
 	libcFree   uintptr
 	libcMalloc uintptr
 
-	// Global tool registry
-	toolRegistry = make(map[string]Tool)
-
-	// Initialization state
+	// Initialization state, set once by Init
+	shimOnce        sync.Once
 	shimInitialized bool
 	shimInitError   error
 )
 
-// Embed the Swift shim library
-//
-//go:embed libFMShim.dylib
-var embeddedShimLib []byte
+// InitOption configures how Init loads the Swift shim library
+type InitOption func(*initConfig)
 
-func init() {
-	// Initialize the shim on first import
-	shimInitError = initializeShim()
-	if shimInitError == nil {
-		shimInitialized = true
+// initConfig holds the settings of InitOptions
+type initConfig struct {
+	shimPath string // Library to load instead of searching (empty = search)
+}
+
+// WithShimPath loads the shim library from path instead of searching for it or
+// extracting the embedded copy, for packaged apps that ship the library in their bundle
+// and must not write to the current or temp directory. It takes precedence over
+// EnvShimPath.
+func WithShimPath(path string) InitOption {
+	return func(c *initConfig) {
+		c.shimPath = path
 	}
 }
 
+// Init loads the Swift shim library, extracting the embedded copy if no library is found
+// on disk. It is called automatically by NewSession and the other entry points, so
+// calling it is optional; call it at startup to surface load failures early, to choose
+// when the cost of loading is paid, or to pass options. Init is idempotent: later calls
+// return the result of the first, and their options are ignored.
+//
+// Before Init existed the shim was loaded when the package was imported, which made
+// failures invisible and slowed startup for programs that never used the model.
+func Init(opts ...InitOption) error {
+	ran := false
+	shimOnce.Do(func() {
+		ran = true
+		var config initConfig
+		for _, opt := range opts {
+			opt(&config)
+		}
+		shimInitError = initializeShim(config)
+		shimInitialized = shimInitError == nil
+	})
+	if !ran && len(opts) > 0 {
+		logger().Warn("Foundation Models shim already initialized, ignoring Init options")
+	}
+	return shimInitError
+}
+
 // initializeShim loads the Swift shim library and sets up all function pointers
-func initializeShim() error {
-	// Load the Swift shim library
+func initializeShim(config initConfig) error {
+	// Load the Swift shim library through the build's transport (see openShim)
+	var shimName string
 	var err error
-	shimPath := findOrExtractShimLibrary()
-
-	shimLib, err = purego.Dlopen(shimPath, purego.RTLD_NOW)
+	shimLib, shimName, err = openShim(config)
 	if err != nil {
-		return fmt.Errorf("failed to load libFMShim.dylib from %s: %v", shimPath, err)
+		return err
+	}
+	if err := checkShimVersion(shimName); err != nil {
+		return err
 	}
 
 	// Load function symbols from the shim
-	createSess, err = purego.Dlsym(shimLib, "CreateSession")
+	createSess, err = dlsym(shimLib, "CreateSession")
 	if err != nil {
 		return fmt.Errorf("failed to load CreateSession: %v", err)
 	}
 
-	createSessionWithInstructions, err = purego.Dlsym(shimLib, "CreateSessionWithInstructions")
+	createSessionWithInstructions, err = dlsym(shimLib, "CreateSessionWithInstructions")
 	if err != nil {
 		return fmt.Errorf("failed to load CreateSessionWithInstructions: %v", err)
 	}
 
-	releaseSession, err = purego.Dlsym(shimLib, "ReleaseSession")
+	releaseSession, err = dlsym(shimLib, "ReleaseSession")
 	if err != nil {
 		return fmt.Errorf("failed to load ReleaseSession: %v", err)
 	}
 
-	checkModelAvailability, err = purego.Dlsym(shimLib, "CheckModelAvailability")
+	checkModelAvailability, err = dlsym(shimLib, "CheckModelAvailability")
 	if err != nil {
 		return fmt.Errorf("failed to load CheckModelAvailability: %v", err)
 	}
 
-	respondSync, err = purego.Dlsym(shimLib, "RespondSync")
+	respondSync, err = dlsym(shimLib, "RespondSync")
 	if err != nil {
 		return fmt.Errorf("failed to load RespondSync: %v", err)
 	}
 
-	respondWithStructuredOutput, err = purego.Dlsym(shimLib, "RespondWithStructuredOutput")
+	respondWithStructuredOutput, err = dlsym(shimLib, "RespondWithStructuredOutput")
 	if err != nil {
 		return fmt.Errorf("failed to load RespondWithStructuredOutput: %v", err)
 	}
 
-	respondWithTools, err = purego.Dlsym(shimLib, "RespondWithTools")
+	respondWithTools, err = dlsym(shimLib, "RespondWithTools")
 	if err != nil {
 		return fmt.Errorf("failed to load RespondWithTools: %v", err)
 	}
 
-	respondWithOptions, err = purego.Dlsym(shimLib, "RespondWithOptions")
-	if err != nil {
-		return fmt.Errorf("failed to load RespondWithOptions: %v", err)
-	}
-
-	getModelInfo, err = purego.Dlsym(shimLib, "GetModelInfo")
+	getModelInfo, err = dlsym(shimLib, "GetModelInfo")
 	if err != nil {
 		return fmt.Errorf("failed to load GetModelInfo: %v", err)
 	}
 
-	registerTool, err = purego.Dlsym(shimLib, "RegisterTool")
+	registerTool, err = dlsym(shimLib, "RegisterTool")
 	if err != nil {
 		return fmt.Errorf("failed to load RegisterTool: %v", err)
 	}
 
-	clearTools, err = purego.Dlsym(shimLib, "ClearTools")
+	clearTools, err = dlsym(shimLib, "ClearTools")
 	if err != nil {
 		return fmt.Errorf("failed to load ClearTools: %v", err)
 	}
 
-	setToolCallback, err = purego.Dlsym(shimLib, "SetToolCallback")
-	if err != nil {
-		return fmt.Errorf("failed to load SetToolCallback: %v", err)
-	}
-
-	getLogs, err = purego.Dlsym(shimLib, "GetLogs")
+	getLogs, err = dlsym(shimLib, "GetLogs")
 	if err != nil {
 		return fmt.Errorf("failed to load GetLogs: %v", err)
 	}
 
-	// Load streaming function symbols
-	respondWithStreaming, err = purego.Dlsym(shimLib, "RespondWithStreaming")
+	respondWithStreaming, err = dlsym(shimLib, "RespondWithStreaming")
 	if err != nil {
 		return fmt.Errorf("failed to load RespondWithStreaming: %v", err)
 	}
 
-	respondWithToolsStreaming, err = purego.Dlsym(shimLib, "RespondWithToolsStreaming")
+	respondWithToolsStreaming, err = dlsym(shimLib, "RespondWithToolsStreaming")
 	if err != nil {
 		return fmt.Errorf("failed to load RespondWithToolsStreaming: %v", err)
 	}
 
+	// Symbols added after the first shim release are optional, so an older shim still
+	// loads. Only the features that need a missing symbol fail, with
+	// ErrShimFeatureUnavailable (see shimSymbol).
+	for _, sym := range []struct {
+		name string
+		addr *uintptr
+	}{
+		{"CreateSessionWithTranscript", &createSessionWithTranscript},
+		{"SetTranscript", &setTranscript},
+		{"PrewarmSession", &prewarmSession},
+		{"IsResponding", &isResponding},
+		{"GetModelAvailability", &getModelAvailability},
+		{"GetModelDetails", &getModelDetails},
+		{"SetSessionUseCase", &setSessionUseCase},
+		{"SetSessionAdapter", &setSessionAdapter},
+		{"CheckAdapterCompatibility", &checkAdapterCompatibility},
+		{"SetSessionGuardrails", &setSessionGuardrails},
+		{"SetSessionMaxToolCalls", &setSessionMaxToolCalls},
+		{"RespondWithGenerationOptions", &respondWithOptions},
+		{"RespondWithSchema", &respondWithSchema},
+		{"SetSessionToolCallback", &setToolCallback},
+		{"RespondStreamingWithID", &respondStreamingWithID},
+		{"RespondStreamingWithOptions", &respondStreamingWithOptions},
+		{"RespondWithSchemaStreaming", &respondWithSchemaStreaming},
+		{"CancelResponse", &cancelResponse},
+		{"GetTranscript", &getTranscript},
+		{"CountTokens", &countTokens},
+		{"AddContext", &addContext},
+	} {
+		if *sym.addr, err = dlsym(shimLib, sym.name); err != nil {
+			*sym.addr = 0
+			logger().Debug("Shim library lacks optional symbol", "symbol", sym.name)
+		}
+	}
+
 	// Load system libc for memory management
-	libcHandle, err := purego.Dlopen("/usr/lib/libc.dylib", purego.RTLD_NOW)
+	libcHandle, err := openLibc()
 	if err != nil {
 		return fmt.Errorf("failed to load libc: %v", err)
 	}
 
-	libcFree, err = purego.Dlsym(libcHandle, "free")
+	libcFree, err = dlsym(libcHandle, "free")
 	if err != nil {
 		return fmt.Errorf("failed to load free function: %v", err)
 	}
 
-	libcMalloc, err = purego.Dlsym(libcHandle, "malloc")
+	libcMalloc, err = dlsym(libcHandle, "malloc")
 	if err != nil {
 		return fmt.Errorf("failed to load malloc function: %v", err)
 	}
 
-	// Set up the tool callback
+	// Set up the tool and streaming callbacks
 	setupToolCallback()
+	setupStreamCallback()
 
 	return nil
 }
@@ -199,8 +279,29 @@ const (
 	ModelUnavailableNotReady
 	ModelUnavailableDeviceNotEligible
 	ModelUnavailableUnknown = -1
+	// ModelUnavailableUnsupportedPlatform means the program isn't running on an Apple
+	// silicon Mac, where Foundation Models can never be available
+	ModelUnavailableUnsupportedPlatform ModelAvailability = -2
 )
 
+// String returns a human-readable description of the availability status
+func (a ModelAvailability) String() string {
+	switch a {
+	case ModelAvailable:
+		return "available"
+	case ModelUnavailableAINotEnabled:
+		return "Apple Intelligence not enabled"
+	case ModelUnavailableNotReady:
+		return "model not ready"
+	case ModelUnavailableDeviceNotEligible:
+		return "device not eligible"
+	case ModelUnavailableUnsupportedPlatform:
+		return "unsupported platform"
+	default:
+		return fmt.Sprintf("unknown availability status (%d)", int(a))
+	}
+}
+
 // Tool represents a tool that can be called by the Foundation Models
 type Tool interface {
 	// Name returns the name of the tool
@@ -231,44 +332,71 @@ type ToolArgument struct {
 	Type        string   `json:"type"` // "string", "number", "integer", "boolean", "array", "object"
 	Description string   `json:"description"`
 	Required    bool     `json:"required"`
-	MinLength   *int     `json:"minLength,omitempty"` // For strings
-	MaxLength   *int     `json:"maxLength,omitempty"` // For strings
-	Minimum     *float64 `json:"minimum,omitempty"`   // For numbers
-	Maximum     *float64 `json:"maximum,omitempty"`   // For numbers
-	Pattern     *string  `json:"pattern,omitempty"`   // Regex pattern for strings
-	Enum        []any    `json:"enum,omitempty"`      // Allowed values
+	MinLength   *int     `json:"minLength,omitempty"`  // For strings
+	MaxLength   *int     `json:"maxLength,omitempty"`  // For strings
+	Minimum     *float64 `json:"minimum,omitempty"`    // For numbers
+	Maximum     *float64 `json:"maximum,omitempty"`    // For numbers
+	Pattern     *string  `json:"pattern,omitempty"`    // Regex pattern for strings
+	Enum        []any    `json:"enum,omitempty"`       // Allowed values
+	LengthUnit  string   `json:"lengthUnit,omitempty"` // "runes" (default) or "bytes" for MinLength/MaxLength
 }
 
+// Length units for ToolArgument string length constraints
+const (
+	LengthUnitRunes = "runes" // Count Unicode code points (default)
+	LengthUnitBytes = "bytes" // Count UTF-8 encoded bytes
+)
+
 // ToolResult represents the result of a tool execution
 type ToolResult struct {
 	Content string `json:"content"`
 	Error   string `json:"error,omitempty"`
+	// TimedOut is set when the call was stopped by its timeout (see WithToolTimeout)
+	TimedOut bool `json:"timedOut,omitempty"`
 }
 
+// SamplingMode selects how the model chooses each token
+type SamplingMode string
+
+const (
+	// SamplingDefault uses the framework's default sampling
+	SamplingDefault SamplingMode = ""
+	// SamplingGreedy always picks the most likely token (argmax decoding), so output is
+	// deterministic for a given prompt and transcript
+	SamplingGreedy SamplingMode = "greedy"
+)
+
 // GenerationOptions represents options for controlling text generation
 type GenerationOptions struct {
+	// SamplingMode selects greedy decoding instead of random sampling (default: random).
+	// Greedy sampling can't be combined with TopP, TopK or Seed.
+	SamplingMode SamplingMode `json:"samplingMode,omitempty"`
+
 	// MaxTokens is the maximum number of tokens to generate (default: no limit)
 	MaxTokens *int `json:"maxTokens,omitempty"`
 
 	// Temperature controls randomness (0.0 = deterministic, 1.0 = very random)
 	Temperature *float32 `json:"temperature,omitempty"`
 
-	// TopP controls nucleus sampling probability threshold (0.0-1.0)
+	// TopP controls nucleus sampling probability threshold (0.0-1.0, exclusive with TopK)
 	TopP *float32 `json:"topP,omitempty"`
 
-	// TopK controls top-K sampling limit (positive integer)
+	// TopK controls top-K sampling limit (positive integer, exclusive with TopP)
 	TopK *int `json:"topK,omitempty"`
 
-	// PresencePenalty penalizes tokens based on their presence in the text so far
+	// PresencePenalty penalizes tokens based on their presence in the text so far.
+	// Not supported by Foundation Models; ignored with a warning.
 	PresencePenalty *float32 `json:"presencePenalty,omitempty"`
 
-	// FrequencyPenalty penalizes tokens based on their frequency in the text so far
+	// FrequencyPenalty penalizes tokens based on their frequency in the text so far.
+	// Not supported by Foundation Models; ignored with a warning.
 	FrequencyPenalty *float32 `json:"frequencyPenalty,omitempty"`
 
-	// StopSequences is an array of sequences that will stop generation
+	// StopSequences is an array of sequences that will stop generation. The response is
+	// cut before the first one; when streaming, generation is cancelled once it appears.
 	StopSequences []string `json:"stopSequences,omitempty"`
 
-	// Seed for reproducible generation (when temperature is 0.0)
+	// Seed makes random sampling reproducible
 	Seed *int `json:"seed,omitempty"`
 }
 
@@ -288,7 +416,15 @@ func WithMaxTokens(maxTokens int) *GenerationOptions {
 	}
 }
 
-// WithDeterministic creates GenerationOptions for deterministic output
+// WithGreedy creates GenerationOptions for greedy (argmax) decoding
+func WithGreedy() *GenerationOptions {
+	return &GenerationOptions{
+		SamplingMode: SamplingGreedy,
+	}
+}
+
+// WithDeterministic creates GenerationOptions for deterministic output. It approximates
+// determinism with temperature 0; use WithGreedy for true argmax decoding.
 func WithDeterministic() *GenerationOptions {
 	temp := float32(0.0)
 	return &GenerationOptions{
@@ -330,82 +466,121 @@ type ToolDefinition struct {
 // Session represents a LanguageModelSession with context tracking
 type Session struct {
 	ptr                unsafe.Pointer
+	busy               chan struct{}   // Held by the running generation (see lock)
+	mu                 sync.Mutex      // Guards contextSize, the tool fields and the settings changed by setters
 	contextSize        int             // Approximate token count
 	maxContextSize     int             // Maximum allowed tokens
 	systemInstructions string          // System instructions provided at creation
-	registeredTools    map[string]Tool // Tools registered with this session
+	registeredTools    map[string]Tool // Tools registered with this session, resolved by tool callbacks
+	toolOrder          []string        // Order tools are presented to the shim
+	fallbackTool       Tool            // Tool called for unknown tool names
+	skipContextCheck   bool            // Disable local context size validation
+	jsonIndent         string          // Indent applied to structured output (empty = as-is)
+	jsonRepair         bool            // Normalize malformed structured output locally
+	checkAvailability  bool            // Re-verify model availability before each call
+	autoTimeContext    bool            // Prepend the current date/time to each prompt
+	timeContextFormat  string          // Time layout for the time context note
+	defaultTimeout     time.Duration   // Cancel generations that run longer (0 = no timeout)
+	contextPolicy      ContextPolicy   // Trims history when a request would not fit
+	autoRefresh        float64         // Context usage fraction that triggers a refresh (0 = off)
+	refreshSummary     bool            // Carry a summary of the conversation across refreshes
+	useCase            UseCase         // Model variant the session runs on
+	adapterPath        string          // Adapter applied to the model (empty = none)
+	guardrails         Guardrails      // Content guardrails applied to the model
+	retryPolicy        *RetryPolicy    // Retries failed generations (nil = no retries)
+	middleware         []Middleware    // Wraps every blocking respond call, outermost first
+	telemetry          Telemetry       // Receives request events, alongside global telemetry
+	inflight           []*requestTrace // Requests reporting telemetry, oldest first
+	cleanup            runtime.Cleanup // Releases the session if it is collected unreleased
+	priority           Priority        // Scheduling class of the session's generations
+	limiter            RateLimiter     // Throttles the session's generations (nil = none)
+	maxToolCalls       int             // Tool calls allowed per generation (0 = unlimited)
+	toolApprover       ToolApprover    // Decides whether tool calls may run (nil = all run)
+	approveMu          sync.Mutex      // Serializes calls to toolApprover
+	toolSlots          chan struct{}   // Bounds concurrent tool calls (nil = default)
+	defaultToolTimeout time.Duration   // Limit on each tool call (0 = no timeout)
+	schedulerSlot      *schedulerSlot  // Scheduler slot of the running generation
 }
 
-// NewSession creates a new LanguageModelSession using the Swift shim
-func NewSession() *Session {
-	slog.Debug("Creating new Foundation Models session")
+// NewSession creates a new LanguageModelSession using the Swift shim, configured by opts
+func NewSession(opts ...SessionOption) *Session {
+	logger().Debug("Creating new Foundation Models session")
 
-	if !shimInitialized {
-		slog.Error("Foundation Models shim not initialized", "error", shimInitError)
-		fmt.Printf("Foundation Models shim not initialized: %v\n", shimInitError)
+	if Init() != nil {
+		logger().Error("Foundation Models shim not initialized", "error", shimInitError)
 		return nil
 	}
 
 	ptr, _, _ := purego.SyscallN(createSess)
 	if ptr == 0 {
-		slog.Error("Failed to create LanguageModelSession")
-		fmt.Println("Failed to create LanguageModelSession")
+		logger().Error("Failed to create LanguageModelSession")
 		return nil
 	}
 
 	session := &Session{
-		ptr:             unsafe.Pointer(ptr),
+		ptr:             shimHandle(ptr),
+		busy:            make(chan struct{}, 1),
 		contextSize:     0,
 		maxContextSize:  MAX_CONTEXT_SIZE,
 		registeredTools: make(map[string]Tool),
 	}
+	for _, opt := range opts {
+		opt(session)
+	}
+
+	trackSession(session)
 
-	slog.Debug("Successfully created Foundation Models session",
+	logger().Debug("Successfully created Foundation Models session",
 		"ptr", ptr,
 		"max_context", MAX_CONTEXT_SIZE)
 
 	return session
 }
 
-// NewSessionWithInstructions creates a new LanguageModelSession with system instructions
-func NewSessionWithInstructions(instructions string) *Session {
-	slog.Debug("Creating new Foundation Models session with instructions",
+// NewSessionWithInstructions creates a new LanguageModelSession with system instructions,
+// configured by opts
+func NewSessionWithInstructions(instructions string, opts ...SessionOption) *Session {
+	logger().Debug("Creating new Foundation Models session with instructions",
 		"instructions_length", len(instructions))
 
-	if !shimInitialized {
-		slog.Error("Foundation Models shim not initialized", "error", shimInitError)
-		fmt.Printf("Foundation Models shim not initialized: %v\n", shimInitError)
+	if Init() != nil {
+		logger().Error("Foundation Models shim not initialized", "error", shimInitError)
 		return nil
 	}
 
 	// Validate instructions length
 	instructionTokens := estimateTokens(instructions)
-	slog.Debug("Estimated instruction tokens", "tokens", instructionTokens)
+	logger().Debug("Estimated instruction tokens", "tokens", instructionTokens)
 
 	if instructionTokens > 1000 { // Reserve space for conversation
-		slog.Warn("System instructions are very long",
+		logger().Warn("System instructions are very long",
 			"tokens", instructionTokens,
 			"recommended_max", 1000)
-		fmt.Printf("Warning: System instructions are very long (%d tokens). Consider shortening them.\n", instructionTokens)
 	}
 
 	cInstructions := cString(instructions)
+	defer freePtr(cInstructions)
 	ptr, _, _ := purego.SyscallN(createSessionWithInstructions, uintptr(cInstructions))
 	if ptr == 0 {
-		slog.Error("Failed to create LanguageModelSession with instructions")
-		fmt.Println("Failed to create LanguageModelSession with instructions")
+		logger().Error("Failed to create LanguageModelSession with instructions")
 		return nil
 	}
 
 	session := &Session{
-		ptr:                unsafe.Pointer(ptr),
+		ptr:                shimHandle(ptr),
+		busy:               make(chan struct{}, 1),
 		contextSize:        instructionTokens,
 		maxContextSize:     MAX_CONTEXT_SIZE,
 		systemInstructions: instructions,
 		registeredTools:    make(map[string]Tool),
 	}
+	for _, opt := range opts {
+		opt(session)
+	}
 
-	slog.Debug("Successfully created Foundation Models session with instructions",
+	trackSession(session)
+
+	logger().Debug("Successfully created Foundation Models session with instructions",
 		"ptr", ptr,
 		"initial_context", instructionTokens,
 		"max_context", MAX_CONTEXT_SIZE)
@@ -413,9 +588,36 @@ func NewSessionWithInstructions(instructions string) *Session {
 	return session
 }
 
-// Release releases the session memory
+// NewSessionWithInstructionsLimited creates a new LanguageModelSession with system
+// instructions, returning ErrInstructionsTooLong instead of only warning if the
+// instructions are estimated to exceed maxInstructionTokens
+func NewSessionWithInstructionsLimited(instructions string, maxInstructionTokens int, opts ...SessionOption) (*Session, error) {
+	instructionTokens := estimateTokens(instructions)
+	if instructionTokens > maxInstructionTokens {
+		logger().Error("System instructions exceed budget",
+			"tokens", instructionTokens,
+			"max", maxInstructionTokens)
+		return nil, fmt.Errorf("%w: %d tokens > %d", ErrInstructionsTooLong, instructionTokens, maxInstructionTokens)
+	}
+
+	if Init() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+
+	session := NewSessionWithInstructions(instructions, opts...)
+	if session == nil {
+		return nil, fmt.Errorf("failed to create LanguageModelSession with instructions")
+	}
+	return session, nil
+}
+
+// Release releases the session memory. It must not be called while other calls on the
+// session are in flight; cancel them with Cancel and wait for them to return first.
+// Sessions that are garbage collected unreleased are released then, but call Release
+// rather than relying on it (see SetSessionLeakDebug).
 func (s *Session) Release() {
 	if s.ptr != nil {
+		untrackSession(s)
 		purego.SyscallN(releaseSession, uintptr(s.ptr))
 		s.ptr = nil
 	}
@@ -423,18 +625,21 @@ func (s *Session) Release() {
 
 // CheckModelAvailability checks if the Foundation Models are available on this device
 func CheckModelAvailability() ModelAvailability {
-	if !shimInitialized {
-		fmt.Printf("Foundation Models shim not initialized: %v\n", shimInitError)
+	if err := Init(); err != nil {
+		if errors.Is(err, ErrUnsupportedPlatform) {
+			return ModelUnavailableUnsupportedPlatform
+		}
+		logger().Error("Foundation Models shim not initialized", "error", shimInitError)
 		return ModelUnavailableUnknown
 	}
 
 	result, _, _ := purego.SyscallN(checkModelAvailability)
-	return ModelAvailability(result)
+	return ModelAvailability(int32(uint32(result)))
 }
 
 // GetModelInfo returns information about the current language model
 func GetModelInfo() string {
-	if !shimInitialized {
+	if Init() != nil {
 		return fmt.Sprintf("Foundation Models shim not initialized: %v", shimInitError)
 	}
 
@@ -443,14 +648,133 @@ func GetModelInfo() string {
 		return "Error: Could not get model info"
 	}
 
-	response := goString(unsafe.Pointer(respPtr))
-	freePtr(unsafe.Pointer(respPtr))
+	response := takeString(respPtr)
 	return response
 }
 
+// ModelInfo describes the on-device language model
+type ModelInfo struct {
+	// OSVersion is the operating system version the model ships with
+	OSVersion string `json:"osVersion"`
+	// ModelIdentifier identifies the model, such as "SystemLanguageModel.default"
+	ModelIdentifier string `json:"modelIdentifier"`
+	// AdapterIdentifier is the path of the adapter applied to the model, if any
+	AdapterIdentifier string `json:"adapterIdentifier,omitempty"`
+	// UseCase is the model variant, such as "general"
+	UseCase string `json:"useCase"`
+	// SupportedLanguages are the BCP 47 tags of the languages the model supports
+	SupportedLanguages []string `json:"supportedLanguages"`
+	// ContextSize is the context window in tokens
+	ContextSize int `json:"contextSize"`
+	// Availability is the model's availability
+	Availability Availability `json:"availability"`
+}
+
+// defaultModelIdentifier identifies the model GetModelDetails describes
+const defaultModelIdentifier = "SystemLanguageModel.default"
+
+// GetModelDetails returns structured information about the default language model,
+// unlike the free-form GetModelInfo. Session.ModelInfo describes the model a session
+// runs on, with its use case and adapter.
+func GetModelDetails() (ModelInfo, error) {
+	if Init() != nil {
+		return ModelInfo{}, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if err := shimSymbol(getModelDetails, "GetModelDetails"); err != nil {
+		return ModelInfo{}, err
+	}
+
+	respPtr, _, _ := purego.SyscallN(getModelDetails)
+	if respPtr == 0 {
+		return ModelInfo{}, ErrNoResponse
+	}
+	response := takeString(respPtr)
+
+	if err := shimError(response); err != nil {
+		return ModelInfo{}, fmt.Errorf("failed to get model details: %w", err)
+	}
+
+	var info ModelInfo
+	if err := json.Unmarshal([]byte(response), &info); err != nil {
+		return ModelInfo{}, fmt.Errorf("failed to parse model details: %w", err)
+	}
+	if info.ContextSize == 0 {
+		info.ContextSize = MAX_CONTEXT_SIZE // Not reported before macOS 26.4
+	}
+	if info.ModelIdentifier == "" {
+		info.ModelIdentifier = defaultModelIdentifier
+		info.UseCase = UseCaseGeneral.String()
+	}
+	return info, nil
+}
+
+// ModelInfo returns structured information about the model the session runs on,
+// including the use case and adapter it was created with
+func (s *Session) ModelInfo() (ModelInfo, error) {
+	info, err := GetModelDetails()
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	return s.describeModel(info), nil
+}
+
+// describeModel fills in the identifiers of the session's model, named after the
+// SystemLanguageModel initializer the shim builds it with
+func (s *Session) describeModel(info ModelInfo) ModelInfo {
+	info.UseCase = s.useCase.String()
+	info.AdapterIdentifier = s.adapterPath
+	switch {
+	case s.adapterPath != "":
+		info.ModelIdentifier = fmt.Sprintf("SystemLanguageModel(adapter: %s)", filepath.Base(s.adapterPath))
+	case s.useCase != UseCaseGeneral || s.guardrails != GuardrailsDefault:
+		info.ModelIdentifier = fmt.Sprintf("SystemLanguageModel(useCase: .%s, guardrails: .%s)", s.useCase, s.guardrails)
+	default:
+		info.ModelIdentifier = defaultModelIdentifier
+	}
+	return info
+}
+
+// SupportedLanguages returns the tags (such as "en" or "zh-Hans") of the languages the
+// model supports
+func SupportedLanguages() ([]language.Tag, error) {
+	details, err := GetModelDetails()
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]language.Tag, 0, len(details.SupportedLanguages))
+	for _, identifier := range details.SupportedLanguages {
+		tag, err := language.Parse(identifier)
+		if err != nil {
+			logger().Debug("Skipping unparseable language identifier", "identifier", identifier, "error", err)
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// SupportsLanguage reports whether the model supports the language of a BCP 47 tag or
+// POSIX locale such as "en-US" or "fr_CA.UTF-8". A tag matches a supported language
+// that is equal to it or a prefix of it, so "en-GB" matches "en".
+func SupportsLanguage(tag string) (bool, error) {
+	languages, err := SupportedLanguages()
+	if err != nil {
+		return false, err
+	}
+	tag, _, _ = strings.Cut(tag, ".")
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	for _, supported := range languages {
+		name := strings.ToLower(supported.String())
+		if tag == name || strings.HasPrefix(tag, name+"-") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // GetLogs returns accumulated logs from the Swift shim and clears them
 func GetLogs() string {
-	if !shimInitialized {
+	if Init() != nil {
 		return fmt.Sprintf("Foundation Models shim not initialized: %v", shimInitError)
 	}
 
@@ -459,20 +783,14 @@ func GetLogs() string {
 		return "No logs available"
 	}
 
-	response := goString(unsafe.Pointer(respPtr))
-	freePtr(unsafe.Pointer(respPtr))
+	response := takeString(respPtr)
 	return response
 }
 
-// estimateTokens provides a rough estimate of token count for text
-// This is a simple approximation: ~4 characters per token on average
-func estimateTokens(text string) int {
-	// Rough approximation: average of 4 characters per token
-	return len(text) / 4
-}
-
 // GetContextSize returns the current estimated context size
 func (s *Session) GetContextSize() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.contextSize
 }
 
@@ -486,24 +804,69 @@ func (s *Session) GetSystemInstructions() string {
 	return s.systemInstructions
 }
 
+// SetContextValidation enables or disables the local context size pre-check.
+// With validation disabled, requests are always sent and the framework itself decides
+// whether the transcript fits, reporting ErrContextExceeded if it does not.
+func (s *Session) SetContextValidation(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipContextCheck = !enabled
+}
+
+// SetCheckAvailabilityBeforeEachCall makes the session re-verify model availability before
+// every generation, failing fast with ErrModelBecameUnavailable if Apple Intelligence was
+// turned off (or the model otherwise became unavailable) after the session was created
+func (s *Session) SetCheckAvailabilityBeforeEachCall(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkAvailability = enabled
+}
+
+// verifyAvailability re-checks model availability if the session is configured to
+func (s *Session) verifyAvailability() error {
+	s.mu.Lock()
+	check := s.checkAvailability
+	s.mu.Unlock()
+	if !check {
+		return nil
+	}
+	if availability := GetModelAvailability(); !availability.Available() {
+		logger().Error("Model became unavailable", "availability", availability.String())
+		err := fmt.Errorf("%w: %w", ErrModelBecameUnavailable, availability.Err())
+		tripCircuitBreaker(err)
+		return err
+	}
+	return nil
+}
+
 // validateContextSize checks if adding new text would exceed context limit
 func (s *Session) validateContextSize(newText string) error {
+	s.mu.Lock()
+	skip := s.skipContextCheck
+	s.mu.Unlock()
+	if skip {
+		return nil
+	}
 	newTokens := estimateTokens(newText)
-	if s.contextSize+newTokens > s.maxContextSize {
-		return fmt.Errorf("context size would exceed limit: current=%d, new=%d, max=%d",
-			s.contextSize, newTokens, s.maxContextSize)
+	if current := s.GetContextSize(); current+newTokens > s.maxContextSize {
+		return fmt.Errorf("%w: current=%d, new=%d, max=%d",
+			ErrContextLimit, current, newTokens, s.maxContextSize)
 	}
 	return nil
 }
 
 // addToContext adds tokens to the context size tracker
 func (s *Session) addToContext(text string) {
-	s.contextSize += estimateTokens(text)
+	// Counting calls into the shim, so do it before taking the lock
+	tokens := estimateTokens(text)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contextSize += tokens
 }
 
 // GetContextUsagePercent returns the percentage of context used
 func (s *Session) GetContextUsagePercent() float64 {
-	return float64(s.contextSize) / float64(s.maxContextSize) * 100
+	return float64(s.GetContextSize()) / float64(s.maxContextSize) * 100
 }
 
 // IsContextNearLimit returns true if context usage is above 80%
@@ -511,12 +874,48 @@ func (s *Session) IsContextNearLimit() bool {
 	return s.GetContextUsagePercent() > 80
 }
 
+// AddContext seeds the session with text (e.g. a document) without generating a
+// response, so later prompts are grounded in it. The text is added to the transcript as
+// an instructions (system) entry and counts against the context. It returns an error
+// wrapping ErrContextLimit if the text would overflow the context. Added context is not
+// carried over by RefreshSession.
+func (s *Session) AddContext(text string) error {
+	if err := s.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := s.validateContextSize(text); err != nil {
+		return err
+	}
+	if err := shimSymbol(addContext, "AddContext"); err != nil {
+		return err
+	}
+
+	cText := cString(text)
+	defer freePtr(cText)
+
+	result, _, _ := purego.SyscallN(addContext, uintptr(s.ptr), uintptr(cText))
+	if result == 0 {
+		return fmt.Errorf("failed to add context in Swift shim")
+	}
+
+	s.addToContext(text)
+	logger().Debug("Added context", "tokens", estimateTokens(text), "context_after", s.GetContextSize())
+
+	return nil
+}
+
 // GetRemainingContextTokens returns the number of tokens remaining in context
 func (s *Session) GetRemainingContextTokens() int {
-	return s.maxContextSize - s.contextSize
+	return s.maxContextSize - s.GetContextSize()
 }
 
-// RefreshSession creates a new session with the same system instructions and tools
+// RefreshSession creates a new session with the same system instructions, tools and
+// settings, including the model's use case, adapter and guardrails.
 // This is useful when context is near the limit and you want to continue the conversation
 func (s *Session) RefreshSession() *Session {
 	var newSess *Session
@@ -527,30 +926,117 @@ func (s *Session) RefreshSession() *Session {
 	}
 
 	if newSess != nil {
-		// Re-register all tools from the old session
-		for _, tool := range s.registeredTools {
-			newSess.RegisterTool(tool)
+		if err := s.copySettings(newSess); err != nil {
+			logger().Warn("Failed to carry model settings over to the refreshed session", "error", err)
 		}
 	}
 
 	return newSess
 }
 
+// copySettings applies this session's tools and settings to newSess. Tools and plain
+// settings are always copied; the error reports model settings (use case, adapter and
+// guardrails) that the shim could not apply.
+func (s *Session) copySettings(newSess *Session) error {
+	s.mu.Lock()
+	tools := make([]Tool, 0, len(s.toolOrder))
+	for _, name := range s.toolOrder {
+		tools = append(tools, s.registeredTools[name])
+	}
+	fallback := s.fallbackTool
+	middleware := s.middleware
+	approver := s.toolApprover
+	toolConcurrency := s.toolSlots
+	skipContextCheck := s.skipContextCheck
+	checkAvailability := s.checkAvailability
+	s.mu.Unlock()
+
+	// Re-register all tools from the old session, preserving their order
+	for _, tool := range tools {
+		newSess.RegisterTool(tool)
+	}
+	if fallback != nil {
+		newSess.SetFallbackTool(fallback)
+	}
+	if approver != nil {
+		newSess.SetToolApprover(approver)
+	}
+	if toolConcurrency != nil {
+		newSess.SetToolConcurrency(cap(toolConcurrency))
+	}
+	newSess.maxContextSize = s.maxContextSize
+	newSess.SetContextValidation(!skipContextCheck)
+	newSess.SetCheckAvailabilityBeforeEachCall(checkAvailability)
+	newSess.autoTimeContext = s.autoTimeContext
+	newSess.timeContextFormat = s.timeContextFormat
+	newSess.SetDefaultTimeout(s.DefaultTimeout())
+	newSess.defaultToolTimeout = s.ToolTimeout()
+	newSess.retryPolicy = s.retryPolicy
+	newSess.priority = s.priority
+	newSess.limiter = s.limiter
+	if s.maxToolCalls > 0 {
+		newSess.SetMaxToolCalls(s.maxToolCalls)
+	}
+	newSess.middleware = middleware
+	newSess.telemetry = s.telemetry
+	newSess.contextPolicy = s.contextPolicy
+	newSess.autoRefresh = s.autoRefresh
+	newSess.refreshSummary = s.refreshSummary
+	newSess.jsonIndent = s.jsonIndent
+	newSess.jsonRepair = s.jsonRepair
+
+	var errs []error
+	if s.useCase != UseCaseGeneral {
+		errs = append(errs, newSess.setUseCase(s.useCase))
+	}
+	if s.adapterPath != "" {
+		errs = append(errs, newSess.setAdapter(s.adapterPath))
+	}
+	if s.guardrails != GuardrailsDefault {
+		errs = append(errs, newSess.setGuardrails(s.guardrails))
+	}
+	return errors.Join(errs...)
+}
+
 // RegisterTool registers a tool with the session
 func (s *Session) RegisterTool(tool Tool) error {
-	slog.Debug("Registering tool",
+	logger().Debug("Registering tool",
 		"tool_name", tool.Name(),
 		"tool_description", tool.Description())
 
+	if err := s.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.unlock()
+
 	if s.ptr == nil {
-		slog.Error("RegisterTool called with invalid session")
-		return fmt.Errorf("invalid session")
+		logger().Error("RegisterTool called with invalid session")
+		return ErrInvalidSession
 	}
 
-	// Store the tool in the Go registry
+	// Store the tool in the Go registry, recording registration order
+	s.mu.Lock()
+	if _, exists := s.registeredTools[tool.Name()]; !exists {
+		s.toolOrder = append(s.toolOrder, tool.Name())
+	}
 	s.registeredTools[tool.Name()] = tool
-	toolRegistry[tool.Name()] = tool
+	total := len(s.registeredTools)
+	s.mu.Unlock()
+
+	if err := registerToolWithShim(s.ptr, tool); err != nil {
+		return err
+	}
+
+	logger().Debug("Successfully registered tool",
+		"tool_name", tool.Name(),
+		"total_tools", total)
+
+	return nil
+}
 
+// registerToolWithShim sends a tool definition to the Swift shim, which appends it to
+// the tools presented to the model
+func registerToolWithShim(ptr unsafe.Pointer, tool Tool) error {
 	// Create tool definition for Swift shim
 	toolDef := ToolDefinition{
 		Name:        tool.Name(),
@@ -580,49 +1066,64 @@ func (s *Session) RegisterTool(tool Tool) error {
 		}
 	}
 
-	slog.Debug("Tool definition created",
+	logger().Debug("Tool definition created",
 		"parameters_count", paramCount,
 		"tool_name", tool.Name())
 
 	toolDefJSON, err := json.Marshal(toolDef)
 	if err != nil {
-		slog.Error("Failed to marshal tool definition", "error", err)
+		logger().Error("Failed to marshal tool definition", "error", err)
 		return fmt.Errorf("failed to marshal tool definition: %v", err)
 	}
 
+	// Without the session tool callback the shim could not call the tool back
+	if err := shimSymbol(setToolCallback, "SetSessionToolCallback"); err != nil {
+		return err
+	}
+
 	cToolDef := cString(string(toolDefJSON))
+	defer freePtr(cToolDef)
 
-	slog.Debug("Calling Swift RegisterTool")
+	logger().Debug("Calling Swift RegisterTool")
 	// Register with Swift shim
 	result, _, _ := purego.SyscallN(
 		registerTool,
-		uintptr(s.ptr),
+		uintptr(ptr),
 		uintptr(cToolDef),
 	)
 
 	if result == 0 {
-		slog.Error("Failed to register tool in Swift shim", "tool_name", tool.Name())
+		logger().Error("Failed to register tool in Swift shim", "tool_name", tool.Name())
 		return fmt.Errorf("failed to register tool in Swift shim")
 	}
 
-	slog.Debug("Successfully registered tool",
-		"tool_name", tool.Name(),
-		"total_tools", len(s.registeredTools))
-
 	return nil
 }
 
 // ClearTools clears all registered tools from the session
 func (s *Session) ClearTools() error {
-	if s.ptr == nil {
-		return fmt.Errorf("invalid session")
+	if err := s.lock(context.Background()); err != nil {
+		return err
 	}
+	defer s.unlock()
 
-	// Clear from Go registry
-	for name := range s.registeredTools {
-		delete(toolRegistry, name)
+	if s.ptr == nil {
+		return ErrInvalidSession
 	}
+
+	// Clear from Go registry, releasing tools that hold resources
+	s.mu.Lock()
+	tools := s.registeredTools
 	s.registeredTools = make(map[string]Tool)
+	s.toolOrder = nil
+	s.fallbackTool = nil
+	s.mu.Unlock()
+
+	for _, tool := range tools {
+		if releasable, ok := tool.(ReleasableTool); ok {
+			releasable.Release()
+		}
+	}
 
 	// Clear from Swift shim
 	result, _, _ := purego.SyscallN(clearTools, uintptr(s.ptr))
@@ -633,27 +1134,141 @@ func (s *Session) ClearTools() error {
 	return nil
 }
 
-// GetRegisteredTools returns a list of registered tool names
+// FallbackToolNameArg is the argument key holding the attempted tool name when a call
+// is routed to the fallback tool
+const FallbackToolNameArg = "toolName"
+
+// SetFallbackTool sets a tool that is invoked whenever the model calls a tool name that
+// is not registered, instead of failing with "tool not found". The fallback receives the
+// model's arguments plus the attempted name under FallbackToolNameArg, so it can reply
+// gracefully (e.g. "that capability isn't available"). Pass nil to remove it.
+func (s *Session) SetFallbackTool(tool Tool) {
+	if s.lock(context.Background()) != nil {
+		return
+	}
+	defer s.unlock()
+
+	if tool != nil {
+		logger().Debug("Setting fallback tool", "tool_name", tool.Name())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallbackTool = tool
+}
+
+// executeFallbackTool runs the fallback tool for an unknown tool name
+func executeFallbackTool(sess *Session, fallbackTool Tool, toolName string, argsJSON string, args *map[string]any) ToolResult {
+	logger().Warn("Model called unknown tool, using fallback",
+		"tool_name", toolName,
+		"fallback", fallbackTool.Name())
+
+	// The arguments were meant for another tool, so tolerate anything that isn't an object
+	if err := json.Unmarshal([]byte(argsJSON), args); err != nil || *args == nil {
+		*args = make(map[string]any)
+	}
+	(*args)[FallbackToolNameArg] = toolName
+
+	if denied, ok := sess.approveToolCall(fallbackTool.Name(), *args); !ok {
+		return denied
+	}
+
+	release := sess.acquireToolSlot()
+	defer release()
+	result, err := sess.runTool(fallbackTool, *args)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// GetRegisteredTools returns a list of registered tool names in the order they are
+// presented to the model
 func (s *Session) GetRegisteredTools() []string {
-	var tools []string
-	for name := range s.registeredTools {
-		tools = append(tools, name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.toolOrder)
+}
+
+// SetToolOrder fixes the order in which registered tools are presented to the model.
+// Tools are presented in registration order by default; tools not named in order keep
+// their relative registration order after the named ones. Useful for checking whether
+// tool selection depends on ordering.
+func (s *Session) SetToolOrder(order []string) error {
+	if err := s.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if s.ptr == nil {
+		return ErrInvalidSession
 	}
-	return tools
+
+	// Tool fields only change while the lock is held, so s.mu is only needed to write them
+	newOrder, err := reorderTools(s.toolOrder, s.registeredTools, order)
+	if err != nil {
+		return err
+	}
+
+	logger().Debug("Setting tool order", "order", newOrder)
+
+	// Re-present the tools to the shim in the new order
+	result, _, _ := purego.SyscallN(clearTools, uintptr(s.ptr))
+	if result == 0 {
+		return fmt.Errorf("failed to clear tools in Swift shim")
+	}
+	for _, name := range newOrder {
+		if err := registerToolWithShim(s.ptr, s.registeredTools[name]); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.toolOrder = newOrder
+	s.mu.Unlock()
+
+	return nil
+}
+
+// reorderTools returns current with the tools named in order moved to the front, in that
+// order
+func reorderTools(current []string, registered map[string]Tool, order []string) ([]string, error) {
+	newOrder := make([]string, 0, len(current))
+	for _, name := range order {
+		if _, exists := registered[name]; !exists {
+			return nil, fmt.Errorf("tool not registered: %s", name)
+		}
+		if slices.Contains(newOrder, name) {
+			return nil, fmt.Errorf("duplicate tool in order: %s", name)
+		}
+		newOrder = append(newOrder, name)
+	}
+	for _, name := range current {
+		if !slices.Contains(newOrder, name) {
+			newOrder = append(newOrder, name)
+		}
+	}
+	return newOrder, nil
 }
 
 // toolCallbackFunc is a global variable to keep the callback function alive
-var toolCallbackFunc func(cToolName, cArgsJSON unsafe.Pointer) unsafe.Pointer
+var toolCallbackFunc func(sessionID uintptr, cToolName, cArgsJSON unsafe.Pointer) unsafe.Pointer
 
-// setupToolCallback sets up the callback mechanism for Swift to call Go tools
+// setupToolCallback sets up the callback mechanism for Swift to call Go tools. Swift
+// passes the pointer of the session that owns the tool, so each call is resolved from
+// that session's registry.
 func setupToolCallback() {
+	if setToolCallback == 0 {
+		return // Tools are unavailable (see registerToolWithShim)
+	}
+
 	// Create a function pointer that Swift can call
-	toolCallbackFunc = func(cToolName, cArgsJSON unsafe.Pointer) unsafe.Pointer {
+	toolCallbackFunc = func(sessionID uintptr, cToolName, cArgsJSON unsafe.Pointer) unsafe.Pointer {
 		toolName := goString(cToolName)
 		argsJSON := goString(cArgsJSON)
 
-		result := executeTool(toolName, argsJSON)
-		return cString(result)
+		// The shim frees the result
+		result := cString(executeTool(lookupSession(sessionID), toolName, argsJSON))
+		trackHandOff(uintptr(result))
+		return result
 	}
 
 	// Register the callback with the Swift shim using purego.NewCallback
@@ -661,86 +1276,120 @@ func setupToolCallback() {
 	purego.SyscallN(setToolCallback, callback)
 }
 
-// findOrExtractShimLibrary finds existing shim library or extracts embedded one
-func findOrExtractShimLibrary() string {
-	// Try to find existing library in various locations
-	searchPaths := []string{
-		"./libFMShim.dylib",       // Current directory
-		"libFMShim.dylib",         // Relative to executable
-		"./lib/libFMShim.dylib",   // lib subdirectory
-		"./build/libFMShim.dylib", // build subdirectory
+// EnvDisableEmbedded is the environment variable that, when set to a true value (e.g.
+// FM_DISABLE_EMBEDDED=1), forces use of an on-disk libFMShim.dylib. Initialization then
+// fails if none is found instead of extracting the embedded copy, so shim development
+// never silently runs against a stale embedded binary.
+const EnvDisableEmbedded = "FM_DISABLE_EMBEDDED"
+
+// EnvShimPath is the environment variable that, when set, names the shim library to load
+// instead of searching for it or extracting the embedded copy (see WithShimPath)
+const EnvShimPath = "FM_SHIM_PATH"
+
+// checkShimVersion verifies that the loaded library exports the interface this package
+// expects, before any other symbol is looked up. A stale libFMShim.dylib left in the
+// working directory would otherwise fail with an unhelpful error for whichever changed
+// export it lacks. Libraries that predate versioning (including older embedded copies)
+// only lack optional symbols, so they are accepted.
+func checkShimVersion(shimPath string) error {
+	versionFn, err := dlsym(shimLib, "ShimVersion")
+	if err != nil {
+		logger().Warn("Shim library predates versioning, newer features are unavailable until it is rebuilt with 'make'",
+			"path", shimPath)
+		return nil
 	}
-
-	for _, path := range searchPaths {
-		if _, err := os.Stat(path); err == nil {
-			return path
-		}
+	version, _, _ := purego.SyscallN(versionFn)
+	if got := int32(version); got != shimVersion {
+		return fmt.Errorf("%w: %s has version %d, want %d; remove it or rebuild it with 'make'",
+			ErrShimVersionMismatch, shimPath, got, shimVersion)
 	}
+	logger().Debug("Loaded shim library", "path", shimPath, "version", shimVersion)
+	return nil
+}
 
-	// No existing library found, extract embedded one
-	return extractEmbeddedShimLibrary()
+// shimSymbol returns an error wrapping ErrShimFeatureUnavailable if the optional shim
+// export name, whose address is addr, was missing from the loaded library
+func shimSymbol(addr uintptr, name string) error {
+	if addr == 0 {
+		return fmt.Errorf("%w: the shim library does not export %s; rebuild it with 'make'",
+			ErrShimFeatureUnavailable, name)
+	}
+	return nil
 }
 
-// extractEmbeddedShimLibrary extracts the embedded shim library to a temporary file
-func extractEmbeddedShimLibrary() string {
-	// Create a temporary file for the shim library
-	tempDir := os.TempDir()
-	shimPath := filepath.Join(tempDir, "libFMShim_embedded.dylib")
+// executeTool executes one of the session's tools by name with the given arguments
+// This is called by the Swift shim via a callback
+func executeTool(sess *Session, toolName string, argsJSON string) string {
+	notifyToolCallStart(sess, toolName, argsJSON)
 
-	// Check if already extracted
-	if _, err := os.Stat(shimPath); err == nil {
-		fmt.Printf("Using previously extracted shim library at: %s\n", shimPath)
-		return shimPath
-	}
+	start := time.Now()
+	var args map[string]any
 
-	// Extract the embedded library
-	if err := os.WriteFile(shimPath, embeddedShimLib, 0755); err != nil {
-		fmt.Printf("Failed to extract embedded shim library: %v\n", err)
-		return ""
-	}
+	toolResult := func() ToolResult {
+		if sess == nil {
+			logger().Error("Tool called for unknown session", "tool_name", toolName)
+			return ToolResult{
+				Error: fmt.Sprintf("tool '%s' not found: session was released", toolName),
+			}
+		}
 
-	fmt.Printf("Extracted embedded shim library to: %s\n", shimPath)
-	return shimPath
-}
+		sess.mu.Lock()
+		tool, exists := sess.registeredTools[toolName]
+		fallbackTool := sess.fallbackTool
+		slot := sess.schedulerSlot
+		sess.mu.Unlock()
 
-// executeTool executes a tool by name with the given arguments
-// This is called by the Swift shim via a callback
-func executeTool(toolName string, argsJSON string) string {
-	tool, exists := toolRegistry[toolName]
-	if !exists {
-		result := ToolResult{
-			Error: fmt.Sprintf("tool '%s' not found", toolName),
+		// Give up the generation's scheduler slot while the tool runs, so generations
+		// the tool starts itself can run
+		resume := slot.yield()
+		defer resume()
+
+		if !exists {
+			if fallbackTool == nil {
+				return ToolResult{
+					Error: fmt.Sprintf("tool '%s' not found", toolName),
+				}
+			}
+			return executeFallbackTool(sess, fallbackTool, toolName, argsJSON, &args)
 		}
-		resultJSON, _ := json.Marshal(result)
-		return string(resultJSON)
-	}
 
-	// Parse arguments from JSON
-	var args map[string]any
-	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
-		result := ToolResult{
-			Error: fmt.Sprintf("failed to parse arguments: %v", err),
+		// Parse arguments from JSON
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return ToolResult{
+				Error: fmt.Sprintf("failed to parse arguments: %v", err),
+			}
 		}
-		resultJSON, _ := json.Marshal(result)
-		return string(resultJSON)
-	}
 
-	// Validate arguments if the tool supports validation
-	if validatedTool, ok := tool.(ValidatedTool); ok {
-		if err := validatedTool.ValidateArguments(args); err != nil {
-			result := ToolResult{
-				Error: fmt.Sprintf("validation failed: %v", err),
+		// Validate arguments if the tool supports validation
+		if validatedTool, ok := tool.(ValidatedTool); ok {
+			if err := validatedTool.ValidateArguments(args); err != nil {
+				return ToolResult{
+					Error: fmt.Sprintf("validation failed: %v", err),
+				}
 			}
-			resultJSON, _ := json.Marshal(result)
-			return string(resultJSON)
 		}
-	}
 
-	// Execute the tool
-	toolResult, err := tool.Execute(args)
-	if err != nil {
-		toolResult.Error = err.Error()
-	}
+		// Ask the session's approver, if any, before running the tool
+		if denied, ok := sess.approveToolCall(toolName, args); !ok {
+			return denied
+		}
+
+		// Execute the tool, once the session has a free slot
+		release := sess.acquireToolSlot()
+		defer release()
+		result, err := sess.runTool(tool, args)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}()
+
+	notifyToolInvocation(sess, ToolInvocation{
+		Name:      toolName,
+		Arguments: args,
+		Result:    toolResult,
+		Duration:  time.Since(start),
+	})
 
 	// Return result as JSON
 	resultJSON, _ := json.Marshal(toolResult)
@@ -757,6 +1406,7 @@ func cString(str string) unsafe.Pointer {
 	if ptr == 0 {
 		return nil
 	}
+	trackAlloc(ptr, "C string")
 
 	// Copy string data to C memory
 	for i, b := range strBytes {
@@ -769,6 +1419,13 @@ func cString(str string) unsafe.Pointer {
 	return unsafe.Pointer(ptr)
 }
 
+// shimHandle converts a session handle returned by the shim to the pointer Session
+// holds. The handle is shim memory, never moved or collected by Go, so reading it back
+// through its address is safe and keeps vet from flagging a uintptr conversion.
+func shimHandle(handle uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&handle))
+}
+
 // goString converts a C string to a Go string
 func goString(cstr unsafe.Pointer) string {
 	if cstr == nil {
@@ -794,230 +1451,313 @@ func goString(cstr unsafe.Pointer) string {
 	return string(bytes)
 }
 
+// takeString converts a C string returned by the shim to a Go string and frees it
+func takeString(ptr uintptr) string {
+	trackAlloc(ptr, "shim string")
+	str := goString(unsafe.Pointer(ptr))
+	freePtr(unsafe.Pointer(ptr))
+	return str
+}
+
 // freePtr safely frees a C pointer using libc's free function
 func freePtr(ptr unsafe.Pointer) {
 	if ptr != nil && libcFree != 0 {
+		trackFree(uintptr(ptr))
 		purego.SyscallN(libcFree, uintptr(ptr))
 	}
 }
 
-// Respond sends a prompt to the language model and returns the response
-// If options is nil, uses default generation settings
-func (s *Session) Respond(prompt string, options *GenerationOptions) string {
-	slog.Debug("Respond called",
-		"prompt_length", len(prompt),
-		"has_options", options != nil,
-		"context_before", s.contextSize)
-
-	if s.ptr == nil {
-		slog.Error("Respond called with invalid session")
-		return "Error: Invalid session"
-	}
+// generationKind selects the shim entry point used by generate
+type generationKind int
 
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		slog.Error("Context size validation failed", "error", err)
-		return fmt.Sprintf("Error: %v", err)
-	}
-
-	// If options are provided, use RespondWithOptions
-	if options != nil {
-		// Extract options with defaults
-		maxTokens := -1 // -1 means no limit
-		if options.MaxTokens != nil {
-			maxTokens = *options.MaxTokens
-		}
+const (
+	generationText generationKind = iota
+	generationStructured
+	generationTools
+	generationSchema // Constrained by a JSON-encoded schemaNode
+)
 
-		temperature := float32(0.7) // Default temperature
-		if options.Temperature != nil {
-			temperature = *options.Temperature
+// generationShim holds the shim calls made by blocking generations and Cancel,
+// replaceable so tests can stub a generation without the shim
+var generationShim = struct {
+	ready func() error // Checks that the shim is loaded
+	// respond runs a blocking generation, returning the response and whether the shim
+	// returned one. options is the encoded GenerationOptions, if any.
+	respond func(s *Session, kind generationKind, prompt, options, schema string) (string, bool)
+	cancel  func(s *Session) // Cancels the session's in-flight generation
+}{
+	ready: func() error {
+		if Init() != nil {
+			return shimInitError
 		}
+		return nil
+	},
+	respond: shimRespond,
+	cancel:  shimCancel,
+}
 
-		slog.Debug("Using RespondWithOptions",
-			"max_tokens", maxTokens,
-			"temperature", temperature)
-		return s.RespondWithOptions(prompt, maxTokens, temperature)
-	}
-
+// shimRespond runs a blocking generation in the shim
+func shimRespond(s *Session, kind generationKind, prompt, options, schema string) (string, bool) {
 	cPrompt := cString(prompt)
+	defer freePtr(cPrompt)
 
-	slog.Debug("Calling Swift RespondSync")
-	// Call RespondSync from the Swift shim
-	respPtr, _, _ := purego.SyscallN(
-		respondSync,
-		uintptr(s.ptr),
-		uintptr(cPrompt),
-	)
-
+	var respPtr uintptr
+	switch {
+	case kind == generationStructured:
+		logger().Debug("Calling Swift RespondWithStructuredOutput")
+		respPtr, _, _ = purego.SyscallN(respondWithStructuredOutput, uintptr(s.ptr), uintptr(cPrompt))
+	case kind == generationTools:
+		logger().Debug("Calling Swift RespondWithTools")
+		respPtr, _, _ = purego.SyscallN(respondWithTools, uintptr(s.ptr), uintptr(cPrompt))
+	case kind == generationSchema:
+		logger().Debug("Calling Swift RespondWithSchema", "schema", schema)
+		cSchema := cString(schema)
+		defer freePtr(cSchema)
+		respPtr, _, _ = purego.SyscallN(respondWithSchema, uintptr(s.ptr), uintptr(cPrompt), uintptr(cSchema))
+	case options != "":
+		logger().Debug("Calling Swift RespondWithGenerationOptions", "options", options)
+		cOptions := cString(options)
+		defer freePtr(cOptions)
+		respPtr, _, _ = purego.SyscallN(respondWithOptions,
+			uintptr(s.ptr),
+			uintptr(cPrompt),
+			uintptr(cOptions))
+	default:
+		logger().Debug("Calling Swift RespondSync")
+		respPtr, _, _ = purego.SyscallN(respondSync, uintptr(s.ptr), uintptr(cPrompt))
+	}
 	if respPtr == 0 {
-		slog.Error("No response from FoundationModels")
-		return "Error: No response from FoundationModels"
+		return "", false
 	}
 
-	// Convert response to Go string
-	response := goString(unsafe.Pointer(respPtr))
-	slog.Debug("Received response",
-		"response_length", len(response),
-		"response_preview", response[:min(50, len(response))])
-
-	// Free the C string returned by the Swift shim
-	freePtr(unsafe.Pointer(respPtr))
+	// Convert response to Go string and free the C string returned by the Swift shim
+	return takeString(respPtr), true
+}
 
-	// Update context size with prompt and response
-	s.addToContext(prompt)
-	s.addToContext(response)
+// String returns the name of the shim function used for the generation kind
+func (k generationKind) String() string {
+	switch k {
+	case generationStructured:
+		return "RespondWithStructuredOutput"
+	case generationTools:
+		return "RespondWithTools"
+	case generationSchema:
+		return "RespondWithSchema"
+	default:
+		return "RespondSync"
+	}
+}
 
-	slog.Debug("Updated context", "context_after", s.contextSize)
+// generate waits for any in-flight generation on the session, then runs a blocking
+// generation in the shim through the session's middleware, retrying it according to the
+// session's retry policy
+func (s *Session) generate(kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	return s.serve(context.Background(), kind, prompt, options, schema,
+		func(_ context.Context, req *Request) (string, error) {
+			return s.generateRetrying(req.kind, req.Prompt, req.Options, req.Schema)
+		})
+}
 
-	return response
+// generateRetrying runs blocking generation attempts according to the retry policy
+func (s *Session) generateRetrying(kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	if s.retryPolicy != nil {
+		var response string
+		err := s.retryPolicy.Do(context.Background(), func(context.Context) error {
+			var err error
+			response, err = s.generateOnce(kind, prompt, options, schema)
+			return err
+		})
+		return response, err
+	}
+	return s.generateOnce(kind, prompt, options, schema)
 }
 
-// RespondWithStructuredOutput sends a prompt and returns structured JSON output
-func (s *Session) RespondWithStructuredOutput(prompt string) string {
-	if s.ptr == nil {
-		return "Error: Invalid session"
+// generateOnce runs a single blocking generation attempt
+func (s *Session) generateOnce(kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	if err := s.lock(context.Background()); err != nil {
+		return "", err
 	}
+	defer s.unlock()
+	return s.generateLocked(context.Background(), kind, prompt, options, schema)
+}
 
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		return fmt.Sprintf("Error: %v", err)
+// generateLocked runs a blocking generation in the shim and returns the response, or a
+// typed error if the request was rejected or generation failed. schema is only used by
+// generationSchema. ctx only bounds waiting for the generation to start. Must be called
+// with the session lock held.
+func (s *Session) generateLocked(ctx context.Context, kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	if err := generationShim.ready(); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrShimNotInitialized, err)
 	}
-
-	cPrompt := cString(prompt)
-
-	respPtr, _, _ := purego.SyscallN(
-		respondWithStructuredOutput,
-		uintptr(s.ptr),
-		uintptr(cPrompt),
-	)
-
-	if respPtr == 0 {
-		return "Error: No response from FoundationModels"
+	if s.ptr == nil {
+		return "", ErrInvalidSession
 	}
 
-	response := goString(unsafe.Pointer(respPtr))
+	prompt = s.withTimeContext(prompt)
 
-	// Free the C string returned by the Swift shim
-	freePtr(unsafe.Pointer(respPtr))
-
-	// Update context size with prompt and response
-	s.addToContext(prompt)
-	s.addToContext(response)
-
-	return response
-}
-
-// RespondWithTools sends a prompt with tool calling enabled
-func (s *Session) RespondWithTools(prompt string) string {
-	slog.Debug("RespondWithTools called",
-		"prompt_length", len(prompt),
-		"registered_tools", len(s.registeredTools),
-		"context_before", s.contextSize)
+	// Validate context size before sending, trimming history if a policy allows
+	if err := s.ensureContextRoom(prompt); err != nil {
+		logger().Error("Context size validation failed", "error", err)
+		return "", err
+	}
 
-	if s.ptr == nil {
-		slog.Error("RespondWithTools called with invalid session")
-		return "Error: Invalid session"
+	if err := s.verifyAvailability(); err != nil {
+		return "", err
 	}
 
-	// Log registered tools
-	if len(s.registeredTools) > 0 {
-		var toolNames []string
-		for name := range s.registeredTools {
-			toolNames = append(toolNames, name)
+	if kind == generationSchema {
+		if err := shimSymbol(respondWithSchema, "RespondWithSchema"); err != nil {
+			return "", err
 		}
-		slog.Debug("Available tools", "tools", toolNames)
-	} else {
-		slog.Warn("RespondWithTools called but no tools registered")
 	}
 
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		slog.Error("Context size validation failed", "error", err)
-		return fmt.Sprintf("Error: %v", err)
+	var encodedOptions string
+	if options != nil && kind == generationText {
+		if err := shimSymbol(respondWithOptions, "RespondWithGenerationOptions"); err != nil {
+			return "", err
+		}
+		encoded, err := optionsJSON(options)
+		if err != nil {
+			return "", err
+		}
+		encodedOptions = encoded
 	}
 
-	cPrompt := cString(prompt)
+	var response string
+	var answered bool
+	timedOut, err := s.runGeneration(ctx, func() {
+		response, answered = generationShim.respond(s, kind, prompt, encodedOptions, schema)
+	})
+	if err != nil {
+		return "", err
+	}
 
-	slog.Debug("Calling Swift RespondWithTools")
-	respPtr, _, _ := purego.SyscallN(
-		respondWithTools,
-		uintptr(s.ptr),
-		uintptr(cPrompt),
-	)
+	if !answered {
+		logger().Error("No response from FoundationModels", "function", kind.String())
+		return "", ErrNoResponse
+	}
 
-	if respPtr == 0 {
-		slog.Error("No response from FoundationModels RespondWithTools")
-		return "Error: No response from FoundationModels"
+	if timedOut > 0 {
+		return "", fmt.Errorf("%w after %v", ErrGenerationTimeout, timedOut)
+	}
+	if err := shimError(response); err != nil {
+		return "", s.withPartialTranscript(err)
+	}
+	if options != nil {
+		response = truncateAtStop(response, options.StopSequences)
 	}
 
-	response := goString(unsafe.Pointer(respPtr))
-	slog.Debug("Received tool response",
+	logger().Debug("Received response",
+		"function", kind.String(),
 		"response_length", len(response),
 		"response_preview", response[:min(50, len(response))])
 
-	// Free the C string returned by the Swift shim
-	freePtr(unsafe.Pointer(respPtr))
-
 	// Update context size with prompt and response
 	s.addToContext(prompt)
 	s.addToContext(response)
 
-	slog.Debug("Updated context after tool response", "context_after", s.contextSize)
+	logger().Debug("Updated context", "context_after", s.GetContextSize())
 
-	return response
+	return response, nil
 }
 
-// RespondWithOptions sends a prompt with specific generation options
-func (s *Session) RespondWithOptions(prompt string, maxTokens int, temperature float32) string {
-	if s.ptr == nil {
-		return "Error: Invalid session"
-	}
-
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		return fmt.Sprintf("Error: %v", err)
-	}
+// Respond sends a prompt to the language model and returns the response.
+// If options is nil, uses default generation settings.
+func (s *Session) Respond(prompt string, options *GenerationOptions) (string, error) {
+	logger().Debug("Respond called",
+		"prompt_length", len(prompt),
+		"has_options", options != nil,
+		"context_before", s.GetContextSize())
 
-	cPrompt := cString(prompt)
+	return s.generate(generationText, prompt, options, "")
+}
 
-	// Convert float32 to uint32 for syscall
-	tempUint32 := *(*uint32)(unsafe.Pointer(&temperature))
+// RespondWithStructuredOutput sends a prompt and returns structured JSON output. If the
+// response is not valid JSON it is repaired (see SetJSONRepair) or, failing that, the
+// model is asked once to correct it; the error wraps ErrInvalidJSON if both fail.
+func (s *Session) RespondWithStructuredOutput(prompt string) (string, error) {
+	response, err := s.generate(generationStructured, prompt, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return s.checkStructuredOutput(context.Background(), response)
+}
 
-	respPtr, _, _ := purego.SyscallN(
-		respondWithOptions,
-		uintptr(s.ptr),
-		uintptr(cPrompt),
-		uintptr(maxTokens),
-		uintptr(tempUint32),
-	)
+// RespondWithTools sends a prompt with tool calling enabled
+func (s *Session) RespondWithTools(prompt string) (string, error) {
+	tools := s.GetRegisteredTools()
+	logger().Debug("RespondWithTools called",
+		"prompt_length", len(prompt),
+		"registered_tools", len(tools),
+		"context_before", s.GetContextSize())
 
-	if respPtr == 0 {
-		return "Error: No response from FoundationModels"
+	// Log registered tools
+	if len(tools) > 0 {
+		logger().Debug("Available tools", "tools", tools)
+	} else {
+		logger().Warn("RespondWithTools called but no tools registered")
 	}
 
-	response := goString(unsafe.Pointer(respPtr))
+	return s.generate(generationTools, prompt, nil, "")
+}
 
-	// Free the C string returned by the Swift shim
-	freePtr(unsafe.Pointer(respPtr))
+// RespondWithOptions sends a prompt with specific generation options
+func (s *Session) RespondWithOptions(prompt string, maxTokens int, temperature float32) (string, error) {
+	return s.generate(generationText, prompt, &GenerationOptions{
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}, "")
+}
 
-	// Update context size with prompt and response
-	s.addToContext(prompt)
-	s.addToContext(response)
+// Context-aware response methods
 
-	return response
+// respondWithContext runs a generation in the background through the session's
+// middleware, cancelling it in the shim if ctx is done first, and retries it according
+// to the session's retry policy
+func (s *Session) respondWithContext(ctx context.Context, kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	return s.serve(ctx, kind, prompt, options, schema,
+		func(ctx context.Context, req *Request) (string, error) {
+			return s.respondRetrying(ctx, req.kind, req.Prompt, req.Options, req.Schema)
+		})
 }
 
-// Context-aware response methods
+// respondRetrying runs generation attempts for respondWithContext according to the
+// retry policy
+func (s *Session) respondRetrying(ctx context.Context, kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
+	if s.retryPolicy != nil {
+		var response string
+		err := s.retryPolicy.Do(ctx, func(ctx context.Context) error {
+			var err error
+			response, err = s.respondOnce(ctx, kind, prompt, options, schema)
+			return err
+		})
+		return response, err
+	}
+	return s.respondOnce(ctx, kind, prompt, options, schema)
+}
 
-// RespondWithContext sends a prompt with context cancellation support
-func (s *Session) RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error) {
+// respondOnce runs a single generation attempt for respondWithContext
+func (s *Session) respondOnce(ctx context.Context, kind generationKind, prompt string, options *GenerationOptions, schema string) (string, error) {
 	if s.ptr == nil {
-		return "", fmt.Errorf("invalid session")
+		return "", ErrInvalidSession
+	}
+
+	// Validate context size before sending. With a context policy or auto refresh,
+	// history is trimmed once the session is locked instead.
+	if s.contextPolicy == nil && s.autoRefresh <= 0 {
+		if err := s.validateContextSize(s.withTimeContext(prompt)); err != nil {
+			return "", fmt.Errorf("context size validation failed: %w", err)
+		}
+	}
+
+	if err := s.verifyAvailability(); err != nil {
+		return "", err
 	}
 
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		return "", fmt.Errorf("context size validation failed: %v", err)
+	// Wait for the global rate limit here so the wait respects ctx; the generation
+	// itself then takes the token
+	if err := waitForRateLimit(ctx, false); err != nil {
+		return "", err
 	}
 
 	// Create a channel to receive the response
@@ -1027,78 +1767,48 @@ func (s *Session) RespondWithContext(ctx context.Context, prompt string, options
 	}
 	resultChan := make(chan result, 1)
 
+	// Wait for other generations on the session here so the wait respects ctx; once the
+	// lock is held, cancelling can only affect this generation
+	if err := s.lock(ctx); err != nil {
+		return "", err
+	}
+
 	// Start the response generation in a goroutine
 	go func() {
-		var response string
-		var err error
-
-		// If options are provided, use RespondWithOptions
-		if options != nil {
-			// Extract options with defaults
-			maxTokens := -1 // -1 means no limit
-			if options.MaxTokens != nil {
-				maxTokens = *options.MaxTokens
-			}
-
-			temperature := float32(0.7) // Default temperature
-			if options.Temperature != nil {
-				temperature = *options.Temperature
-			}
-
-			response = s.RespondWithOptions(prompt, maxTokens, temperature)
-		} else {
-			response = s.Respond(prompt, nil)
-		}
-
+		defer s.unlock()
+		response, err := s.generateLocked(ctx, kind, prompt, options, schema)
 		resultChan <- result{response: response, err: err}
 	}()
 
 	// Wait for either completion or context cancellation
 	select {
 	case <-ctx.Done():
+		// Stop the generation in the shim instead of leaving it running
+		s.Cancel()
 		return "", ctx.Err()
 	case res := <-resultChan:
-		if res.err != nil {
-			return "", res.err
-		}
-		return res.response, nil
+		return res.response, res.err
 	}
 }
 
+// RespondWithContext sends a prompt with context cancellation support
+func (s *Session) RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error) {
+	return s.respondWithContext(ctx, generationText, prompt, options, "")
+}
+
 // RespondWithToolsContext sends a prompt with tool calling enabled and context cancellation support
 func (s *Session) RespondWithToolsContext(ctx context.Context, prompt string) (string, error) {
-	if s.ptr == nil {
-		return "", fmt.Errorf("invalid session")
-	}
-
-	// Validate context size before sending
-	if err := s.validateContextSize(prompt); err != nil {
-		return "", fmt.Errorf("context size validation failed: %v", err)
-	}
-
-	// Create a channel to receive the response
-	type result struct {
-		response string
-		err      error
-	}
-	resultChan := make(chan result, 1)
-
-	// Start the response generation in a goroutine
-	go func() {
-		response := s.RespondWithTools(prompt)
-		resultChan <- result{response: response, err: nil}
-	}()
+	return s.respondWithContext(ctx, generationTools, prompt, nil, "")
+}
 
-	// Wait for either completion or context cancellation
-	select {
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case res := <-resultChan:
-		if res.err != nil {
-			return "", res.err
-		}
-		return res.response, nil
+// RespondWithStructuredOutputContext sends a prompt for structured JSON output with context
+// cancellation support. Invalid JSON is handled as by RespondWithStructuredOutput.
+func (s *Session) RespondWithStructuredOutputContext(ctx context.Context, prompt string) (string, error) {
+	response, err := s.respondWithContext(ctx, generationStructured, prompt, nil, "")
+	if err != nil {
+		return "", err
 	}
+	return s.checkStructuredOutput(ctx, response)
 }
 
 // RespondWithTimeout is a convenience method that creates a context with timeout
@@ -1125,16 +1835,31 @@ func (s *Session) RespondWithStreaming(prompt string, callback StreamingCallback
 		return
 	}
 
-	if !shimInitialized {
+	if Init() != nil {
 		callback(fmt.Sprintf("Error: Foundation Models shim not initialized: %v", shimInitError), true)
 		return
 	}
 
+	if err := s.lock(context.Background()); err != nil {
+		callback(fmt.Sprintf("Error: %v", err), true)
+		return
+	}
+	defer s.unlock()
+
+	prompt = s.withTimeContext(prompt)
+
 	// Validate context before proceeding
-	if err := s.validateContextSize(prompt); err != nil {
+	if err := s.ensureContextRoom(prompt); err != nil {
+		callback(fmt.Sprintf("Error: %v", err), true)
+		return
+	}
+
+	release, err := s.waitToStart(context.Background())
+	if err != nil {
 		callback(fmt.Sprintf("Error: %v", err), true)
 		return
 	}
+	defer release()
 
 	cPrompt := cString(prompt)
 	defer freePtr(cPrompt)
@@ -1146,6 +1871,9 @@ func (s *Session) RespondWithStreaming(prompt string, callback StreamingCallback
 			return
 		}
 		chunk := goString(unsafe.Pointer(cChunk))
+		if err := shimError(chunk); err != nil {
+			chunk = fmt.Sprintf("Error: %v", err)
+		}
 		callback(chunk, isLast)
 	}
 
@@ -1166,16 +1894,31 @@ func (s *Session) RespondWithToolsStreaming(prompt string, callback StreamingCal
 		return
 	}
 
-	if !shimInitialized {
+	if Init() != nil {
 		callback(fmt.Sprintf("Error: Foundation Models shim not initialized: %v", shimInitError), true)
 		return
 	}
 
+	if err := s.lock(context.Background()); err != nil {
+		callback(fmt.Sprintf("Error: %v", err), true)
+		return
+	}
+	defer s.unlock()
+
+	prompt = s.withTimeContext(prompt)
+
 	// Validate context before proceeding
-	if err := s.validateContextSize(prompt); err != nil {
+	if err := s.ensureContextRoom(prompt); err != nil {
+		callback(fmt.Sprintf("Error: %v", err), true)
+		return
+	}
+
+	release, err := s.waitToStart(context.Background())
+	if err != nil {
 		callback(fmt.Sprintf("Error: %v", err), true)
 		return
 	}
+	defer release()
 
 	cPrompt := cString(prompt)
 	defer freePtr(cPrompt)
@@ -1187,6 +1930,9 @@ func (s *Session) RespondWithToolsStreaming(prompt string, callback StreamingCal
 			return
 		}
 		chunk := goString(unsafe.Pointer(cChunk))
+		if err := shimError(chunk); err != nil {
+			chunk = fmt.Sprintf("Error: %v", err)
+		}
 		callback(chunk, isLast)
 	}
 
@@ -1256,11 +2002,20 @@ func validateStringArgument(value any, argDef ToolArgument) error {
 	}
 
 	// Check length constraints
-	if argDef.MinLength != nil && len(str) < *argDef.MinLength {
-		return fmt.Errorf("string too short: %d < %d", len(str), *argDef.MinLength)
+	var length int
+	switch argDef.LengthUnit {
+	case "", LengthUnitRunes:
+		length = utf8.RuneCountInString(str)
+	case LengthUnitBytes:
+		length = len(str)
+	default:
+		return fmt.Errorf("unsupported length unit: %s", argDef.LengthUnit)
+	}
+	if argDef.MinLength != nil && length < *argDef.MinLength {
+		return fmt.Errorf("string too short: %d < %d", length, *argDef.MinLength)
 	}
-	if argDef.MaxLength != nil && len(str) > *argDef.MaxLength {
-		return fmt.Errorf("string too long: %d > %d", len(str), *argDef.MaxLength)
+	if argDef.MaxLength != nil && length > *argDef.MaxLength {
+		return fmt.Errorf("string too long: %d > %d", length, *argDef.MaxLength)
 	}
 
 	// Check pattern if provided
@@ -0,0 +1,463 @@
+package fm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// testTool is a tool running fn, for tests that don't need the shim
+type testTool struct {
+	name string
+	fn   func(args map[string]any) (ToolResult, error)
+}
+
+func (t *testTool) Name() string        { return t.name }
+func (t *testTool) Description() string { return "Test tool " + t.name }
+func (t *testTool) Execute(args map[string]any) (ToolResult, error) {
+	return t.fn(args)
+}
+
+// newTestSession returns a session with tools registered on the Go side only, for tests
+// that don't need the shim
+func newTestSession(tools ...Tool) *Session {
+	s := &Session{
+		busy:            make(chan struct{}, 1),
+		maxContextSize:  MAX_CONTEXT_SIZE,
+		registeredTools: make(map[string]Tool),
+	}
+	for _, tool := range tools {
+		s.registeredTools[tool.Name()] = tool
+		s.toolOrder = append(s.toolOrder, tool.Name())
+	}
+	return s
+}
+
+// decodeToolResult decodes the JSON returned by executeTool
+func decodeToolResult(t *testing.T, resultJSON string) ToolResult {
+	t.Helper()
+	var result ToolResult
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		t.Fatalf("invalid tool result %q: %v", resultJSON, err)
+	}
+	return result
+}
+
+func TestValidateStringArgumentLength(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		value   string
+		unit    string
+		min     *int
+		max     *int
+		wantErr string
+	}{
+		{name: "ascii within max", value: "hello", max: intPtr(5)},
+		{name: "runes by default", value: "héllo", max: intPtr(5)},
+		{name: "explicit runes", value: "日本語", unit: LengthUnitRunes, min: intPtr(3), max: intPtr(3)},
+		{name: "emoji counted as one rune", value: "🙂", max: intPtr(1)},
+		{name: "bytes over max", value: "héllo", unit: LengthUnitBytes, max: intPtr(5), wantErr: "string too long: 6 > 5"},
+		{name: "bytes within max", value: "héllo", unit: LengthUnitBytes, max: intPtr(6)},
+		{name: "runes under min", value: "日本", min: intPtr(3), wantErr: "string too short: 2 < 3"},
+		{name: "bytes meet min", value: "日本", unit: LengthUnitBytes, min: intPtr(3)},
+		{name: "runes over max", value: "日本語", max: intPtr(2), wantErr: "string too long: 3 > 2"},
+		{name: "unknown unit", value: "x", unit: "graphemes", max: intPtr(1), wantErr: "unsupported length unit: graphemes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStringArgument(tt.value, ToolArgument{
+				Name:       "text",
+				Type:       "string",
+				MinLength:  tt.min,
+				MaxLength:  tt.max,
+				LengthUnit: tt.unit,
+			})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validateStringArgument(%q) error = %v", tt.value, err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("validateStringArgument(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteFallbackTool(t *testing.T) {
+	var fallbackArgs map[string]any
+	fallback := &testTool{name: "fallback", fn: func(args map[string]any) (ToolResult, error) {
+		fallbackArgs = maps.Clone(args)
+		return ToolResult{Content: "that capability isn't available"}, nil
+	}}
+	weather := &testTool{name: "get_weather", fn: func(args map[string]any) (ToolResult, error) {
+		return ToolResult{Content: "sunny"}, nil
+	}}
+
+	tests := []struct {
+		name         string
+		fallback     Tool
+		toolName     string
+		argsJSON     string
+		wantResult   ToolResult
+		wantFallback map[string]any
+	}{
+		{
+			name:       "unknown tool without fallback",
+			toolName:   "get_stock",
+			argsJSON:   `{"symbol":"AAPL"}`,
+			wantResult: ToolResult{Error: "tool 'get_stock' not found"},
+		},
+		{
+			name:         "unknown tool with fallback",
+			fallback:     fallback,
+			toolName:     "get_stock",
+			argsJSON:     `{"symbol":"AAPL"}`,
+			wantResult:   ToolResult{Content: "that capability isn't available"},
+			wantFallback: map[string]any{"symbol": "AAPL", FallbackToolNameArg: "get_stock"},
+		},
+		{
+			name:         "fallback tolerates invalid arguments",
+			fallback:     fallback,
+			toolName:     "get_stock",
+			argsJSON:     `["AAPL"]`,
+			wantResult:   ToolResult{Content: "that capability isn't available"},
+			wantFallback: map[string]any{FallbackToolNameArg: "get_stock"},
+		},
+		{
+			name:         "fallback tolerates null arguments",
+			fallback:     fallback,
+			toolName:     "get_stock",
+			argsJSON:     `null`,
+			wantResult:   ToolResult{Content: "that capability isn't available"},
+			wantFallback: map[string]any{FallbackToolNameArg: "get_stock"},
+		},
+		{
+			name:       "registered tool wins over fallback",
+			fallback:   fallback,
+			toolName:   "get_weather",
+			argsJSON:   `{"city":"Paris"}`,
+			wantResult: ToolResult{Content: "sunny"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallbackArgs = nil
+			sess := newTestSession(weather)
+			sess.SetFallbackTool(tt.fallback)
+
+			result := decodeToolResult(t, executeTool(sess, tt.toolName, tt.argsJSON))
+			if result != tt.wantResult {
+				t.Errorf("executeTool() = %+v, want %+v", result, tt.wantResult)
+			}
+			if !maps.Equal(fallbackArgs, tt.wantFallback) {
+				t.Errorf("fallback arguments = %v, want %v", fallbackArgs, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestFallbackToolIsPerSession(t *testing.T) {
+	fallback := &testTool{name: "fallback", fn: func(args map[string]any) (ToolResult, error) {
+		return ToolResult{Content: "handled"}, nil
+	}}
+	withFallback := newTestSession()
+	withFallback.SetFallbackTool(fallback)
+	without := newTestSession()
+
+	if result := decodeToolResult(t, executeTool(withFallback, "missing", `{}`)); result.Content != "handled" {
+		t.Errorf("session with fallback returned %+v", result)
+	}
+	if result := decodeToolResult(t, executeTool(without, "missing", `{}`)); result.Error != "tool 'missing' not found" {
+		t.Errorf("session without fallback returned %+v", result)
+	}
+
+	withFallback.SetFallbackTool(nil)
+	if result := decodeToolResult(t, executeTool(withFallback, "missing", `{}`)); result.Error != "tool 'missing' not found" {
+		t.Errorf("session with removed fallback returned %+v", result)
+	}
+}
+
+func TestModelAvailabilityString(t *testing.T) {
+	tests := []struct {
+		availability ModelAvailability
+		want         string
+	}{
+		{ModelAvailable, "available"},
+		{ModelUnavailableAINotEnabled, "Apple Intelligence not enabled"},
+		{ModelUnavailableNotReady, "model not ready"},
+		{ModelUnavailableDeviceNotEligible, "device not eligible"},
+		{ModelUnavailableUnsupportedPlatform, "unsupported platform"},
+		{ModelAvailability(42), "unknown availability status (42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.availability.String(); got != tt.want {
+			t.Errorf("ModelAvailability(%d).String() = %q, want %q", int(tt.availability), got, tt.want)
+		}
+	}
+}
+
+func TestVerifyAvailability(t *testing.T) {
+	availability := GetModelAvailability()
+
+	tests := []struct {
+		name    string
+		check   bool
+		wantErr bool
+	}{
+		{name: "check disabled", check: false},
+		{name: "check enabled", check: true, wantErr: !availability.Available()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			sess.checkAvailability = tt.check
+			err := sess.verifyAvailability()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("verifyAvailability() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrModelBecameUnavailable) || !errors.Is(err, availability.Err()) {
+				t.Errorf("verifyAvailability() error = %v, want ErrModelBecameUnavailable wrapping %v", err, availability.Err())
+			}
+		})
+	}
+}
+
+func TestNewSessionWithInstructionsLimited(t *testing.T) {
+	instructions := strings.Repeat("Answer briefly and cite your sources. ", 20)
+	tokens := estimateTokens(instructions)
+
+	tests := []struct {
+		name    string
+		budget  int
+		wantErr error
+		wantMsg string
+	}{
+		{name: "over budget", budget: tokens - 1, wantErr: ErrInstructionsTooLong, wantMsg: fmt.Sprintf("instructions exceed token budget: %d tokens > %d", tokens, tokens-1)},
+		{name: "zero budget", budget: 0, wantErr: ErrInstructionsTooLong},
+		{name: "within budget", budget: tokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, err := NewSessionWithInstructionsLimited(instructions, tt.budget)
+			if sess != nil {
+				defer sess.Release()
+			}
+			if tt.wantErr == nil {
+				// Within budget the session is created, or creation fails without the shim
+				if err != nil && !errors.Is(err, ErrShimNotInitialized) {
+					t.Fatalf("NewSessionWithInstructionsLimited() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewSessionWithInstructionsLimited() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+				t.Errorf("NewSessionWithInstructionsLimited() error = %q, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestReorderTools(t *testing.T) {
+	noop := func(args map[string]any) (ToolResult, error) { return ToolResult{}, nil }
+	sess := newTestSession(
+		&testTool{name: "weather", fn: noop},
+		&testTool{name: "calculator", fn: noop},
+		&testTool{name: "search", fn: noop},
+	)
+
+	tests := []struct {
+		name    string
+		order   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "empty order keeps registration order", order: nil, want: []string{"weather", "calculator", "search"}},
+		{name: "full order", order: []string{"search", "weather", "calculator"}, want: []string{"search", "weather", "calculator"}},
+		{name: "partial order", order: []string{"search"}, want: []string{"search", "weather", "calculator"}},
+		{name: "unknown tool", order: []string{"search", "stocks"}, wantErr: "tool not registered: stocks"},
+		{name: "duplicate tool", order: []string{"search", "search"}, wantErr: "duplicate tool in order: search"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reorderTools(sess.toolOrder, sess.registeredTools, tt.order)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("reorderTools() = %v, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("reorderTools() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+	if want := []string{"weather", "calculator", "search"}; !slices.Equal(sess.GetRegisteredTools(), want) {
+		t.Errorf("GetRegisteredTools() = %v, want %v", sess.GetRegisteredTools(), want)
+	}
+}
+
+func TestAddContextValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		ptr     bool
+		size    int
+		text    string
+		wantErr error
+	}{
+		{name: "released session", text: "Some notes", wantErr: ErrInvalidSession},
+		{name: "over the limit", ptr: true, size: MAX_CONTEXT_SIZE, text: "One more document", wantErr: ErrContextLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			if tt.ptr {
+				// Validation fails before the shim is called with this pointer
+				sess.ptr = unsafe.Pointer(new(byte))
+			}
+			sess.contextSize = tt.size
+
+			if err := sess.AddContext(tt.text); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := sess.GetContextSize(); got != tt.size {
+				t.Errorf("GetContextSize() = %d after a failed AddContext, want %d", got, tt.size)
+			}
+		})
+	}
+}
+
+func TestShimSymbol(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    uintptr
+		wantErr error
+	}{
+		{name: "exported", addr: 1},
+		{name: "missing", addr: 0, wantErr: ErrShimFeatureUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := shimSymbol(tt.addr, "AddContext")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("shimSymbol() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "AddContext") {
+				t.Errorf("shimSymbol() error = %q, want it to name the export", err)
+			}
+		})
+	}
+}
+
+func TestCopySettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		useCase    UseCase
+		adapter    string
+		guardrails Guardrails
+		wantErr    error
+	}{
+		{name: "default model"},
+		{name: "use case", useCase: UseCaseContentTagging, wantErr: ErrInvalidSession},
+		{name: "adapter", adapter: "adapter.fmadapter", wantErr: ErrInvalidSession},
+		{name: "guardrails", guardrails: GuardrailsPermissiveContentTransformations, wantErr: ErrInvalidSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newTestSession()
+			src.useCase = tt.useCase
+			src.adapterPath = tt.adapter
+			src.guardrails = tt.guardrails
+			src.maxContextSize = 1024
+			src.SetContextValidation(false)
+			src.SetDefaultTimeout(time.Minute)
+			src.SetContextPolicy(DropOldest())
+			src.autoRefresh = 0.9
+			src.refreshSummary = true
+			src.jsonIndent = "  "
+			src.jsonRepair = true
+
+			// The destination has no shim session, so model settings can't be applied
+			dst := newTestSession()
+			if err := src.copySettings(dst); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("copySettings() error = %v, want %v", err, tt.wantErr)
+			}
+			if dst.maxContextSize != 1024 || !dst.skipContextCheck || dst.DefaultTimeout() != time.Minute {
+				t.Errorf("context settings not copied: max %d, skip check %v, timeout %v",
+					dst.maxContextSize, dst.skipContextCheck, dst.DefaultTimeout())
+			}
+			if dst.contextPolicy == nil || dst.autoRefresh != 0.9 || !dst.refreshSummary {
+				t.Errorf("refresh settings not copied: policy %v, auto refresh %v, summary %v",
+					dst.contextPolicy, dst.autoRefresh, dst.refreshSummary)
+			}
+			if dst.jsonIndent != "  " || !dst.jsonRepair {
+				t.Errorf("JSON settings not copied: indent %q, repair %v", dst.jsonIndent, dst.jsonRepair)
+			}
+		})
+	}
+}
+
+func TestDescribeModel(t *testing.T) {
+	tests := []struct {
+		name           string
+		useCase        UseCase
+		adapter        string
+		guardrails     Guardrails
+		wantIdentifier string
+		wantUseCase    string
+	}{
+		{name: "default", wantIdentifier: "SystemLanguageModel.default", wantUseCase: "general"},
+		{
+			name:           "use case",
+			useCase:        UseCaseContentTagging,
+			wantIdentifier: "SystemLanguageModel(useCase: .contentTagging, guardrails: .default)",
+			wantUseCase:    "contentTagging",
+		},
+		{
+			name:           "guardrails",
+			guardrails:     GuardrailsPermissiveContentTransformations,
+			wantIdentifier: "SystemLanguageModel(useCase: .general, guardrails: .permissiveContentTransformations)",
+			wantUseCase:    "general",
+		},
+		{
+			name:           "adapter",
+			adapter:        "/adapters/tagger.fmadapter",
+			wantIdentifier: "SystemLanguageModel(adapter: tagger.fmadapter)",
+			wantUseCase:    "general",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			sess.useCase = tt.useCase
+			sess.adapterPath = tt.adapter
+			sess.guardrails = tt.guardrails
+
+			details := ModelInfo{OSVersion: "26.0", ModelIdentifier: defaultModelIdentifier, UseCase: "general", ContextSize: 4096}
+			got := sess.describeModel(details)
+			if got.ModelIdentifier != tt.wantIdentifier {
+				t.Errorf("ModelIdentifier = %q, want %q", got.ModelIdentifier, tt.wantIdentifier)
+			}
+			if got.AdapterIdentifier != tt.adapter {
+				t.Errorf("AdapterIdentifier = %q, want %q", got.AdapterIdentifier, tt.adapter)
+			}
+			if got.UseCase != tt.wantUseCase {
+				t.Errorf("UseCase = %q, want %q", got.UseCase, tt.wantUseCase)
+			}
+			if got.OSVersion != details.OSVersion || got.ContextSize != details.ContextSize {
+				t.Errorf("describeModel() = %+v, want the model details kept", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+// Package fmagent runs multi-step tasks on a session with tools, such as "get the
+// weather in three cities and compare them", that a single tool-calling response can't
+// finish. The agent repeats a plan, tool, observe loop: each step asks the model to make
+// progress with its tools, and the model signals that it is done by replying with a
+// final answer.
+//
+//	sess := fm.NewSession()
+//	defer sess.Release()
+//	sess.RegisterTool(&WeatherTool{})
+//
+//	agent := fmagent.New(sess, fmagent.WithMaxIterations(5))
+//	result, err := agent.Run(ctx, "Get the weather in Paris, Tokyo and Lima and say which is warmest")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.Answer)
+//
+// Steps run on the session, so the model sees its earlier steps and their tool results.
+package fmagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+)
+
+// DefaultMaxIterations is the default limit on the steps of a run
+const DefaultMaxIterations = 8
+
+// FinalAnswerMarker starts the model's reply when it has finished the task
+const FinalAnswerMarker = "Final answer:"
+
+// ErrMaxIterations is returned when a run reaches its step limit without a final answer
+var ErrMaxIterations = errors.New("fmagent: maximum iterations reached without a final answer")
+
+// Prompts that drive the loop
+const (
+	taskPrompt = "%s\n\nWork on this task step by step. Use the available tools to gather what you " +
+		"need. When you have everything needed, reply with \"" + FinalAnswerMarker + "\" followed by " +
+		"the complete answer."
+	continuePrompt = "Continue with the next step of the task. If you have everything needed, reply " +
+		"with \"" + FinalAnswerMarker + "\" followed by the complete answer."
+	extractPrompt = "Reply with \"" + FinalAnswerMarker + "\" followed by the complete answer to the " +
+		"task, using what you have found so far. Do not call any more tools."
+)
+
+// Step is one iteration of the loop
+type Step struct {
+	// Index is the step's position in the run, starting at 1
+	Index int
+	// Prompt is the prompt the step sent to the model
+	Prompt string
+	// Response is the model's reply
+	Response string
+	// ToolCalls are the tools the model called during the step, in the order they finished
+	ToolCalls []fm.ToolInvocation
+	// Duration is the time the step took
+	Duration time.Duration
+}
+
+// StepHook is called after each step. Returning an error stops the run with it.
+type StepHook func(ctx context.Context, step Step) error
+
+// Result is the outcome of a run
+type Result struct {
+	// Answer is the final answer, without FinalAnswerMarker
+	Answer string
+	// Steps are the run's steps, including the final one
+	Steps []Step
+}
+
+// ToolCalls returns the number of tool calls made during the run
+func (r *Result) ToolCalls() int {
+	n := 0
+	for _, step := range r.Steps {
+		n += len(step.ToolCalls)
+	}
+	return n
+}
+
+// Option configures an Agent
+type Option func(*Agent)
+
+// WithMaxIterations limits the number of steps of a run (default: DefaultMaxIterations)
+func WithMaxIterations(n int) Option {
+	return func(a *Agent) {
+		a.maxIterations = n
+	}
+}
+
+// WithMaxToolCalls limits the number of tool calls of a run (default: no limit). The
+// call that would exceed the limit is not run, and a run that reaches the limit without a
+// final answer stops with an *fm.ToolBudgetError carrying the session's transcript.
+func WithMaxToolCalls(n int) Option {
+	return func(a *Agent) {
+		a.maxToolCalls = n
+	}
+}
+
+// WithStepHook adds a hook called after each step, e.g. to log progress or stop a run
+func WithStepHook(hook StepHook) Option {
+	return func(a *Agent) {
+		a.hooks = append(a.hooks, hook)
+	}
+}
+
+// session is the part of *fm.Session the agent uses
+type session interface {
+	RespondAgentStream(ctx context.Context, prompt string) (<-chan fm.AgentEvent, error)
+	SetMaxToolCalls(n int) error
+	MaxToolCalls() int
+	Transcript() ([]fm.TranscriptEntry, error)
+}
+
+// Agent runs tasks on a session in a loop of tool-calling steps
+type Agent struct {
+	sess          session
+	maxIterations int
+	maxToolCalls  int
+	hooks         []StepHook
+}
+
+// New creates an agent that runs tasks on sess, using the tools registered with it
+func New(sess *fm.Session, opts ...Option) *Agent {
+	a := &Agent{sess: sess, maxIterations: DefaultMaxIterations}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Run works on task until the model gives a final answer. A step in which the model
+// calls no tools ends the loop too; its reply is the answer if it has FinalAnswerMarker,
+// and otherwise the model is asked for the answer in a final step. If the run is stopped
+// by an error, a hook, the iteration limit (ErrMaxIterations) or the tool call limit
+// (fm.ErrToolBudgetExceeded), the steps so far are returned with the error.
+func (a *Agent) Run(ctx context.Context, task string) (*Result, error) {
+	if a.maxToolCalls > 0 {
+		// Each step's budget is what the run has left; restore the session's own after
+		defer a.sess.SetMaxToolCalls(a.sess.MaxToolCalls())
+	}
+
+	result := &Result{}
+	prompt := fmt.Sprintf(taskPrompt, task)
+	for i := 1; i <= a.maxIterations; i++ {
+		if err := a.limitToolCalls(result); err != nil {
+			return result, err
+		}
+		step, err := a.step(ctx, result, i, prompt)
+		if err != nil {
+			return result, err
+		}
+
+		if answer, ok := extractFinalAnswer(step.Response); ok {
+			result.Answer = answer
+			return result, nil
+		}
+		if len(step.ToolCalls) == 0 {
+			return a.extract(ctx, result, i+1)
+		}
+		prompt = continuePrompt
+	}
+	return result, ErrMaxIterations
+}
+
+// limitToolCalls sets the session's tool call budget to what the run has left, failing
+// if nothing is left
+func (a *Agent) limitToolCalls(result *Result) error {
+	if a.maxToolCalls <= 0 {
+		return nil
+	}
+	remaining := a.maxToolCalls - result.ToolCalls()
+	if remaining <= 0 {
+		return a.budgetError()
+	}
+	return a.sess.SetMaxToolCalls(remaining)
+}
+
+// budgetError reports that the run's tool call budget is spent
+func (a *Agent) budgetError() error {
+	transcript, _ := a.sess.Transcript()
+	return &fm.ToolBudgetError{Limit: a.maxToolCalls, Transcript: transcript}
+}
+
+// extract asks the model for the final answer in step index
+func (a *Agent) extract(ctx context.Context, result *Result, index int) (*Result, error) {
+	step, err := a.step(ctx, result, index, extractPrompt)
+	if err != nil {
+		return result, err
+	}
+
+	answer, ok := extractFinalAnswer(step.Response)
+	if !ok {
+		answer = strings.TrimSpace(step.Response)
+	}
+	result.Answer = answer
+	return result, nil
+}
+
+// step sends prompt to the model, adding the step with its reply and tool calls to
+// result, and runs the hooks
+func (a *Agent) step(ctx context.Context, result *Result, index int, prompt string) (Step, error) {
+	step := Step{Index: index, Prompt: prompt}
+	start := time.Now()
+
+	events, err := a.sess.RespondAgentStream(ctx, prompt)
+	if err != nil {
+		return step, err
+	}
+	for event := range events {
+		switch event := event.(type) {
+		case fm.ToolResultEvent:
+			step.ToolCalls = append(step.ToolCalls, fm.ToolInvocation{
+				Name:      event.Name,
+				Arguments: event.Arguments,
+				Result:    event.Result,
+				Duration:  event.Duration,
+			})
+		case fm.DoneEvent:
+			step.Response = event.Text
+			err = event.Err
+		}
+	}
+	step.Duration = time.Since(start)
+	result.Steps = append(result.Steps, step)
+	var budgetErr *fm.ToolBudgetError
+	if errors.As(err, &budgetErr) && a.maxToolCalls > 0 {
+		// The session's budget was the run's remainder; report the run's limit
+		budgetErr.Limit = a.maxToolCalls
+	}
+	if err != nil {
+		return step, err
+	}
+
+	for _, hook := range a.hooks {
+		if err := hook(ctx, step); err != nil {
+			return step, err
+		}
+	}
+	return step, nil
+}
+
+// extractFinalAnswer returns the text after the last FinalAnswerMarker in response,
+// ignoring case, and whether there was one
+func extractFinalAnswer(response string) (string, bool) {
+	for i := len(response) - len(FinalAnswerMarker); i >= 0; i-- {
+		if strings.EqualFold(response[i:i+len(FinalAnswerMarker)], FinalAnswerMarker) {
+			return strings.TrimSpace(response[i+len(FinalAnswerMarker):]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,251 @@
+package fmagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	fm "github.com/blacktop/go-foundationmodels"
+)
+
+// fakeStep is the model's scripted reply to one step
+type fakeStep struct {
+	toolCalls int
+	response  string
+	err       error
+}
+
+// fakeSession replays scripted steps, recording the prompts and tool call budgets
+type fakeSession struct {
+	steps        []fakeStep
+	prompts      []string
+	budgets      []int
+	maxToolCalls int
+}
+
+func (f *fakeSession) RespondAgentStream(_ context.Context, prompt string) (<-chan fm.AgentEvent, error) {
+	if len(f.prompts) == len(f.steps) {
+		return nil, errors.New("unexpected step")
+	}
+	step := f.steps[len(f.prompts)]
+	f.prompts = append(f.prompts, prompt)
+
+	events := make(chan fm.AgentEvent, step.toolCalls+1)
+	for range step.toolCalls {
+		events <- fm.ToolResultEvent{Name: "weather", Result: fm.ToolResult{Content: "sunny"}}
+	}
+	events <- fm.DoneEvent{Text: step.response, Err: step.err, ToolCalls: step.toolCalls}
+	close(events)
+	return events, nil
+}
+
+func (f *fakeSession) SetMaxToolCalls(n int) error {
+	f.budgets = append(f.budgets, n)
+	f.maxToolCalls = n
+	return nil
+}
+
+func (f *fakeSession) MaxToolCalls() int { return f.maxToolCalls }
+
+func (f *fakeSession) Transcript() ([]fm.TranscriptEntry, error) {
+	return []fm.TranscriptEntry{{Role: "user", Content: "task"}}, nil
+}
+
+func TestExtractFinalAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantOK   bool
+	}{
+		{name: "marker", response: "Final answer: Lima is warmest.", want: "Lima is warmest.", wantOK: true},
+		{name: "after reasoning", response: "I checked all three.\nFinal answer: Lima", want: "Lima", wantOK: true},
+		{name: "any case", response: "FINAL ANSWER:  Tokyo ", want: "Tokyo", wantOK: true},
+		{name: "last marker wins", response: "Final answer: Paris\nFinal answer: Lima", want: "Lima", wantOK: true},
+		{name: "empty answer", response: "Final answer:", want: "", wantOK: true},
+		{name: "no marker", response: "Let me check the weather first."},
+		{name: "empty", response: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := extractFinalAnswer(tt.response)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("extractFinalAnswer(%q) = %q, %v, want %q, %v", tt.response, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("generation failed")
+	errHook := errors.New("stopped by hook")
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		steps         []fakeStep
+		wantAnswer    string
+		wantErr       error
+		wantSteps     int
+		wantPrompts   []string // Prompts after the task prompt
+		wantBudgets   []int    // Budgets set on the session, including the restored one
+		wantLimit     int      // Limit of a returned *fm.ToolBudgetError
+		wantToolCalls int
+	}{
+		{
+			name:       "answer in first step",
+			steps:      []fakeStep{{response: "Final answer: 42"}},
+			wantAnswer: "42",
+			wantSteps:  1,
+		},
+		{
+			name: "tools then answer",
+			steps: []fakeStep{
+				{toolCalls: 2, response: "Paris is 18°C, Tokyo is 22°C."},
+				{toolCalls: 1, response: "Lima is 25°C."},
+				{response: "Final answer: Lima is warmest."},
+			},
+			wantAnswer:    "Lima is warmest.",
+			wantSteps:     3,
+			wantPrompts:   []string{continuePrompt, continuePrompt},
+			wantToolCalls: 3,
+		},
+		{
+			name: "extract answer",
+			steps: []fakeStep{
+				{toolCalls: 1, response: "It's sunny in Paris."},
+				{response: "So it's sunny."},
+				{response: "Final answer: Sunny in Paris."},
+			},
+			wantAnswer:    "Sunny in Paris.",
+			wantSteps:     3,
+			wantPrompts:   []string{continuePrompt, extractPrompt},
+			wantToolCalls: 1,
+		},
+		{
+			name: "extract without marker",
+			steps: []fakeStep{
+				{response: "I need no tools."},
+				{response: "  Sunny in Paris.  "},
+			},
+			wantAnswer:  "Sunny in Paris.",
+			wantSteps:   2,
+			wantPrompts: []string{extractPrompt},
+		},
+		{
+			name: "max iterations",
+			opts: []Option{WithMaxIterations(2)},
+			steps: []fakeStep{
+				{toolCalls: 1, response: "Checking Paris."},
+				{toolCalls: 1, response: "Checking Tokyo."},
+			},
+			wantErr:       ErrMaxIterations,
+			wantSteps:     2,
+			wantPrompts:   []string{continuePrompt},
+			wantToolCalls: 2,
+		},
+		{
+			name: "generation error",
+			steps: []fakeStep{
+				{toolCalls: 1, response: "Checking Paris."},
+				{response: "Partial", err: errFailed},
+			},
+			wantErr:       errFailed,
+			wantSteps:     2,
+			wantPrompts:   []string{continuePrompt},
+			wantToolCalls: 1,
+		},
+		{
+			name: "hook stops run",
+			opts: []Option{WithStepHook(func(ctx context.Context, step Step) error {
+				if step.Index == 1 {
+					return errHook
+				}
+				return nil
+			})},
+			steps:         []fakeStep{{toolCalls: 1, response: "Checking Paris."}},
+			wantErr:       errHook,
+			wantSteps:     1,
+			wantToolCalls: 1,
+		},
+		{
+			name: "tool budget spent between steps",
+			opts: []Option{WithMaxToolCalls(3)},
+			steps: []fakeStep{
+				{toolCalls: 2, response: "Checking Paris and Tokyo."},
+				{toolCalls: 1, response: "Checking Lima."},
+			},
+			wantErr:       fm.ErrToolBudgetExceeded,
+			wantSteps:     2,
+			wantPrompts:   []string{continuePrompt},
+			wantBudgets:   []int{3, 1, 0},
+			wantLimit:     3,
+			wantToolCalls: 3,
+		},
+		{
+			name: "tool budget exceeded in a step",
+			opts: []Option{WithMaxToolCalls(3)},
+			steps: []fakeStep{
+				{toolCalls: 2, response: "Checking Paris and Tokyo."},
+				{toolCalls: 1, response: "Checking Lima.", err: &fm.ToolBudgetError{Limit: 1}},
+			},
+			wantErr:       fm.ErrToolBudgetExceeded,
+			wantSteps:     2,
+			wantPrompts:   []string{continuePrompt},
+			wantBudgets:   []int{3, 1, 0},
+			wantLimit:     3,
+			wantToolCalls: 3,
+		},
+		{
+			name:          "tool budget with answer",
+			opts:          []Option{WithMaxToolCalls(5)},
+			steps:         []fakeStep{{toolCalls: 1, response: "Final answer: sunny"}},
+			wantAnswer:    "sunny",
+			wantSteps:     1,
+			wantBudgets:   []int{5, 0}, // The session's own budget (none) is restored
+			wantToolCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := &fakeSession{steps: tt.steps}
+			agent := New(nil, tt.opts...)
+			agent.sess = sess
+
+			result, err := agent.Run(context.Background(), "Which city is warmest?")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if result.Answer != tt.wantAnswer {
+				t.Errorf("Answer = %q, want %q", result.Answer, tt.wantAnswer)
+			}
+			if len(result.Steps) != tt.wantSteps {
+				t.Errorf("got %d steps, want %d", len(result.Steps), tt.wantSteps)
+			}
+			for i, step := range result.Steps {
+				if step.Index != i+1 {
+					t.Errorf("Steps[%d].Index = %d, want %d", i, step.Index, i+1)
+				}
+			}
+			if got := result.ToolCalls(); got != tt.wantToolCalls {
+				t.Errorf("ToolCalls() = %d, want %d", got, tt.wantToolCalls)
+			}
+			if want := fmt.Sprintf(taskPrompt, "Which city is warmest?"); sess.prompts[0] != want {
+				t.Errorf("first prompt = %q, want %q", sess.prompts[0], want)
+			}
+			if got := sess.prompts[1:]; !slices.Equal(got, tt.wantPrompts) {
+				t.Errorf("later prompts = %q, want %q", got, tt.wantPrompts)
+			}
+			if !slices.Equal(sess.budgets, tt.wantBudgets) {
+				t.Errorf("tool call budgets = %v, want %v", sess.budgets, tt.wantBudgets)
+			}
+
+			var budgetErr *fm.ToolBudgetError
+			if errors.As(err, &budgetErr) && budgetErr.Limit != tt.wantLimit {
+				t.Errorf("ToolBudgetError.Limit = %d, want %d", budgetErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+// Package fmgenkit is a Genkit plugin that provides Foundation Models as a model, so
+// Genkit flows can run on the on-device model:
+//
+//	g := genkit.Init(ctx, genkit.WithPlugins(&fmgenkit.FoundationModels{}))
+//	resp, err := genkit.Generate(ctx, g,
+//		ai.WithModel(fmgenkit.Model(g)),
+//		ai.WithPrompt("Name three rivers in Europe"))
+//
+// Each request runs in a new session seeded with the request's messages: system messages
+// become the instructions, earlier messages the conversation, and the last message, which
+// must be from the user, the prompt. JSON output with a schema is constrained to it by
+// the model (see fm.SchemaFromJSON).
+//
+// Foundation Models runs tools itself rather than returning tool requests, so the
+// request's tools are looked up in the Genkit registry and executed by the model during
+// generation. The response holds the final answer; ai.WithReturnToolRequests has no
+// effect.
+package fmgenkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+const (
+	// provider is the plugin's name and the provider part of its model name
+	provider = "foundationmodels"
+	// modelName is the name of the system language model
+	modelName = "default"
+)
+
+var (
+	// ErrNoPrompt is returned when the last message isn't a user message with text
+	ErrNoPrompt = errors.New("fmgenkit: last message must be a user message")
+
+	// ErrUnsupportedContent is returned for message parts other than text, tool requests
+	// and tool responses, such as media
+	ErrUnsupportedContent = errors.New("fmgenkit: unsupported message content")
+)
+
+// FoundationModels is the Genkit plugin for Foundation Models
+type FoundationModels struct {
+	// SessionOptions are applied to the session created for each request
+	SessionOptions []fm.SessionOption
+
+	// registry is where the model was registered and the requests' tools are looked up
+	registry api.Registry
+}
+
+// Name returns the plugin's name
+func (p *FoundationModels) Name() string {
+	return provider
+}
+
+// Init returns the Foundation Models model for Genkit to register
+func (p *FoundationModels) Init(ctx context.Context) []api.Action {
+	model := ai.NewModel(api.NewName(provider, modelName), &ai.ModelOptions{
+		Label: "Apple Foundation Models",
+		Supports: &ai.ModelSupports{
+			Multiturn:  true,
+			SystemRole: true,
+			Tools:      true,
+		},
+	}, p.generate)
+	return []api.Action{&pluginModel{Action: model.(api.Action), plugin: p}}
+}
+
+// pluginModel is the plugin's model action, which remembers the registry it's registered
+// with so requests can look up their tools
+type pluginModel struct {
+	api.Action
+	plugin *FoundationModels
+}
+
+// Register registers the model with r
+func (m *pluginModel) Register(r api.Registry) {
+	m.plugin.registry = r
+	m.Action.Register(r)
+}
+
+// Model returns the Foundation Models model registered with g by the plugin
+func Model(g *genkit.Genkit) ai.Model {
+	return genkit.LookupModel(g, api.NewName(provider, modelName))
+}
+
+// generate runs req in a new session, streaming to cb if it is set
+func (p *FoundationModels) generate(ctx context.Context, req *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	entries, prompt, err := transcript(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	config, err := generationConfig(req.Config)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := p.newSession(ctx, entries, req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+
+	var resp *ai.ModelResponse
+	switch {
+	case req.Output != nil && req.Output.Format == "json":
+		resp, err = respondJSON(ctx, sess, prompt, req.Output.Schema, cb)
+	case cb != nil:
+		resp, err = respondStream(ctx, sess, prompt, generationOptions(config), cb)
+	default:
+		resp, err = respond(ctx, sess, prompt, generationOptions(config))
+	}
+	if errors.Is(err, fm.ErrGuardrailViolation) {
+		resp, err = &ai.ModelResponse{
+			Message:       ai.NewModelTextMessage(""),
+			FinishReason:  ai.FinishReasonBlocked,
+			FinishMessage: err.Error(),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// newSession creates a session with the plugin's options, seeded with entries, that can
+// call the registered Genkit tools named by tools
+func (p *FoundationModels) newSession(ctx context.Context, entries []fm.TranscriptEntry, tools []*ai.ToolDefinition) (*fm.Session, error) {
+	if err := fm.Init(); err != nil {
+		return nil, err
+	}
+	if err := fm.GetModelAvailability().Err(); err != nil {
+		return nil, err
+	}
+
+	sess := fm.NewSession(p.SessionOptions...)
+	if sess == nil {
+		return nil, fm.ErrInvalidSession
+	}
+	if len(entries) > 0 {
+		if err := sess.SetTranscript(entries); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("fmgenkit: failed to seed session: %w", err)
+		}
+	}
+	for _, def := range tools {
+		var tool ai.Tool
+		if p.registry != nil {
+			tool = ai.LookupTool(p.registry, def.Name)
+		}
+		if tool == nil {
+			sess.Release()
+			return nil, fmt.Errorf("fmgenkit: tool %q is not registered", def.Name)
+		}
+		if err := sess.RegisterTool(&genkitTool{ctx: ctx, def: def, tool: tool}); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("fmgenkit: failed to register tool %q: %w", def.Name, err)
+		}
+	}
+	return sess, nil
+}
+
+// respond generates a complete text response
+func respond(ctx context.Context, sess *fm.Session, prompt string, options *fm.GenerationOptions) (*ai.ModelResponse, error) {
+	response, err := sess.RespondDetailed(ctx, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(response.Text),
+		FinishReason: finishReason(response.FinishReason),
+		Usage: &ai.GenerationUsage{
+			InputTokens:  response.PromptTokens,
+			OutputTokens: response.CompletionTokens,
+			TotalTokens:  response.TotalTokens(),
+		},
+	}, nil
+}
+
+// respondStream streams a text response to cb, stopping generation if it returns an
+// error
+func respondStream(ctx context.Context, sess *fm.Session, prompt string, options *fm.GenerationOptions, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := sess.RespondStream(ctx, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var reason fm.FinishReason
+	var streamErr error
+	for chunk := range stream {
+		if chunk.Text != "" && streamErr == nil {
+			text.WriteString(chunk.Text)
+			streamErr = cb(ctx, &ai.ModelResponseChunk{
+				Role:    ai.RoleModel,
+				Content: []*ai.Part{ai.NewTextPart(chunk.Text)},
+			})
+			if streamErr != nil {
+				cancel()
+			}
+		}
+		if chunk.Done {
+			if streamErr == nil {
+				streamErr = chunk.Err
+			}
+			reason = chunk.FinishReason
+		}
+	}
+	if streamErr != nil {
+		return nil, streamErr
+	}
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(text.String()),
+		FinishReason: finishReason(reason),
+	}, nil
+}
+
+// respondJSON generates a JSON response, constrained to schema if it is set, and passes
+// it to cb, if set, in one piece. Generation options don't apply to structured output.
+func respondJSON(ctx context.Context, sess *fm.Session, prompt string, schema map[string]any, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	var response string
+	if len(schema) == 0 {
+		var err error
+		if response, err = sess.RespondWithStructuredOutputContext(ctx, prompt); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("fmgenkit: failed to marshal output schema: %w", err)
+		}
+		generationSchema, err := fm.SchemaFromJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("fmgenkit: unsupported output schema: %w", err)
+		}
+		stream, err := sess.RespondWithSchemaStream(ctx, prompt, generationSchema)
+		if err != nil {
+			return nil, err
+		}
+		for chunk := range stream {
+			if chunk.Done {
+				if chunk.Err != nil {
+					return nil, chunk.Err
+				}
+				response = chunk.JSON
+			}
+		}
+	}
+
+	if cb != nil {
+		if err := cb(ctx, &ai.ModelResponseChunk{
+			Role:    ai.RoleModel,
+			Content: []*ai.Part{ai.NewTextPart(response)},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(response),
+		FinishReason: ai.FinishReasonStop,
+	}, nil
+}
+
+// finishReason maps a Foundation Models finish reason to Genkit's
+func finishReason(reason fm.FinishReason) ai.FinishReason {
+	switch reason {
+	case fm.FinishStop, fm.FinishStopSequence:
+		return ai.FinishReasonStop
+	case fm.FinishLength:
+		return ai.FinishReasonLength
+	case fm.FinishGuardrail:
+		return ai.FinishReasonBlocked
+	case fm.FinishCancelled:
+		return ai.FinishReasonInterrupted
+	case "":
+		return ai.FinishReasonUnknown
+	default:
+		return ai.FinishReasonOther
+	}
+}
+
+// generationConfig decodes a request's config, which is an *ai.GenerationCommonConfig
+// from Go callers or a JSON object from the developer UI
+func generationConfig(config any) (*ai.GenerationCommonConfig, error) {
+	switch config := config.(type) {
+	case nil:
+		return &ai.GenerationCommonConfig{}, nil
+	case *ai.GenerationCommonConfig:
+		return config, nil
+	case ai.GenerationCommonConfig:
+		return &config, nil
+	default:
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("fmgenkit: invalid config: %w", err)
+		}
+		var common ai.GenerationCommonConfig
+		if err := json.Unmarshal(data, &common); err != nil {
+			return nil, fmt.Errorf("fmgenkit: invalid config: %w", err)
+		}
+		return &common, nil
+	}
+}
+
+// generationOptions maps a Genkit config to generation options. Zero values mean unset.
+func generationOptions(config *ai.GenerationCommonConfig) *fm.GenerationOptions {
+	options := &fm.GenerationOptions{StopSequences: config.StopSequences}
+	if config.MaxOutputTokens > 0 {
+		options.MaxTokens = &config.MaxOutputTokens
+	}
+	if config.Temperature > 0 {
+		temperature := float32(config.Temperature)
+		options.Temperature = &temperature
+	}
+	if config.TopK > 0 {
+		options.TopK = &config.TopK
+	} else if config.TopP > 0 {
+		topP := float32(config.TopP)
+		options.TopP = &topP
+	}
+	return options
+}
+
+// transcript converts messages to transcript entries and the prompt, which is the text of
+// the last message. System messages are joined into one instructions entry at the start.
+func transcript(messages []*ai.Message) ([]fm.TranscriptEntry, string, error) {
+	if len(messages) == 0 {
+		return nil, "", ErrNoPrompt
+	}
+	last := messages[len(messages)-1]
+	if last.Role != ai.RoleUser {
+		return nil, "", ErrNoPrompt
+	}
+	prompt, err := messageText(last)
+	if err != nil {
+		return nil, "", err
+	}
+	if prompt == "" {
+		return nil, "", ErrNoPrompt
+	}
+
+	var instructions []string
+	var entries []fm.TranscriptEntry
+	for _, message := range messages[:len(messages)-1] {
+		if message.Role == ai.RoleSystem {
+			text, err := messageText(message)
+			if err != nil {
+				return nil, "", err
+			}
+			instructions = append(instructions, text)
+			continue
+		}
+		converted, err := messageEntries(message)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, converted...)
+	}
+
+	if len(instructions) > 0 {
+		entries = append([]fm.TranscriptEntry{{
+			Role:    fm.TranscriptRoleInstructions,
+			Content: strings.Join(instructions, "\n\n"),
+		}}, entries...)
+	}
+	return entries, prompt, nil
+}
+
+// messageEntries converts a non-system message to transcript entries
+func messageEntries(message *ai.Message) ([]fm.TranscriptEntry, error) {
+	var entries []fm.TranscriptEntry
+	for _, part := range message.Content {
+		switch {
+		case part.IsText():
+			role := fm.TranscriptRolePrompt
+			if message.Role == ai.RoleModel {
+				role = fm.TranscriptRoleResponse
+			}
+			entries = append(entries, fm.TranscriptEntry{Role: role, Content: part.Text})
+		case part.IsToolRequest():
+			input, err := json.Marshal(part.ToolRequest.Input)
+			if err != nil {
+				return nil, fmt.Errorf("fmgenkit: invalid tool request: %w", err)
+			}
+			entries = append(entries, fm.TranscriptEntry{
+				Role:     fm.TranscriptRoleToolCall,
+				Content:  string(input),
+				ToolName: part.ToolRequest.Name,
+			})
+		case part.IsToolResponse():
+			entries = append(entries, fm.TranscriptEntry{
+				Role:     fm.TranscriptRoleToolOutput,
+				Content:  toolOutput(part.ToolResponse.Output),
+				ToolName: part.ToolResponse.Name,
+			})
+		default:
+			return nil, fmt.Errorf("%w: %s part in %s message", ErrUnsupportedContent, partKind(part), message.Role)
+		}
+	}
+	return entries, nil
+}
+
+// messageText returns the text parts of message joined together
+func messageText(message *ai.Message) (string, error) {
+	var texts []string
+	for _, part := range message.Content {
+		if !part.IsText() {
+			return "", fmt.Errorf("%w: %s part in %s message", ErrUnsupportedContent, partKind(part), message.Role)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// partKind names the kind of content part holds
+func partKind(part *ai.Part) string {
+	switch part.Kind {
+	case ai.PartText:
+		return "text"
+	case ai.PartMedia:
+		return "media"
+	case ai.PartData:
+		return "data"
+	case ai.PartToolRequest:
+		return "tool request"
+	case ai.PartToolResponse:
+		return "tool response"
+	case ai.PartReasoning:
+		return "reasoning"
+	case ai.PartResource:
+		return "resource"
+	default:
+		return "custom"
+	}
+}
+
+// toolOutput renders a tool's output for the model: strings as they are, anything else
+// as JSON
+func toolOutput(output any) string {
+	if text, ok := output.(string); ok {
+		return text
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Sprint(output)
+	}
+	return string(data)
+}
+
+// genkitTool exposes a registered Genkit tool to the model
+type genkitTool struct {
+	ctx  context.Context // The request's context, passed to the tool
+	def  *ai.ToolDefinition
+	tool ai.Tool
+}
+
+// Name returns the tool's name
+func (t *genkitTool) Name() string {
+	return t.def.Name
+}
+
+// Description returns the tool's description
+func (t *genkitTool) Description() string {
+	return t.def.Description
+}
+
+// GetParameters returns the top-level properties of the tool's input schema
+func (t *genkitTool) GetParameters() []fm.ToolArgument {
+	data, err := json.Marshal(t.def.InputSchema)
+	if err != nil {
+		return nil
+	}
+	args, _ := fm.ToolArgumentsFromJSON(data)
+	return args
+}
+
+// Execute runs the Genkit tool with the model's arguments
+func (t *genkitTool) Execute(arguments map[string]any) (fm.ToolResult, error) {
+	output, err := t.tool.RunRaw(t.ctx, arguments)
+	if err != nil {
+		return fm.ToolResult{}, err
+	}
+	return fm.ToolResult{Content: toolOutput(output)}, nil
+}
+
+var (
+	_ api.Plugin         = (*FoundationModels)(nil)
+	_ fm.SchematizedTool = (*genkitTool)(nil)
+)
@@ -0,0 +1,520 @@
+// Package fmgrpc serves go-foundationmodels over gRPC with the foundationmodels.v1
+// service, so processes in other languages can use the on-device model through a typed
+// interface. Register it with a gRPC server:
+//
+//	srv := fmgrpc.NewServer()
+//	defer srv.Close()
+//	g := grpc.NewServer()
+//	srv.Register(g)
+//	g.Serve(lis)
+//
+// Clients create a session, which keeps its conversation between Respond and
+// StreamRespond calls, and may offer it tools they implement themselves with
+// RegisterTool. `found serve --grpc` runs this server.
+package fmgrpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	pb "github.com/blacktop/go-foundationmodels/fmgrpc/foundationmodels/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithSessionOptions sets options for the sessions the server creates
+func WithSessionOptions(opts ...fm.SessionOption) Option {
+	return func(s *Server) {
+		s.sessionOptions = append(s.sessionOptions, opts...)
+	}
+}
+
+// Server implements the foundationmodels.v1 FoundationModelsService
+type Server struct {
+	pb.UnimplementedFoundationModelsServiceServer
+
+	sessionOptions []fm.SessionOption
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// NewServer creates a server with no sessions
+func NewServer(opts ...Option) *Server {
+	s := &Server{sessions: make(map[string]*session)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register registers the service with a gRPC server
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	pb.RegisterFoundationModelsServiceServer(r, s)
+}
+
+// Close cancels generation in all sessions and releases them
+func (s *Server) Close() {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = make(map[string]*session)
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// session is a client's session with the tools it registered
+type session struct {
+	*fm.Session
+
+	inflight sync.WaitGroup // Calls using the session, waited for before releasing it
+
+	mu     sync.Mutex
+	tools  []*remoteTool
+	closed bool
+}
+
+// close cancels generation, waits for the calls using the session and releases it
+func (sess *session) close() {
+	sess.Cancel()
+	sess.inflight.Wait()
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.closed = true
+	sess.Release()
+}
+
+// addTool registers a client's tool with the session
+func (sess *session) addTool(tool *remoteTool) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.closed {
+		return status.Error(codes.NotFound, "session not found")
+	}
+	for _, t := range sess.tools {
+		if t.name == tool.name {
+			return status.Errorf(codes.AlreadyExists, "tool %q is already registered", tool.name)
+		}
+	}
+	if err := sess.RegisterTool(tool); err != nil {
+		return statusError(err)
+	}
+	sess.tools = append(sess.tools, tool)
+	return nil
+}
+
+// removeTool unregisters a client's tool. Sessions can't unregister a single tool, so
+// the others are registered again.
+func (sess *session) removeTool(tool *remoteTool) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.closed {
+		return nil
+	}
+
+	var remaining []*remoteTool
+	for _, t := range sess.tools {
+		if t != tool {
+			remaining = append(remaining, t)
+		}
+	}
+	sess.tools = remaining
+
+	if err := sess.ClearTools(); err != nil {
+		return err
+	}
+	for _, t := range remaining {
+		if err := sess.RegisterTool(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquire returns the session with id, marking it in use until done is called
+func (s *Server) acquire(id string) (sess *session, done func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, nil, status.Errorf(codes.NotFound, "session %q not found", id)
+	}
+	sess.inflight.Add(1)
+	return sess, sess.inflight.Done, nil
+}
+
+// GetInfo describes the model and whether it is available
+func (s *Server) GetInfo(ctx context.Context, req *pb.GetInfoRequest) (*pb.GetInfoResponse, error) {
+	availability := fm.GetModelAvailability()
+	resp := &pb.GetInfoResponse{Available: availability.Available()}
+	if !resp.Available {
+		resp.AvailabilityReason = availability.String()
+		return resp, nil
+	}
+
+	info, err := fm.GetModelDetails()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp.ModelIdentifier = info.ModelIdentifier
+	resp.AdapterIdentifier = info.AdapterIdentifier
+	resp.UseCase = info.UseCase
+	resp.OsVersion = info.OSVersion
+	resp.SupportedLanguages = info.SupportedLanguages
+	resp.ContextSize = int32(info.ContextSize)
+	return resp, nil
+}
+
+// CreateSession starts a conversation
+func (s *Server) CreateSession(ctx context.Context, req *pb.CreateSessionRequest) (*pb.CreateSessionResponse, error) {
+	if err := fm.GetModelAvailability().Err(); err != nil {
+		return nil, statusError(err)
+	}
+
+	var sess *fm.Session
+	if req.GetInstructions() != "" {
+		sess = fm.NewSessionWithInstructions(req.GetInstructions(), s.sessionOptions...)
+	} else {
+		sess = fm.NewSession(s.sessionOptions...)
+	}
+	if sess == nil {
+		return nil, status.Error(codes.Internal, "failed to create session")
+	}
+
+	id := newSessionID()
+	s.mu.Lock()
+	s.sessions[id] = &session{Session: sess}
+	s.mu.Unlock()
+
+	return &pb.CreateSessionResponse{
+		SessionId:      id,
+		MaxContextSize: int32(sess.GetMaxContextSize()),
+	}, nil
+}
+
+// DeleteSession ends a conversation, cancelling its generation, and releases the
+// session
+func (s *Server) DeleteSession(ctx context.Context, req *pb.DeleteSessionRequest) (*pb.DeleteSessionResponse, error) {
+	s.mu.Lock()
+	sess, ok := s.sessions[req.GetSessionId()]
+	delete(s.sessions, req.GetSessionId())
+	s.mu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "session %q not found", req.GetSessionId())
+	}
+
+	sess.close()
+	return &pb.DeleteSessionResponse{}, nil
+}
+
+// Respond generates the complete response to a prompt
+func (s *Server) Respond(ctx context.Context, req *pb.RespondRequest) (*pb.RespondResponse, error) {
+	sess, done, err := s.acquire(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	response, err := sess.RespondDetailed(ctx, req.GetPrompt(), generationOptions(req.GetOptions()))
+	if err != nil && (response == nil || response.FinishReason != fm.FinishGuardrail) {
+		return nil, statusError(err)
+	}
+	return &pb.RespondResponse{
+		Text:         response.Text,
+		FinishReason: finishReason(response.FinishReason),
+		Usage: &pb.Usage{
+			PromptTokens:     int32(response.PromptTokens),
+			CompletionTokens: int32(response.CompletionTokens),
+			ContextTokens:    int32(sess.GetContextSize()),
+		},
+	}, nil
+}
+
+// StreamRespond generates the response to a prompt, sending text as it is generated.
+// Errors after generation started are reported on the last message.
+func (s *Server) StreamRespond(req *pb.RespondRequest, stream grpc.ServerStreamingServer[pb.StreamRespondResponse]) error {
+	sess, done, err := s.acquire(req.GetSessionId())
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	chunks, err := sess.RespondStream(ctx, req.GetPrompt(), generationOptions(req.GetOptions()))
+	if err != nil {
+		return statusError(err)
+	}
+
+	for chunk := range chunks {
+		msg := &pb.StreamRespondResponse{Text: chunk.Text}
+		if chunk.Done {
+			msg.Done = true
+			msg.FinishReason = finishReason(chunk.FinishReason)
+			if chunk.Err != nil {
+				msg.Error = chunk.Err.Error()
+			}
+		}
+		if err := stream.Send(msg); err != nil {
+			cancel()
+			for range chunks {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterTool offers a tool implemented by the client to a session's model for as long
+// as the stream is open, relaying the model's calls to the client and its results back
+func (s *Server) RegisterTool(stream grpc.BidiStreamingServer[pb.RegisterToolRequest, pb.RegisterToolResponse]) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	def := req.GetDefinition()
+	if def == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be a tool definition")
+	}
+	if def.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "the tool has no name")
+	}
+
+	tool := &remoteTool{
+		ctx:         stream.Context(),
+		stream:      stream,
+		name:        def.GetName(),
+		description: def.GetDescription(),
+		pending:     make(map[string]chan *pb.ToolResult),
+	}
+	if schema := def.GetParametersJsonSchema(); schema != "" {
+		if tool.parameters, err = fm.ToolArgumentsFromJSON([]byte(schema)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid parameters schema: %v", err)
+		}
+	}
+
+	sess, done, err := s.acquire(def.GetSessionId())
+	if err != nil {
+		return err
+	}
+	err = sess.addTool(tool)
+	done()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sess.removeTool(tool); err != nil {
+			slog.Warn("Failed to unregister tool", "tool_name", tool.name, "error", err)
+		}
+	}()
+
+	if err := tool.send(&pb.RegisterToolResponse{
+		Message: &pb.RegisterToolResponse_Registered{Registered: &pb.ToolRegistered{}},
+	}); err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result := req.GetResult()
+		if result == nil {
+			return status.Error(codes.InvalidArgument, "expected a tool result")
+		}
+		if !tool.deliver(result) {
+			return status.Errorf(codes.InvalidArgument, "unknown tool call %q", result.GetCallId())
+		}
+	}
+}
+
+// remoteTool is a tool implemented by a gRPC client. Its calls are sent over the
+// client's RegisterTool stream and wait for the client's result.
+type remoteTool struct {
+	ctx         context.Context // Done when the client's stream ends
+	name        string
+	description string
+	parameters  []fm.ToolArgument
+
+	sendMu sync.Mutex
+	stream grpc.BidiStreamingServer[pb.RegisterToolRequest, pb.RegisterToolResponse]
+
+	mu      sync.Mutex
+	pending map[string]chan *pb.ToolResult // Calls waiting for a result, by call ID
+}
+
+// Name returns the tool's name
+func (t *remoteTool) Name() string {
+	return t.name
+}
+
+// Description returns the tool's description
+func (t *remoteTool) Description() string {
+	return t.description
+}
+
+// GetParameters returns the properties of the tool's parameters schema
+func (t *remoteTool) GetParameters() []fm.ToolArgument {
+	return t.parameters
+}
+
+// Execute sends the call to the client and waits for its result
+func (t *remoteTool) Execute(arguments map[string]any) (fm.ToolResult, error) {
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return fm.ToolResult{}, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	id := newCallID()
+	result := make(chan *pb.ToolResult, 1)
+	t.mu.Lock()
+	t.pending[id] = result
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.send(&pb.RegisterToolResponse{
+		Message: &pb.RegisterToolResponse_Call{Call: &pb.ToolCall{CallId: id, ArgumentsJson: string(args)}},
+	}); err != nil {
+		return fm.ToolResult{}, fmt.Errorf("failed to send tool call: %w", err)
+	}
+
+	select {
+	case r := <-result:
+		return fm.ToolResult{Content: r.GetContent(), Error: r.GetError()}, nil
+	case <-t.ctx.Done():
+		return fm.ToolResult{}, errors.New("tool client disconnected")
+	}
+}
+
+// deliver hands a result to the call waiting for it, reporting whether there was one
+func (t *remoteTool) deliver(result *pb.ToolResult) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch, ok := t.pending[result.GetCallId()]
+	if ok {
+		delete(t.pending, result.GetCallId())
+		ch <- result
+	}
+	return ok
+}
+
+// send writes a message to the client's stream
+func (t *remoteTool) send(msg *pb.RegisterToolResponse) error {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+	return t.stream.Send(msg)
+}
+
+// generationOptions converts request options, returning nil for none
+func generationOptions(opts *pb.GenerationOptions) *fm.GenerationOptions {
+	if opts == nil {
+		return nil
+	}
+	options := &fm.GenerationOptions{StopSequences: opts.GetStopSequences()}
+	if opts.GetGreedy() {
+		options.SamplingMode = fm.SamplingGreedy
+	}
+	if opts.MaxTokens != nil {
+		maxTokens := int(opts.GetMaxTokens())
+		options.MaxTokens = &maxTokens
+	}
+	options.Temperature = opts.Temperature
+	options.TopP = opts.TopP
+	if opts.TopK != nil {
+		topK := int(opts.GetTopK())
+		options.TopK = &topK
+	}
+	if opts.Seed != nil {
+		seed := int(opts.GetSeed())
+		options.Seed = &seed
+	}
+	return options
+}
+
+// finishReason converts a finish reason to its protobuf enum
+func finishReason(reason fm.FinishReason) pb.FinishReason {
+	switch reason {
+	case fm.FinishStop:
+		return pb.FinishReason_FINISH_REASON_STOP
+	case fm.FinishLength:
+		return pb.FinishReason_FINISH_REASON_LENGTH
+	case fm.FinishStopSequence:
+		return pb.FinishReason_FINISH_REASON_STOP_SEQUENCE
+	case fm.FinishGuardrail:
+		return pb.FinishReason_FINISH_REASON_GUARDRAIL
+	case fm.FinishCancelled:
+		return pb.FinishReason_FINISH_REASON_CANCELLED
+	case fm.FinishError:
+		return pb.FinishReason_FINISH_REASON_ERROR
+	default:
+		return pb.FinishReason_FINISH_REASON_UNSPECIFIED
+	}
+}
+
+// statusError converts an error to a gRPC status error with a matching code
+func statusError(err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, fm.ErrModelUnavailable), errors.Is(err, fm.ErrModelBecameUnavailable):
+		code = codes.Unavailable
+	case errors.Is(err, fm.ErrRateLimited):
+		code = codes.ResourceExhausted
+	case errors.Is(err, fm.ErrContextLimit), errors.Is(err, fm.ErrContextExceeded):
+		code = codes.InvalidArgument
+	case errors.Is(err, fm.ErrGenerationCancelled), errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// newSessionID returns a random session ID
+func newSessionID() string {
+	return "sess_" + randomHex()
+}
+
+// newCallID returns a random tool call ID
+func newCallID() string {
+	return "call_" + randomHex()
+}
+
+// randomHex returns 24 random hex digits
+func randomHex() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var (
+	_ pb.FoundationModelsServiceServer = (*Server)(nil)
+	_ fm.SchematizedTool               = (*remoteTool)(nil)
+)
@@ -0,0 +1,1363 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: foundationmodels/v1/foundationmodels.proto
+
+// Package foundationmodels.v1 serves Apple's on-device Foundation Models to other
+// processes and languages, for example with `found serve --grpc`.
+
+package foundationmodelsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FinishReason explains why generation ended
+type FinishReason int32
+
+const (
+	FinishReason_FINISH_REASON_UNSPECIFIED FinishReason = 0
+	// The model finished its answer
+	FinishReason_FINISH_REASON_STOP FinishReason = 1
+	// Generation reached max_tokens, so the answer is probably truncated
+	FinishReason_FINISH_REASON_LENGTH FinishReason = 2
+	// Generation reached one of the stop sequences
+	FinishReason_FINISH_REASON_STOP_SEQUENCE FinishReason = 3
+	// The model refused for safety reasons
+	FinishReason_FINISH_REASON_GUARDRAIL FinishReason = 4
+	// Generation was cancelled or timed out
+	FinishReason_FINISH_REASON_CANCELLED FinishReason = 5
+	// Generation failed for another reason
+	FinishReason_FINISH_REASON_ERROR FinishReason = 6
+)
+
+// Enum value maps for FinishReason.
+var (
+	FinishReason_name = map[int32]string{
+		0: "FINISH_REASON_UNSPECIFIED",
+		1: "FINISH_REASON_STOP",
+		2: "FINISH_REASON_LENGTH",
+		3: "FINISH_REASON_STOP_SEQUENCE",
+		4: "FINISH_REASON_GUARDRAIL",
+		5: "FINISH_REASON_CANCELLED",
+		6: "FINISH_REASON_ERROR",
+	}
+	FinishReason_value = map[string]int32{
+		"FINISH_REASON_UNSPECIFIED":   0,
+		"FINISH_REASON_STOP":          1,
+		"FINISH_REASON_LENGTH":        2,
+		"FINISH_REASON_STOP_SEQUENCE": 3,
+		"FINISH_REASON_GUARDRAIL":     4,
+		"FINISH_REASON_CANCELLED":     5,
+		"FINISH_REASON_ERROR":         6,
+	}
+)
+
+func (x FinishReason) Enum() *FinishReason {
+	p := new(FinishReason)
+	*p = x
+	return p
+}
+
+func (x FinishReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FinishReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_foundationmodels_v1_foundationmodels_proto_enumTypes[0].Descriptor()
+}
+
+func (FinishReason) Type() protoreflect.EnumType {
+	return &file_foundationmodels_v1_foundationmodels_proto_enumTypes[0]
+}
+
+func (x FinishReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FinishReason.Descriptor instead.
+func (FinishReason) EnumDescriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{0}
+}
+
+type GetInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInfoRequest) Reset() {
+	*x = GetInfoRequest{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInfoRequest) ProtoMessage() {}
+
+func (x *GetInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetInfoRequest) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{0}
+}
+
+type GetInfoResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether the model can be used
+	Available bool `protobuf:"varint,1,opt,name=available,proto3" json:"available,omitempty"`
+	// Why the model is unavailable, if it is
+	AvailabilityReason string `protobuf:"bytes,2,opt,name=availability_reason,json=availabilityReason,proto3" json:"availability_reason,omitempty"`
+	// Identifies the base model
+	ModelIdentifier string `protobuf:"bytes,3,opt,name=model_identifier,json=modelIdentifier,proto3" json:"model_identifier,omitempty"`
+	// Identifies the adapter applied to the model, if any
+	AdapterIdentifier string `protobuf:"bytes,4,opt,name=adapter_identifier,json=adapterIdentifier,proto3" json:"adapter_identifier,omitempty"`
+	// The model variant, such as "general"
+	UseCase string `protobuf:"bytes,5,opt,name=use_case,json=useCase,proto3" json:"use_case,omitempty"`
+	// The operating system version the model ships with
+	OsVersion string `protobuf:"bytes,6,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	// BCP 47 tags of the languages the model supports
+	SupportedLanguages []string `protobuf:"bytes,7,rep,name=supported_languages,json=supportedLanguages,proto3" json:"supported_languages,omitempty"`
+	// The context window in tokens
+	ContextSize   int32 `protobuf:"varint,8,opt,name=context_size,json=contextSize,proto3" json:"context_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInfoResponse) Reset() {
+	*x = GetInfoResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInfoResponse) ProtoMessage() {}
+
+func (x *GetInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetInfoResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetInfoResponse) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *GetInfoResponse) GetAvailabilityReason() string {
+	if x != nil {
+		return x.AvailabilityReason
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetModelIdentifier() string {
+	if x != nil {
+		return x.ModelIdentifier
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetAdapterIdentifier() string {
+	if x != nil {
+		return x.AdapterIdentifier
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetUseCase() string {
+	if x != nil {
+		return x.UseCase
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetSupportedLanguages() []string {
+	if x != nil {
+		return x.SupportedLanguages
+	}
+	return nil
+}
+
+func (x *GetInfoResponse) GetContextSize() int32 {
+	if x != nil {
+		return x.ContextSize
+	}
+	return 0
+}
+
+type CreateSessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Instructions that guide the model for the whole conversation
+	Instructions  string `protobuf:"bytes,1,opt,name=instructions,proto3" json:"instructions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateSessionRequest) GetInstructions() string {
+	if x != nil {
+		return x.Instructions
+	}
+	return ""
+}
+
+type CreateSessionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the session in later requests
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// The context window in tokens
+	MaxContextSize int32 `protobuf:"varint,2,opt,name=max_context_size,json=maxContextSize,proto3" json:"max_context_size,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CreateSessionResponse) Reset() {
+	*x = CreateSessionResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionResponse) ProtoMessage() {}
+
+func (x *CreateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionResponse.ProtoReflect.Descriptor instead.
+func (*CreateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *CreateSessionResponse) GetMaxContextSize() int32 {
+	if x != nil {
+		return x.MaxContextSize
+	}
+	return 0
+}
+
+type DeleteSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionRequest) Reset() {
+	*x = DeleteSessionRequest{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionRequest) ProtoMessage() {}
+
+func (x *DeleteSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSessionRequest) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type DeleteSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionResponse) Reset() {
+	*x = DeleteSessionResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionResponse) ProtoMessage() {}
+
+func (x *DeleteSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSessionResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{5}
+}
+
+// GenerationOptions control how the response is generated. Unset fields use the
+// model's defaults.
+type GenerationOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Always pick the most likely token; can't be combined with top_p, top_k or seed
+	Greedy bool `protobuf:"varint,1,opt,name=greedy,proto3" json:"greedy,omitempty"`
+	// The maximum number of tokens to generate
+	MaxTokens *int32 `protobuf:"varint,2,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	// Randomness, from 0.0 (deterministic) to 1.0 (very random)
+	Temperature *float32 `protobuf:"fixed32,3,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	// Nucleus sampling probability threshold (0.0-1.0); exclusive with top_k
+	TopP *float32 `protobuf:"fixed32,4,opt,name=top_p,json=topP,proto3,oneof" json:"top_p,omitempty"`
+	// Top-K sampling limit; exclusive with top_p
+	TopK *int32 `protobuf:"varint,5,opt,name=top_k,json=topK,proto3,oneof" json:"top_k,omitempty"`
+	// Sequences that stop generation; the response is cut before the first one
+	StopSequences []string `protobuf:"bytes,6,rep,name=stop_sequences,json=stopSequences,proto3" json:"stop_sequences,omitempty"`
+	// Makes random sampling reproducible
+	Seed          *int64 `protobuf:"varint,7,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerationOptions) Reset() {
+	*x = GenerationOptions{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationOptions) ProtoMessage() {}
+
+func (x *GenerationOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationOptions.ProtoReflect.Descriptor instead.
+func (*GenerationOptions) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GenerationOptions) GetGreedy() bool {
+	if x != nil {
+		return x.Greedy
+	}
+	return false
+}
+
+func (x *GenerationOptions) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetTemperature() float32 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetTopP() float32 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetTopK() int32 {
+	if x != nil && x.TopK != nil {
+		return *x.TopK
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetStopSequences() []string {
+	if x != nil {
+		return x.StopSequences
+	}
+	return nil
+}
+
+func (x *GenerationOptions) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+type RespondRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Prompt        string                 `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Options       *GenerationOptions     `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RespondRequest) Reset() {
+	*x = RespondRequest{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RespondRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RespondRequest) ProtoMessage() {}
+
+func (x *RespondRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RespondRequest.ProtoReflect.Descriptor instead.
+func (*RespondRequest) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RespondRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RespondRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *RespondRequest) GetOptions() *GenerationOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// Usage reports estimated token counts
+type Usage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The size of the prompt, not counting the conversation before it
+	PromptTokens int32 `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	// The size of the generated text
+	CompletionTokens int32 `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	// The tokens used in the session's context window after the response
+	ContextTokens int32 `protobuf:"varint,3,opt,name=context_tokens,json=contextTokens,proto3" json:"context_tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Usage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetContextTokens() int32 {
+	if x != nil {
+		return x.ContextTokens
+	}
+	return 0
+}
+
+type RespondResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	FinishReason  FinishReason           `protobuf:"varint,2,opt,name=finish_reason,json=finishReason,proto3,enum=foundationmodels.v1.FinishReason" json:"finish_reason,omitempty"`
+	Usage         *Usage                 `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RespondResponse) Reset() {
+	*x = RespondResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RespondResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RespondResponse) ProtoMessage() {}
+
+func (x *RespondResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RespondResponse.ProtoReflect.Descriptor instead.
+func (*RespondResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RespondResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *RespondResponse) GetFinishReason() FinishReason {
+	if x != nil {
+		return x.FinishReason
+	}
+	return FinishReason_FINISH_REASON_UNSPECIFIED
+}
+
+func (x *RespondResponse) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type StreamRespondResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Text generated since the previous message
+	Text string `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// Set on the last message
+	Done bool `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`
+	// Why generation ended, on the last message
+	FinishReason FinishReason `protobuf:"varint,3,opt,name=finish_reason,json=finishReason,proto3,enum=foundationmodels.v1.FinishReason" json:"finish_reason,omitempty"`
+	// Why generation failed, on the last message
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRespondResponse) Reset() {
+	*x = StreamRespondResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRespondResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRespondResponse) ProtoMessage() {}
+
+func (x *StreamRespondResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRespondResponse.ProtoReflect.Descriptor instead.
+func (*StreamRespondResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StreamRespondResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *StreamRespondResponse) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *StreamRespondResponse) GetFinishReason() FinishReason {
+	if x != nil {
+		return x.FinishReason
+	}
+	return FinishReason_FINISH_REASON_UNSPECIFIED
+}
+
+func (x *StreamRespondResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ToolDefinition struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session the tool is offered to
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// The name the model calls the tool by
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// What the tool does, for the model
+	Description string `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	// The JSON Schema of the tool's arguments, an object schema with properties
+	ParametersJsonSchema string `protobuf:"bytes,4,opt,name=parameters_json_schema,json=parametersJsonSchema,proto3" json:"parameters_json_schema,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *ToolDefinition) Reset() {
+	*x = ToolDefinition{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolDefinition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolDefinition) ProtoMessage() {}
+
+func (x *ToolDefinition) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolDefinition.ProtoReflect.Descriptor instead.
+func (*ToolDefinition) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ToolDefinition) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetParametersJsonSchema() string {
+	if x != nil {
+		return x.ParametersJsonSchema
+	}
+	return ""
+}
+
+type ToolCall struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the call in its ToolResult
+	CallId string `protobuf:"bytes,1,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	// The tool's arguments as a JSON object
+	ArgumentsJson string `protobuf:"bytes,2,opt,name=arguments_json,json=argumentsJson,proto3" json:"arguments_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCall) Reset() {
+	*x = ToolCall{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCall) ProtoMessage() {}
+
+func (x *ToolCall) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
+func (*ToolCall) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ToolCall) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+func (x *ToolCall) GetArgumentsJson() string {
+	if x != nil {
+		return x.ArgumentsJson
+	}
+	return ""
+}
+
+type ToolResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The call being answered
+	CallId string `protobuf:"bytes,1,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	// The tool's output, returned to the model
+	Content string `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	// Why the tool failed, if it did
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolResult) Reset() {
+	*x = ToolResult{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolResult) ProtoMessage() {}
+
+func (x *ToolResult) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolResult.ProtoReflect.Descriptor instead.
+func (*ToolResult) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ToolResult) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+func (x *ToolResult) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ToolResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RegisterToolRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*RegisterToolRequest_Definition
+	//	*RegisterToolRequest_Result
+	Message       isRegisterToolRequest_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterToolRequest) Reset() {
+	*x = RegisterToolRequest{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterToolRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterToolRequest) ProtoMessage() {}
+
+func (x *RegisterToolRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterToolRequest.ProtoReflect.Descriptor instead.
+func (*RegisterToolRequest) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RegisterToolRequest) GetMessage() isRegisterToolRequest_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *RegisterToolRequest) GetDefinition() *ToolDefinition {
+	if x != nil {
+		if x, ok := x.Message.(*RegisterToolRequest_Definition); ok {
+			return x.Definition
+		}
+	}
+	return nil
+}
+
+func (x *RegisterToolRequest) GetResult() *ToolResult {
+	if x != nil {
+		if x, ok := x.Message.(*RegisterToolRequest_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isRegisterToolRequest_Message interface {
+	isRegisterToolRequest_Message()
+}
+
+type RegisterToolRequest_Definition struct {
+	// Sent first to register the tool
+	Definition *ToolDefinition `protobuf:"bytes,1,opt,name=definition,proto3,oneof"`
+}
+
+type RegisterToolRequest_Result struct {
+	// Sent to answer each ToolCall
+	Result *ToolResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*RegisterToolRequest_Definition) isRegisterToolRequest_Message() {}
+
+func (*RegisterToolRequest_Result) isRegisterToolRequest_Message() {}
+
+type RegisterToolResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*RegisterToolResponse_Registered
+	//	*RegisterToolResponse_Call
+	Message       isRegisterToolResponse_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterToolResponse) Reset() {
+	*x = RegisterToolResponse{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterToolResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterToolResponse) ProtoMessage() {}
+
+func (x *RegisterToolResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterToolResponse.ProtoReflect.Descriptor instead.
+func (*RegisterToolResponse) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RegisterToolResponse) GetMessage() isRegisterToolResponse_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *RegisterToolResponse) GetRegistered() *ToolRegistered {
+	if x != nil {
+		if x, ok := x.Message.(*RegisterToolResponse_Registered); ok {
+			return x.Registered
+		}
+	}
+	return nil
+}
+
+func (x *RegisterToolResponse) GetCall() *ToolCall {
+	if x != nil {
+		if x, ok := x.Message.(*RegisterToolResponse_Call); ok {
+			return x.Call
+		}
+	}
+	return nil
+}
+
+type isRegisterToolResponse_Message interface {
+	isRegisterToolResponse_Message()
+}
+
+type RegisterToolResponse_Registered struct {
+	// Sent once the tool is registered
+	Registered *ToolRegistered `protobuf:"bytes,1,opt,name=registered,proto3,oneof"`
+}
+
+type RegisterToolResponse_Call struct {
+	// Sent whenever the model calls the tool
+	Call *ToolCall `protobuf:"bytes,2,opt,name=call,proto3,oneof"`
+}
+
+func (*RegisterToolResponse_Registered) isRegisterToolResponse_Message() {}
+
+func (*RegisterToolResponse_Call) isRegisterToolResponse_Message() {}
+
+type ToolRegistered struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolRegistered) Reset() {
+	*x = ToolRegistered{}
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolRegistered) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolRegistered) ProtoMessage() {}
+
+func (x *ToolRegistered) ProtoReflect() protoreflect.Message {
+	mi := &file_foundationmodels_v1_foundationmodels_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolRegistered.ProtoReflect.Descriptor instead.
+func (*ToolRegistered) Descriptor() ([]byte, []int) {
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP(), []int{16}
+}
+
+var File_foundationmodels_v1_foundationmodels_proto protoreflect.FileDescriptor
+
+const file_foundationmodels_v1_foundationmodels_proto_rawDesc = "" +
+	"\n" +
+	"*foundationmodels/v1/foundationmodels.proto\x12\x13foundationmodels.v1\"\x10\n" +
+	"\x0eGetInfoRequest\"\xc8\x02\n" +
+	"\x0fGetInfoResponse\x12\x1c\n" +
+	"\tavailable\x18\x01 \x01(\bR\tavailable\x12/\n" +
+	"\x13availability_reason\x18\x02 \x01(\tR\x12availabilityReason\x12)\n" +
+	"\x10model_identifier\x18\x03 \x01(\tR\x0fmodelIdentifier\x12-\n" +
+	"\x12adapter_identifier\x18\x04 \x01(\tR\x11adapterIdentifier\x12\x19\n" +
+	"\buse_case\x18\x05 \x01(\tR\auseCase\x12\x1d\n" +
+	"\n" +
+	"os_version\x18\x06 \x01(\tR\tosVersion\x12/\n" +
+	"\x13supported_languages\x18\a \x03(\tR\x12supportedLanguages\x12!\n" +
+	"\fcontext_size\x18\b \x01(\x05R\vcontextSize\":\n" +
+	"\x14CreateSessionRequest\x12\"\n" +
+	"\finstructions\x18\x01 \x01(\tR\finstructions\"`\n" +
+	"\x15CreateSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12(\n" +
+	"\x10max_context_size\x18\x02 \x01(\x05R\x0emaxContextSize\"5\n" +
+	"\x14DeleteSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x17\n" +
+	"\x15DeleteSessionResponse\"\xa6\x02\n" +
+	"\x11GenerationOptions\x12\x16\n" +
+	"\x06greedy\x18\x01 \x01(\bR\x06greedy\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x02 \x01(\x05H\x00R\tmaxTokens\x88\x01\x01\x12%\n" +
+	"\vtemperature\x18\x03 \x01(\x02H\x01R\vtemperature\x88\x01\x01\x12\x18\n" +
+	"\x05top_p\x18\x04 \x01(\x02H\x02R\x04topP\x88\x01\x01\x12\x18\n" +
+	"\x05top_k\x18\x05 \x01(\x05H\x03R\x04topK\x88\x01\x01\x12%\n" +
+	"\x0estop_sequences\x18\x06 \x03(\tR\rstopSequences\x12\x17\n" +
+	"\x04seed\x18\a \x01(\x03H\x04R\x04seed\x88\x01\x01B\r\n" +
+	"\v_max_tokensB\x0e\n" +
+	"\f_temperatureB\b\n" +
+	"\x06_top_pB\b\n" +
+	"\x06_top_kB\a\n" +
+	"\x05_seed\"\x89\x01\n" +
+	"\x0eRespondRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12@\n" +
+	"\aoptions\x18\x03 \x01(\v2&.foundationmodels.v1.GenerationOptionsR\aoptions\"\x80\x01\n" +
+	"\x05Usage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12%\n" +
+	"\x0econtext_tokens\x18\x03 \x01(\x05R\rcontextTokens\"\x9f\x01\n" +
+	"\x0fRespondResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12F\n" +
+	"\rfinish_reason\x18\x02 \x01(\x0e2!.foundationmodels.v1.FinishReasonR\ffinishReason\x120\n" +
+	"\x05usage\x18\x03 \x01(\v2\x1a.foundationmodels.v1.UsageR\x05usage\"\x9d\x01\n" +
+	"\x15StreamRespondResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x12\n" +
+	"\x04done\x18\x02 \x01(\bR\x04done\x12F\n" +
+	"\rfinish_reason\x18\x03 \x01(\x0e2!.foundationmodels.v1.FinishReasonR\ffinishReason\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x9b\x01\n" +
+	"\x0eToolDefinition\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x124\n" +
+	"\x16parameters_json_schema\x18\x04 \x01(\tR\x14parametersJsonSchema\"J\n" +
+	"\bToolCall\x12\x17\n" +
+	"\acall_id\x18\x01 \x01(\tR\x06callId\x12%\n" +
+	"\x0earguments_json\x18\x02 \x01(\tR\rargumentsJson\"U\n" +
+	"\n" +
+	"ToolResult\x12\x17\n" +
+	"\acall_id\x18\x01 \x01(\tR\x06callId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xa2\x01\n" +
+	"\x13RegisterToolRequest\x12E\n" +
+	"\n" +
+	"definition\x18\x01 \x01(\v2#.foundationmodels.v1.ToolDefinitionH\x00R\n" +
+	"definition\x129\n" +
+	"\x06result\x18\x02 \x01(\v2\x1f.foundationmodels.v1.ToolResultH\x00R\x06resultB\t\n" +
+	"\amessage\"\x9d\x01\n" +
+	"\x14RegisterToolResponse\x12E\n" +
+	"\n" +
+	"registered\x18\x01 \x01(\v2#.foundationmodels.v1.ToolRegisteredH\x00R\n" +
+	"registered\x123\n" +
+	"\x04call\x18\x02 \x01(\v2\x1d.foundationmodels.v1.ToolCallH\x00R\x04callB\t\n" +
+	"\amessage\"\x10\n" +
+	"\x0eToolRegistered*\xd3\x01\n" +
+	"\fFinishReason\x12\x1d\n" +
+	"\x19FINISH_REASON_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12FINISH_REASON_STOP\x10\x01\x12\x18\n" +
+	"\x14FINISH_REASON_LENGTH\x10\x02\x12\x1f\n" +
+	"\x1bFINISH_REASON_STOP_SEQUENCE\x10\x03\x12\x1b\n" +
+	"\x17FINISH_REASON_GUARDRAIL\x10\x04\x12\x1b\n" +
+	"\x17FINISH_REASON_CANCELLED\x10\x05\x12\x17\n" +
+	"\x13FINISH_REASON_ERROR\x10\x062\xe2\x04\n" +
+	"\x17FoundationModelsService\x12T\n" +
+	"\aGetInfo\x12#.foundationmodels.v1.GetInfoRequest\x1a$.foundationmodels.v1.GetInfoResponse\x12f\n" +
+	"\rCreateSession\x12).foundationmodels.v1.CreateSessionRequest\x1a*.foundationmodels.v1.CreateSessionResponse\x12f\n" +
+	"\rDeleteSession\x12).foundationmodels.v1.DeleteSessionRequest\x1a*.foundationmodels.v1.DeleteSessionResponse\x12T\n" +
+	"\aRespond\x12#.foundationmodels.v1.RespondRequest\x1a$.foundationmodels.v1.RespondResponse\x12b\n" +
+	"\rStreamRespond\x12#.foundationmodels.v1.RespondRequest\x1a*.foundationmodels.v1.StreamRespondResponse0\x01\x12g\n" +
+	"\fRegisterTool\x12(.foundationmodels.v1.RegisterToolRequest\x1a).foundationmodels.v1.RegisterToolResponse(\x010\x01BWZUgithub.com/blacktop/go-foundationmodels/fmgrpc/foundationmodels/v1;foundationmodelsv1b\x06proto3"
+
+var (
+	file_foundationmodels_v1_foundationmodels_proto_rawDescOnce sync.Once
+	file_foundationmodels_v1_foundationmodels_proto_rawDescData []byte
+)
+
+func file_foundationmodels_v1_foundationmodels_proto_rawDescGZIP() []byte {
+	file_foundationmodels_v1_foundationmodels_proto_rawDescOnce.Do(func() {
+		file_foundationmodels_v1_foundationmodels_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_foundationmodels_v1_foundationmodels_proto_rawDesc), len(file_foundationmodels_v1_foundationmodels_proto_rawDesc)))
+	})
+	return file_foundationmodels_v1_foundationmodels_proto_rawDescData
+}
+
+var file_foundationmodels_v1_foundationmodels_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_foundationmodels_v1_foundationmodels_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_foundationmodels_v1_foundationmodels_proto_goTypes = []any{
+	(FinishReason)(0),             // 0: foundationmodels.v1.FinishReason
+	(*GetInfoRequest)(nil),        // 1: foundationmodels.v1.GetInfoRequest
+	(*GetInfoResponse)(nil),       // 2: foundationmodels.v1.GetInfoResponse
+	(*CreateSessionRequest)(nil),  // 3: foundationmodels.v1.CreateSessionRequest
+	(*CreateSessionResponse)(nil), // 4: foundationmodels.v1.CreateSessionResponse
+	(*DeleteSessionRequest)(nil),  // 5: foundationmodels.v1.DeleteSessionRequest
+	(*DeleteSessionResponse)(nil), // 6: foundationmodels.v1.DeleteSessionResponse
+	(*GenerationOptions)(nil),     // 7: foundationmodels.v1.GenerationOptions
+	(*RespondRequest)(nil),        // 8: foundationmodels.v1.RespondRequest
+	(*Usage)(nil),                 // 9: foundationmodels.v1.Usage
+	(*RespondResponse)(nil),       // 10: foundationmodels.v1.RespondResponse
+	(*StreamRespondResponse)(nil), // 11: foundationmodels.v1.StreamRespondResponse
+	(*ToolDefinition)(nil),        // 12: foundationmodels.v1.ToolDefinition
+	(*ToolCall)(nil),              // 13: foundationmodels.v1.ToolCall
+	(*ToolResult)(nil),            // 14: foundationmodels.v1.ToolResult
+	(*RegisterToolRequest)(nil),   // 15: foundationmodels.v1.RegisterToolRequest
+	(*RegisterToolResponse)(nil),  // 16: foundationmodels.v1.RegisterToolResponse
+	(*ToolRegistered)(nil),        // 17: foundationmodels.v1.ToolRegistered
+}
+var file_foundationmodels_v1_foundationmodels_proto_depIdxs = []int32{
+	7,  // 0: foundationmodels.v1.RespondRequest.options:type_name -> foundationmodels.v1.GenerationOptions
+	0,  // 1: foundationmodels.v1.RespondResponse.finish_reason:type_name -> foundationmodels.v1.FinishReason
+	9,  // 2: foundationmodels.v1.RespondResponse.usage:type_name -> foundationmodels.v1.Usage
+	0,  // 3: foundationmodels.v1.StreamRespondResponse.finish_reason:type_name -> foundationmodels.v1.FinishReason
+	12, // 4: foundationmodels.v1.RegisterToolRequest.definition:type_name -> foundationmodels.v1.ToolDefinition
+	14, // 5: foundationmodels.v1.RegisterToolRequest.result:type_name -> foundationmodels.v1.ToolResult
+	17, // 6: foundationmodels.v1.RegisterToolResponse.registered:type_name -> foundationmodels.v1.ToolRegistered
+	13, // 7: foundationmodels.v1.RegisterToolResponse.call:type_name -> foundationmodels.v1.ToolCall
+	1,  // 8: foundationmodels.v1.FoundationModelsService.GetInfo:input_type -> foundationmodels.v1.GetInfoRequest
+	3,  // 9: foundationmodels.v1.FoundationModelsService.CreateSession:input_type -> foundationmodels.v1.CreateSessionRequest
+	5,  // 10: foundationmodels.v1.FoundationModelsService.DeleteSession:input_type -> foundationmodels.v1.DeleteSessionRequest
+	8,  // 11: foundationmodels.v1.FoundationModelsService.Respond:input_type -> foundationmodels.v1.RespondRequest
+	8,  // 12: foundationmodels.v1.FoundationModelsService.StreamRespond:input_type -> foundationmodels.v1.RespondRequest
+	15, // 13: foundationmodels.v1.FoundationModelsService.RegisterTool:input_type -> foundationmodels.v1.RegisterToolRequest
+	2,  // 14: foundationmodels.v1.FoundationModelsService.GetInfo:output_type -> foundationmodels.v1.GetInfoResponse
+	4,  // 15: foundationmodels.v1.FoundationModelsService.CreateSession:output_type -> foundationmodels.v1.CreateSessionResponse
+	6,  // 16: foundationmodels.v1.FoundationModelsService.DeleteSession:output_type -> foundationmodels.v1.DeleteSessionResponse
+	10, // 17: foundationmodels.v1.FoundationModelsService.Respond:output_type -> foundationmodels.v1.RespondResponse
+	11, // 18: foundationmodels.v1.FoundationModelsService.StreamRespond:output_type -> foundationmodels.v1.StreamRespondResponse
+	16, // 19: foundationmodels.v1.FoundationModelsService.RegisterTool:output_type -> foundationmodels.v1.RegisterToolResponse
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_foundationmodels_v1_foundationmodels_proto_init() }
+func file_foundationmodels_v1_foundationmodels_proto_init() {
+	if File_foundationmodels_v1_foundationmodels_proto != nil {
+		return
+	}
+	file_foundationmodels_v1_foundationmodels_proto_msgTypes[6].OneofWrappers = []any{}
+	file_foundationmodels_v1_foundationmodels_proto_msgTypes[14].OneofWrappers = []any{
+		(*RegisterToolRequest_Definition)(nil),
+		(*RegisterToolRequest_Result)(nil),
+	}
+	file_foundationmodels_v1_foundationmodels_proto_msgTypes[15].OneofWrappers = []any{
+		(*RegisterToolResponse_Registered)(nil),
+		(*RegisterToolResponse_Call)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_foundationmodels_v1_foundationmodels_proto_rawDesc), len(file_foundationmodels_v1_foundationmodels_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_foundationmodels_v1_foundationmodels_proto_goTypes,
+		DependencyIndexes: file_foundationmodels_v1_foundationmodels_proto_depIdxs,
+		EnumInfos:         file_foundationmodels_v1_foundationmodels_proto_enumTypes,
+		MessageInfos:      file_foundationmodels_v1_foundationmodels_proto_msgTypes,
+	}.Build()
+	File_foundationmodels_v1_foundationmodels_proto = out.File
+	file_foundationmodels_v1_foundationmodels_proto_goTypes = nil
+	file_foundationmodels_v1_foundationmodels_proto_depIdxs = nil
+}
@@ -0,0 +1,339 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: foundationmodels/v1/foundationmodels.proto
+
+// Package foundationmodels.v1 serves Apple's on-device Foundation Models to other
+// processes and languages, for example with `found serve --grpc`.
+
+package foundationmodelsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FoundationModelsService_GetInfo_FullMethodName       = "/foundationmodels.v1.FoundationModelsService/GetInfo"
+	FoundationModelsService_CreateSession_FullMethodName = "/foundationmodels.v1.FoundationModelsService/CreateSession"
+	FoundationModelsService_DeleteSession_FullMethodName = "/foundationmodels.v1.FoundationModelsService/DeleteSession"
+	FoundationModelsService_Respond_FullMethodName       = "/foundationmodels.v1.FoundationModelsService/Respond"
+	FoundationModelsService_StreamRespond_FullMethodName = "/foundationmodels.v1.FoundationModelsService/StreamRespond"
+	FoundationModelsService_RegisterTool_FullMethodName  = "/foundationmodels.v1.FoundationModelsService/RegisterTool"
+)
+
+// FoundationModelsServiceClient is the client API for FoundationModelsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// FoundationModelsService generates text with the on-device model. Conversations are
+// kept in server-side sessions, created with CreateSession and referred to by ID.
+type FoundationModelsServiceClient interface {
+	// GetInfo describes the model and whether it is available
+	GetInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoResponse, error)
+	// CreateSession starts a conversation
+	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error)
+	// DeleteSession ends a conversation and frees its session
+	DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error)
+	// Respond generates the complete response to a prompt, continuing the session's
+	// conversation
+	Respond(ctx context.Context, in *RespondRequest, opts ...grpc.CallOption) (*RespondResponse, error)
+	// StreamRespond generates the response to a prompt, sending text as it is generated
+	StreamRespond(ctx context.Context, in *RespondRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamRespondResponse], error)
+	// RegisterTool offers a tool implemented by the client to a session's model. The
+	// client first sends the tool's definition, which the server confirms. The server then
+	// sends a ToolCall whenever the model calls the tool, and the client answers each with
+	// a ToolResult. The tool is removed from the session when the stream ends.
+	RegisterTool(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RegisterToolRequest, RegisterToolResponse], error)
+}
+
+type foundationModelsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFoundationModelsServiceClient(cc grpc.ClientConnInterface) FoundationModelsServiceClient {
+	return &foundationModelsServiceClient{cc}
+}
+
+func (c *foundationModelsServiceClient) GetInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInfoResponse)
+	err := c.cc.Invoke(ctx, FoundationModelsService_GetInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *foundationModelsServiceClient) CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateSessionResponse)
+	err := c.cc.Invoke(ctx, FoundationModelsService_CreateSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *foundationModelsServiceClient) DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSessionResponse)
+	err := c.cc.Invoke(ctx, FoundationModelsService_DeleteSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *foundationModelsServiceClient) Respond(ctx context.Context, in *RespondRequest, opts ...grpc.CallOption) (*RespondResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RespondResponse)
+	err := c.cc.Invoke(ctx, FoundationModelsService_Respond_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *foundationModelsServiceClient) StreamRespond(ctx context.Context, in *RespondRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamRespondResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FoundationModelsService_ServiceDesc.Streams[0], FoundationModelsService_StreamRespond_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RespondRequest, StreamRespondResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FoundationModelsService_StreamRespondClient = grpc.ServerStreamingClient[StreamRespondResponse]
+
+func (c *foundationModelsServiceClient) RegisterTool(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RegisterToolRequest, RegisterToolResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FoundationModelsService_ServiceDesc.Streams[1], FoundationModelsService_RegisterTool_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RegisterToolRequest, RegisterToolResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FoundationModelsService_RegisterToolClient = grpc.BidiStreamingClient[RegisterToolRequest, RegisterToolResponse]
+
+// FoundationModelsServiceServer is the server API for FoundationModelsService service.
+// All implementations must embed UnimplementedFoundationModelsServiceServer
+// for forward compatibility.
+//
+// FoundationModelsService generates text with the on-device model. Conversations are
+// kept in server-side sessions, created with CreateSession and referred to by ID.
+type FoundationModelsServiceServer interface {
+	// GetInfo describes the model and whether it is available
+	GetInfo(context.Context, *GetInfoRequest) (*GetInfoResponse, error)
+	// CreateSession starts a conversation
+	CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error)
+	// DeleteSession ends a conversation and frees its session
+	DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error)
+	// Respond generates the complete response to a prompt, continuing the session's
+	// conversation
+	Respond(context.Context, *RespondRequest) (*RespondResponse, error)
+	// StreamRespond generates the response to a prompt, sending text as it is generated
+	StreamRespond(*RespondRequest, grpc.ServerStreamingServer[StreamRespondResponse]) error
+	// RegisterTool offers a tool implemented by the client to a session's model. The
+	// client first sends the tool's definition, which the server confirms. The server then
+	// sends a ToolCall whenever the model calls the tool, and the client answers each with
+	// a ToolResult. The tool is removed from the session when the stream ends.
+	RegisterTool(grpc.BidiStreamingServer[RegisterToolRequest, RegisterToolResponse]) error
+	mustEmbedUnimplementedFoundationModelsServiceServer()
+}
+
+// UnimplementedFoundationModelsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFoundationModelsServiceServer struct{}
+
+func (UnimplementedFoundationModelsServiceServer) GetInfo(context.Context, *GetInfoRequest) (*GetInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSession not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) Respond(context.Context, *RespondRequest) (*RespondResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Respond not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) StreamRespond(*RespondRequest, grpc.ServerStreamingServer[StreamRespondResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRespond not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) RegisterTool(grpc.BidiStreamingServer[RegisterToolRequest, RegisterToolResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RegisterTool not implemented")
+}
+func (UnimplementedFoundationModelsServiceServer) mustEmbedUnimplementedFoundationModelsServiceServer() {
+}
+func (UnimplementedFoundationModelsServiceServer) testEmbeddedByValue() {}
+
+// UnsafeFoundationModelsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FoundationModelsServiceServer will
+// result in compilation errors.
+type UnsafeFoundationModelsServiceServer interface {
+	mustEmbedUnimplementedFoundationModelsServiceServer()
+}
+
+func RegisterFoundationModelsServiceServer(s grpc.ServiceRegistrar, srv FoundationModelsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedFoundationModelsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FoundationModelsService_ServiceDesc, srv)
+}
+
+func _FoundationModelsService_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FoundationModelsServiceServer).GetInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FoundationModelsService_GetInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FoundationModelsServiceServer).GetInfo(ctx, req.(*GetInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FoundationModelsService_CreateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FoundationModelsServiceServer).CreateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FoundationModelsService_CreateSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FoundationModelsServiceServer).CreateSession(ctx, req.(*CreateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FoundationModelsService_DeleteSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FoundationModelsServiceServer).DeleteSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FoundationModelsService_DeleteSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FoundationModelsServiceServer).DeleteSession(ctx, req.(*DeleteSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FoundationModelsService_Respond_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RespondRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FoundationModelsServiceServer).Respond(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FoundationModelsService_Respond_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FoundationModelsServiceServer).Respond(ctx, req.(*RespondRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FoundationModelsService_StreamRespond_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RespondRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FoundationModelsServiceServer).StreamRespond(m, &grpc.GenericServerStream[RespondRequest, StreamRespondResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FoundationModelsService_StreamRespondServer = grpc.ServerStreamingServer[StreamRespondResponse]
+
+func _FoundationModelsService_RegisterTool_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FoundationModelsServiceServer).RegisterTool(&grpc.GenericServerStream[RegisterToolRequest, RegisterToolResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FoundationModelsService_RegisterToolServer = grpc.BidiStreamingServer[RegisterToolRequest, RegisterToolResponse]
+
+// FoundationModelsService_ServiceDesc is the grpc.ServiceDesc for FoundationModelsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FoundationModelsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "foundationmodels.v1.FoundationModelsService",
+	HandlerType: (*FoundationModelsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetInfo",
+			Handler:    _FoundationModelsService_GetInfo_Handler,
+		},
+		{
+			MethodName: "CreateSession",
+			Handler:    _FoundationModelsService_CreateSession_Handler,
+		},
+		{
+			MethodName: "DeleteSession",
+			Handler:    _FoundationModelsService_DeleteSession_Handler,
+		},
+		{
+			MethodName: "Respond",
+			Handler:    _FoundationModelsService_Respond_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRespond",
+			Handler:       _FoundationModelsService_StreamRespond_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RegisterTool",
+			Handler:       _FoundationModelsService_RegisterTool_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "foundationmodels/v1/foundationmodels.proto",
+}
@@ -0,0 +1,19 @@
+module github.com/blacktop/go-foundationmodels/fmgrpc
+
+go 1.24
+
+replace github.com/blacktop/go-foundationmodels => ..
+
+require (
+	github.com/blacktop/go-foundationmodels v0.1.1
+	google.golang.org/grpc v1.73.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/ebitengine/purego v0.8.4 // indirect
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
+)
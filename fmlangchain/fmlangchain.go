@@ -0,0 +1,323 @@
+// Package fmlangchain implements langchaingo's llms.Model on top of go-foundationmodels,
+// so existing langchaingo chains and agents run on the on-device model:
+//
+//	llm := fmlangchain.New()
+//	answer, err := llms.GenerateFromSinglePrompt(ctx, llm, "Name three rivers in Europe")
+//
+//	chain := chains.NewLLMChain(llm, prompts.NewPromptTemplate("Summarize: {{.text}}", []string{"text"}))
+//
+// Each call runs in a new session seeded with the call's messages: system messages become
+// the instructions, earlier messages the conversation, and the last message, which must
+// be from the human, the prompt. llms.WithStreamingFunc streams the response.
+//
+// Foundation Models runs tools itself rather than returning tool calls, so llms.WithTools
+// is rejected with ErrToolCallsUnsupported. Register fm tools with WithTools instead;
+// agents that choose tools in text, such as agents.NewOneShotAgent, work unchanged.
+package fmlangchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/tmc/langchaingo/llms"
+)
+
+var (
+	// ErrToolCallsUnsupported is returned when a call passes langchaingo tools or functions
+	ErrToolCallsUnsupported = errors.New("fmlangchain: native tool calls are not supported, register fm tools with WithTools")
+
+	// ErrNoPrompt is returned when the last message isn't a human message with text
+	ErrNoPrompt = errors.New("fmlangchain: last message must be a human message")
+
+	// ErrUnsupportedContent is returned for message parts other than text, tool calls and
+	// tool responses, such as images
+	ErrUnsupportedContent = errors.New("fmlangchain: unsupported message content")
+)
+
+// Option configures an LLM
+type Option func(*LLM)
+
+// WithSessionOptions sets options applied to the session created for each call
+func WithSessionOptions(opts ...fm.SessionOption) Option {
+	return func(l *LLM) {
+		l.sessionOptions = append(l.sessionOptions, opts...)
+	}
+}
+
+// WithTools registers tools with the session created for each call. The model calls them
+// during generation and answers with their results.
+func WithTools(tools ...fm.Tool) Option {
+	return func(l *LLM) {
+		l.tools = append(l.tools, tools...)
+	}
+}
+
+// LLM implements llms.Model with Foundation Models
+type LLM struct {
+	sessionOptions []fm.SessionOption
+	tools          []fm.Tool
+}
+
+// New creates an LLM configured by opts
+func New(opts ...Option) *LLM {
+	l := &LLM{}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Call generates a response to a single prompt
+func (l *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, l, prompt, options...)
+}
+
+// GenerateContent generates a response to the last of messages, with the others as the
+// conversation so far. The response has a single choice.
+func (l *LLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if len(opts.Tools) > 0 || len(opts.Functions) > 0 {
+		return nil, ErrToolCallsUnsupported
+	}
+
+	entries, prompt, err := transcript(messages)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := l.newSession(entries)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+
+	var choice *llms.ContentChoice
+	switch {
+	case opts.JSONMode:
+		choice, err = respondJSON(ctx, sess, prompt, opts)
+	case opts.StreamingFunc != nil:
+		choice, err = respondStream(ctx, sess, prompt, opts)
+	default:
+		choice, err = respond(ctx, sess, prompt, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// newSession creates a session with the LLM's options and tools, seeded with entries
+func (l *LLM) newSession(entries []fm.TranscriptEntry) (*fm.Session, error) {
+	if err := fm.Init(); err != nil {
+		return nil, err
+	}
+	if err := fm.GetModelAvailability().Err(); err != nil {
+		return nil, err
+	}
+
+	sess := fm.NewSession(l.sessionOptions...)
+	if sess == nil {
+		return nil, fm.ErrInvalidSession
+	}
+	if len(entries) > 0 {
+		if err := sess.SetTranscript(entries); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("fmlangchain: failed to seed session: %w", err)
+		}
+	}
+	for _, tool := range l.tools {
+		if err := sess.RegisterTool(tool); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("fmlangchain: failed to register tool %q: %w", tool.Name(), err)
+		}
+	}
+	return sess, nil
+}
+
+// respond generates a complete response
+func respond(ctx context.Context, sess *fm.Session, prompt string, opts llms.CallOptions) (*llms.ContentChoice, error) {
+	response, err := sess.RespondDetailed(ctx, prompt, generationOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &llms.ContentChoice{
+		Content:    response.Text,
+		StopReason: string(response.FinishReason),
+		GenerationInfo: map[string]any{
+			"PromptTokens":     response.PromptTokens,
+			"CompletionTokens": response.CompletionTokens,
+			"TotalTokens":      response.TotalTokens(),
+		},
+	}, nil
+}
+
+// respondStream streams a response to opts.StreamingFunc, stopping generation if it
+// returns an error
+func respondStream(ctx context.Context, sess *fm.Session, prompt string, opts llms.CallOptions) (*llms.ContentChoice, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := sess.RespondStream(ctx, prompt, generationOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &llms.ContentChoice{}
+	var text strings.Builder
+	var streamErr error
+	for chunk := range stream {
+		if chunk.Text != "" && streamErr == nil {
+			text.WriteString(chunk.Text)
+			if streamErr = opts.StreamingFunc(ctx, []byte(chunk.Text)); streamErr != nil {
+				cancel()
+			}
+		}
+		if chunk.Done {
+			if streamErr == nil {
+				streamErr = chunk.Err
+			}
+			choice.StopReason = string(chunk.FinishReason)
+		}
+	}
+	if streamErr != nil {
+		return nil, streamErr
+	}
+	choice.Content = text.String()
+	return choice, nil
+}
+
+// respondJSON generates a JSON response and passes it to opts.StreamingFunc, if set, in
+// one piece. Generation options don't apply to structured output.
+func respondJSON(ctx context.Context, sess *fm.Session, prompt string, opts llms.CallOptions) (*llms.ContentChoice, error) {
+	response, err := sess.RespondWithStructuredOutputContext(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StreamingFunc != nil {
+		if err := opts.StreamingFunc(ctx, []byte(response)); err != nil {
+			return nil, err
+		}
+	}
+	return &llms.ContentChoice{Content: response, StopReason: string(fm.FinishStop)}, nil
+}
+
+// generationOptions maps call options to generation options. Zero values mean unset, as
+// langchaingo doesn't distinguish them.
+func generationOptions(opts llms.CallOptions) *fm.GenerationOptions {
+	options := &fm.GenerationOptions{StopSequences: opts.StopWords}
+	if opts.MaxTokens > 0 {
+		options.MaxTokens = &opts.MaxTokens
+	}
+	if opts.Temperature > 0 {
+		temperature := float32(opts.Temperature)
+		options.Temperature = &temperature
+	}
+	if opts.TopK > 0 {
+		options.TopK = &opts.TopK
+	} else if opts.TopP > 0 {
+		topP := float32(opts.TopP)
+		options.TopP = &topP
+	}
+	if opts.Seed != 0 {
+		options.Seed = &opts.Seed
+	}
+	return options
+}
+
+// transcript converts messages to transcript entries and the prompt, which is the text of
+// the last message. System messages are joined into one instructions entry at the start.
+func transcript(messages []llms.MessageContent) ([]fm.TranscriptEntry, string, error) {
+	if len(messages) == 0 {
+		return nil, "", ErrNoPrompt
+	}
+	last := messages[len(messages)-1]
+	if last.Role != llms.ChatMessageTypeHuman && last.Role != llms.ChatMessageTypeGeneric {
+		return nil, "", ErrNoPrompt
+	}
+	prompt, err := messageText(last)
+	if err != nil {
+		return nil, "", err
+	}
+	if prompt == "" {
+		return nil, "", ErrNoPrompt
+	}
+
+	var instructions []string
+	var entries []fm.TranscriptEntry
+	for _, message := range messages[:len(messages)-1] {
+		if message.Role == llms.ChatMessageTypeSystem {
+			text, err := messageText(message)
+			if err != nil {
+				return nil, "", err
+			}
+			instructions = append(instructions, text)
+			continue
+		}
+		converted, err := messageEntries(message)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, converted...)
+	}
+
+	if len(instructions) > 0 {
+		entries = append([]fm.TranscriptEntry{{
+			Role:    fm.TranscriptRoleInstructions,
+			Content: strings.Join(instructions, "\n\n"),
+		}}, entries...)
+	}
+	return entries, prompt, nil
+}
+
+// messageEntries converts a non-system message to transcript entries
+func messageEntries(message llms.MessageContent) ([]fm.TranscriptEntry, error) {
+	var entries []fm.TranscriptEntry
+	for _, part := range message.Parts {
+		switch part := part.(type) {
+		case llms.TextContent:
+			role := fm.TranscriptRolePrompt
+			if message.Role == llms.ChatMessageTypeAI {
+				role = fm.TranscriptRoleResponse
+			}
+			entries = append(entries, fm.TranscriptEntry{Role: role, Content: part.Text})
+		case llms.ToolCall:
+			if part.FunctionCall == nil {
+				continue
+			}
+			entries = append(entries, fm.TranscriptEntry{
+				Role:     fm.TranscriptRoleToolCall,
+				Content:  part.FunctionCall.Arguments,
+				ToolName: part.FunctionCall.Name,
+			})
+		case llms.ToolCallResponse:
+			entries = append(entries, fm.TranscriptEntry{
+				Role:     fm.TranscriptRoleToolOutput,
+				Content:  part.Content,
+				ToolName: part.Name,
+			})
+		default:
+			return nil, fmt.Errorf("%w: %T in %s message", ErrUnsupportedContent, part, message.Role)
+		}
+	}
+	return entries, nil
+}
+
+// messageText returns the text parts of message joined together
+func messageText(message llms.MessageContent) (string, error) {
+	var texts []string
+	for _, part := range message.Parts {
+		text, ok := part.(llms.TextContent)
+		if !ok {
+			return "", fmt.Errorf("%w: %T in %s message", ErrUnsupportedContent, part, message.Role)
+		}
+		texts = append(texts, text.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+var _ llms.Model = (*LLM)(nil)
@@ -0,0 +1,18 @@
+module github.com/blacktop/go-foundationmodels/fmlangchain
+
+go 1.24
+
+require (
+	github.com/blacktop/go-foundationmodels v0.1.1
+	github.com/tmc/langchaingo v0.1.13
+)
+
+require (
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/ebitengine/purego v0.8.4 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pkoukk/tiktoken-go v0.1.6 // indirect
+	golang.org/x/text v0.28.0 // indirect
+)
+
+replace github.com/blacktop/go-foundationmodels => ..
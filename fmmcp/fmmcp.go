@@ -0,0 +1,239 @@
+// Package fmmcp connects go-foundationmodels sessions to Model Context Protocol (MCP)
+// servers, so the model can call their tools. Connect to a server over stdio or HTTP and
+// register its tools with a session:
+//
+//	client, err := fmmcp.ConnectCommand(ctx, exec.Command("npx", "-y", "@modelcontextprotocol/server-filesystem", "."))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer client.Close()
+//
+//	sess := fm.NewSession()
+//	defer sess.Release()
+//	if err := client.RegisterTools(ctx, sess); err != nil {
+//		log.Fatal(err)
+//	}
+//	response, err := sess.RespondWithTools("What files are in this directory?")
+//
+// Each MCP tool becomes an fm.Tool whose parameters come from the tool's input schema.
+// Calls are forwarded to the server and their text content returned to the model.
+package fmmcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// modulePath is the import path of this module
+const modulePath = "github.com/blacktop/go-foundationmodels/fmmcp"
+
+// implementation identifies this module to MCP peers
+var implementation = &mcp.Implementation{Name: "go-foundationmodels", Version: moduleVersion()}
+
+// moduleVersion returns the version of this module in the running binary
+func moduleVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				return dep.Version
+			}
+		}
+	}
+	return "(devel)"
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithPrefix prefixes the names of the server's tools, so that tools from several
+// servers can be registered with one session without colliding
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = prefix
+	}
+}
+
+// WithToolFilter registers only the server's tools for which include returns true,
+// given their names on the server
+func WithToolFilter(include func(name string) bool) Option {
+	return func(c *Client) {
+		c.include = include
+	}
+}
+
+// WithCallTimeout limits how long a tool call may take (default: no limit)
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.callTimeout = timeout
+	}
+}
+
+// Client is a connection to an MCP server
+type Client struct {
+	session     *mcp.ClientSession
+	prefix      string
+	include     func(name string) bool
+	callTimeout time.Duration
+}
+
+// Connect connects to an MCP server over transport, such as an mcp.SSEClientTransport
+func Connect(ctx context.Context, transport mcp.Transport, opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	session, err := mcp.NewClient(implementation, nil).Connect(ctx, transport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fmmcp: failed to connect: %w", err)
+	}
+	c.session = session
+	return c, nil
+}
+
+// ConnectCommand starts an MCP server as a subprocess and connects to it over its
+// standard input and output. Close stops the server.
+func ConnectCommand(ctx context.Context, cmd *exec.Cmd, opts ...Option) (*Client, error) {
+	return Connect(ctx, &mcp.CommandTransport{Command: cmd}, opts...)
+}
+
+// ConnectHTTP connects to an MCP server's streamable HTTP endpoint
+func ConnectHTTP(ctx context.Context, endpoint string, opts ...Option) (*Client, error) {
+	return Connect(ctx, &mcp.StreamableClientTransport{Endpoint: endpoint}, opts...)
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.session.Close()
+}
+
+// Tools lists the server's tools as fm.Tools
+func (c *Client) Tools(ctx context.Context) ([]fm.Tool, error) {
+	var tools []fm.Tool
+	for tool, err := range c.session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("fmmcp: failed to list tools: %w", err)
+		}
+		if c.include != nil && !c.include(tool.Name) {
+			continue
+		}
+		params, err := toolArguments(tool.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("fmmcp: unsupported input schema for tool %q: %w", tool.Name, err)
+		}
+		tools = append(tools, &mcpTool{client: c, tool: tool, params: params})
+	}
+	return tools, nil
+}
+
+// RegisterTools registers the server's tools with sess
+func (c *Client) RegisterTools(ctx context.Context, sess *fm.Session) error {
+	tools, err := c.Tools(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		if err := sess.RegisterTool(tool); err != nil {
+			return fmt.Errorf("fmmcp: failed to register tool %q: %w", tool.Name(), err)
+		}
+	}
+	return nil
+}
+
+// toolArguments converts a tool's input schema to parameter definitions
+func toolArguments(schema any) ([]fm.ToolArgument, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	return fm.ToolArgumentsFromJSON(data)
+}
+
+// mcpTool is a tool of an MCP server
+type mcpTool struct {
+	client *Client
+	tool   *mcp.Tool
+	params []fm.ToolArgument
+}
+
+// Name returns the tool's name, with the client's prefix
+func (t *mcpTool) Name() string {
+	return t.client.prefix + t.tool.Name
+}
+
+// Description returns the tool's description
+func (t *mcpTool) Description() string {
+	if t.tool.Description == "" {
+		return t.tool.Title
+	}
+	return t.tool.Description
+}
+
+// GetParameters returns the top-level properties of the tool's input schema
+func (t *mcpTool) GetParameters() []fm.ToolArgument {
+	return t.params
+}
+
+// Execute calls the tool on the server. Errors reported by the tool are returned to the
+// model as the result's error, so it can correct its call.
+func (t *mcpTool) Execute(arguments map[string]any) (fm.ToolResult, error) {
+	ctx := context.Background()
+	if t.client.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.client.callTimeout)
+		defer cancel()
+	}
+
+	result, err := t.client.session.CallTool(ctx, &mcp.CallToolParams{Name: t.tool.Name, Arguments: arguments})
+	if err != nil {
+		return fm.ToolResult{}, fmt.Errorf("fmmcp: tool %q failed: %w", t.tool.Name, err)
+	}
+	content := resultText(result)
+	if result.IsError {
+		return fm.ToolResult{Error: content}, nil
+	}
+	return fm.ToolResult{Content: content}, nil
+}
+
+// resultText converts a tool result to text for the model. Text and text resources are
+// joined; other content is described by its type. Structured content is used as JSON if
+// there is no other content.
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		switch content := content.(type) {
+		case *mcp.TextContent:
+			parts = append(parts, content.Text)
+		case *mcp.EmbeddedResource:
+			if content.Resource != nil && content.Resource.Text != "" {
+				parts = append(parts, content.Resource.Text)
+			} else if content.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource %s]", content.Resource.URI))
+			}
+		case *mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource %s]", content.URI))
+		case *mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image %s]", content.MIMEType))
+		case *mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio %s]", content.MIMEType))
+		}
+	}
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			return string(data)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+var _ fm.SchematizedTool = (*mcpTool)(nil)
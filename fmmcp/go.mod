@@ -0,0 +1,18 @@
+module github.com/blacktop/go-foundationmodels/fmmcp
+
+go 1.24
+
+replace github.com/blacktop/go-foundationmodels => ..
+
+require (
+	github.com/blacktop/go-foundationmodels v0.1.1
+	github.com/modelcontextprotocol/go-sdk v1.1.0
+)
+
+require (
+	github.com/ebitengine/purego v0.8.4 // indirect
+	github.com/google/jsonschema-go v0.3.0 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/oauth2 v0.30.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+)
@@ -0,0 +1,247 @@
+package fmmcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithServerTools offers tools to MCP clients, which can call them directly, and to the
+// model in "generate" calls
+func WithServerTools(tools ...fm.Tool) ServerOption {
+	return func(s *Server) {
+		s.tools = append(s.tools, tools...)
+	}
+}
+
+// WithServerSessionOptions sets options for the sessions created for "generate" and
+// "summarize" calls
+func WithServerSessionOptions(opts ...fm.SessionOption) ServerOption {
+	return func(s *Server) {
+		s.sessionOptions = append(s.sessionOptions, opts...)
+	}
+}
+
+// Server is an MCP server that lets MCP clients, such as desktop assistants and editors,
+// delegate work to the on-device model. It offers the tools:
+//
+//	generate   responds to a prompt, with optional instructions and sampling options
+//	summarize  summarizes text
+//
+// and any Go tools given with WithServerTools. Each call uses a new session.
+type Server struct {
+	server         *mcp.Server
+	tools          []fm.Tool
+	sessionOptions []fm.SessionOption
+}
+
+// generateInput is the input of the "generate" tool
+type generateInput struct {
+	Prompt       string   `json:"prompt" jsonschema:"the prompt to respond to"`
+	Instructions string   `json:"instructions,omitempty" jsonschema:"instructions that guide the model's behavior, such as its role or the format of its answer"`
+	Temperature  *float32 `json:"temperature,omitempty" jsonschema:"randomness, from 0.0 (deterministic) to 1.0 (very random)"`
+	MaxTokens    *int     `json:"max_tokens,omitempty" jsonschema:"the maximum number of tokens to generate"`
+}
+
+// summarizeInput is the input of the "summarize" tool
+type summarizeInput struct {
+	Text  string `json:"text" jsonschema:"the text to summarize"`
+	Focus string `json:"focus,omitempty" jsonschema:"what the summary should concentrate on"`
+}
+
+// NewServer creates an MCP server. Serve it with ServeStdio, Run or HTTPHandler.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{server: mcp.NewServer(implementation, nil)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name: "generate",
+		Description: "Generate text with Apple's on-device Foundation Model, which runs locally and " +
+			"privately. Good for drafting, rewriting, classification and extraction.",
+	}, s.generate)
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:        "summarize",
+		Description: "Summarize text with Apple's on-device Foundation Model, which runs locally and privately.",
+	}, s.summarize)
+	for _, tool := range s.tools {
+		s.server.AddTool(&mcp.Tool{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: inputSchema(tool),
+		}, toolHandler(tool))
+	}
+	return s
+}
+
+// Run serves a single client over transport until it disconnects or ctx is done
+func (s *Server) Run(ctx context.Context, transport mcp.Transport) error {
+	return s.server.Run(ctx, transport)
+}
+
+// ServeStdio serves the client that started the process over its standard input and
+// output, as MCP clients expect of local servers
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Run(ctx, &mcp.StdioTransport{})
+}
+
+// HTTPHandler returns a handler serving clients over MCP's streamable HTTP transport
+func (s *Server) HTTPHandler() http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return s.server }, nil)
+}
+
+// generate handles calls to the "generate" tool
+func (s *Server) generate(ctx context.Context, req *mcp.CallToolRequest, in generateInput) (*mcp.CallToolResult, any, error) {
+	if in.Prompt == "" {
+		return nil, nil, errors.New("prompt is required")
+	}
+	sess, err := s.newSession(in.Instructions, s.tools)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sess.Release()
+
+	options := &fm.GenerationOptions{Temperature: in.Temperature, MaxTokens: in.MaxTokens}
+	response, err := sess.RespondWithContext(ctx, in.Prompt, options)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	return textResult(response), nil, nil
+}
+
+// summarize handles calls to the "summarize" tool
+func (s *Server) summarize(ctx context.Context, req *mcp.CallToolRequest, in summarizeInput) (*mcp.CallToolResult, any, error) {
+	if strings.TrimSpace(in.Text) == "" {
+		return nil, nil, errors.New("text is required")
+	}
+	instructions := "You summarize text accurately and concisely, keeping key facts, names and numbers. " +
+		"Reply with the summary only."
+	if in.Focus != "" {
+		instructions += " Focus on " + in.Focus + "."
+	}
+	sess, err := s.newSession(instructions, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sess.Release()
+
+	response, err := sess.RespondWithContext(ctx, "Summarize the following text:\n\n"+in.Text, nil)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	return textResult(strings.TrimSpace(response)), nil, nil
+}
+
+// newSession creates a session for one call with instructions and tools
+func (s *Server) newSession(instructions string, tools []fm.Tool) (*fm.Session, error) {
+	if err := fm.GetModelAvailability().Err(); err != nil {
+		return nil, err
+	}
+
+	var sess *fm.Session
+	if instructions != "" {
+		sess = fm.NewSessionWithInstructions(instructions, s.sessionOptions...)
+	} else {
+		sess = fm.NewSession(s.sessionOptions...)
+	}
+	if sess == nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	for _, tool := range tools {
+		if err := sess.RegisterTool(tool); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("failed to register tool %q: %w", tool.Name(), err)
+		}
+	}
+	return sess, nil
+}
+
+// toolHandler handles MCP calls to a Go tool
+func toolHandler(tool fm.Tool) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := make(map[string]any)
+		if len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		if schematized, ok := tool.(fm.SchematizedTool); ok {
+			if err := fm.ValidateToolArguments(args, schematized.GetParameters()); err != nil {
+				return errorResult(err), nil
+			}
+		}
+		if validated, ok := tool.(fm.ValidatedTool); ok {
+			if err := validated.ValidateArguments(args); err != nil {
+				return errorResult(err), nil
+			}
+		}
+
+		result, err := tool.Execute(args)
+		switch {
+		case err != nil:
+			return errorResult(err), nil
+		case result.Error != "":
+			return errorResult(errors.New(result.Error)), nil
+		default:
+			return textResult(result.Content), nil
+		}
+	}
+}
+
+// inputSchema returns the JSON Schema of a tool's parameters
+func inputSchema(tool fm.Tool) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	if schematized, ok := tool.(fm.SchematizedTool); ok {
+		for _, arg := range schematized.GetParameters() {
+			property := map[string]any{"type": arg.Type}
+			if arg.Description != "" {
+				property["description"] = arg.Description
+			}
+			if arg.Enum != nil {
+				property["enum"] = arg.Enum
+			}
+			if arg.Pattern != nil {
+				property["pattern"] = *arg.Pattern
+			}
+			if arg.Minimum != nil {
+				property["minimum"] = *arg.Minimum
+			}
+			if arg.Maximum != nil {
+				property["maximum"] = *arg.Maximum
+			}
+			if arg.MinLength != nil {
+				property["minLength"] = *arg.MinLength
+			}
+			if arg.MaxLength != nil {
+				property["maxLength"] = *arg.MaxLength
+			}
+			properties[arg.Name] = property
+			if arg.Required {
+				required = append(required, arg.Name)
+			}
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// textResult returns a successful tool result with text
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}
+}
+
+// errorResult returns a tool result reporting err, so the client's model can see it
+func errorResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}, IsError: true}
+}
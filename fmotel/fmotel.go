@@ -0,0 +1,145 @@
+// Package fmotel emits OpenTelemetry spans for go-foundationmodels. Register it as
+// telemetry for all sessions or for one:
+//
+//	fm.SetGlobalTelemetry(fmotel.New())
+//	sess := fm.NewSession(fm.WithTelemetry(fmotel.New(fmotel.WithTracerProvider(tp))))
+//
+// Each respond call becomes an "fm.respond" span, parented by the span in the call's
+// context, with a child "fm.tool" span per tool execution and a "first_token" event for
+// streams. Spans carry the request kind, token counts and model availability.
+package fmotel
+
+import (
+	"sync"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans' tracer
+const instrumentationName = "github.com/blacktop/go-foundationmodels/fmotel"
+
+// Span attribute keys
+const (
+	attrRequestID    = attribute.Key("fm.request.id")
+	attrRequestKind  = attribute.Key("fm.request.kind")
+	attrStreaming    = attribute.Key("fm.request.streaming")
+	attrPromptTokens = attribute.Key("fm.tokens.prompt")
+	attrOutputTokens = attribute.Key("fm.tokens.completion")
+	attrContextSize  = attribute.Key("fm.tokens.context")
+	attrContextMax   = attribute.Key("fm.tokens.context_max")
+	attrAvailability = attribute.Key("fm.model.availability")
+	attrLatency      = attribute.Key("fm.latency_ms")
+	attrToolName     = attribute.Key("fm.tool.name")
+)
+
+// Option configures Telemetry
+type Option func(*Telemetry)
+
+// WithTracerProvider sets the tracer provider (the global provider by default)
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Telemetry) {
+		t.provider = provider
+	}
+}
+
+// Telemetry implements fm.Telemetry by emitting OpenTelemetry spans
+type Telemetry struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+
+	mu    sync.Mutex
+	spans map[int64]trace.Span // Open request spans by request ID
+}
+
+// New creates telemetry that emits spans configured by opts
+func New(opts ...Option) *Telemetry {
+	t := &Telemetry{spans: make(map[int64]trace.Span)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return t
+}
+
+// OnRequestStart starts the request's span
+func (t *Telemetry) OnRequestStart(info fm.RequestInfo) {
+	_, span := t.tracer.Start(info.Context, "fm.respond",
+		trace.WithTimestamp(info.Start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrRequestID.Int64(info.ID),
+			attrRequestKind.String(info.Kind),
+			attrStreaming.Bool(info.Streaming),
+			attrPromptTokens.Int(info.PromptTokens),
+			attrAvailability.String(fm.GetModelAvailability().Status.String()),
+		))
+
+	t.mu.Lock()
+	t.spans[info.ID] = span
+	t.mu.Unlock()
+}
+
+// OnFirstToken records the first token latency as an event on the request's span
+func (t *Telemetry) OnFirstToken(info fm.RequestInfo, latency time.Duration) {
+	if span := t.span(info.ID); span != nil {
+		span.AddEvent("first_token", trace.WithAttributes(
+			attrLatency.Int64(latency.Milliseconds())))
+	}
+}
+
+// OnToolCall records the tool execution as a child span of the request's span
+func (t *Telemetry) OnToolCall(info fm.RequestInfo, invocation fm.ToolInvocation) {
+	parent := t.span(info.ID)
+	if parent == nil {
+		return
+	}
+	end := time.Now()
+	_, span := t.tracer.Start(trace.ContextWithSpan(info.Context, parent), "fm.tool",
+		trace.WithTimestamp(end.Add(-invocation.Duration)),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrToolName.String(invocation.Name)))
+	if invocation.Result.Error != "" {
+		span.SetStatus(codes.Error, invocation.Result.Error)
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// OnRequestEnd ends the request's span, recording its error if it failed
+func (t *Telemetry) OnRequestEnd(info fm.RequestInfo, duration time.Duration, err error) {
+	t.mu.Lock()
+	span := t.spans[info.ID]
+	delete(t.spans, info.ID)
+	t.mu.Unlock()
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(attrOutputTokens.Int(info.CompletionTokens))
+	if info.Session != nil {
+		span.SetAttributes(
+			attrContextSize.Int(info.Session.GetContextSize()),
+			attrContextMax.Int(info.Session.GetMaxContextSize()))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(info.Start.Add(duration)))
+}
+
+// span returns the open span of a request, or nil
+func (t *Telemetry) span(id int64) trace.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spans[id]
+}
+
+var _ fm.Telemetry = (*Telemetry)(nil)
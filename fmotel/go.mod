@@ -0,0 +1,20 @@
+module github.com/blacktop/go-foundationmodels/fmotel
+
+go 1.24
+
+require (
+	github.com/blacktop/go-foundationmodels v0.1.1
+	go.opentelemetry.io/otel v1.37.0
+	go.opentelemetry.io/otel/trace v1.37.0
+)
+
+require (
+	github.com/ebitengine/purego v0.8.4 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.37.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+)
+
+replace github.com/blacktop/go-foundationmodels => ..
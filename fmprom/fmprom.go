@@ -0,0 +1,175 @@
+// Package fmprom exposes Prometheus metrics for go-foundationmodels: requests, tokens,
+// latency, tool invocations, guardrail refusals and scheduler queue depth. Register the
+// collectors and the package's telemetry, then serve them:
+//
+//	metrics, err := fmprom.New(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fm.SetGlobalTelemetry(metrics)
+//	http.Handle("/metrics", promhttp.Handler())
+package fmprom
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	fm "github.com/blacktop/go-foundationmodels"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace prefixes every metric name
+const namespace = "foundationmodels"
+
+// Metrics implements fm.Telemetry by recording Prometheus metrics
+type Metrics struct {
+	requests          *prometheus.CounterVec
+	inFlight          prometheus.Gauge
+	duration          *prometheus.HistogramVec
+	firstToken        prometheus.Histogram
+	promptTokens      prometheus.Counter
+	completionTokens  prometheus.Counter
+	toolInvocations   *prometheus.CounterVec
+	toolDuration      *prometheus.HistogramVec
+	guardrailRefusals prometheus.Counter
+	queued            *prometheus.GaugeVec
+}
+
+// New creates the metrics and registers their collectors on registerer
+func New(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Respond calls by kind and outcome (ok, error, cancelled or guardrail).",
+		}, []string{"kind", "outcome"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Respond calls in progress.",
+		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time from the start to the end of respond calls.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40},
+		}, []string{"kind"}),
+		firstToken: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "first_token_seconds",
+			Help:      "Time until streaming responses produce their first text.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8},
+		}),
+		promptTokens: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "prompt_tokens_total",
+			Help:      "Estimated tokens sent in prompts.",
+		}),
+		completionTokens: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "completion_tokens_total",
+			Help:      "Estimated tokens generated in responses.",
+		}),
+		toolInvocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_invocations_total",
+			Help:      "Tool calls made by the model, by tool and outcome (ok or error).",
+		}, []string{"tool", "outcome"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_duration_seconds",
+			Help:      "Time spent executing tools.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"tool"}),
+		guardrailRefusals: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "guardrail_refusals_total",
+			Help:      "Respond calls refused by the model's safety guardrails.",
+		}),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduler_queued",
+			Help:      "Generations waiting for the global scheduler, by priority.",
+		}, []string{"priority"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.requests, m.inFlight, m.duration, m.firstToken, m.promptTokens,
+		m.completionTokens, m.toolInvocations, m.toolDuration, m.guardrailRefusals,
+		schedulerCollector{m.queued},
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// OnRequestStart counts the request as in flight
+func (m *Metrics) OnRequestStart(info fm.RequestInfo) {
+	m.inFlight.Inc()
+	m.promptTokens.Add(float64(info.PromptTokens))
+}
+
+// OnFirstToken records the first token latency
+func (m *Metrics) OnFirstToken(info fm.RequestInfo, latency time.Duration) {
+	m.firstToken.Observe(latency.Seconds())
+}
+
+// OnToolCall counts the tool invocation and records its duration
+func (m *Metrics) OnToolCall(info fm.RequestInfo, invocation fm.ToolInvocation) {
+	outcome := "ok"
+	if invocation.Result.Error != "" {
+		outcome = "error"
+	}
+	m.toolInvocations.WithLabelValues(invocation.Name, outcome).Inc()
+	m.toolDuration.WithLabelValues(invocation.Name).Observe(invocation.Duration.Seconds())
+}
+
+// OnRequestEnd counts the request by outcome and records its duration and tokens
+func (m *Metrics) OnRequestEnd(info fm.RequestInfo, duration time.Duration, err error) {
+	m.inFlight.Dec()
+	m.requests.WithLabelValues(info.Kind, outcome(err)).Inc()
+	m.duration.WithLabelValues(info.Kind).Observe(duration.Seconds())
+	m.completionTokens.Add(float64(info.CompletionTokens))
+	if errors.Is(err, fm.ErrGuardrailViolation) {
+		m.guardrailRefusals.Inc()
+	}
+}
+
+// schedulerCollector samples the global scheduler's queue depth when scraped
+type schedulerCollector struct {
+	queued *prometheus.GaugeVec
+}
+
+func (c schedulerCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.queued.Describe(ch)
+}
+
+func (c schedulerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := fm.GetSchedulerStats()
+	c.queued.WithLabelValues(fm.PriorityInteractive.String()).Set(float64(stats.QueuedInteractive))
+	c.queued.WithLabelValues(fm.PriorityBatch.String()).Set(float64(stats.QueuedBatch))
+	c.queued.Collect(ch)
+}
+
+// outcome labels how a request ended
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, fm.ErrGuardrailViolation):
+		return "guardrail"
+	case errors.Is(err, fm.ErrGenerationCancelled),
+		errors.Is(err, fm.ErrStreamStopped),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	default:
+		return "error"
+	}
+}
+
+var _ fm.Telemetry = (*Metrics)(nil)
@@ -0,0 +1,126 @@
+package fm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// validateOptions checks generation options for values Foundation Models can't honour
+func validateOptions(options *GenerationOptions) error {
+	switch options.SamplingMode {
+	case SamplingDefault:
+	case SamplingGreedy:
+		if options.TopP != nil || options.TopK != nil || options.Seed != nil {
+			return fmt.Errorf("greedy sampling can't be combined with TopP, TopK or Seed")
+		}
+	default:
+		return fmt.Errorf("unknown sampling mode: %q", options.SamplingMode)
+	}
+	if options.TopP != nil && options.TopK != nil {
+		return fmt.Errorf("TopP and TopK are mutually exclusive")
+	}
+	if options.TopP != nil && (*options.TopP <= 0 || *options.TopP > 1) {
+		return fmt.Errorf("TopP must be in (0, 1], got %v", *options.TopP)
+	}
+	if options.TopK != nil && *options.TopK < 1 {
+		return fmt.Errorf("TopK must be positive, got %d", *options.TopK)
+	}
+	if options.Seed != nil && *options.Seed < 0 {
+		return fmt.Errorf("Seed must not be negative, got %d", *options.Seed)
+	}
+	return nil
+}
+
+// optionsJSON encodes generation options for the Swift shim, which maps them onto
+// FoundationModels' GenerationOptions: MaxTokens and Temperature directly, SamplingMode,
+// TopK, TopP and Seed onto its sampling mode. Foundation Models has no presence or frequency penalties,
+// so those are dropped with a warning; it has no stop sequences either, so those are
+// enforced on the Go side instead (see stopMatcher).
+func optionsJSON(options *GenerationOptions) (string, error) {
+	if err := validateOptions(options); err != nil {
+		return "", err
+	}
+	if options.PresencePenalty != nil || options.FrequencyPenalty != nil {
+		logger().Warn("Foundation Models does not support presence or frequency penalties, ignoring them")
+	}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal generation options: %w", err)
+	}
+	return string(data), nil
+}
+
+// stopMatcher truncates streamed text at the first stop sequence. Text that could be the
+// start of a stop sequence split across chunks is held back until it can be decided.
+type stopMatcher struct {
+	sequences []string
+	pending   string
+}
+
+// newStopMatcher returns a matcher for the non-empty sequences, or nil if there are none
+func newStopMatcher(sequences []string) *stopMatcher {
+	var nonEmpty []string
+	for _, seq := range sequences {
+		if seq != "" {
+			nonEmpty = append(nonEmpty, seq)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return nil
+	}
+	return &stopMatcher{sequences: nonEmpty}
+}
+
+// push adds newly generated text and returns the text that can be emitted, and whether
+// a stop sequence was reached (in which case nothing after it may be emitted)
+func (m *stopMatcher) push(text string) (string, bool) {
+	buf := m.pending + text
+	if i := m.index(buf); i >= 0 {
+		m.pending = ""
+		return buf[:i], true
+	}
+
+	// Hold back the longest suffix that is a prefix of a stop sequence
+	hold := 0
+	for _, seq := range m.sequences {
+		for n := min(len(seq)-1, len(buf)); n > hold; n-- {
+			if strings.HasSuffix(buf, seq[:n]) {
+				hold = n
+				break
+			}
+		}
+	}
+	m.pending = buf[len(buf)-hold:]
+	return buf[:len(buf)-hold], false
+}
+
+// flush returns the held back text once generation has finished
+func (m *stopMatcher) flush() string {
+	pending := m.pending
+	m.pending = ""
+	return pending
+}
+
+// index returns the position of the earliest stop sequence in text, or -1
+func (m *stopMatcher) index(text string) int {
+	first := -1
+	for _, seq := range m.sequences {
+		if i := strings.Index(text, seq); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// truncateAtStop cuts a complete response at the first of the stop sequences
+func truncateAtStop(text string, sequences []string) string {
+	if m := newStopMatcher(sequences); m != nil {
+		if i := m.index(text); i >= 0 {
+			logger().Debug("Response truncated at stop sequence", "length", i)
+			return text[:i]
+		}
+	}
+	return text
+}
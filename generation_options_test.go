@@ -0,0 +1,118 @@
+package fm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStopMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		sequences []string
+		chunks    []string
+		want      []string // Text emitted for each chunk
+		wantStop  int      // Index of the chunk that reaches a stop sequence, -1 if none
+		wantFlush string
+	}{
+		{
+			name:      "no stop",
+			sequences: []string{"END"},
+			chunks:    []string{"Hello", " world"},
+			want:      []string{"Hello", " world"},
+			wantStop:  -1,
+		},
+		{
+			name:      "stop in one chunk",
+			sequences: []string{"END"},
+			chunks:    []string{"Hello END ignored"},
+			want:      []string{"Hello "},
+			wantStop:  0,
+		},
+		{
+			name:      "stop split across chunks",
+			sequences: []string{"END"},
+			chunks:    []string{"Hello E", "N", "D more"},
+			want:      []string{"Hello ", "", ""},
+			wantStop:  2,
+		},
+		{
+			name:      "false start is released",
+			sequences: []string{"END"},
+			chunks:    []string{"Hello EN", "ough"},
+			want:      []string{"Hello ", "ENough"},
+			wantStop:  -1,
+		},
+		{
+			name:      "held text is flushed",
+			sequences: []string{"END"},
+			chunks:    []string{"The E"},
+			want:      []string{"The "},
+			wantStop:  -1,
+			wantFlush: "E",
+		},
+		{
+			name:      "earliest of several sequences",
+			sequences: []string{"\n\n", "Q:"},
+			chunks:    []string{"A: yes Q: next\n\nmore"},
+			want:      []string{"A: yes "},
+			wantStop:  0,
+		},
+		{
+			name:      "longest held prefix",
+			sequences: []string{"abc", "xabd"},
+			chunks:    []string{"1xab", "d"},
+			want:      []string{"1", ""},
+			wantStop:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newStopMatcher(tt.sequences)
+			var got []string
+			stopped := -1
+			for i, chunk := range tt.chunks {
+				text, stop := m.push(chunk)
+				got = append(got, text)
+				if stop {
+					stopped = i
+					break
+				}
+			}
+			if !slices.Equal(got, tt.want) || stopped != tt.wantStop {
+				t.Fatalf("push() emitted %q, stopped at %d, want %q, stopped at %d", got, stopped, tt.want, tt.wantStop)
+			}
+			if flushed := m.flush(); flushed != tt.wantFlush {
+				t.Errorf("flush() = %q, want %q", flushed, tt.wantFlush)
+			}
+		})
+	}
+}
+
+func TestNewStopMatcherEmpty(t *testing.T) {
+	for _, sequences := range [][]string{nil, {}, {""}} {
+		if m := newStopMatcher(sequences); m != nil {
+			t.Errorf("newStopMatcher(%q) = %v, want nil", sequences, m)
+		}
+	}
+}
+
+func TestTruncateAtStop(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		sequences []string
+		want      string
+	}{
+		{name: "no sequences", text: "one\n\ntwo", want: "one\n\ntwo"},
+		{name: "not found", text: "one two", sequences: []string{"END"}, want: "one two"},
+		{name: "found", text: "one\n\ntwo", sequences: []string{"\n\n"}, want: "one"},
+		{name: "earliest", text: "a STOP b END", sequences: []string{"END", "STOP"}, want: "a "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateAtStop(tt.text, tt.sequences); got != tt.want {
+				t.Errorf("truncateAtStop(%q, %q) = %q, want %q", tt.text, tt.sequences, got, tt.want)
+			}
+		})
+	}
+}
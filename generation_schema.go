@@ -0,0 +1,340 @@
+package fm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// GenerationSchema describes the shape of structured output built at runtime, for when
+// the shape isn't known at compile time (otherwise pass a Go struct to RespondWithSchema).
+// It mirrors FoundationModels' DynamicGenerationSchema and is passed through the shim to
+// constrain decoding. Constraint methods return the schema so calls can be chained;
+// applying a constraint to the wrong kind of schema is reported when generating.
+type GenerationSchema struct {
+	node *schemaNode
+	errs []error
+}
+
+// ObjectSchema creates an object schema. The name identifies the type to the model.
+func ObjectSchema(name string) *GenerationSchema {
+	return &GenerationSchema{node: &schemaNode{Type: schemaObject, Name: name}}
+}
+
+// ArraySchema creates an array schema whose elements have the items schema
+func ArraySchema(items *GenerationSchema) *GenerationSchema {
+	return &GenerationSchema{
+		node: &schemaNode{Type: schemaArray, Items: items.node},
+		errs: items.errs,
+	}
+}
+
+// EnumSchema creates a string schema restricted to one of values. The name identifies
+// the set of choices to the model.
+func EnumSchema(name string, values ...string) *GenerationSchema {
+	g := &GenerationSchema{node: &schemaNode{Type: schemaString, Name: name, Enum: values}}
+	if len(values) == 0 {
+		g.errs = append(g.errs, fmt.Errorf("enum %s has no values", name))
+	}
+	return g
+}
+
+// StringSchema creates a string schema
+func StringSchema() *GenerationSchema {
+	return &GenerationSchema{node: &schemaNode{Type: schemaString}}
+}
+
+// IntegerSchema creates an integer schema
+func IntegerSchema() *GenerationSchema {
+	return &GenerationSchema{node: &schemaNode{Type: schemaInteger}}
+}
+
+// NumberSchema creates a floating-point number schema
+func NumberSchema() *GenerationSchema {
+	return &GenerationSchema{node: &schemaNode{Type: schemaNumber}}
+}
+
+// BooleanSchema creates a boolean schema
+func BooleanSchema() *GenerationSchema {
+	return &GenerationSchema{node: &schemaNode{Type: schemaBoolean}}
+}
+
+// SchemaFor builds a GenerationSchema from a Go struct, as RespondWithSchema does, so it
+// can be extended at runtime
+func SchemaFor(v any) (*GenerationSchema, error) {
+	node, err := schemaFor(v)
+	if err != nil {
+		return nil, err
+	}
+	return &GenerationSchema{node: node}, nil
+}
+
+// require records an error unless the schema is one of the given types
+func (g *GenerationSchema) require(constraint string, types ...string) bool {
+	for _, t := range types {
+		if g.node.Type == t {
+			return true
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("%s does not apply to %s schemas", constraint, g.node.Type))
+	return false
+}
+
+// Description sets a description of the object or enum for the model
+func (g *GenerationSchema) Description(description string) *GenerationSchema {
+	g.node.Description = description
+	return g
+}
+
+// Property adds a required property to an object schema
+func (g *GenerationSchema) Property(name, description string, schema *GenerationSchema) *GenerationSchema {
+	return g.addProperty(name, description, false, schema)
+}
+
+// OptionalProperty adds a property the model may omit to an object schema
+func (g *GenerationSchema) OptionalProperty(name, description string, schema *GenerationSchema) *GenerationSchema {
+	return g.addProperty(name, description, true, schema)
+}
+
+func (g *GenerationSchema) addProperty(name, description string, optional bool, schema *GenerationSchema) *GenerationSchema {
+	if !g.require("Property", schemaObject) {
+		return g
+	}
+	g.errs = append(g.errs, schema.errs...)
+	g.node.Properties = append(g.node.Properties, schemaProperty{
+		Name:        name,
+		Description: description,
+		Optional:    optional,
+		Schema:      schema.node,
+	})
+	return g
+}
+
+// Pattern restricts a string schema to values matching the regular expression
+func (g *GenerationSchema) Pattern(pattern string) *GenerationSchema {
+	if !g.require("Pattern", schemaString) {
+		return g
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		g.errs = append(g.errs, fmt.Errorf("invalid pattern: %w", err))
+		return g
+	}
+	g.node.Pattern = pattern
+	return g
+}
+
+// Range restricts an integer or number schema to [minimum, maximum]
+func (g *GenerationSchema) Range(minimum, maximum float64) *GenerationSchema {
+	return g.Minimum(minimum).Maximum(maximum)
+}
+
+// Minimum restricts an integer or number schema to values of at least minimum
+func (g *GenerationSchema) Minimum(minimum float64) *GenerationSchema {
+	if g.require("Minimum", schemaInteger, schemaNumber) {
+		g.node.Minimum = &minimum
+	}
+	return g
+}
+
+// Maximum restricts an integer or number schema to values of at most maximum
+func (g *GenerationSchema) Maximum(maximum float64) *GenerationSchema {
+	if g.require("Maximum", schemaInteger, schemaNumber) {
+		g.node.Maximum = &maximum
+	}
+	return g
+}
+
+// Count restricts an array schema to between minimum and maximum elements
+func (g *GenerationSchema) Count(minimum, maximum int) *GenerationSchema {
+	if g.require("Count", schemaArray) {
+		g.node.MinItems = &minimum
+		g.node.MaxItems = &maximum
+	}
+	return g
+}
+
+// Err returns the errors from building the schema, or nil if it is valid
+func (g *GenerationSchema) Err() error {
+	return errors.Join(g.errs...)
+}
+
+// jsonSchema is the subset of JSON Schema that SchemaFromJSON understands
+type jsonSchema struct {
+	Type        any              `json:"type"` // A type name, or a list of them with "null"
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Properties  jsonSchemaFields `json:"properties"`
+	Required    []string         `json:"required"`
+	Items       *jsonSchema      `json:"items"`
+	MinItems    *int             `json:"minItems"`
+	MaxItems    *int             `json:"maxItems"`
+	Enum        []any            `json:"enum"`
+	Pattern     string           `json:"pattern"`
+	Minimum     *float64         `json:"minimum"`
+	Maximum     *float64         `json:"maximum"`
+}
+
+// jsonSchemaField is a named property of a JSON Schema object
+type jsonSchemaField struct {
+	name   string
+	schema *jsonSchema
+}
+
+// jsonSchemaFields holds the properties of a JSON Schema object in document order, which
+// is the order the model generates them in
+type jsonSchemaFields []jsonSchemaField
+
+// UnmarshalJSON decodes a properties object, keeping its key order
+func (f *jsonSchemaFields) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		field := jsonSchemaField{name: key.(string), schema: &jsonSchema{}}
+		if err := dec.Decode(field.schema); err != nil {
+			return err
+		}
+		*f = append(*f, field)
+	}
+	return nil
+}
+
+// SchemaFromJSON builds a GenerationSchema from a JSON Schema document, as used by
+// OpenAI-style response formats and tool definitions. It understands type, title,
+// description, properties, required, items, minItems, maxItems, enum, pattern, minimum
+// and maximum; a nullable type ["string", "null"] is treated as its non-null type.
+func SchemaFromJSON(data []byte) (*GenerationSchema, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	g := schemaFromJSON(&schema, schema.Title)
+	if err := g.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// schemaFromJSON converts a decoded JSON Schema, naming objects and enums name unless
+// they have a title
+func schemaFromJSON(schema *jsonSchema, name string) *GenerationSchema {
+	if schema.Title != "" {
+		name = schema.Title
+	}
+
+	var g *GenerationSchema
+	switch t := jsonSchemaType(schema); t {
+	case schemaObject:
+		g = ObjectSchema(name)
+		for _, field := range schema.Properties {
+			property := schemaFromJSON(field.schema, field.name)
+			if slices.Contains(schema.Required, field.name) {
+				g.Property(field.name, field.schema.Description, property)
+			} else {
+				g.OptionalProperty(field.name, field.schema.Description, property)
+			}
+		}
+	case schemaArray:
+		items := &jsonSchema{}
+		if schema.Items != nil {
+			items = schema.Items
+		}
+		g = ArraySchema(schemaFromJSON(items, name))
+		if schema.MinItems != nil || schema.MaxItems != nil {
+			g.node.MinItems = schema.MinItems
+			g.node.MaxItems = schema.MaxItems
+		}
+	case schemaString:
+		if len(schema.Enum) > 0 {
+			values := make([]string, len(schema.Enum))
+			for i, value := range schema.Enum {
+				values[i] = fmt.Sprint(value)
+			}
+			g = EnumSchema(name, values...)
+		} else {
+			g = StringSchema()
+		}
+		if schema.Pattern != "" {
+			g.Pattern(schema.Pattern)
+		}
+	case schemaInteger, schemaNumber:
+		if t == schemaInteger {
+			g = IntegerSchema()
+		} else {
+			g = NumberSchema()
+		}
+		if schema.Minimum != nil {
+			g.Minimum(*schema.Minimum)
+		}
+		if schema.Maximum != nil {
+			g.Maximum(*schema.Maximum)
+		}
+	case schemaBoolean:
+		g = BooleanSchema()
+	default:
+		g = StringSchema()
+		g.errs = append(g.errs, fmt.Errorf("JSON schema type %q is not supported", t))
+	}
+
+	if schema.Description != "" && (g.node.Type == schemaObject || len(g.node.Enum) > 0) {
+		g.Description(schema.Description)
+	}
+	return g
+}
+
+// jsonSchemaType returns the schema's type, inferring it from its keywords if unset
+func jsonSchemaType(schema *jsonSchema) string {
+	switch t := schema.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, name := range t {
+			if name, ok := name.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	switch {
+	case len(schema.Properties) > 0:
+		return schemaObject
+	case schema.Items != nil:
+		return schemaArray
+	default:
+		return schemaString
+	}
+}
+
+// ToolArgumentsFromJSON converts the top-level properties of a JSON Schema object, such
+// as the parameters of an OpenAI function definition, to tool argument definitions in
+// document order, for tools defined at runtime
+func ToolArgumentsFromJSON(data []byte) ([]ToolArgument, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	args := make([]ToolArgument, 0, len(schema.Properties))
+	for _, field := range schema.Properties {
+		args = append(args, ToolArgument{
+			Name:        field.name,
+			Type:        jsonSchemaType(field.schema),
+			Description: field.schema.Description,
+			Required:    slices.Contains(schema.Required, field.name),
+			Minimum:     field.schema.Minimum,
+			Maximum:     field.schema.Maximum,
+			Enum:        field.schema.Enum,
+		})
+		if field.schema.Pattern != "" {
+			args[len(args)-1].Pattern = &field.schema.Pattern
+		}
+	}
+	return args, nil
+}
@@ -2,4 +2,7 @@ module github.com/blacktop/go-foundationmodels
 
 go 1.24
 
-require github.com/ebitengine/purego v0.8.4
+require (
+	github.com/ebitengine/purego v0.8.4
+	golang.org/x/text v0.28.0
+)
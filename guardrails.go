@@ -0,0 +1,64 @@
+package fm
+
+import (
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// Guardrails selects how strictly the model's safety guardrails treat prompts and
+// responses
+type Guardrails int
+
+const (
+	// GuardrailsDefault blocks unsafe prompts and responses. Sessions use it unless
+	// configured otherwise.
+	GuardrailsDefault Guardrails = iota
+	// GuardrailsPermissiveContentTransformations lets the model transform text it is
+	// given, such as summarizing, rewriting or tagging user-generated content on sensitive
+	// topics, reducing false-positive refusals. It applies to plain text responses only;
+	// structured output keeps the default guardrails.
+	GuardrailsPermissiveContentTransformations
+)
+
+// String returns the name of the guardrails
+func (g Guardrails) String() string {
+	switch g {
+	case GuardrailsDefault:
+		return "default"
+	case GuardrailsPermissiveContentTransformations:
+		return "permissiveContentTransformations"
+	default:
+		return fmt.Sprintf("unknown guardrails (%d)", int(g))
+	}
+}
+
+// WithGuardrails applies guardrails to the session's model in place of the strict default
+func WithGuardrails(guardrails Guardrails) SessionOption {
+	return func(s *Session) {
+		if err := s.setGuardrails(guardrails); err != nil {
+			logger().Warn("Failed to set session guardrails", "guardrails", guardrails, "error", err)
+		}
+	}
+}
+
+// Guardrails returns the guardrails applied to the session's model
+func (s *Session) Guardrails() Guardrails {
+	return s.guardrails
+}
+
+// setGuardrails rebuilds the shim session's model with guardrails
+func (s *Session) setGuardrails(guardrails Guardrails) error {
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := shimSymbol(setSessionGuardrails, "SetSessionGuardrails"); err != nil {
+		return err
+	}
+	result, _, _ := purego.SyscallN(setSessionGuardrails, uintptr(s.ptr), uintptr(guardrails))
+	if result == 0 {
+		return fmt.Errorf("shim rejected guardrails %s", guardrails)
+	}
+	s.guardrails = guardrails
+	return nil
+}
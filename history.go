@@ -0,0 +1,178 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// historyVersion is the format version written by ExportHistory
+const historyVersion = 1
+
+// sessionHistory is the serialized form of a conversation
+type sessionHistory struct {
+	Version      int               `json:"version"`
+	Instructions string            `json:"instructions,omitempty"`
+	ContextSize  int               `json:"contextSize"`
+	UseCase      UseCase           `json:"useCase,omitempty"`
+	Adapter      string            `json:"adapter,omitempty"`
+	Guardrails   Guardrails        `json:"guardrails,omitempty"`
+	Entries      []TranscriptEntry `json:"entries"`
+}
+
+// ExportHistory serializes the session's conversation (its instructions and the
+// framework transcript of prompts, tool calls and responses) as JSON, so it can be saved
+// and resumed later with NewSessionFromHistory. It waits for any in-flight generation
+// to finish first.
+func (s *Session) ExportHistory() ([]byte, error) {
+	if err := s.lock(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	entries, err := s.Transcript()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export history: %w", err)
+	}
+	return json.Marshal(sessionHistory{
+		Version:      historyVersion,
+		Instructions: s.systemInstructions,
+		ContextSize:  s.GetContextSize(),
+		UseCase:      s.useCase,
+		Adapter:      s.adapterPath,
+		Guardrails:   s.guardrails,
+		Entries:      entries,
+	})
+}
+
+// NewSessionFromHistory creates a session that resumes a conversation exported with
+// ExportHistory. The prior turns are replayed into the new session's transcript, so the
+// model sees them as context. Tools are not part of the history and must be registered
+// again.
+func NewSessionFromHistory(data []byte) (*Session, error) {
+	if Init() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+
+	var history sessionHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	if history.Version != historyVersion {
+		return nil, fmt.Errorf("unsupported history version %d", history.Version)
+	}
+
+	entries, err := json.Marshal(history.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	if err := shimSymbol(createSessionWithTranscript, "CreateSessionWithTranscript"); err != nil {
+		return nil, err
+	}
+	cEntries := cString(string(entries))
+	defer freePtr(cEntries)
+
+	ptr, _, _ := purego.SyscallN(createSessionWithTranscript, uintptr(cEntries))
+	if ptr == 0 {
+		return nil, fmt.Errorf("failed to create LanguageModelSession from history")
+	}
+
+	contextSize := history.ContextSize
+	if contextSize == 0 {
+		for _, entry := range history.Entries {
+			contextSize += estimateTokens(entry.Content)
+		}
+	}
+
+	session := &Session{
+		ptr:                shimHandle(ptr),
+		busy:               make(chan struct{}, 1),
+		contextSize:        contextSize,
+		maxContextSize:     MAX_CONTEXT_SIZE,
+		systemInstructions: history.Instructions,
+		registeredTools:    make(map[string]Tool),
+	}
+	if history.UseCase != UseCaseGeneral {
+		if err := session.setUseCase(history.UseCase); err != nil {
+			purego.SyscallN(releaseSession, ptr)
+			return nil, fmt.Errorf("failed to restore session: %w", err)
+		}
+	}
+	if history.Adapter != "" {
+		if err := session.setAdapter(history.Adapter); err != nil {
+			purego.SyscallN(releaseSession, ptr)
+			return nil, fmt.Errorf("failed to restore session: %w", err)
+		}
+	}
+	if history.Guardrails != GuardrailsDefault {
+		if err := session.setGuardrails(history.Guardrails); err != nil {
+			purego.SyscallN(releaseSession, ptr)
+			return nil, fmt.Errorf("failed to restore session: %w", err)
+		}
+	}
+
+	trackSession(session)
+
+	logger().Debug("Restored Foundation Models session from history",
+		"ptr", ptr,
+		"entries", len(history.Entries),
+		"initial_context", contextSize)
+
+	return session, nil
+}
+
+// Clone creates a new session seeded with this session's instructions and transcript, so
+// a conversation can be branched ("what if I ask X instead") without affecting this
+// session's context. Registered tools and session settings are carried over. It waits
+// for any in-flight generation to finish first.
+func (s *Session) Clone() (*Session, error) {
+	history, err := s.ExportHistory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone session: %w", err)
+	}
+	clone, err := NewSessionFromHistory(history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone session: %w", err)
+	}
+
+	s.mu.Lock()
+	tools := make([]Tool, 0, len(s.toolOrder))
+	for _, name := range s.toolOrder {
+		tools = append(tools, s.registeredTools[name])
+	}
+	fallback := s.fallbackTool
+	middleware := s.middleware
+	skipContextCheck := s.skipContextCheck
+	checkAvailability := s.checkAvailability
+	defaultTimeout := s.defaultTimeout
+	s.mu.Unlock()
+
+	for _, tool := range tools {
+		if err := clone.RegisterTool(tool); err != nil {
+			clone.Release()
+			return nil, fmt.Errorf("failed to clone session: %w", err)
+		}
+	}
+	if fallback != nil {
+		clone.SetFallbackTool(fallback)
+	}
+
+	clone.maxContextSize = s.maxContextSize
+	clone.skipContextCheck = skipContextCheck
+	clone.jsonIndent = s.jsonIndent
+	clone.jsonRepair = s.jsonRepair
+	clone.checkAvailability = checkAvailability
+	clone.autoTimeContext = s.autoTimeContext
+	clone.timeContextFormat = s.timeContextFormat
+	clone.defaultTimeout = defaultTimeout
+	clone.retryPolicy = s.retryPolicy
+	clone.priority = s.priority
+	clone.limiter = s.limiter
+	clone.middleware = middleware
+	clone.telemetry = s.telemetry
+
+	logger().Debug("Cloned session", "from", s.ptr, "to", clone.ptr)
+	return clone, nil
+}
@@ -0,0 +1,290 @@
+package fm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// ExtractJSON returns the first complete JSON object or array found in text, skipping
+// any surrounding prose or markdown code fences the model may have added. The second
+// return value is false if no valid JSON value was found.
+func ExtractJSON(text string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if json.Valid([]byte(trimmed)) && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) {
+		return trimmed, true
+	}
+
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+		if end := matchingBracket(text, start); end > start {
+			candidate := text[start : end+1]
+			if json.Valid([]byte(candidate)) {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchingBracket returns the index of the bracket closing the one at start, or -1
+func matchingBracket(text string, start int) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// completePartialJSON closes a truncated JSON object or array so that it can be decoded.
+// A string cut off mid-value is closed; a trailing key, number or literal that is still
+// being generated is dropped. The second return value is false if text has no prefix
+// that can be completed.
+func completePartialJSON(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return text, true
+	}
+
+	// closers[i] holds the brackets that close text[:i] when it ends outside a string
+	closers := make([]string, len(text)+1)
+	outside := make([]bool, len(text)+1)
+	var stack []byte
+	inString := false
+	escaped := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		} else {
+			switch c {
+			case '"':
+				inString = true
+			case '{':
+				stack = append(stack, '}')
+			case '[':
+				stack = append(stack, ']')
+			case '}', ']':
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+		if !inString {
+			closers[i+1] = closingBrackets(stack)
+			outside[i+1] = true
+		}
+	}
+
+	if inString {
+		partial := text
+		if escaped {
+			partial = partial[:len(partial)-1]
+		}
+		if candidate := partial + `"` + closingBrackets(stack); json.Valid([]byte(candidate)) {
+			return candidate, true
+		}
+	}
+	for i := len(text); i > 0; i-- {
+		if !outside[i] {
+			continue
+		}
+		if candidate := text[:i] + closers[i]; json.Valid([]byte(candidate)) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// closingBrackets returns the brackets that close the open ones in stack, innermost first
+func closingBrackets(stack []byte) string {
+	closing := make([]byte, len(stack))
+	for i, c := range stack {
+		closing[len(stack)-1-i] = c
+	}
+	return string(closing)
+}
+
+// NormalizeJSON returns the JSON value in text, fixing the mistakes models commonly make:
+// surrounding prose or code fences, trailing commas, unquoted keys, single-quoted strings
+// and output truncated before the closing brackets (the incomplete trailing value is
+// dropped). The second return value is false if no JSON value could be recovered.
+func NormalizeJSON(text string) (string, bool) {
+	if raw, ok := ExtractJSON(text); ok {
+		return raw, true
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", false
+	}
+	candidate := text[start:]
+	if end := matchingBracket(candidate, 0); end > 0 {
+		candidate = candidate[:end+1]
+	} else {
+		candidate = strings.TrimSuffix(strings.TrimSpace(candidate), "```")
+	}
+
+	fixed, ok := completePartialJSON(normalizeJSONSyntax(candidate))
+	if !ok || (!strings.HasPrefix(fixed, "{") && !strings.HasPrefix(fixed, "[")) {
+		return "", false
+	}
+	return fixed, true
+}
+
+// normalizeJSONSyntax removes trailing commas, quotes unquoted object keys and converts
+// single-quoted strings to double-quoted ones
+func normalizeJSONSyntax(text string) string {
+	var b strings.Builder
+	inString := false
+	escaped := false
+	var quote byte
+	var last byte // Last significant byte outside strings
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+				b.WriteByte('\'') // \' is not a JSON escape
+				i++
+				continue
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				inString = false
+				c = '"'
+			case c == '"':
+				b.WriteString(`\"`) // A double quote inside a single-quoted string
+				continue
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case c == '"' || c == '\'':
+			inString = true
+			quote = c
+			b.WriteByte('"')
+			last = '"'
+			continue
+		case c == ',':
+			if j := skipJSONSpace(text, i+1); j < len(text) && (text[j] == '}' || text[j] == ']') {
+				continue // Trailing comma
+			}
+		case (last == '{' || last == ',') && isJSONIdentifier(c, true):
+			j := i
+			for j < len(text) && isJSONIdentifier(text[j], false) {
+				j++
+			}
+			if k := skipJSONSpace(text, j); k < len(text) && text[k] == ':' {
+				b.WriteString(`"` + text[i:j] + `"`)
+				last = '"'
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			last = c
+		}
+	}
+	return b.String()
+}
+
+// skipJSONSpace returns the index of the first non-whitespace byte in text from i
+func skipJSONSpace(text string, i int) int {
+	for i < len(text) && strings.IndexByte(" \t\n\r", text[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// isJSONIdentifier reports whether c can start (or continue) an unquoted object key
+func isJSONIdentifier(c byte, start bool) bool {
+	return c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !start && '0' <= c && c <= '9'
+}
+
+// IndentJSON extracts the JSON value from text and re-indents it with the given indent
+// string. If text contains no valid JSON it is returned unchanged and ok is false.
+func IndentJSON(text, indent string) (string, bool) {
+	raw, ok := ExtractJSON(text)
+	if !ok {
+		return text, false
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(raw), "", indent); err != nil {
+		return text, false
+	}
+	return buf.String(), true
+}
+
+// SetJSONIndent enables re-indenting of RespondWithStructuredOutput results using the
+// given indent string (e.g. "  " or "\t"). An empty string disables post-processing and
+// returns the model output as-is, which is the default.
+func (s *Session) SetJSONIndent(indent string) {
+	s.jsonIndent = indent
+}
+
+// SetJSONRepair enables normalizing malformed structured output locally (see
+// NormalizeJSON) before asking the model to correct it, which saves a round trip for
+// common mistakes. It is disabled by default, as normalizing can drop a truncated value.
+func (s *Session) SetJSONRepair(enabled bool) {
+	s.jsonRepair = enabled
+}
+
+// parseJSON returns the JSON value in text, normalizing it if JSON repair is enabled
+func (s *Session) parseJSON(text string) (string, bool) {
+	if s.jsonRepair {
+		return NormalizeJSON(text)
+	}
+	return ExtractJSON(text)
+}
+
+// formatStructuredOutput applies the session's JSON indentation to a structured response
+func (s *Session) formatStructuredOutput(response string) string {
+	if s.jsonIndent == "" {
+		return response
+	}
+	formatted, ok := IndentJSON(response, s.jsonIndent)
+	if !ok {
+		logger().Warn("Structured output is not valid JSON, returning it unchanged",
+			"response_preview", response[:min(50, len(response))])
+		return response
+	}
+	return formatted
+}
@@ -0,0 +1,55 @@
+package fm
+
+import "testing"
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{name: "object", text: `{"a": 1}`, want: `{"a": 1}`, wantOK: true},
+		{name: "array with whitespace", text: "  [1, 2]\n", want: "[1, 2]", wantOK: true},
+		{name: "code fence", text: "```json\n{\"a\": 1}\n```", want: `{"a": 1}`, wantOK: true},
+		{name: "surrounding prose", text: `Here you go: {"name": "Go"} Hope that helps!`, want: `{"name": "Go"}`, wantOK: true},
+		{name: "brackets in strings", text: `Result: {"s": "a } or ]", "t": "\"{"}.`, want: `{"s": "a } or ]", "t": "\"{"}`, wantOK: true},
+		{name: "nested", text: `x {"a": {"b": [1, {"c": 2}]}} y`, want: `{"a": {"b": [1, {"c": 2}]}}`, wantOK: true},
+		{name: "skips invalid candidate", text: `{not json} then {"ok": true}`, want: `{"ok": true}`, wantOK: true},
+		{name: "bare string", text: `"just a string"`, wantOK: false},
+		{name: "bare number", text: "42", wantOK: false},
+		{name: "truncated", text: `{"a": [1, 2`, wantOK: false},
+		{name: "no JSON", text: "No JSON here.", wantOK: false},
+		{name: "empty", text: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractJSON(tt.text)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ExtractJSON(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIndentJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		indent string
+		want   string
+		wantOK bool
+	}{
+		{name: "spaces", text: `{"a":[1,2]}`, indent: "  ", want: "{\n  \"a\": [\n    1,\n    2\n  ]\n}", wantOK: true},
+		{name: "tabs in prose", text: `Sure: {"a":1}`, indent: "\t", want: "{\n\t\"a\": 1\n}", wantOK: true},
+		{name: "not JSON", text: "Sorry, I can't.", indent: "  ", want: "Sorry, I can't.", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := IndentJSON(tt.text, tt.indent)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("IndentJSON(%q, %q) = %q, %v, want %q, %v", tt.text, tt.indent, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package fm
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// pkgLogger receives the package's diagnostics (nil = slog.Default())
+var pkgLogger atomic.Pointer[slog.Logger]
+
+// SetLogger routes the package's diagnostics (debug traces, warnings and errors) to
+// logger, so embedding applications control their verbosity and destination. The
+// package never writes to stdout or stderr itself. Pass nil to go back to
+// slog.Default().
+func SetLogger(l *slog.Logger) {
+	pkgLogger.Store(l)
+}
+
+// logger returns the logger for the package's diagnostics
+func logger() *slog.Logger {
+	if l := pkgLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,81 @@
+package fm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultMatchAttempts is the number of unconstrained attempts RespondMatching makes when
+// the pattern can't be enforced by the shim
+const DefaultMatchAttempts = 2
+
+// RespondMatching generates text that matches the regular expression pattern in full,
+// for machine-consumed values such as IDs, dates and codes. Decoding is constrained by
+// the pattern through a generation schema where the shim can apply it; otherwise, or if
+// the result still doesn't match, the model is asked with the pattern in the prompt and
+// its answer validated, up to DefaultMatchAttempts times. The error wraps ErrNoMatch if
+// no response matched.
+func (s *Session) RespondMatching(prompt, pattern string) (string, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	value, err := s.respondMatchingConstrained(prompt, pattern)
+	switch {
+	case err == nil && re.MatchString(value):
+		return value, nil
+	case err == nil:
+		logger().Debug("Constrained response does not match pattern, retrying unconstrained",
+			"pattern", pattern, "response_preview", value[:min(50, len(value))])
+	case errors.Is(err, ErrGenerationFailed):
+		// The shim's regex engine may reject patterns Go accepts
+		logger().Debug("Pattern could not be enforced by the shim, retrying unconstrained",
+			"pattern", pattern, "error", err)
+	default:
+		return "", err
+	}
+
+	constrained := fmt.Sprintf(
+		"%s\n\nReply with only a value matching the regular expression %s, without any other text.",
+		prompt, pattern)
+	for attempt := 1; attempt <= DefaultMatchAttempts; attempt++ {
+		response, err := s.Respond(constrained, nil)
+		if err != nil {
+			return "", err
+		}
+		value = strings.Trim(response, " \t\r\n\"'`")
+		if re.MatchString(value) {
+			return value, nil
+		}
+		logger().Debug("Response does not match pattern",
+			"attempt", attempt,
+			"pattern", pattern,
+			"response_preview", value[:min(50, len(value))])
+	}
+	return "", fmt.Errorf("%w %s after %d attempts", ErrNoMatch, pattern, DefaultMatchAttempts+1)
+}
+
+// respondMatchingConstrained generates a string constrained to pattern by the shim
+func (s *Session) respondMatchingConstrained(prompt, pattern string) (string, error) {
+	encoded, err := encodeSchema(ObjectSchema("Match").
+		Property("value", "A value matching the regular expression "+pattern, StringSchema().Pattern(pattern)))
+	if err != nil {
+		return "", err
+	}
+	response, err := s.generate(generationSchema, prompt, nil, encoded)
+	if err != nil {
+		return "", err
+	}
+
+	var match struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(response), &match); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrGenerationFailed, err)
+	}
+	return match.Value, nil
+}
@@ -0,0 +1,72 @@
+package fm
+
+import "context"
+
+// Request is a respond call passing through a session's middleware. Middleware may
+// rewrite the prompt or options before calling the next handler.
+type Request struct {
+	// Kind is the kind of generation: "text", "structured" (JSON output), "tools" or
+	// "schema" (constrained by Schema)
+	Kind string
+	// Prompt is the prompt to send to the model
+	Prompt string
+	// Options are the generation options, or nil for the defaults
+	Options *GenerationOptions
+	// Schema is the JSON schema constraining the response, or empty for none
+	Schema string
+
+	kind generationKind
+}
+
+// Handler handles a respond call, returning the response text
+type Handler func(ctx context.Context, req *Request) (string, error)
+
+// Middleware wraps a Handler, e.g. to log, rewrite prompts, redact PII, cache responses
+// or record metrics. It can return without calling next to short-circuit the request.
+type Middleware func(next Handler) Handler
+
+// Use appends middleware to the chain wrapping every blocking respond call on the
+// session (streams are not wrapped). The first middleware added is the outermost, and
+// the chain runs outside of retries, so a middleware sees each call once.
+func (s *Session) Use(middleware ...Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// requestKind names a generation kind for middleware
+func requestKind(kind generationKind) string {
+	switch kind {
+	case generationStructured:
+		return "structured"
+	case generationTools:
+		return "tools"
+	case generationSchema:
+		return "schema"
+	default:
+		return "text"
+	}
+}
+
+// serve runs a respond call through the session's middleware to final
+func (s *Session) serve(ctx context.Context, kind generationKind, prompt string, options *GenerationOptions, schema string, final Handler) (string, error) {
+	s.mu.Lock()
+	chain := s.middleware
+	s.mu.Unlock()
+
+	handler := final
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+
+	telemetry := s.startTelemetry(ctx, requestKind(kind), false, prompt)
+	response, err := handler(ctx, &Request{
+		Kind:    requestKind(kind),
+		Prompt:  prompt,
+		Options: options,
+		Schema:  schema,
+		kind:    kind,
+	})
+	telemetry.end(response, err)
+	return response, err
+}
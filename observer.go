@@ -0,0 +1,239 @@
+package fm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// ToolInvocation describes a single tool call made by Foundation Models
+type ToolInvocation struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    ToolResult     `json:"result"`
+	Duration  time.Duration  `json:"duration"`
+}
+
+// ToolObserver is notified after every tool invocation made through the Swift shim. Tool
+// calls made in the same turn run concurrently, so it may be called concurrently.
+type ToolObserver func(invocation ToolInvocation)
+
+// ToolLogFormat controls how tool invocations are rendered for the tool log callback
+type ToolLogFormat int
+
+const (
+	// ToolLogFormatText renders invocations as a single human-readable line
+	ToolLogFormatText ToolLogFormat = iota
+	// ToolLogFormatJSONRPC renders invocations as a JSON-RPC style tools/call envelope
+	ToolLogFormatJSONRPC
+)
+
+// String returns the name of the tool log format
+func (f ToolLogFormat) String() string {
+	switch f {
+	case ToolLogFormatText:
+		return "text"
+	case ToolLogFormatJSONRPC:
+		return "json-rpc"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(f))
+	}
+}
+
+// ParseToolLogFormat parses a tool log format name ("text" or "json-rpc")
+func ParseToolLogFormat(name string) (ToolLogFormat, error) {
+	switch name {
+	case "", "text":
+		return ToolLogFormatText, nil
+	case "json-rpc", "jsonrpc":
+		return ToolLogFormatJSONRPC, nil
+	default:
+		return ToolLogFormatText, fmt.Errorf("unknown tool log format: %s", name)
+	}
+}
+
+// ToolCallEnvelope is the JSON-RPC style envelope emitted for each tool invocation
+type ToolCallEnvelope struct {
+	Method string             `json:"method"`
+	Params ToolCallParams     `json:"params"`
+	Result *ToolCallRPCResult `json:"result,omitempty"`
+	Error  *ToolCallRPCError  `json:"error,omitempty"`
+}
+
+// ToolCallParams holds the params member of a tools/call envelope
+type ToolCallParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// ToolCallRPCResult holds the result member of a successful tools/call envelope
+type ToolCallRPCResult struct {
+	Content string `json:"content"`
+}
+
+// ToolCallRPCError holds the error member of a failed tools/call envelope
+type ToolCallRPCError struct {
+	Message string `json:"message"`
+}
+
+var (
+	observerMu      sync.RWMutex
+	toolObservers   []ToolObserver
+	toolLogFormat   = ToolLogFormatText
+	toolLogCallback func(entry string)
+
+	// Internal hooks notified when a tool call starts and finishes, keyed by hook ID
+	toolHooks      = make(map[int64]toolHook)
+	nextToolHookID int64
+)
+
+// toolHook receives a tool invocation on sess before execution (done is false, no
+// result yet) and again after it (done is true). sess is nil if the session is unknown.
+type toolHook func(sess *Session, invocation ToolInvocation, done bool)
+
+// addToolHook registers an internal tool hook and returns a function removing it
+func addToolHook(hook toolHook) func() {
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	nextToolHookID++
+	id := nextToolHookID
+	toolHooks[id] = hook
+	return func() {
+		observerMu.Lock()
+		defer observerMu.Unlock()
+		delete(toolHooks, id)
+	}
+}
+
+// snapshotToolHooks returns the registered internal tool hooks
+func snapshotToolHooks() []toolHook {
+	observerMu.RLock()
+	defer observerMu.RUnlock()
+	hooks := make([]toolHook, 0, len(toolHooks))
+	for _, hook := range toolHooks {
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// notifyToolCallStart tells internal hooks that the model called one of sess's tools
+func notifyToolCallStart(sess *Session, toolName, argsJSON string) {
+	hooks := snapshotToolHooks()
+	if len(hooks) == 0 {
+		return
+	}
+	var args map[string]any
+	_ = json.Unmarshal([]byte(argsJSON), &args)
+	for _, hook := range hooks {
+		hook(sess, ToolInvocation{Name: toolName, Arguments: args}, false)
+	}
+}
+
+// AddToolObserver registers an observer that is called after every tool invocation
+func AddToolObserver(observer ToolObserver) {
+	if observer == nil {
+		return
+	}
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	toolObservers = append(toolObservers, observer)
+}
+
+// ClearToolObservers removes all registered tool observers
+func ClearToolObservers() {
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	toolObservers = nil
+}
+
+// SetToolLogFormat sets the format used when rendering tool invocations for the log callback
+func SetToolLogFormat(format ToolLogFormat) {
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	toolLogFormat = format
+}
+
+// SetToolLogCallback sets a callback that receives one formatted entry per tool invocation.
+// Pass nil to fall back to slog debug logging.
+func SetToolLogCallback(callback func(entry string)) {
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	toolLogCallback = callback
+}
+
+// FormatToolInvocation renders a tool invocation in the given log format
+func FormatToolInvocation(invocation ToolInvocation, format ToolLogFormat) string {
+	switch format {
+	case ToolLogFormatJSONRPC:
+		envelope := ToolCallEnvelope{
+			Method: "tools/call",
+			Params: ToolCallParams{
+				Name:      invocation.Name,
+				Arguments: encodableArguments(invocation.Arguments),
+			},
+		}
+		if envelope.Params.Arguments == nil {
+			envelope.Params.Arguments = map[string]any{}
+		}
+		if invocation.Result.Error != "" {
+			envelope.Error = &ToolCallRPCError{Message: invocation.Result.Error}
+		} else {
+			envelope.Result = &ToolCallRPCResult{Content: invocation.Result.Content}
+		}
+		data, _ := json.Marshal(envelope)
+		return string(data)
+	default:
+		args, _ := json.Marshal(encodableArguments(invocation.Arguments))
+		if invocation.Result.Error != "" {
+			return fmt.Sprintf("tool %s(%s) failed in %v: %s",
+				invocation.Name, args, invocation.Duration, invocation.Result.Error)
+		}
+		return fmt.Sprintf("tool %s(%s) returned in %v: %s",
+			invocation.Name, args, invocation.Duration, invocation.Result.Content)
+	}
+}
+
+// encodableArguments returns args with any value that encoding/json can't encode (such as
+// NaN, or a channel set by middleware) replaced by its text, so the log entry keeps the
+// other arguments
+func encodableArguments(args map[string]any) map[string]any {
+	var encodable map[string]any
+	for key, value := range args {
+		if _, err := json.Marshal(value); err == nil {
+			continue
+		}
+		if encodable == nil {
+			encodable = maps.Clone(args)
+		}
+		encodable[key] = fmt.Sprint(value)
+	}
+	if encodable == nil {
+		return args
+	}
+	return encodable
+}
+
+// notifyToolInvocation logs the invocation and fans it out to registered observers
+func notifyToolInvocation(sess *Session, invocation ToolInvocation) {
+	observerMu.RLock()
+	observers := append([]ToolObserver(nil), toolObservers...)
+	format := toolLogFormat
+	callback := toolLogCallback
+	observerMu.RUnlock()
+
+	entry := FormatToolInvocation(invocation, format)
+	if callback != nil {
+		callback(entry)
+	} else {
+		logger().Debug("Tool invocation", "format", format.String(), "entry", entry)
+	}
+
+	for _, observer := range observers {
+		observer(invocation)
+	}
+	for _, hook := range snapshotToolHooks() {
+		hook(sess, invocation, true)
+	}
+}
@@ -0,0 +1,160 @@
+package fm
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseToolLogFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    ToolLogFormat
+		wantErr bool
+	}{
+		{name: "", want: ToolLogFormatText},
+		{name: "text", want: ToolLogFormatText},
+		{name: "json-rpc", want: ToolLogFormatJSONRPC},
+		{name: "jsonrpc", want: ToolLogFormatJSONRPC},
+		{name: "xml", want: ToolLogFormatText, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToolLogFormat(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseToolLogFormat(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseToolLogFormat(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolLogFormatString(t *testing.T) {
+	tests := []struct {
+		format ToolLogFormat
+		want   string
+	}{
+		{ToolLogFormatText, "text"},
+		{ToolLogFormatJSONRPC, "json-rpc"},
+		{ToolLogFormat(7), "unknown(7)"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.String(); got != tt.want {
+			t.Errorf("ToolLogFormat(%d).String() = %q, want %q", int(tt.format), got, tt.want)
+		}
+	}
+}
+
+func TestFormatToolInvocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		invocation ToolInvocation
+		format     ToolLogFormat
+		want       string
+	}{
+		{
+			name: "text result",
+			invocation: ToolInvocation{
+				Name:      "get_weather",
+				Arguments: map[string]any{"city": "Paris"},
+				Result:    ToolResult{Content: "18°C"},
+				Duration:  2 * time.Millisecond,
+			},
+			format: ToolLogFormatText,
+			want:   `tool get_weather({"city":"Paris"}) returned in 2ms: 18°C`,
+		},
+		{
+			name: "text error",
+			invocation: ToolInvocation{
+				Name:     "get_weather",
+				Result:   ToolResult{Error: "no such city"},
+				Duration: time.Second,
+			},
+			format: ToolLogFormatText,
+			want:   `tool get_weather(null) failed in 1s: no such city`,
+		},
+		{
+			name: "json-rpc result",
+			invocation: ToolInvocation{
+				Name:      "calculate",
+				Arguments: map[string]any{"expression": "2+2"},
+				Result:    ToolResult{Content: "4"},
+			},
+			format: ToolLogFormatJSONRPC,
+			want:   `{"method":"tools/call","params":{"name":"calculate","arguments":{"expression":"2+2"}},"result":{"content":"4"}}`,
+		},
+		{
+			name: "json-rpc unencodable argument",
+			invocation: ToolInvocation{
+				Name:      "calculate",
+				Arguments: map[string]any{"expression": "0/0\x01", "value": math.NaN()},
+				Result:    ToolResult{Content: "NaN"},
+			},
+			format: ToolLogFormatJSONRPC,
+			want:   `{"method":"tools/call","params":{"name":"calculate","arguments":{"expression":"0/0\u0001","value":"NaN"}},"result":{"content":"NaN"}}`,
+		},
+		{
+			name: "text unencodable argument",
+			invocation: ToolInvocation{
+				Name:      "calculate",
+				Arguments: map[string]any{"value": math.Inf(1)},
+				Result:    ToolResult{Content: "+Inf"},
+			},
+			format: ToolLogFormatText,
+			want:   `tool calculate({"value":"+Inf"}) returned in 0s: +Inf`,
+		},
+		{
+			name: "json-rpc error without arguments",
+			invocation: ToolInvocation{
+				Name:   "calculate",
+				Result: ToolResult{Error: "division by zero"},
+			},
+			format: ToolLogFormatJSONRPC,
+			want:   `{"method":"tools/call","params":{"name":"calculate","arguments":{}},"error":{"message":"division by zero"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatToolInvocation(tt.invocation, tt.format); got != tt.want {
+				t.Errorf("FormatToolInvocation() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyToolInvocation(t *testing.T) {
+	t.Cleanup(func() {
+		ClearToolObservers()
+		SetToolLogCallback(nil)
+		SetToolLogFormat(ToolLogFormatText)
+	})
+
+	var entries []string
+	var observed []ToolInvocation
+	SetToolLogFormat(ToolLogFormatJSONRPC)
+	SetToolLogCallback(func(entry string) { entries = append(entries, entry) })
+	AddToolObserver(func(invocation ToolInvocation) { observed = append(observed, invocation) })
+	AddToolObserver(nil)
+
+	var hooked []bool
+	remove := addToolHook(func(sess *Session, invocation ToolInvocation, done bool) {
+		hooked = append(hooked, done)
+	})
+	defer remove()
+
+	notifyToolCallStart(nil, "calculate", `{"expression":"1+1"}`)
+	notifyToolInvocation(nil, ToolInvocation{Name: "calculate", Result: ToolResult{Content: "2"}})
+
+	want := `{"method":"tools/call","params":{"name":"calculate","arguments":{}},"result":{"content":"2"}}`
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("log entries = %q, want [%s]", entries, want)
+	}
+	if len(observed) != 1 || observed[0].Name != "calculate" {
+		t.Errorf("observed = %+v, want one calculate invocation", observed)
+	}
+	if len(hooked) != 2 || hooked[0] || !hooked[1] {
+		t.Errorf("hook calls = %v, want [false true]", hooked)
+	}
+}
@@ -0,0 +1,201 @@
+package fm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIProvider is a Provider backed by an OpenAI-compatible chat completions endpoint,
+// such as OpenAI itself, Ollama or a `found serve` instance on another Mac. Each call is
+// a single-turn conversation: Instructions, if set, then the prompt.
+type OpenAIProvider struct {
+	// BaseURL is the API root, e.g. "https://api.openai.com/v1"
+	BaseURL string
+	// APIKey is sent as a bearer token (empty = no Authorization header)
+	APIKey string
+	// Model is the model name sent with each request
+	Model string
+	// Instructions is sent as the system message (empty = none)
+	Instructions string
+	// Client sends the requests (nil = http.DefaultClient)
+	Client *http.Client
+}
+
+// NewOpenAIProvider returns a provider for the chat completions endpoint under baseURL
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{BaseURL: baseURL, APIKey: apiKey, Model: model}
+}
+
+// openAIRequest is a chat completions request
+type openAIRequest struct {
+	Model            string          `json:"model"`
+	Messages         []openAIMessage `json:"messages"`
+	Stream           bool            `json:"stream,omitempty"`
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+}
+
+// openAIMessage is a chat message
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIResponse is a chat completions response, or one chunk of a streamed response
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RespondWithContext generates a complete response
+func (p *OpenAIProvider) RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error) {
+	body, err := p.post(ctx, prompt, options, false)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	var response openAIResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode chat completion: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", ErrNoResponse
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// RespondStream streams a response using server-sent events
+func (p *OpenAIProvider) RespondStream(ctx context.Context, prompt string, options *GenerationOptions) (<-chan StreamChunk, error) {
+	body, err := p.post(ctx, prompt, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, streamBufferSize)
+	go func() {
+		defer close(out)
+		defer body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		finish := func(err error, reason FinishReason) {
+			if ctx.Err() != nil {
+				err, reason = ctx.Err(), FinishCancelled
+			}
+			out <- StreamChunk{Done: true, Err: err, FinishReason: reason} // The buffer has room
+		}
+
+		reason := FinishStop
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				break
+			}
+			var chunk openAIResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				finish(fmt.Errorf("failed to decode chat completion chunk: %w", err), FinishError)
+				return
+			}
+			if chunk.Error != nil {
+				finish(fmt.Errorf("%w: %s", ErrGenerationFailed, chunk.Error.Message), FinishError)
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			if chunk.Choices[0].FinishReason == "length" {
+				reason = FinishLength
+			}
+			if text := chunk.Choices[0].Delta.Content; text != "" && !send(StreamChunk{Text: text}) {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			finish(err, FinishError)
+			return
+		}
+		finish(nil, reason)
+	}()
+	return out, nil
+}
+
+// post sends a chat completions request and returns the response body, or an error for
+// a non-2xx status
+func (p *OpenAIProvider) post(ctx context.Context, prompt string, options *GenerationOptions, stream bool) (io.ReadCloser, error) {
+	request := openAIRequest{Model: p.Model, Stream: stream}
+	if p.Instructions != "" {
+		request.Messages = append(request.Messages, openAIMessage{Role: "system", Content: p.Instructions})
+	}
+	request.Messages = append(request.Messages, openAIMessage{Role: "user", Content: prompt})
+	if options != nil {
+		request.MaxTokens = options.MaxTokens
+		request.Temperature = options.Temperature
+		request.TopP = options.TopP
+		request.PresencePenalty = options.PresencePenalty
+		request.FrequencyPenalty = options.FrequencyPenalty
+		request.Stop = options.StopSequences
+		request.Seed = options.Seed
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(p.BaseURL, "/")+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var response openAIResponse
+		if json.NewDecoder(resp.Body).Decode(&response) == nil && response.Error != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrGenerationFailed, resp.Status, response.Error.Message)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrGenerationFailed, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+var _ Provider = (*OpenAIProvider)(nil)
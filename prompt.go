@@ -0,0 +1,107 @@
+package fm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fewShotExample is a single input/output pair used in a few-shot prompt
+type fewShotExample struct {
+	input  string
+	output string
+}
+
+// FewShotBuilder assembles few-shot prompts from example input/output pairs,
+// keeping the assembled prompt within a token budget
+type FewShotBuilder struct {
+	// Preamble is optional text placed before the examples (e.g. a task description)
+	Preamble string
+	// InputLabel and OutputLabel prefix each example's input and output lines
+	InputLabel  string
+	OutputLabel string
+	// TokenBudget is the maximum estimated tokens for the assembled prompt (0 = context limit)
+	TokenBudget int
+
+	examples []fewShotExample
+}
+
+// NewFewShotBuilder creates a few-shot prompt builder with "Input"/"Output" labels
+func NewFewShotBuilder() *FewShotBuilder {
+	return &FewShotBuilder{
+		InputLabel:  "Input",
+		OutputLabel: "Output",
+	}
+}
+
+// AddExample appends an example input/output pair
+func (b *FewShotBuilder) AddExample(input, output string) *FewShotBuilder {
+	b.examples = append(b.examples, fewShotExample{input: input, output: output})
+	return b
+}
+
+// Examples returns the number of examples added
+func (b *FewShotBuilder) Examples() int {
+	return len(b.examples)
+}
+
+// Build assembles the prompt for finalInput. If the estimated token count exceeds the
+// budget, the oldest examples are dropped (with a warning) until it fits.
+func (b *FewShotBuilder) Build(finalInput string) string {
+	return b.build(finalInput, b.budget())
+}
+
+// BuildFor assembles the prompt for finalInput, trimming examples so the prompt also
+// fits in the session's remaining context
+func (b *FewShotBuilder) BuildFor(sess *Session, finalInput string) string {
+	return b.build(finalInput, min(b.budget(), sess.GetRemainingContextTokens()))
+}
+
+// EstimateTokens returns the estimated token count of the assembled prompt for finalInput
+// using all examples
+func (b *FewShotBuilder) EstimateTokens(finalInput string) int {
+	return estimateTokens(b.assemble(b.examples, finalInput))
+}
+
+func (b *FewShotBuilder) budget() int {
+	if b.TokenBudget > 0 {
+		return b.TokenBudget
+	}
+	return MAX_CONTEXT_SIZE
+}
+
+func (b *FewShotBuilder) build(finalInput string, budget int) string {
+	examples := b.examples
+	prompt := b.assemble(examples, finalInput)
+	for len(examples) > 0 && estimateTokens(prompt) > budget {
+		examples = examples[1:]
+		prompt = b.assemble(examples, finalInput)
+	}
+
+	tokens := estimateTokens(prompt)
+	if dropped := len(b.examples) - len(examples); dropped > 0 {
+		logger().Warn("Few-shot prompt exceeded token budget, dropped oldest examples",
+			"dropped", dropped,
+			"kept", len(examples),
+			"tokens", tokens,
+			"budget", budget)
+	}
+	if tokens > budget {
+		logger().Warn("Few-shot prompt exceeds token budget even without examples",
+			"tokens", tokens,
+			"budget", budget)
+	}
+	return prompt
+}
+
+func (b *FewShotBuilder) assemble(examples []fewShotExample, finalInput string) string {
+	var sb strings.Builder
+	if b.Preamble != "" {
+		sb.WriteString(strings.TrimSpace(b.Preamble))
+		sb.WriteString("\n\n")
+	}
+	for _, ex := range examples {
+		fmt.Fprintf(&sb, "%s: %s\n%s: %s\n\n", b.InputLabel, ex.input, b.OutputLabel, ex.output)
+	}
+	fmt.Fprintf(&sb, "%s: %s\n%s:", b.InputLabel, finalInput, b.OutputLabel)
+	return sb.String()
+}
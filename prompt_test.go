@@ -0,0 +1,114 @@
+package fm
+
+import "testing"
+
+func TestFewShotBuilderBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  func() *FewShotBuilder
+		input    string
+		want     string
+		wantKept int
+	}{
+		{
+			name:    "no examples",
+			builder: NewFewShotBuilder,
+			input:   "2+2",
+			want:    "Input: 2+2\nOutput:",
+		},
+		{
+			name: "examples and preamble",
+			builder: func() *FewShotBuilder {
+				b := NewFewShotBuilder().AddExample("1+1", "2").AddExample("2+3", "5")
+				b.Preamble = "  Add the numbers.\n"
+				return b
+			},
+			input:    "2+2",
+			want:     "Add the numbers.\n\nInput: 1+1\nOutput: 2\n\nInput: 2+3\nOutput: 5\n\nInput: 2+2\nOutput:",
+			wantKept: 2,
+		},
+		{
+			name: "custom labels",
+			builder: func() *FewShotBuilder {
+				b := NewFewShotBuilder().AddExample("bonjour", "hello")
+				b.InputLabel, b.OutputLabel = "French", "English"
+				return b
+			},
+			input:    "merci",
+			want:     "French: bonjour\nEnglish: hello\n\nFrench: merci\nEnglish:",
+			wantKept: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.builder()
+			if got := b.Build(tt.input); got != tt.want {
+				t.Errorf("Build() = %q, want %q", got, tt.want)
+			}
+			if got := b.Examples(); got != tt.wantKept {
+				t.Errorf("Examples() = %d, want %d", got, tt.wantKept)
+			}
+			if got, want := b.EstimateTokens(tt.input), estimateTokens(tt.want); got != want {
+				t.Errorf("EstimateTokens() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestFewShotBuilderBudget(t *testing.T) {
+	newBuilder := func(budget int) *FewShotBuilder {
+		b := NewFewShotBuilder().
+			AddExample("the first example input", "the first example output").
+			AddExample("the second example input", "the second example output").
+			AddExample("the third example input", "the third example output")
+		b.TokenBudget = budget
+		return b
+	}
+	const input = "the final input"
+	base := newBuilder(0)
+	all := base.assemble(base.examples, input)
+	lastTwo := base.assemble(base.examples[1:], input)
+	lastOne := base.assemble(base.examples[2:], input)
+	none := base.assemble(nil, input)
+
+	tests := []struct {
+		name   string
+		budget int
+		want   string
+	}{
+		{name: "no budget", budget: 0, want: all},
+		{name: "fits", budget: estimateTokens(all), want: all},
+		{name: "drops oldest", budget: estimateTokens(all) - 1, want: lastTwo},
+		{name: "keeps newest", budget: estimateTokens(lastOne), want: lastOne},
+		{name: "drops all", budget: estimateTokens(lastOne) - 1, want: none},
+		{name: "over budget without examples", budget: 1, want: none},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuilder(tt.budget)
+			if got := b.Build(input); got != tt.want {
+				t.Errorf("Build() = %q, want %q", got, tt.want)
+			}
+			if b.Examples() != 3 {
+				t.Errorf("Build() removed examples from the builder: %d left", b.Examples())
+			}
+		})
+	}
+}
+
+func TestFewShotBuilderBuildFor(t *testing.T) {
+	b := NewFewShotBuilder().AddExample("a long example input for the model", "a long example output for the model")
+	const input = "the final input"
+	none := b.assemble(nil, input)
+
+	sess := newTestSession()
+	sess.maxContextSize = estimateTokens(none)
+	if got := b.BuildFor(sess, input); got != none {
+		t.Errorf("BuildFor() = %q, want the examples dropped to fit the session: %q", got, none)
+	}
+
+	sess.maxContextSize = MAX_CONTEXT_SIZE
+	if got, want := b.BuildFor(sess, input), b.Build(input); got != want {
+		t.Errorf("BuildFor() = %q, want %q", got, want)
+	}
+}
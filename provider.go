@@ -0,0 +1,151 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Provider generates responses to prompts. *Session implements it, as do
+// SessionProvider and OpenAIProvider, so an app can write against Provider and choose a
+// backend at runtime, or combine several with Fallback.
+type Provider interface {
+	// RespondWithContext generates a complete response
+	RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error)
+	// RespondStream streams a response; the final value has Done set and carries any error
+	RespondStream(ctx context.Context, prompt string, options *GenerationOptions) (<-chan StreamChunk, error)
+}
+
+// SessionProvider is a Provider backed by a Foundation Models session that is created on
+// first use. Unlike NewSession, which returns nil on devices that can't run the model,
+// it reports why the model is unavailable as an error matching ErrModelUnavailable, and
+// tries again on the next call, so it can be the primary provider of a Fallback.
+type SessionProvider struct {
+	instructions string
+	opts         []SessionOption
+
+	mu      sync.Mutex
+	session *Session
+}
+
+// NewSessionProvider returns a provider whose session is created on first use with the
+// given system instructions (empty for none) and options
+func NewSessionProvider(instructions string, opts ...SessionOption) *SessionProvider {
+	return &SessionProvider{instructions: instructions, opts: opts}
+}
+
+// Session returns the provider's session, creating it if needed
+func (p *SessionProvider) Session() (*Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.session != nil {
+		return p.session, nil
+	}
+	if err := Init(); err != nil {
+		return nil, err
+	}
+	if err := GetModelAvailability().Err(); err != nil {
+		return nil, err
+	}
+
+	if p.instructions != "" {
+		p.session = NewSessionWithInstructions(p.instructions, p.opts...)
+	} else {
+		p.session = NewSession(p.opts...)
+	}
+	if p.session == nil {
+		return nil, ErrInvalidSession
+	}
+	return p.session, nil
+}
+
+// RespondWithContext generates a complete response with the provider's session
+func (p *SessionProvider) RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error) {
+	s, err := p.Session()
+	if err != nil {
+		return "", err
+	}
+	return s.RespondWithContext(ctx, prompt, options)
+}
+
+// RespondStream streams a response from the provider's session
+func (p *SessionProvider) RespondStream(ctx context.Context, prompt string, options *GenerationOptions) (<-chan StreamChunk, error) {
+	s, err := p.Session()
+	if err != nil {
+		return nil, err
+	}
+	return s.RespondStream(ctx, prompt, options)
+}
+
+// Release releases the provider's session, if it was created. A later call creates a
+// new one.
+func (p *SessionProvider) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.session != nil {
+		p.session.Release()
+		p.session = nil
+	}
+}
+
+// fallbackProvider tries its providers in order (see Fallback)
+type fallbackProvider struct {
+	providers []Provider
+}
+
+// Fallback returns a Provider that tries primary and then each secondary provider in
+// turn until one succeeds, e.g. Foundation Models first and a remote model when the
+// device is ineligible:
+//
+//	provider := fm.Fallback(
+//		fm.NewSessionProvider(instructions),
+//		fm.NewOpenAIProvider("https://api.openai.com/v1", apiKey, "gpt-4o-mini"),
+//	)
+//
+// A provider is skipped when it fails for any reason except a guardrail refusal, which
+// is an answer rather than a failure, or ctx being done. If all fail, the returned error
+// joins their errors. Streams fall back only if a provider fails to start one; once the
+// stream has started, its errors are delivered on the stream.
+func Fallback(primary Provider, secondary ...Provider) Provider {
+	return &fallbackProvider{providers: append([]Provider{primary}, secondary...)}
+}
+
+// RespondWithContext returns the first successful response
+func (f *fallbackProvider) RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error) {
+	var errs []error
+	for i, provider := range f.providers {
+		response, err := provider.RespondWithContext(ctx, prompt, options)
+		if err == nil || !shouldFallBack(ctx, err) {
+			return response, err
+		}
+		logger().Info("Provider failed", "provider", i, "error", err)
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
+}
+
+// RespondStream returns the first stream that starts
+func (f *fallbackProvider) RespondStream(ctx context.Context, prompt string, options *GenerationOptions) (<-chan StreamChunk, error) {
+	var errs []error
+	for i, provider := range f.providers {
+		stream, err := provider.RespondStream(ctx, prompt, options)
+		if err == nil || !shouldFallBack(ctx, err) {
+			return stream, err
+		}
+		logger().Info("Provider failed to start stream", "provider", i, "error", err)
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// shouldFallBack reports whether err is a failure another provider might not have
+func shouldFallBack(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrGuardrailViolation)
+}
+
+var (
+	_ Provider = (*Session)(nil)
+	_ Provider = (*SessionProvider)(nil)
+)
@@ -0,0 +1,149 @@
+package fm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a token bucket limiting how often generations may start
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second
+	tokens float64
+	last   time.Time
+}
+
+var (
+	// Global generation rate limiter (nil = unlimited)
+	rateLimitMu sync.Mutex
+	rateLimiter *tokenBucket
+
+	// rateLimitNow returns the time for refilling token buckets, replaceable for
+	// deterministic limits
+	rateLimitNow = time.Now
+)
+
+// SetGlobalRateLimit throttles generation starts across all sessions to perSecond using
+// a token bucket (with a burst of one), protecting the device from thermal throttling
+// during batch jobs. Calls block until a token is available; the context-aware methods
+// stop waiting when their context is done. Zero or a negative rate removes the limit.
+func SetGlobalRateLimit(perSecond float64) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	if perSecond <= 0 {
+		logger().Debug("Removing global rate limit")
+		rateLimiter = nil
+		return
+	}
+	logger().Debug("Setting global rate limit", "per_second", perSecond)
+	rateLimiter = &tokenBucket{rate: perSecond, tokens: 1, last: rateLimitNow()}
+}
+
+// RateLimiter throttles a session's generations (see WithRateLimit). *rate.Limiter from
+// golang.org/x/time/rate implements it.
+type RateLimiter interface {
+	// Wait blocks until a generation may start or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimit throttles the session's generations with limiter, e.g. so a background
+// batch job doesn't starve interactive requests on the same machine:
+//
+//	fm.NewSession(fm.WithRateLimit(rate.NewLimiter(rate.Every(5*time.Second), 1)))
+//
+// Sessions may share a limiter to throttle them together. It applies on top of the
+// global rate limit and scheduler, and a throttled generation waits for it before
+// queueing for the scheduler, so it never holds a slot others could use.
+func WithRateLimit(limiter RateLimiter) SessionOption {
+	return func(s *Session) {
+		s.limiter = limiter
+	}
+}
+
+// waitToStart blocks until the session's rate limit, the global scheduler and the global
+// rate limit all let a generation start, or ctx is done. It fails immediately while the
+// circuit breaker is open. The returned function must be
+// called once the generation finishes.
+func (s *Session) waitToStart(ctx context.Context) (release func(), err error) {
+	if err := checkCircuitBreaker(); err != nil {
+		return nil, err
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	slot, err := waitForScheduler(ctx, s.priority)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitForRateLimit(ctx, true); err != nil {
+		slot.release()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.schedulerSlot = slot
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		if s.schedulerSlot == slot {
+			s.schedulerSlot = nil
+		}
+		s.mu.Unlock()
+		slot.release()
+	}, nil
+}
+
+// waitForRateLimit blocks until the global rate limit allows a generation to start or ctx
+// is done. If take is false the token is left in the bucket, so a caller can wait for
+// availability (respecting its context) before a generation that takes the token itself.
+func waitForRateLimit(ctx context.Context, take bool) error {
+	rateLimitMu.Lock()
+	bucket := rateLimiter
+	rateLimitMu.Unlock()
+	if bucket == nil {
+		return nil
+	}
+
+	waited := false
+	for {
+		wait := bucket.reserve(take)
+		if wait == 0 {
+			return nil
+		}
+		if !waited {
+			logger().Debug("Waiting for global rate limit", "wait", wait)
+			waited = true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token (if take is set) and returns 0 if one is available, or the
+// time until the next token otherwise
+func (b *tokenBucket) reserve(take bool) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := rateLimitNow()
+	b.tokens = min(1, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	start := time.Date(2025, time.June, 9, 12, 0, 0, 0, time.UTC)
+	now := start
+	saved := rateLimitNow
+	rateLimitNow = func() time.Time { return now }
+	t.Cleanup(func() { rateLimitNow = saved })
+
+	// Two generations per second with a burst of one
+	b := &tokenBucket{rate: 2, tokens: 1, last: start}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		take    bool
+		want    time.Duration
+	}{
+		{name: "peek at full bucket", take: false, want: 0},
+		{name: "take burst token", take: true, want: 0},
+		{name: "empty", take: true, want: 500 * time.Millisecond},
+		{name: "partly refilled", advance: 200 * time.Millisecond, take: true, want: 300 * time.Millisecond},
+		{name: "peek when refilled", advance: 300 * time.Millisecond, take: false, want: 0},
+		{name: "take refilled token", take: true, want: 0},
+		{name: "burst is capped at one", advance: 10 * time.Second, take: true, want: 0},
+		{name: "empty after cap", take: true, want: 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if got := b.reserve(step.take); got != step.want {
+			t.Fatalf("%s: reserve(%v) = %v, want %v", step.name, step.take, got, step.want)
+		}
+	}
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	t.Cleanup(func() { SetGlobalRateLimit(0) })
+
+	SetGlobalRateLimit(0)
+	if err := waitForRateLimit(context.Background(), true); err != nil {
+		t.Fatalf("waitForRateLimit() without a limit error = %v", err)
+	}
+
+	// One generation a minute: the first starts immediately, the second must wait
+	SetGlobalRateLimit(1.0 / 60)
+	if err := waitForRateLimit(context.Background(), true); err != nil {
+		t.Fatalf("first waitForRateLimit() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := waitForRateLimit(ctx, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second waitForRateLimit() error = %v, want context.DeadlineExceeded", err)
+	}
+}
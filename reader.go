@@ -0,0 +1,63 @@
+package fm
+
+import (
+	"context"
+	"io"
+)
+
+// streamReader exposes a channel-based stream as an io.ReadCloser
+type streamReader struct {
+	chunks <-chan StreamChunk
+	cancel context.CancelFunc
+	buf    []byte
+	err    error // Returned once buf is drained (io.EOF at the end of the response)
+}
+
+// RespondReader generates a response and exposes it as a byte stream, so it can be
+// piped into io.Copy, an http.ResponseWriter or a bufio.Scanner as it is generated,
+// without buffering the whole answer. Generation errors, including ctx being done, are
+// returned by Read. Close cancels generation if it is still running and must be called.
+func (s *Session) RespondReader(ctx context.Context, prompt string, options *GenerationOptions) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, err := s.RespondStream(ctx, prompt, options)
+	if err != nil {
+		cancel()
+		return &streamReader{cancel: cancel, err: err}
+	}
+	return &streamReader{chunks: chunks, cancel: cancel}
+}
+
+// Read reads generated text, waiting for the model to produce more if none is buffered
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk, ok := <-r.chunks
+		switch {
+		case !ok:
+			r.err = io.EOF
+		case chunk.Err != nil:
+			r.err = chunk.Err
+		default:
+			r.buf = []byte(chunk.Text)
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close cancels generation if it is still running and waits for the stream to end, so
+// the session is free for the next call
+func (r *streamReader) Close() error {
+	r.cancel()
+	if r.chunks != nil {
+		for range r.chunks {
+		}
+		r.chunks = nil
+	}
+	r.buf = nil
+	r.err = io.ErrClosedPipe
+	return nil
+}
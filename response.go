@@ -0,0 +1,109 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// FinishReason explains why generation ended, so a truncated answer can be told apart
+// from a complete one
+type FinishReason string
+
+const (
+	// FinishStop means the model finished its answer
+	FinishStop FinishReason = "stop"
+	// FinishLength means generation reached GenerationOptions.MaxTokens, so the answer is
+	// probably truncated and may be worth continuing
+	FinishLength FinishReason = "length"
+	// FinishStopSequence means generation reached one of GenerationOptions.StopSequences
+	FinishStopSequence FinishReason = "stop_sequence"
+	// FinishGuardrail means the model refused for safety reasons (see ErrGuardrailViolation)
+	FinishGuardrail FinishReason = "guardrail"
+	// FinishCancelled means generation was cancelled, stopped or timed out
+	FinishCancelled FinishReason = "cancelled"
+	// FinishError means generation failed for another reason
+	FinishError FinishReason = "error"
+)
+
+// finishReason determines why a generation that produced text ended, given its error
+// and whether it reached a stop sequence
+func finishReason(err error, stopped bool, text string, options *GenerationOptions) FinishReason {
+	switch {
+	case errors.Is(err, ErrGuardrailViolation):
+		return FinishGuardrail
+	case errors.Is(err, ErrGenerationCancelled),
+		errors.Is(err, ErrStreamStopped),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return FinishCancelled
+	case err != nil:
+		return FinishError
+	case stopped:
+		return FinishStopSequence
+	case options != nil && options.MaxTokens != nil && estimateTokens(text) >= *options.MaxTokens:
+		return FinishLength
+	default:
+		return FinishStop
+	}
+}
+
+// Response is a generated response with the numbers integrations need for display and
+// accounting. Token counts are estimates (see CountTokens).
+type Response struct {
+	// Text is the generated text
+	Text string
+	// PromptTokens is the size of the prompt, not counting the conversation before it
+	PromptTokens int
+	// CompletionTokens is the size of the generated text
+	CompletionTokens int
+	// FirstTokenLatency is the time from the request to the first generated text
+	FirstTokenLatency time.Duration
+	// Duration is the time from the request to the end of generation
+	Duration time.Duration
+	// FinishReason explains why generation ended
+	FinishReason FinishReason
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (r *Response) TotalTokens() int {
+	return r.PromptTokens + r.CompletionTokens
+}
+
+// RespondDetailed generates a response like RespondWithContext, returning it with token
+// counts, timing and the reason generation ended. The response is streamed from the
+// shim to time the first token. If generation fails after it started, the partial
+// response is returned along with the error.
+func (s *Session) RespondDetailed(ctx context.Context, prompt string, options *GenerationOptions) (*Response, error) {
+	start := time.Now()
+	chunks, _, err := s.startStream(ctx, prompt, options, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{PromptTokens: estimateTokens(prompt)}
+	var text strings.Builder
+	for chunk := range chunks {
+		if chunk.Text != "" && text.Len() == 0 {
+			response.FirstTokenLatency = time.Since(start)
+		}
+		text.WriteString(chunk.Text)
+		if chunk.Done {
+			err = chunk.Err
+			response.FinishReason = chunk.FinishReason
+		}
+	}
+	response.Duration = time.Since(start)
+	response.Text = text.String()
+	response.CompletionTokens = estimateTokens(response.Text)
+
+	logger().Debug("Detailed response completed",
+		"prompt_tokens", response.PromptTokens,
+		"completion_tokens", response.CompletionTokens,
+		"first_token_latency", response.FirstTokenLatency,
+		"duration", response.Duration,
+		"finish_reason", response.FinishReason)
+
+	return response, err
+}
@@ -0,0 +1,163 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how failed generations are retried, using exponential backoff
+// with optional jitter so concurrent callers don't retry in lockstep
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first (values < 1 mean 1)
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+	// Multiplier grows the backoff after each retry (values < 1 mean 1, a fixed backoff)
+	Multiplier float64
+	// MaxBackoff caps the backoff (0 = no cap)
+	MaxBackoff time.Duration
+	// Jitter randomizes each wait between half and all of the computed backoff
+	Jitter bool
+	// Retryable reports whether an error should be retried (nil = IsRetryable)
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns a conservative policy: 3 attempts, backoff from 500ms
+// doubling up to 5s, with jitter
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		Multiplier:     2,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         true,
+	}
+}
+
+// AggressiveRetryPolicy returns a policy for latency-sensitive callers: 5 attempts,
+// backoff from 100ms growing 1.5x up to 2s, with jitter
+func AggressiveRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		Multiplier:     1.5,
+		MaxBackoff:     2 * time.Second,
+		Jitter:         true,
+	}
+}
+
+// Retry timing hooks, replaceable for deterministic timing
+var (
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	retryRandFloat = rand.Float64
+)
+
+// Backoff returns the wait before the given retry (1 = first retry), without jitter
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := max(p.Multiplier, 1)
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	if backoff > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(backoff)
+}
+
+// wait returns the jittered wait before the given retry
+func (p RetryPolicy) wait(retry int) time.Duration {
+	backoff := p.Backoff(retry)
+	if !p.Jitter || backoff <= 0 {
+		return backoff
+	}
+	half := backoff / 2
+	return half + time.Duration(retryRandFloat()*float64(backoff-half))
+}
+
+// IsRetryable reports whether an error is worth retrying. Context limit errors,
+// guardrail violations, exceeded tool budgets, cancellation and timeouts are not, since
+// retrying would fail the same way.
+func IsRetryable(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrContextLimit),
+		errors.Is(err, ErrContextExceeded),
+		errors.Is(err, ErrInstructionsTooLong),
+		errors.Is(err, ErrGuardrailViolation),
+		errors.Is(err, ErrToolBudgetExceeded),
+		errors.Is(err, ErrGenerationCancelled),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	default:
+		return true
+	}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempts are
+// exhausted or ctx is done, waiting between attempts according to the policy.
+// It returns the last error.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil || !retryable(err) || attempt == attempts {
+			return err
+		}
+
+		wait := p.wait(attempt)
+		logger().Info("Retrying after error",
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"wait", wait,
+			"error", err)
+		if sleepErr := retrySleep(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// WithRetry retries the session's failed generations according to policy, so every
+// respond call gets the retries without wrapping it (streams are not retried)
+func WithRetry(policy RetryPolicy) SessionOption {
+	return func(s *Session) {
+		s.retryPolicy = &policy
+	}
+}
+
+// RespondWithRetry calls RespondWithContext, retrying failures according to policy. The
+// policy replaces the session's own (see WithRetry) for this call.
+func (s *Session) RespondWithRetry(ctx context.Context, prompt string, options *GenerationOptions, policy RetryPolicy) (string, error) {
+	return s.serve(ctx, generationText, prompt, options, "",
+		func(ctx context.Context, req *Request) (string, error) {
+			var response string
+			err := policy.Do(ctx, func(ctx context.Context) error {
+				var err error
+				response, err = s.respondOnce(ctx, req.kind, req.Prompt, req.Options, req.Schema)
+				return err
+			})
+			return response, err
+		})
+}
@@ -0,0 +1,215 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeRetryClock replaces the retry timing hooks for the duration of a test, recording
+// each sleep instead of waiting
+func fakeRetryClock(t *testing.T, rand float64) *[]time.Duration {
+	t.Helper()
+	var slept []time.Duration
+	origSleep, origRand := retrySleep, retryRandFloat
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return ctx.Err()
+	}
+	retryRandFloat = func() float64 { return rand }
+	t.Cleanup(func() { retrySleep, retryRandFloat = origSleep, origRand })
+	return &slept
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration // Backoff for retries 1, 2, ...
+	}{
+		{
+			name:   "exponential",
+			policy: RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2, MaxBackoff: 300 * time.Millisecond},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+		{
+			name:   "fixed",
+			policy: RetryPolicy{InitialBackoff: 50 * time.Millisecond, Multiplier: 0.5},
+			want:   []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond},
+		},
+		{
+			name:   "no backoff",
+			policy: RetryPolicy{Multiplier: 2},
+			want:   []time.Duration{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(0); got != 0 {
+				t.Errorf("Backoff(0) = %v, want 0", got)
+			}
+			for i, want := range tt.want {
+				if got := tt.policy.Backoff(i + 1); got != want {
+					t.Errorf("Backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffOverflow(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, Multiplier: 10}
+	if got := p.Backoff(100); got != time.Duration(math.MaxInt64) {
+		t.Errorf("Backoff(100) = %v, want the maximum duration", got)
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2, Jitter: true}
+
+	tests := []struct {
+		name string
+		rand float64
+		want time.Duration
+	}{
+		{name: "lowest", rand: 0, want: 100 * time.Millisecond},
+		{name: "middle", rand: 0.5, want: 150 * time.Millisecond},
+		{name: "highest", rand: 1, want: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRetryClock(t, tt.rand)
+			// The second retry backs off 200ms, jittered into [100ms, 200ms]
+			if got := p.wait(2); got != tt.want {
+				t.Errorf("wait(2) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	errBusy := errors.New("model busy")
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, Multiplier: 2}
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      []error // Errors returned by successive attempts, nil after the last
+		wantErr   error
+		wantCalls int
+		wantSlept []time.Duration
+	}{
+		{name: "succeeds first time", policy: policy, wantCalls: 1},
+		{
+			name:      "succeeds after retries",
+			policy:    policy,
+			errs:      []error{errBusy, errBusy},
+			wantCalls: 3,
+			wantSlept: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			name:      "attempts exhausted",
+			policy:    policy,
+			errs:      []error{errBusy, errBusy, errBusy, errBusy},
+			wantErr:   errBusy,
+			wantCalls: 3,
+			wantSlept: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			name:      "not retryable",
+			policy:    policy,
+			errs:      []error{ErrGuardrailViolation},
+			wantErr:   ErrGuardrailViolation,
+			wantCalls: 1,
+		},
+		{
+			name:      "custom retryable",
+			policy:    RetryPolicy{MaxAttempts: 3, Retryable: func(err error) bool { return !errors.Is(err, errBusy) }},
+			errs:      []error{errBusy},
+			wantErr:   errBusy,
+			wantCalls: 1,
+		},
+		{
+			name:      "zero attempts means one",
+			policy:    RetryPolicy{},
+			errs:      []error{errBusy},
+			wantErr:   errBusy,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slept := fakeRetryClock(t, 0)
+
+			calls := 0
+			err := tt.policy.Do(context.Background(), func(context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do() made %d attempts, want %d", calls, tt.wantCalls)
+			}
+			if !slices.Equal(*slept, tt.wantSlept) {
+				t.Errorf("Do() slept %v, want %v", *slept, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoContextDone(t *testing.T) {
+	fakeRetryClock(t, 0)
+	errBusy := errors.New("model busy")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second}.Do(ctx, func(context.Context) error {
+		calls++
+		return errBusy
+	})
+	// The wait is cut short and the last attempt's error is returned
+	if !errors.Is(err, errBusy) || calls != 1 {
+		t.Errorf("Do() = %v after %d attempts, want %v after 1", err, calls, errBusy)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("model busy"), want: true},
+		{err: ErrModelBecameUnavailable, want: true},
+		{err: ErrContextLimit, want: false},
+		{err: ErrContextExceeded, want: false},
+		{err: ErrInstructionsTooLong, want: false},
+		{err: ErrGuardrailViolation, want: false},
+		{err: ErrToolBudgetExceeded, want: false},
+		{err: ErrGenerationCancelled, want: false},
+		{err: context.Canceled, want: false},
+		{err: fmt.Errorf("generation failed: %w", context.DeadlineExceeded), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
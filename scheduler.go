@@ -0,0 +1,265 @@
+package fm
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority is the scheduling class of a session's generations (see SetGlobalScheduler)
+type Priority int
+
+const (
+	// PriorityInteractive is for generations a user is waiting on. It is the default.
+	PriorityInteractive Priority = iota
+	// PriorityBatch is for background work. Queued batch generations only start when no
+	// interactive generation is waiting.
+	PriorityBatch
+)
+
+// String returns the name of the priority class
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	default:
+		return "unknown"
+	}
+}
+
+// WithPriority sets the scheduling class of the session's generations
+func WithPriority(p Priority) SessionOption {
+	return func(s *Session) {
+		s.priority = p
+	}
+}
+
+// SchedulerStats is a snapshot of the global scheduler's load
+type SchedulerStats struct {
+	// MaxInFlight is the configured concurrency limit (0 = no scheduler)
+	MaxInFlight int
+	// InFlight is the number of generations running
+	InFlight int
+	// QueuedInteractive is the number of interactive generations waiting to start
+	QueuedInteractive int
+	// QueuedBatch is the number of batch generations waiting to start
+	QueuedBatch int
+}
+
+// Queued returns the number of generations waiting to start
+func (s SchedulerStats) Queued() int {
+	return s.QueuedInteractive + s.QueuedBatch
+}
+
+// scheduler limits how many generations run at once, starting queued ones by priority
+// and then in arrival order
+type scheduler struct {
+	mu       sync.Mutex
+	max      int
+	inFlight int
+	queues   [PriorityBatch + 1][]chan struct{} // Waiters by priority, closed when started
+}
+
+var (
+	// Global generation scheduler (nil = unlimited)
+	schedulerMu     sync.Mutex
+	globalScheduler *scheduler
+)
+
+// SetGlobalScheduler limits the number of generations running at once across all
+// sessions to maxInFlight, since the on-device model handles little concurrency and
+// extra requests only slow each other down. Further generations queue, interactive ones
+// ahead of batch ones (see WithPriority), and start as others finish. The
+// context-aware methods stop waiting when their context is done. A generation gives up
+// its slot while its tools run, so tools such as a SessionTool can start generations of
+// their own. Zero or a negative
+// limit removes the scheduler; generations already queued still start as others finish.
+func SetGlobalScheduler(maxInFlight int) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+
+	if maxInFlight <= 0 {
+		logger().Debug("Removing global scheduler")
+		globalScheduler = nil
+		return
+	}
+	if globalScheduler != nil {
+		logger().Debug("Changing global scheduler limit", "max_in_flight", maxInFlight)
+		globalScheduler.setMax(maxInFlight)
+		return
+	}
+	logger().Debug("Setting global scheduler", "max_in_flight", maxInFlight)
+	globalScheduler = &scheduler{max: maxInFlight}
+}
+
+// GetSchedulerStats returns the global scheduler's current load, e.g. to export queue
+// depth as a metric
+func GetSchedulerStats() SchedulerStats {
+	schedulerMu.Lock()
+	sched := globalScheduler
+	schedulerMu.Unlock()
+	if sched == nil {
+		return SchedulerStats{}
+	}
+
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+	return SchedulerStats{
+		MaxInFlight:       sched.max,
+		InFlight:          sched.inFlight,
+		QueuedInteractive: len(sched.queues[PriorityInteractive]),
+		QueuedBatch:       len(sched.queues[PriorityBatch]),
+	}
+}
+
+// waitForScheduler blocks until the global scheduler lets a generation of the given
+// priority start, or ctx is done. The returned slot must be released once the
+// generation finishes.
+func waitForScheduler(ctx context.Context, priority Priority) (*schedulerSlot, error) {
+	schedulerMu.Lock()
+	sched := globalScheduler
+	schedulerMu.Unlock()
+	if sched == nil {
+		return &schedulerSlot{}, nil
+	}
+
+	if priority != PriorityBatch {
+		priority = PriorityInteractive
+	}
+	if err := sched.acquire(ctx, priority); err != nil {
+		return nil, err
+	}
+	return &schedulerSlot{sched: sched, priority: priority}, nil
+}
+
+// schedulerSlot is a generation's place in the global scheduler. The generation gives it
+// up while its tools run, since a tool may start generations of its own (e.g. a
+// SessionTool) that would otherwise wait for the slot forever.
+type schedulerSlot struct {
+	sched    *scheduler // nil without a scheduler
+	priority Priority
+	mu       sync.Mutex // Held while taking the slot back, so yields wait for it
+	yielded  int        // Tool calls running; the slot is given up while there are any
+	released bool
+}
+
+// yield gives up the slot while a tool call runs. The returned function takes it back
+// once no tool calls are running.
+func (s *schedulerSlot) yield() (resume func()) {
+	if s == nil || s.sched == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	s.yielded++
+	if s.yielded == 1 && !s.released {
+		s.sched.release()
+	}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.yielded--
+			if s.yielded == 0 && !s.released {
+				// The generation can't go on without its tool result, so it waits
+				// however long it takes
+				s.sched.acquire(context.Background(), s.priority)
+			}
+		})
+	}
+}
+
+// release frees the slot once the generation finishes. It is safe to call more than once.
+func (s *schedulerSlot) release() {
+	if s == nil || s.sched == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	if s.yielded == 0 {
+		s.sched.release()
+	}
+}
+
+// acquire takes a slot, queueing until one is free
+func (q *scheduler) acquire(ctx context.Context, priority Priority) error {
+	q.mu.Lock()
+	if q.inFlight < q.max && q.queued() == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.queues[priority] = append(q.queues[priority], ready)
+	logger().Debug("Queued generation", "priority", priority, "in_flight", q.inFlight, "queued", q.queued())
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, waiter := range q.queues[priority] {
+			if waiter == ready {
+				q.queues[priority] = append(q.queues[priority][:i:i], q.queues[priority][i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// Started just as ctx was done: hand the slot on
+		q.inFlight--
+		q.dispatch()
+		return ctx.Err()
+	}
+}
+
+// release frees a slot, starting the next queued generation
+func (q *scheduler) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight--
+	q.dispatch()
+}
+
+// setMax changes the limit, starting queued generations if it grew
+func (q *scheduler) setMax(maxInFlight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.max = maxInFlight
+	q.dispatch()
+}
+
+// dispatch starts queued generations while slots are free. Must be called with q.mu held.
+func (q *scheduler) dispatch() {
+	for q.inFlight < q.max {
+		started := false
+		for priority := range q.queues {
+			if len(q.queues[priority]) > 0 {
+				close(q.queues[priority][0])
+				q.queues[priority] = q.queues[priority][1:]
+				q.inFlight++
+				started = true
+				break
+			}
+		}
+		if !started {
+			return
+		}
+	}
+}
+
+// queued returns the number of waiters. Must be called with q.mu held.
+func (q *scheduler) queued() int {
+	n := 0
+	for _, waiters := range q.queues {
+		n += len(waiters)
+	}
+	return n
+}
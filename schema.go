@@ -0,0 +1,377 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Schema node types understood by the Swift shim
+const (
+	schemaObject  = "object"
+	schemaArray   = "array"
+	schemaString  = "string"
+	schemaInteger = "integer"
+	schemaNumber  = "number"
+	schemaBoolean = "boolean"
+)
+
+// schemaNode describes a generation schema as sent to the Swift shim, which builds a
+// DynamicGenerationSchema from it
+type schemaNode struct {
+	Type        string           `json:"type"`
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Properties  []schemaProperty `json:"properties,omitempty"` // object
+	Items       *schemaNode      `json:"items,omitempty"`      // array
+	MinItems    *int             `json:"minItems,omitempty"`   // array
+	MaxItems    *int             `json:"maxItems,omitempty"`   // array
+	Enum        []string         `json:"enum,omitempty"`       // string
+	Pattern     string           `json:"pattern,omitempty"`    // string
+	Minimum     *float64         `json:"minimum,omitempty"`    // integer, number
+	Maximum     *float64         `json:"maximum,omitempty"`    // integer, number
+}
+
+// schemaProperty is a named property of an object schema
+type schemaProperty struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Optional    bool        `json:"optional,omitempty"`
+	Schema      *schemaNode `json:"schema"`
+}
+
+// schemaTagKeys are the constraints accepted in fm struct tags
+var schemaTagKeys = []string{"description", "enum", "pattern", "min", "max", "minItems", "maxItems"}
+
+// schemaCache holds the encoded schema for each struct type passed to encodeSchema
+var schemaCache sync.Map // reflect.Type -> string
+
+// schemaFor builds a generation schema from a Go value, which must be a struct or a
+// pointer to one. Properties follow encoding/json naming: exported fields, named by
+// their json tag, with "-" skipped and embedded structs flattened. Pointer and omitempty
+// fields are optional. The fm tag adds constraints, separated by semicolons or commas (a
+// comma only separates when followed by a known key, so values may contain commas):
+//
+//	Name  string   `json:"name" fm:"description=The person's full name"`
+//	Age   int      `json:"age" fm:"description=Age in years;min=0;max=150"`
+//	Mood  string   `json:"mood" fm:"description=Current mood,enum=happy|sad|neutral"`
+//	Code  string   `json:"code" fm:"pattern=[A-Z]{3}-[0-9]{4}"`
+//	Tags  []string `json:"tags" fm:"minItems=1,maxItems=5"`
+func schemaFor(v any) (*schemaNode, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema must be a struct or a pointer to one, got %T", v)
+	}
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+// schemaForType builds the schema for t, rejecting recursive types via visiting
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) (*schemaNode, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &schemaNode{Type: schemaString}, nil
+	case reflect.Bool:
+		return &schemaNode{Type: schemaBoolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schemaNode{Type: schemaInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schemaNode{Type: schemaNumber}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &schemaNode{Type: schemaArray, Items: items}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return nil, fmt.Errorf("recursive type %s is not supported in a schema", t)
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		node := &schemaNode{Type: schemaObject, Name: t.Name()}
+		if node.Name == "" {
+			node.Name = "Object"
+		}
+		if err := addStructProperties(node, t, visiting); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("type %s is not supported in a schema", t)
+	}
+}
+
+// addStructProperties adds the properties for t's fields to node, flattening embedded
+// structs without a json name
+func addStructProperties(node *schemaNode, t reflect.Type, visiting map[reflect.Type]bool) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := addStructProperties(node, embedded, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, err := schemaForType(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		prop := schemaProperty{
+			Name:     name,
+			Optional: field.Type.Kind() == reflect.Pointer || strings.Contains(opts, "omitempty"),
+			Schema:   schema,
+		}
+		if err := applySchemaTag(&prop, field.Tag.Get("fm")); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		node.Properties = append(node.Properties, prop)
+	}
+	return nil
+}
+
+// applySchemaTag applies the constraints in an fm struct tag to a property
+func applySchemaTag(prop *schemaProperty, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	node := prop.Schema
+	for _, part := range splitSchemaTag(tag) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid fm tag entry %q (expected key=value)", part)
+		}
+
+		var err error
+		switch strings.TrimSpace(key) {
+		case "description":
+			prop.Description = value
+		case "enum":
+			node.Enum = strings.Split(value, "|")
+			node.Name = prop.Name // Choice schemas are named, so name them after the field
+		case "pattern":
+			node.Pattern = value
+		case "min":
+			node.Minimum, err = parseSchemaFloat(value)
+		case "max":
+			node.Maximum, err = parseSchemaFloat(value)
+		case "minItems":
+			node.MinItems, err = parseSchemaInt(value)
+		case "maxItems":
+			node.MaxItems, err = parseSchemaInt(value)
+		default:
+			return fmt.Errorf("unknown fm tag key %q", key)
+		}
+		if err != nil {
+			return fmt.Errorf("fm tag %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// splitSchemaTag splits an fm tag into key=value entries at semicolons, and at commas
+// that are followed by a known key
+func splitSchemaTag(tag string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case ';':
+		case ',':
+			if !startsWithSchemaKey(tag[i+1:]) {
+				continue
+			}
+		default:
+			continue
+		}
+		parts = append(parts, tag[start:i])
+		start = i + 1
+	}
+	return append(parts, tag[start:])
+}
+
+// startsWithSchemaKey reports whether text starts with a known fm tag key and "="
+func startsWithSchemaKey(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, key := range schemaTagKeys {
+		if strings.HasPrefix(text, key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSchemaFloat(value string) (*float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func parseSchemaInt(value string) (*int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// schemaJSON encodes a schema for the Swift shim
+func schemaJSON(node *schemaNode) (string, error) {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeSchema encodes a Go struct or *GenerationSchema for the Swift shim. Struct
+// schemas are built once per type and cached.
+func encodeSchema(schema any) (string, error) {
+	if g, ok := schema.(*GenerationSchema); ok {
+		if err := g.Err(); err != nil {
+			return "", fmt.Errorf("invalid generation schema: %w", err)
+		}
+		return schemaJSON(g.node)
+	}
+
+	t := reflect.TypeOf(schema)
+	if encoded, ok := schemaCache.Load(t); ok {
+		return encoded.(string), nil
+	}
+	node, err := schemaFor(schema)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := schemaJSON(node)
+	if err != nil {
+		return "", err
+	}
+	schemaCache.Store(t, encoded)
+	return encoded, nil
+}
+
+// RespondWithSchema generates JSON constrained to the shape of schema, which is either a
+// Go struct or a pointer to one (see the "Schema-Constrained Output" section of the
+// package documentation for the supported field types and fm tags) or a
+// *GenerationSchema built at runtime. Unlike RespondWithStructuredOutput, decoding is
+// constrained by the model, so the response always has exactly that shape.
+func (s *Session) RespondWithSchema(prompt string, schema any) (string, error) {
+	encoded, err := encodeSchema(schema)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := s.generate(generationSchema, prompt, nil, encoded)
+	if err != nil {
+		return "", err
+	}
+	return s.formatStructuredOutput(response), nil
+}
+
+// RespondWithSchemaStream generates JSON constrained to schema, as RespondWithSchema
+// does, and delivers progressively filled snapshots of the value as it is generated so
+// structured results can be rendered incrementally. Each value's JSON holds the whole
+// value so far as valid JSON, with fields appearing as they are generated, so it can be
+// decoded into a fresh value of the target type. The final value has Done set and JSON
+// holding the complete value, formatted with the session's JSON indent, or Err if
+// generation failed; if ctx is done first, generation is cancelled and Err is ctx.Err().
+// The channel is closed after the final value and must be drained.
+func (s *Session) RespondWithSchemaStream(ctx context.Context, prompt string, schema any) (<-chan StreamChunk, error) {
+	encoded, err := encodeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	in, _, err := s.startStream(ctx, prompt, nil, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, streamBufferSize)
+	go func() {
+		defer close(out)
+
+		var latest string
+		for chunk := range in {
+			if chunk.Text != "" {
+				latest = chunk.Text
+			}
+			if !chunk.Done {
+				snapshot, ok := completePartialJSON(chunk.Text)
+				if !ok {
+					continue // Nothing decodable generated yet
+				}
+				out <- StreamChunk{JSON: snapshot}
+				continue
+			}
+			chunk.Text = ""
+			if chunk.Err == nil {
+				chunk.JSON = s.formatStructuredOutput(latest)
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, nil
+}
+
+// RespondInto generates a response constrained to the type of out, which must be a
+// non-nil pointer to a struct, and decodes it into out. The schema is derived from the
+// struct as for RespondWithSchema. It returns an error wrapping ErrSchemaMismatch if the
+// response can't be decoded into out.
+func (s *Session) RespondInto(ctx context.Context, prompt string, out any) error {
+	if v := reflect.ValueOf(out); v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("RespondInto requires a non-nil pointer, got %T", out)
+	}
+	encoded, err := encodeSchema(out)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.respondWithContext(ctx, generationSchema, prompt, nil, encoded)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(response))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		logger().Debug("Structured response does not match target type",
+			"type", fmt.Sprintf("%T", out),
+			"response_preview", response[:min(50, len(response))])
+		return fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Self-test steps reported in SelfTestError
+const (
+	SelfTestStepShim         = "shim"
+	SelfTestStepAvailability = "availability"
+	SelfTestStepSession      = "session"
+	SelfTestStepGeneration   = "generation"
+)
+
+// SelfTestError reports the self-test step that failed
+type SelfTestError struct {
+	Step string
+	Err  error
+}
+
+func (e *SelfTestError) Error() string {
+	return fmt.Sprintf("self-test failed at %s: %v", e.Step, e.Err)
+}
+
+func (e *SelfTestError) Unwrap() error {
+	return e.Err
+}
+
+// selfTestPrompt is a trivial prompt with a short, predictable answer
+const selfTestPrompt = "Reply with only the word OK."
+
+// selfTestResponder is the part of a session SelfTest generates with
+type selfTestResponder interface {
+	RespondWithContext(ctx context.Context, prompt string, options *GenerationOptions) (string, error)
+	Release()
+}
+
+// Self-test hooks, replaceable so each step can fail without the shim
+var (
+	selfTestInit         = Init
+	selfTestAvailability = GetModelAvailability
+	selfTestNewSession   = func() selfTestResponder {
+		if sess := NewSession(); sess != nil {
+			return sess
+		}
+		return nil
+	}
+)
+
+// SelfTest verifies that Foundation Models is ready for use: the Swift shim loaded, the
+// model is available, and a trivial generation succeeds. It returns a *SelfTestError for
+// the first failing step, or nil. The generation honours ctx, so apps can bound the time
+// spent gating startup on readiness.
+func SelfTest(ctx context.Context) error {
+	if err := selfTestInit(); err != nil {
+		return &SelfTestError{Step: SelfTestStepShim, Err: err}
+	}
+
+	if availability := selfTestAvailability(); !availability.Available() {
+		return &SelfTestError{Step: SelfTestStepAvailability, Err: availability.Err()}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &SelfTestError{Step: SelfTestStepGeneration, Err: err}
+	}
+
+	sess := selfTestNewSession()
+	if sess == nil {
+		return &SelfTestError{Step: SelfTestStepSession, Err: errors.New("failed to create session")}
+	}
+	defer sess.Release()
+
+	maxTokens := 8
+	response, err := sess.RespondWithContext(ctx, selfTestPrompt, &GenerationOptions{MaxTokens: &maxTokens})
+	if err != nil {
+		return &SelfTestError{Step: SelfTestStepGeneration, Err: err}
+	}
+	if strings.TrimSpace(response) == "" {
+		return &SelfTestError{Step: SelfTestStepGeneration, Err: errors.New("empty response")}
+	}
+
+	logger().Debug("Self-test passed", "response", response)
+	return nil
+}
@@ -0,0 +1,152 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSelfTestError(t *testing.T) {
+	cause := errors.New("model not ready")
+	err := error(&SelfTestError{Step: SelfTestStepAvailability, Err: cause})
+
+	if got, want := err.Error(), "self-test failed at availability: model not ready"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("SelfTestError does not unwrap to its cause")
+	}
+}
+
+func TestSelfTestWithoutShim(t *testing.T) {
+	if Init() == nil {
+		t.Skip("shim is available")
+	}
+
+	err := SelfTest(context.Background())
+	var stErr *SelfTestError
+	if !errors.As(err, &stErr) {
+		t.Fatalf("SelfTest() error = %v, want a *SelfTestError", err)
+	}
+	if stErr.Step != SelfTestStepShim {
+		t.Errorf("Step = %q, want %q", stErr.Step, SelfTestStepShim)
+	}
+	if !errors.Is(err, shimInitError) {
+		t.Errorf("SelfTest() error = %v, want it to wrap %v", err, shimInitError)
+	}
+}
+
+// fakeResponder is a selfTestResponder answering with response and err
+type fakeResponder struct {
+	response string
+	err      error
+	released bool
+}
+
+func (f *fakeResponder) RespondWithContext(context.Context, string, *GenerationOptions) (string, error) {
+	return f.response, f.err
+}
+
+func (f *fakeResponder) Release() { f.released = true }
+
+func TestSelfTestSteps(t *testing.T) {
+	initErr := errors.New("shim missing")
+	generateErr := errors.New("generation failed")
+
+	tests := []struct {
+		name         string
+		initErr      error
+		availability Availability
+		responder    *fakeResponder // nil: session creation fails
+		cancelled    bool
+		wantStep     string // Empty when the self-test passes
+		wantErr      error
+	}{
+		{
+			name:         "passes",
+			availability: Availability{Status: ModelAvailable},
+			responder:    &fakeResponder{response: "OK"},
+		},
+		{
+			name:     "shim",
+			initErr:  initErr,
+			wantStep: SelfTestStepShim,
+			wantErr:  initErr,
+		},
+		{
+			name:         "availability",
+			availability: Availability{Status: ModelUnavailableNotReady},
+			wantStep:     SelfTestStepAvailability,
+			wantErr:      ErrModelNotReady,
+		},
+		{
+			name:         "cancelled",
+			availability: Availability{Status: ModelAvailable},
+			responder:    &fakeResponder{response: "OK"},
+			cancelled:    true,
+			wantStep:     SelfTestStepGeneration,
+			wantErr:      context.Canceled,
+		},
+		{
+			name:         "session",
+			availability: Availability{Status: ModelAvailable},
+			wantStep:     SelfTestStepSession,
+		},
+		{
+			name:         "generation",
+			availability: Availability{Status: ModelAvailable},
+			responder:    &fakeResponder{err: generateErr},
+			wantStep:     SelfTestStepGeneration,
+			wantErr:      generateErr,
+		},
+		{
+			name:         "empty response",
+			availability: Availability{Status: ModelAvailable},
+			responder:    &fakeResponder{response: "  "},
+			wantStep:     SelfTestStepGeneration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldInit, oldAvailability, oldNewSession := selfTestInit, selfTestAvailability, selfTestNewSession
+			t.Cleanup(func() {
+				selfTestInit, selfTestAvailability, selfTestNewSession = oldInit, oldAvailability, oldNewSession
+			})
+			selfTestInit = func(...InitOption) error { return tt.initErr }
+			selfTestAvailability = func() Availability { return tt.availability }
+			selfTestNewSession = func() selfTestResponder {
+				if tt.responder == nil {
+					return nil
+				}
+				return tt.responder
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			err := SelfTest(ctx)
+			if tt.wantStep == "" {
+				if err != nil {
+					t.Fatalf("SelfTest() error = %v, want nil", err)
+				}
+			} else {
+				var stErr *SelfTestError
+				if !errors.As(err, &stErr) {
+					t.Fatalf("SelfTest() error = %v, want a *SelfTestError", err)
+				}
+				if stErr.Step != tt.wantStep {
+					t.Errorf("Step = %q, want %q", stErr.Step, tt.wantStep)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("SelfTest() error = %v, want it to wrap %v", err, tt.wantErr)
+				}
+			}
+			if tt.responder != nil && !tt.cancelled && !tt.responder.released {
+				t.Error("SelfTest() did not release its session")
+			}
+		})
+	}
+}
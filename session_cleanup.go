@@ -0,0 +1,55 @@
+package fm
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ebitengine/purego"
+)
+
+// sessionLeakDebug records where sessions are created (see SetSessionLeakDebug)
+var sessionLeakDebug atomic.Bool
+
+// SetSessionLeakDebug makes the package log a warning, with the stack that created it,
+// for every session that is garbage collected without being released. Sessions are
+// released when collected either way, but only after an unpredictable delay, so call
+// Release as soon as a session is no longer needed. Recording stacks slows session
+// creation, so enable it only while hunting leaks. It applies to sessions created
+// afterwards.
+func SetSessionLeakDebug(enabled bool) {
+	sessionLeakDebug.Store(enabled)
+}
+
+// collectedSession is what a session's cleanup needs once the session itself is gone
+type collectedSession struct {
+	ptr     uintptr
+	created []uintptr // Stack of the creating call, recorded in leak debug mode
+}
+
+// addSessionCleanup releases the session's shim-side session if it is garbage collected
+// before Release is called
+func addSessionCleanup(s *Session) {
+	info := collectedSession{ptr: uintptr(s.ptr)}
+	if sessionLeakDebug.Load() {
+		info.created = callers(2)
+	}
+	s.cleanup = runtime.AddCleanup(s, releaseCollectedSession, info)
+}
+
+// releaseCollectedSession releases a session that was garbage collected without Release
+func releaseCollectedSession(info collectedSession) {
+	sessionsMu.Lock()
+	delete(liveSessions, info.ptr)
+	sessionsMu.Unlock()
+	trackFree(info.ptr)
+
+	if Init() == nil {
+		purego.SyscallN(releaseSession, info.ptr)
+	}
+
+	if info.created == nil {
+		logger().Debug("Released session that was collected without Release", "ptr", info.ptr)
+		return
+	}
+	logger().Warn("Session was garbage collected without Release", "ptr", info.ptr, "created", formatStack(info.created))
+}
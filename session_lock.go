@@ -0,0 +1,52 @@
+package fm
+
+import (
+	"context"
+
+	"github.com/ebitengine/purego"
+)
+
+// lock waits until no other generation is running on the session, or ctx is done.
+// Generations and tool changes hold the lock for their whole duration, so the shim never
+// sees overlapping requests on one LanguageModelSession.
+func (s *Session) lock(ctx context.Context) error {
+	if s.busy == nil {
+		return ErrInvalidSession
+	}
+	select {
+	case s.busy <- struct{}{}:
+		return nil
+	default:
+	}
+
+	logger().Debug("Waiting for in-flight generation on session")
+	select {
+	case s.busy <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlock releases the lock taken by lock
+func (s *Session) unlock() {
+	<-s.busy
+}
+
+// IsResponding reports whether a generation is in flight on the session, mirroring the
+// framework session's isResponding. It is also true while another call (a generation
+// still being set up, or a tool change) holds the session, so UIs can disable input
+// until a new request would start immediately rather than wait.
+func (s *Session) IsResponding() bool {
+	if s.ptr == nil || s.busy == nil {
+		return false
+	}
+	if len(s.busy) > 0 {
+		return true
+	}
+	if Init() != nil || isResponding == 0 {
+		return false
+	}
+	responding, _, _ := purego.SyscallN(isResponding, uintptr(s.ptr))
+	return responding != 0
+}
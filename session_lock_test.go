@@ -0,0 +1,127 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestSessionLockSerializesCallers(t *testing.T) {
+	sess := newTestSession()
+
+	const callers = 20
+	counter := 0 // Guarded only by the session lock, so -race flags any overlap
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sess.lock(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			defer sess.unlock()
+			counter++
+		}()
+	}
+	wg.Wait()
+
+	if counter != callers {
+		t.Errorf("counter = %d, want %d", counter, callers)
+	}
+}
+
+func TestSessionLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		held    bool
+		noBusy  bool
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{name: "free", ctx: background},
+		{name: "released session", noBusy: true, ctx: background, wantErr: ErrInvalidSession},
+		{
+			name: "held until timeout",
+			held: true,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "held and cancelled",
+			held: true,
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			if tt.noBusy {
+				sess.busy = nil
+			}
+			if tt.held {
+				if err := sess.lock(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				defer sess.unlock()
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			err := sess.lock(ctx)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("lock() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				sess.unlock()
+			}
+		})
+	}
+}
+
+// background returns a context that is never done
+func background() (context.Context, context.CancelFunc) {
+	return context.Background(), func() {}
+}
+
+func TestSessionLockWaitsForUnlock(t *testing.T) {
+	sess := newTestSession()
+	sess.ptr = unsafe.Pointer(new(byte)) // IsResponding needs a session to report on
+	if err := sess.lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !sess.IsResponding() {
+		t.Error("IsResponding() = false while the session is held")
+	}
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- sess.lock(context.Background())
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("lock() returned %v while the session was held", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	sess.unlock()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("lock() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lock() did not acquire the session after unlock")
+	}
+	sess.unlock()
+}
@@ -0,0 +1,98 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// SessionOption configures a session when it is created
+type SessionOption func(*Session)
+
+// WithAutoRefresh makes the session refresh itself in place when a request would take
+// context usage to threshold (a fraction of the context window, e.g. 0.9) or beyond:
+// the conversation is cleared, keeping the instructions, added context and tools, so the
+// session keeps working instead of returning context limit errors mid-conversation. The
+// model forgets the earlier conversation unless WithRefreshSummary is also given.
+func WithAutoRefresh(threshold float64) SessionOption {
+	return func(s *Session) {
+		s.autoRefresh = threshold
+	}
+}
+
+// WithRefreshSummary makes automatic refreshes (see WithAutoRefresh) carry a summary of
+// the conversation so far, written by the model in a separate session
+func WithRefreshSummary() SessionOption {
+	return func(s *Session) {
+		s.refreshSummary = true
+	}
+}
+
+// WithPrewarm prewarms the session as soon as it is created (see Session.Prewarm)
+func WithPrewarm() SessionOption {
+	return func(s *Session) {
+		if err := s.Prewarm(); err != nil {
+			logger().Warn("Failed to prewarm session", "error", err)
+		}
+	}
+}
+
+// Prewarm asks the framework to load the model resources for the session ahead of its
+// first request, hiding the multi-second cold start from an interactive user. It returns
+// without waiting for loading to finish. Register tools before prewarming: registering
+// them afterwards recreates the underlying session, which then starts cold again.
+func (s *Session) Prewarm() error {
+	if Init() != nil {
+		return fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if err := s.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.unlock()
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := shimSymbol(prewarmSession, "PrewarmSession"); err != nil {
+		return err
+	}
+
+	purego.SyscallN(prewarmSession, uintptr(s.ptr))
+	logger().Debug("Prewarmed session")
+	return nil
+}
+
+// maybeAutoRefresh refreshes the session if prompt would take context usage past the
+// auto refresh threshold. The session must be locked.
+func (s *Session) maybeAutoRefresh(prompt string) error {
+	if s.autoRefresh <= 0 {
+		return nil
+	}
+	usage := float64(s.GetContextSize()+estimateTokens(prompt)) / float64(s.maxContextSize)
+	if usage < s.autoRefresh {
+		return nil
+	}
+
+	entries, err := s.Transcript()
+	if err != nil {
+		return fmt.Errorf("auto refresh failed: %w", err)
+	}
+	policy := KeepLast(0)
+	if s.refreshSummary {
+		policy = SummarizeOldest(0)
+	}
+	kept, err := policy.Trim(entries, s.maxContextSize)
+	if err != nil {
+		return fmt.Errorf("auto refresh failed: %w", err)
+	}
+	if err := s.setTranscript(kept); err != nil {
+		return fmt.Errorf("auto refresh failed: %w", err)
+	}
+
+	logger().Debug("Session refreshed automatically",
+		"usage_percent", usage*100,
+		"threshold_percent", s.autoRefresh*100,
+		"summary", s.refreshSummary,
+		"context_after", s.GetContextSize())
+	return nil
+}
@@ -0,0 +1,161 @@
+package fm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load when no session is saved under the ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionIDRegex restricts session IDs to names that are safe as file names
+var sessionIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SessionStore persists sessions to a directory, one JSON file per session ID, so a
+// conversation can be continued across process runs
+type SessionStore struct {
+	dir string
+}
+
+// storedSession is the file format of a saved session
+type storedSession struct {
+	ID      string          `json:"id"`
+	SavedAt time.Time       `json:"savedAt"`
+	Tools   []string        `json:"tools,omitempty"`
+	History json.RawMessage `json:"history"`
+}
+
+// NewSessionStore returns a store that saves sessions in dir, creating it if needed
+func NewSessionStore(dir string) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session store: %w", err)
+	}
+	return &SessionStore{dir: dir}, nil
+}
+
+// path returns the file a session is saved in, validating the ID
+func (st *SessionStore) path(id string) (string, error) {
+	if !sessionIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid session ID %q (use letters, digits, '.', '_' and '-')", id)
+	}
+	return filepath.Join(st.dir, id+".json"), nil
+}
+
+// Save saves the session's instructions, registered tool names, history and context
+// size under id, replacing any session previously saved under it
+func (st *SessionStore) Save(id string, s *Session) error {
+	path, err := st.path(id)
+	if err != nil {
+		return err
+	}
+	history, err := s.ExportHistory()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(storedSession{
+		ID:      id,
+		SavedAt: time.Now(),
+		Tools:   s.GetRegisteredTools(),
+		History: history,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write can't corrupt the saved session
+	tmp, err := os.CreateTemp(st.dir, id+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	logger().Debug("Saved session", "id", id, "path", path)
+	return nil
+}
+
+// Load rehydrates the session saved under id, registering the given tools with it. Tool
+// implementations can't be saved, so a warning is logged for each tool the session had
+// registered that is not passed in. The error wraps ErrSessionNotFound if there is no
+// session saved under id.
+func (st *SessionStore) Load(id string, tools ...Tool) (*Session, error) {
+	path, err := st.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	var stored storedSession
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	sess, err := NewSessionFromHistory(stored.History)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tool := range tools {
+		if err := sess.RegisterTool(tool); err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("failed to register tool %s: %w", tool.Name(), err)
+		}
+	}
+	for _, name := range stored.Tools {
+		if !slices.ContainsFunc(tools, func(t Tool) bool { return t.Name() == name }) {
+			logger().Warn("Saved session used a tool that was not provided", "session", id, "tool", name)
+		}
+	}
+
+	logger().Debug("Loaded session", "id", id, "saved_at", stored.SavedAt)
+	return sess, nil
+}
+
+// Delete removes the session saved under id. Deleting a session that doesn't exist is
+// not an error.
+func (st *SessionStore) Delete(id string) error {
+	path, err := st.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// List returns the IDs of the saved sessions in lexical order
+func (st *SessionStore) List() ([]string, error) {
+	files, err := os.ReadDir(st.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var ids []string
+	for _, file := range files {
+		if id, ok := strings.CutSuffix(file.Name(), ".json"); ok && !file.IsDir() && sessionIDRegex.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
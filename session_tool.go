@@ -0,0 +1,124 @@
+package fm
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ReleasableTool is implemented by tools that hold resources which must be released
+// when the tool is removed from a session with ClearTools
+type ReleasableTool interface {
+	Tool
+	// Release frees any resources held by the tool
+	Release()
+}
+
+// SessionTool is a tool that delegates its prompt to a separate, specialized session.
+// It lets the primary model hand sub-tasks to a sub-agent with its own instructions
+// and its own context window.
+type SessionTool struct {
+	name         string
+	description  string
+	instructions string
+
+	mu   sync.Mutex
+	sess *Session
+}
+
+// NewSessionTool creates a tool that answers prompts with a dedicated sub-session using
+// the given instructions. The sub-session is created on first use and released when the
+// tool is cleared from the session it is registered with.
+func NewSessionTool(name, description, instructions string) Tool {
+	return &SessionTool{
+		name:         name,
+		description:  description,
+		instructions: instructions,
+	}
+}
+
+func (t *SessionTool) Name() string {
+	return t.name
+}
+
+func (t *SessionTool) Description() string {
+	return t.description
+}
+
+// GetParameters returns the parameter definitions for the session tool
+func (t *SessionTool) GetParameters() []ToolArgument {
+	return []ToolArgument{
+		{
+			Name:        "prompt",
+			Type:        "string",
+			Description: "The task or question to delegate",
+			Required:    true,
+		},
+	}
+}
+
+// ValidateArguments validates the session tool arguments
+func (t *SessionTool) ValidateArguments(args map[string]any) error {
+	return ValidateToolArguments(args, t.GetParameters())
+}
+
+func (t *SessionTool) Execute(args map[string]any) (ToolResult, error) {
+	prompt, _ := args["prompt"].(string)
+	if prompt == "" {
+		return ToolResult{Error: "Missing required argument: prompt"}, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, err := t.session(prompt)
+	if err != nil {
+		return ToolResult{Error: err.Error()}, nil
+	}
+
+	logger().Debug("Delegating to sub-session", "tool_name", t.name, "prompt_length", len(prompt))
+	response, err := sess.Respond(prompt, nil)
+	if err != nil {
+		return ToolResult{Error: err.Error()}, nil
+	}
+
+	return ToolResult{Content: response}, nil
+}
+
+// session returns the sub-session, creating it or refreshing it if the prompt would
+// not fit in its remaining context. Must be called with t.mu held.
+func (t *SessionTool) session(prompt string) (*Session, error) {
+	if t.sess != nil && t.sess.validateContextSize(prompt) != nil {
+		logger().Debug("Refreshing sub-session with full context", "tool_name", t.name)
+		t.sess.Release()
+		t.sess = nil
+	}
+
+	if t.sess == nil {
+		if t.instructions != "" {
+			t.sess = NewSessionWithInstructions(t.instructions)
+		} else {
+			t.sess = NewSession()
+		}
+		if t.sess == nil {
+			return nil, fmt.Errorf("failed to create sub-session for tool '%s'", t.name)
+		}
+	}
+	return t.sess, nil
+}
+
+// Session returns the sub-session, or nil if it has not been created yet
+func (t *SessionTool) Session() *Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sess
+}
+
+// Release releases the sub-session. A later Execute creates a fresh one.
+func (t *SessionTool) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sess != nil {
+		t.sess.Release()
+		t.sess = nil
+	}
+}
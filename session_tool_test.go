@@ -0,0 +1,77 @@
+package fm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionToolValidateArguments(t *testing.T) {
+	tool := NewSessionTool("research", "Researches a topic", "You are a researcher").(*SessionTool)
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantErr string
+	}{
+		{name: "prompt", args: map[string]any{"prompt": "Summarize Go generics"}},
+		{name: "extra argument", args: map[string]any{"prompt": "hi", "depth": 2}},
+		{name: "missing prompt", args: map[string]any{}, wantErr: "missing required argument: prompt"},
+		{name: "nil arguments", args: nil, wantErr: "missing required argument: prompt"},
+		{name: "non-string prompt", args: map[string]any{"prompt": 42}, wantErr: "invalid argument prompt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateArguments(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateArguments() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateArguments() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionToolExecuteErrors(t *testing.T) {
+	tool := NewSessionTool("research", "Researches a topic", "").(*SessionTool)
+
+	type test struct {
+		name    string
+		args    map[string]any
+		wantErr string
+	}
+	tests := []test{
+		{name: "missing prompt", args: map[string]any{}, wantErr: "Missing required argument: prompt"},
+		{name: "empty prompt", args: map[string]any{"prompt": ""}, wantErr: "Missing required argument: prompt"},
+	}
+	if Init() != nil {
+		// Without the shim the sub-session can't be created, which is reported to the model
+		tests = append(tests, test{
+			name:    "no sub-session",
+			args:    map[string]any{"prompt": "hi"},
+			wantErr: "failed to create sub-session for tool 'research'",
+		})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(tt.args)
+			if err != nil {
+				t.Fatalf("Execute() error = %v, want the failure in the result", err)
+			}
+			if result.Error != tt.wantErr {
+				t.Errorf("Execute() result error = %q, want %q", result.Error, tt.wantErr)
+			}
+			if result.Content != "" {
+				t.Errorf("Execute() content = %q, want none", result.Content)
+			}
+		})
+	}
+
+	if tool.Session() != nil {
+		t.Error("Session() is set after failed calls")
+	}
+	tool.Release() // releasing without a sub-session is a no-op
+}
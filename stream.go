@@ -0,0 +1,456 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// ErrStreamStopped is carried by the final chunk of a stream that was stopped by the caller
+var ErrStreamStopped = errors.New("stream stopped")
+
+// StreamChunk is a single value received from a channel-based streaming response
+type StreamChunk struct {
+	// Text is the newly generated text since the previous chunk
+	Text string
+	// Done is true for the final value sent before the channel is closed
+	Done bool
+	// Err is set on the final value if generation failed or was stopped
+	Err error
+	// Metrics is set on the final value once generation has started
+	Metrics *StreamMetrics
+	// JSON is the validated object on the final value of StreamStructuredOutput, and the
+	// snapshot of the value generated so far on every value of RespondWithSchemaStream
+	JSON string
+	// FinishReason is set on the final value to explain why generation ended
+	FinishReason FinishReason
+}
+
+// StreamMetrics describes where a channel-based stream spent its time. A large
+// Backpressure relative to Generating means the consumer is throttling generation.
+type StreamMetrics struct {
+	// Chunks is the number of chunks delivered before the final value
+	Chunks int
+	// Backpressure is the time spent blocked sending chunks to a slow consumer
+	Backpressure time.Duration
+	// Generating is the time spent waiting for the model to produce chunks
+	Generating time.Duration
+	// Total is the wall time from the start of the stream to the final value
+	Total time.Duration
+}
+
+// streamMetricsRecorder accumulates StreamMetrics for an active stream
+type streamMetricsRecorder struct {
+	start   time.Time
+	metrics StreamMetrics
+}
+
+// send delivers chunk to out, recording the time blocked as backpressure. It returns
+// false if the stream was stopped before the chunk could be delivered.
+func (r *streamMetricsRecorder) send(out chan<- StreamChunk, chunk StreamChunk, done <-chan struct{}) bool {
+	blocked := time.Now()
+	defer func() { r.metrics.Backpressure += time.Since(blocked) }()
+	select {
+	case out <- chunk:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// snapshot returns the metrics so far, with Total and Generating filled in
+func (r *streamMetricsRecorder) snapshot() *StreamMetrics {
+	m := r.metrics
+	m.Total = time.Since(r.start)
+	m.Generating = m.Total - m.Backpressure
+	return &m
+}
+
+// streamStopper ends a stream exactly once: stopped early, which cancels the generation,
+// or finished by itself, after which stopping does nothing so it can't cancel whatever
+// the session is generating by then
+type streamStopper struct {
+	once    sync.Once
+	done    chan struct{}
+	cancel  func()
+	err     error // Why the stream was stopped, readable once done is closed
+	stopped bool  // Whether the stream was stopped early, readable once done is closed
+}
+
+// newStreamStopper creates a stopper closing done and calling cancel when stopped
+func newStreamStopper(done chan struct{}, cancel func()) *streamStopper {
+	return &streamStopper{done: done, cancel: cancel, err: ErrStreamStopped}
+}
+
+// stop ends the stream with err and cancels its generation, unless it already ended
+func (st *streamStopper) stop(err error) {
+	st.once.Do(func() {
+		st.err = err
+		st.stopped = true
+		close(st.done)
+		st.cancel()
+	})
+}
+
+// finish ends the stream after its final chunk, unless it already ended
+func (st *streamStopper) finish() {
+	st.once.Do(func() { close(st.done) })
+}
+
+// awaitUncancelled blocks after the stream was stopped early until the shim finishes the
+// generation, if the shim can't cancel it (an older library without CancelResponse), so
+// the session isn't reused while the generation still runs
+func (st *streamStopper) awaitUncancelled(finished <-chan struct{}) {
+	if st.stopped && cancelResponse == 0 {
+		logger().Debug("Shim can't cancel, waiting for the stopped generation to finish")
+		<-finished
+	}
+}
+
+// streamBufferSize is the number of chunks buffered between the shim and the consumer
+const streamBufferSize = 16
+
+// activeStream routes chunks from the Swift shim to a single channel-based stream
+type activeStream struct {
+	in       chan StreamChunk
+	done     chan struct{}
+	finished chan struct{} // Closed after the final chunk when set
+	stop     func()
+	deliver  func(chunk StreamChunk) // Overrides sending to in when set
+}
+
+var (
+	streamsMu    sync.Mutex
+	streams      = make(map[int64]*activeStream)
+	nextStreamID atomic.Int64
+
+	// streamCallbackFunc is a global variable to keep the callback function alive
+	streamCallbackFunc func(streamID int64, cChunk unsafe.Pointer, isLast bool)
+	streamCallback     uintptr
+)
+
+// setupStreamCallback creates the single callback Swift uses to deliver stream chunks
+func setupStreamCallback() {
+	streamCallbackFunc = func(streamID int64, cChunk unsafe.Pointer, isLast bool) {
+		routeStreamChunk(streamID, goString(cChunk), isLast)
+	}
+	streamCallback = purego.NewCallback(streamCallbackFunc)
+}
+
+// routeStreamChunk delivers a chunk from the shim to the stream with streamID
+func routeStreamChunk(streamID int64, text string, isLast bool) {
+	streamsMu.Lock()
+	stream, ok := streams[streamID]
+	streamsMu.Unlock()
+	if !ok {
+		return // stream already stopped or finished
+	}
+
+	chunk := StreamChunk{Text: text, Done: isLast}
+	if isLast && stream.finished != nil {
+		defer close(stream.finished)
+	}
+	if isLast {
+		if err := shimError(chunk.Text); err != nil {
+			chunk.Err = err
+			chunk.Text = ""
+		}
+	}
+
+	if stream.deliver != nil {
+		stream.deliver(chunk)
+		return
+	}
+	select {
+	case stream.in <- chunk:
+	case <-stream.done:
+	}
+}
+
+// StreamResponse generates a response and delivers it incrementally over a channel.
+// The final value has Done set (and Err on failure) and the channel is closed after it.
+// The returned stop function cancels generation and closes the channel; the final value
+// then carries ErrStreamStopped. Calling stop more than once, or after the stream has
+// finished, is safe. If the shim can't cancel generation (a libFMShim.dylib without
+// CancelResponse), stop still closes the channel, but the session stays busy until the
+// generation finishes by itself.
+func (s *Session) StreamResponse(prompt string) (<-chan StreamChunk, func()) {
+	out, stop, err := s.startStream(context.Background(), prompt, nil, "")
+	if err != nil {
+		out := make(chan StreamChunk, 1)
+		out <- StreamChunk{Done: true, Err: err, FinishReason: finishReason(err, false, "", nil)}
+		close(out)
+		return out, func() {}
+	}
+	return out, stop
+}
+
+// RespondStream generates a response and delivers real incremental chunks over a
+// channel as the model produces them. If options is nil, default generation settings
+// are used. Errors before generation starts (invalid session, context limit, ctx done
+// while waiting for the session) are returned directly. Afterwards the final value has
+// Done set, and Err if generation failed; if ctx is done first, generation is cancelled
+// and the final value carries ctx.Err(). The channel is closed after the final value.
+func (s *Session) RespondStream(ctx context.Context, prompt string, options *GenerationOptions) (<-chan StreamChunk, error) {
+	out, _, err := s.startStream(ctx, prompt, options, "")
+	return out, err
+}
+
+// startStream starts a streaming generation in the shim. The returned stop function
+// cancels it, as does ctx being done; the final value then carries ErrStreamStopped or
+// ctx.Err() respectively. If schema is set, generation is constrained to it and each
+// chunk's Text is a snapshot of the whole value so far rather than new text.
+func (s *Session) startStream(ctx context.Context, prompt string, options *GenerationOptions, schema string) (<-chan StreamChunk, func(), error) {
+	if Init() != nil {
+		return nil, nil, shimInitError
+	}
+	if s.ptr == nil {
+		return nil, nil, ErrInvalidSession
+	}
+	if err := streamSymbol(options, schema); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var cOptions unsafe.Pointer
+	if options != nil {
+		encoded, err := optionsJSON(options)
+		if err != nil {
+			return nil, nil, err
+		}
+		cOptions = cString(encoded)
+		defer freePtr(cOptions)
+	}
+	var cSchema unsafe.Pointer
+	if schema != "" {
+		cSchema = cString(schema)
+		defer freePtr(cSchema)
+	}
+
+	// The lock is held until the stream finishes or is stopped
+	if err := s.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	prompt = s.withTimeContext(prompt)
+	if err := s.ensureContextRoom(prompt); err != nil {
+		s.unlock()
+		return nil, nil, err
+	}
+	if err := s.verifyAvailability(); err != nil {
+		s.unlock()
+		return nil, nil, err
+	}
+	release, err := s.waitToStart(ctx)
+	if err != nil {
+		s.unlock()
+		return nil, nil, err
+	}
+
+	out := make(chan StreamChunk, streamBufferSize)
+	id := nextStreamID.Add(1)
+	stream := &activeStream{
+		in:       make(chan StreamChunk),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	unregister := func() {
+		streamsMu.Lock()
+		delete(streams, id)
+		streamsMu.Unlock()
+	}
+
+	stopper := newStreamStopper(stream.done, s.Cancel)
+	stop := func() { stopper.stop(ErrStreamStopped) }
+	stream.stop = stop
+
+	streamsMu.Lock()
+	streams[id] = stream
+	streamsMu.Unlock()
+
+	s.addToContext(prompt)
+
+	kind := "text"
+	if cSchema != nil {
+		kind = "schema"
+	}
+	telemetry := s.startTelemetry(ctx, kind, true, prompt)
+	endErr := ErrStreamStopped
+
+	go func() {
+		var response strings.Builder
+		defer func() { telemetry.end(response.String(), endErr) }()
+		defer s.unlock()
+		defer release()
+		defer unregister()
+		defer stopper.awaitUncancelled(stream.finished)
+		defer close(out)
+
+		recorder := &streamMetricsRecorder{start: time.Now()}
+		var matcher *stopMatcher
+		if options != nil {
+			matcher = newStopMatcher(options.StopSequences)
+		}
+		for {
+			select {
+			case chunk := <-stream.in:
+				stopped := false
+				if matcher != nil {
+					if !chunk.Done {
+						chunk.Text, stopped = matcher.push(chunk.Text)
+						chunk.Done = stopped
+					} else if chunk.Err == nil {
+						chunk.Text = matcher.flush() + chunk.Text
+					}
+					if !chunk.Done && chunk.Text == "" {
+						continue // held back as a possible stop sequence
+					}
+				}
+				telemetry.text(chunk.Text)
+				if cSchema != nil && chunk.Text != "" {
+					response.Reset() // Only the latest snapshot is kept in context
+				}
+				response.WriteString(chunk.Text)
+				if chunk.Done {
+					chunk.Metrics = recorder.snapshot()
+					chunk.FinishReason = finishReason(chunk.Err, stopped, response.String(), options)
+				}
+				if !recorder.send(out, chunk, stream.done) {
+					endErr = stopper.err
+					finishStopped(out, stopper.err, recorder.snapshot())
+					return
+				}
+				if chunk.Done {
+					endErr = chunk.Err
+					if stopped {
+						// Reached a stop sequence: the rest of the generation is not needed
+						logger().Debug("Stream reached stop sequence, cancelling", "stream_id", id)
+						stopper.stop(nil)
+					}
+					stopper.finish()
+					s.addToContext(response.String())
+					logger().Debug("Stream finished",
+						"stream_id", id,
+						"chunks", chunk.Metrics.Chunks,
+						"backpressure", chunk.Metrics.Backpressure,
+						"generating", chunk.Metrics.Generating)
+					return
+				}
+				recorder.metrics.Chunks++
+			case <-stream.done:
+				endErr = stopper.err
+				finishStopped(out, stopper.err, recorder.snapshot())
+				return
+			case <-ctx.Done():
+				logger().Debug("Stream context done, cancelling", "stream_id", id)
+				stopper.stop(ctx.Err())
+				endErr = stopper.err
+				finishStopped(out, stopper.err, recorder.snapshot())
+				return
+			}
+		}
+	}()
+
+	cPrompt := cString(prompt)
+	switch {
+	case cSchema != nil:
+		logger().Debug("Calling Swift RespondWithSchemaStreaming", "stream_id", id)
+		purego.SyscallN(respondWithSchemaStreaming,
+			uintptr(s.ptr),
+			uintptr(cPrompt),
+			uintptr(cSchema),
+			uintptr(id),
+			streamCallback)
+	case cOptions != nil:
+		logger().Debug("Calling Swift RespondStreamingWithOptions", "stream_id", id)
+		purego.SyscallN(respondStreamingWithOptions,
+			uintptr(s.ptr),
+			uintptr(cPrompt),
+			uintptr(cOptions),
+			uintptr(id),
+			streamCallback)
+	default:
+		logger().Debug("Calling Swift RespondStreamingWithID", "stream_id", id)
+		purego.SyscallN(respondStreamingWithID,
+			uintptr(s.ptr),
+			uintptr(cPrompt),
+			uintptr(id),
+			streamCallback)
+	}
+	freePtr(cPrompt)
+
+	return out, stop, nil
+}
+
+// streamSymbol checks that the shim exports the streaming function startStream calls
+// for options and schema
+func streamSymbol(options *GenerationOptions, schema string) error {
+	switch {
+	case schema != "":
+		return shimSymbol(respondWithSchemaStreaming, "RespondWithSchemaStreaming")
+	case options != nil:
+		return shimSymbol(respondStreamingWithOptions, "RespondStreamingWithOptions")
+	default:
+		return shimSymbol(respondStreamingWithID, "RespondStreamingWithID")
+	}
+}
+
+// finishStopped discards undelivered chunks and sends the final cancellation value
+func finishStopped(out chan StreamChunk, err error, metrics *StreamMetrics) {
+	for {
+		select {
+		case <-out:
+		default:
+			out <- StreamChunk{Done: true, Err: err, Metrics: metrics, FinishReason: FinishCancelled}
+			return
+		}
+	}
+}
+
+// Stream generates a response as an iterator over newly generated text, for use with
+// range-over-func. If generation fails, the error is yielded once and iteration ends.
+// Breaking out of the loop cancels generation.
+//
+//	for chunk, err := range sess.Stream(ctx, "Write a story", nil) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(chunk)
+//	}
+func (s *Session) Stream(ctx context.Context, prompt string, options *GenerationOptions) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		chunks, err := s.RespondStream(ctx, prompt, options)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				yield("", chunk.Err)
+				return
+			}
+			if chunk.Text == "" {
+				continue
+			}
+			if !yield(chunk.Text, nil) {
+				// Cancel and wait for the stream to close so the session is free again
+				cancel()
+				for range chunks {
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package fm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStreamStopper(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(st *streamStopper)
+		wantCancels int
+		wantErr     error
+	}{
+		{
+			name:        "stop",
+			run:         func(st *streamStopper) { st.stop(ErrStreamStopped) },
+			wantCancels: 1,
+			wantErr:     ErrStreamStopped,
+		},
+		{
+			name: "stop twice",
+			run: func(st *streamStopper) {
+				st.stop(ErrStreamStopped)
+				st.stop(ErrStreamStopped)
+			},
+			wantCancels: 1,
+			wantErr:     ErrStreamStopped,
+		},
+		{
+			name:        "stop with context error",
+			run:         func(st *streamStopper) { st.stop(errors.New("deadline")) },
+			wantCancels: 1,
+			wantErr:     errors.New("deadline"),
+		},
+		{
+			name:        "finish",
+			run:         func(st *streamStopper) { st.finish() },
+			wantCancels: 0,
+			wantErr:     ErrStreamStopped,
+		},
+		{
+			name: "stop after finish",
+			run: func(st *streamStopper) {
+				st.finish()
+				st.stop(ErrStreamStopped)
+				st.stop(ErrStreamStopped)
+			},
+			wantCancels: 0,
+			wantErr:     ErrStreamStopped,
+		},
+		{
+			name: "stop sequence then finish",
+			run: func(st *streamStopper) {
+				st.stop(nil)
+				st.finish()
+				st.stop(ErrStreamStopped)
+			},
+			wantCancels: 1,
+			wantErr:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancels := 0
+			done := make(chan struct{})
+			st := newStreamStopper(done, func() { cancels++ })
+			tt.run(st)
+
+			select {
+			case <-done:
+			default:
+				t.Fatal("done is not closed")
+			}
+			if cancels != tt.wantCancels {
+				t.Errorf("cancels = %d, want %d", cancels, tt.wantCancels)
+			}
+			if (st.err == nil) != (tt.wantErr == nil) || (st.err != nil && st.err.Error() != tt.wantErr.Error()) {
+				t.Errorf("err = %v, want %v", st.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamStopperAwaitUncancelled(t *testing.T) {
+	saved := cancelResponse
+	defer func() { cancelResponse = saved }()
+
+	tests := []struct {
+		name      string
+		canCancel bool
+		stop      bool
+		wantWait  bool
+	}{
+		{name: "finished", stop: false, wantWait: false},
+		{name: "stopped and cancelled", canCancel: true, stop: true, wantWait: false},
+		{name: "stopped without CancelResponse", stop: true, wantWait: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancelResponse = 0
+			if tt.canCancel {
+				cancelResponse = 1
+			}
+			st := newStreamStopper(make(chan struct{}), func() {})
+			if tt.stop {
+				st.stop(ErrStreamStopped)
+			} else {
+				st.finish()
+			}
+
+			finished := make(chan struct{})
+			returned := make(chan struct{})
+			go func() {
+				st.awaitUncancelled(finished)
+				close(returned)
+			}()
+			select {
+			case <-returned:
+				if tt.wantWait {
+					t.Fatal("awaitUncancelled() returned before the generation finished")
+				}
+			case <-time.After(20 * time.Millisecond):
+				if !tt.wantWait {
+					t.Fatal("awaitUncancelled() is still waiting")
+				}
+			}
+			close(finished)
+			<-returned
+		})
+	}
+}
+
+func TestStreamMetricsRecorderSend(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	tests := []struct {
+		name             string
+		buffer           int
+		readAfter        time.Duration // How long the consumer waits before reading; <0 never reads
+		stop             bool
+		want             bool
+		wantBackpressure time.Duration
+	}{
+		{name: "buffered", buffer: 1, readAfter: -1, want: true},
+		{name: "slow consumer", buffer: 0, readAfter: delay, want: true, wantBackpressure: delay},
+		{name: "stopped", buffer: 0, readAfter: -1, stop: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := make(chan StreamChunk, tt.buffer)
+			done := make(chan struct{})
+			if tt.stop {
+				close(done)
+			}
+			if tt.readAfter >= 0 {
+				go func() {
+					time.Sleep(tt.readAfter)
+					<-out
+				}()
+			}
+
+			r := &streamMetricsRecorder{start: time.Now()}
+			if got := r.send(out, StreamChunk{Text: "hi"}, done); got != tt.want {
+				t.Fatalf("send() = %v, want %v", got, tt.want)
+			}
+			if r.metrics.Backpressure < tt.wantBackpressure {
+				t.Errorf("Backpressure = %v, want at least %v", r.metrics.Backpressure, tt.wantBackpressure)
+			}
+
+			m := r.snapshot()
+			if m.Total < m.Backpressure {
+				t.Errorf("Total %v is less than Backpressure %v", m.Total, m.Backpressure)
+			}
+			if m.Generating != m.Total-m.Backpressure {
+				t.Errorf("Generating = %v, want Total - Backpressure = %v", m.Generating, m.Total-m.Backpressure)
+			}
+		})
+	}
+}
+
+func TestValidateStream(t *testing.T) {
+	t.Run("valid JSON", func(t *testing.T) {
+		in := make(chan StreamChunk, 3)
+		in <- StreamChunk{Text: `{"fruit":`}
+		in <- StreamChunk{Text: ` "apple"}`}
+		in <- StreamChunk{Done: true}
+		close(in)
+
+		out, _ := newTestSession().validateStream(in, func() {})
+		var last StreamChunk
+		for chunk := range out {
+			last = chunk
+		}
+		if !last.Done || last.Err != nil || last.JSON != `{"fruit": "apple"}` {
+			t.Errorf("final chunk = %+v, want Done with the JSON", last)
+		}
+	})
+
+	t.Run("stopped without a reader", func(t *testing.T) {
+		in := make(chan StreamChunk)
+		stopped := make(chan struct{})
+		out, stop := newTestSession().validateStream(in, func() { close(stopped) })
+		go func() {
+			// Fill the output buffer so the next send blocks until stop
+			for {
+				select {
+				case in <- StreamChunk{Text: "x"}:
+				case <-stopped:
+					close(in)
+					return
+				}
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+		stop()
+		stop() // Stopping twice is harmless
+
+		var last StreamChunk
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case chunk, ok := <-out:
+				if !ok {
+					if !errors.Is(last.Err, ErrStreamStopped) {
+						t.Errorf("final chunk error = %v, want ErrStreamStopped", last.Err)
+					}
+					return
+				}
+				last = chunk
+			case <-deadline:
+				t.Fatal("output channel not closed after stop")
+			}
+		}
+	})
+}
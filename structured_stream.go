@@ -0,0 +1,142 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidJSON is returned when structured output is still not valid JSON after repair
+var ErrInvalidJSON = errors.New("structured output is not valid JSON")
+
+// DefaultJSONRepairAttempts is the number of repair attempts made by StreamStructuredOutput
+const DefaultJSONRepairAttempts = 2
+
+// structuredStreamSuffix asks the model to answer with JSON only when streaming
+const structuredStreamSuffix = "\n\nRespond only with valid JSON, without any surrounding text or markdown."
+
+// RepairJSON returns the JSON value contained in text. If text holds no valid JSON, it is
+// normalized locally if JSON repair is enabled (see SetJSONRepair), and otherwise the
+// model is asked (without streaming) to correct it, up to attempts times. The error wraps
+// ErrInvalidJSON if no valid JSON could be obtained.
+func (s *Session) RepairJSON(text string, attempts int) (string, error) {
+	return s.repairJSON(context.Background(), text, attempts)
+}
+
+// repairJSON implements RepairJSON, cancelling the correction requests if ctx is done
+func (s *Session) repairJSON(ctx context.Context, text string, attempts int) (string, error) {
+	if fixed, ok := s.parseJSON(text); ok {
+		return fixed, nil
+	}
+
+	invalid := text
+	for attempt := 1; attempt <= attempts; attempt++ {
+		logger().Debug("Repairing invalid JSON", "attempt", attempt, "max_attempts", attempts)
+
+		response, err := s.respondWithContext(ctx, generationText, fmt.Sprintf(
+			"The following is not valid JSON. Reply with only the corrected JSON, keeping its content:\n\n%s",
+			invalid), nil, "")
+		if err != nil {
+			return "", fmt.Errorf("JSON repair failed: %w", err)
+		}
+		if fixed, ok := s.parseJSON(response); ok {
+			logger().Debug("Repaired invalid JSON", "attempts", attempt)
+			return fixed, nil
+		}
+		invalid = response
+	}
+
+	return "", fmt.Errorf("%w after %d repair attempts", ErrInvalidJSON, attempts)
+}
+
+// checkStructuredOutput makes sure a structured response holds valid JSON, repairing it
+// if needed with a single correction request to the model
+func (s *Session) checkStructuredOutput(ctx context.Context, response string) (string, error) {
+	if _, ok := ExtractJSON(response); ok {
+		return s.formatStructuredOutput(response), nil
+	}
+	logger().Debug("Structured output is not valid JSON, repairing",
+		"response_preview", response[:min(50, len(response))])
+	fixed, err := s.repairJSON(ctx, response, 1)
+	if err != nil {
+		return "", err
+	}
+	return s.formatStructuredOutput(fixed), nil
+}
+
+// StreamStructuredOutput streams a JSON response in two phases. First the partial output
+// is delivered live over the channel, exactly as with StreamResponse, so it can be
+// rendered as it arrives. Then, once generation finishes, the complete output is
+// validated: if it is not valid JSON the model is asked to repair it (without streaming,
+// up to DefaultJSONRepairAttempts times). The final value carries the guaranteed-valid
+// object in JSON, formatted with the session's JSON indent, or an error wrapping
+// ErrInvalidJSON. Streamed Text may therefore differ from the final JSON. Calling stop ends
+// the stream in either phase, cancelling a repair in progress.
+func (s *Session) StreamStructuredOutput(prompt string) (<-chan StreamChunk, func()) {
+	return s.validateStream(s.StreamResponse(prompt + structuredStreamSuffix))
+}
+
+// validateStream forwards the chunks of in and validates the complete output once it is
+// done. The returned stop ends both the underlying stream and any repair in progress.
+func (s *Session) validateStream(in <-chan StreamChunk, stopStream func()) (<-chan StreamChunk, func()) {
+	out := make(chan StreamChunk, streamBufferSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+			stopStream()
+		})
+	}
+
+	// send delivers chunk unless the stream is stopped first
+	send := func(chunk StreamChunk) bool {
+		select {
+		case out <- chunk:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		var response strings.Builder
+		for chunk := range in {
+			response.WriteString(chunk.Text)
+			if !chunk.Done || chunk.Err != nil {
+				if !send(chunk) {
+					break
+				}
+				continue
+			}
+
+			// Phase two: validate the complete output, repairing it if needed
+			fixed, err := s.repairJSON(ctx, response.String(), DefaultJSONRepairAttempts)
+			if err != nil {
+				chunk.Err = err
+				chunk.FinishReason = FinishError
+			} else {
+				chunk.JSON = s.formatStructuredOutput(fixed)
+			}
+			if send(chunk) {
+				return
+			}
+			break
+		}
+
+		select {
+		case <-done:
+			finishStopped(out, ErrStreamStopped, nil)
+		default:
+		}
+	}()
+
+	return out, stop
+}
@@ -0,0 +1,189 @@
+package fm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Telemetry receives events about respond calls, so observability can be attached with
+// any metrics or tracing library. Register one for all sessions with SetGlobalTelemetry,
+// or for one session with WithTelemetry. Methods are called synchronously from the
+// generating goroutine (tool calls from the shim's callback) and should return quickly.
+type Telemetry interface {
+	// OnRequestStart is called when a respond call starts
+	OnRequestStart(info RequestInfo)
+	// OnFirstToken is called when a streaming response produces its first text
+	OnFirstToken(info RequestInfo, latency time.Duration)
+	// OnToolCall is called after each tool call the model makes during the request,
+	// concurrently for calls made in the same turn
+	OnToolCall(info RequestInfo, invocation ToolInvocation)
+	// OnRequestEnd is called when a respond call finishes, with its error if it failed
+	OnRequestEnd(info RequestInfo, duration time.Duration, err error)
+}
+
+// RequestInfo identifies a respond call in Telemetry events
+type RequestInfo struct {
+	// ID is unique to the request within the process
+	ID int64
+	// Session is the session the request runs on
+	Session *Session
+	// Kind is the kind of generation, as in Request.Kind
+	Kind string
+	// Streaming is true for channel-based and iterator streams
+	Streaming bool
+	// PromptTokens is the estimated size of the prompt
+	PromptTokens int
+	// CompletionTokens is the estimated size of the response, set for OnRequestEnd
+	CompletionTokens int
+	// Start is when the request started
+	Start time.Time
+	// Context is the request's context, e.g. to parent trace spans. It is
+	// context.Background() for calls that don't take one.
+	Context context.Context
+}
+
+var (
+	telemetryMu       sync.RWMutex
+	globalTelemetry   Telemetry
+	nextRequestID     atomic.Int64
+	telemetryToolHook sync.Once
+)
+
+// SetGlobalTelemetry registers telemetry for every session, alongside any registered with
+// WithTelemetry. Pass nil to remove it.
+func SetGlobalTelemetry(telemetry Telemetry) {
+	telemetryMu.Lock()
+	globalTelemetry = telemetry
+	telemetryMu.Unlock()
+	if telemetry != nil {
+		registerTelemetryToolHook()
+	}
+}
+
+// WithTelemetry registers telemetry for the session, alongside any global telemetry
+func WithTelemetry(telemetry Telemetry) SessionOption {
+	return func(s *Session) {
+		s.telemetry = telemetry
+		if telemetry != nil {
+			registerTelemetryToolHook()
+		}
+	}
+}
+
+// telemetrySinks returns the global and session telemetry that is set
+func (s *Session) telemetrySinks() []Telemetry {
+	telemetryMu.RLock()
+	global := globalTelemetry
+	telemetryMu.RUnlock()
+
+	var sinks []Telemetry
+	if global != nil {
+		sinks = append(sinks, global)
+	}
+	if s.telemetry != nil {
+		sinks = append(sinks, s.telemetry)
+	}
+	return sinks
+}
+
+// requestTrace reports the events of one request to the telemetry sinks
+type requestTrace struct {
+	session    *Session
+	info       RequestInfo
+	sinks      []Telemetry
+	firstToken bool
+}
+
+// startTelemetry reports the start of a request and returns its tracker, or nil if no
+// telemetry is registered
+func (s *Session) startTelemetry(ctx context.Context, kind string, streaming bool, prompt string) *requestTrace {
+	sinks := s.telemetrySinks()
+	if len(sinks) == 0 {
+		return nil
+	}
+	t := &requestTrace{
+		session: s,
+		sinks:   sinks,
+		info: RequestInfo{
+			ID:           nextRequestID.Add(1),
+			Session:      s,
+			Kind:         kind,
+			Streaming:    streaming,
+			PromptTokens: estimateTokens(prompt),
+			Start:        time.Now(),
+			Context:      ctx,
+		},
+	}
+	s.mu.Lock()
+	s.inflight = append(s.inflight, t)
+	s.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.OnRequestStart(t.info)
+	}
+	return t
+}
+
+// text reports the first token the first time the request produces text
+func (t *requestTrace) text(text string) {
+	if t == nil || t.firstToken || text == "" {
+		return
+	}
+	t.firstToken = true
+	latency := time.Since(t.info.Start)
+	for _, sink := range t.sinks {
+		sink.OnFirstToken(t.info, latency)
+	}
+}
+
+// end reports the end of the request, which generated response
+func (t *requestTrace) end(response string, err error) {
+	if t == nil {
+		return
+	}
+	s := t.session
+	s.mu.Lock()
+	for i, active := range s.inflight {
+		if active == t {
+			s.inflight = append(s.inflight[:i], s.inflight[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	duration := time.Since(t.info.Start)
+	info := t.info
+	if response != "" {
+		info.CompletionTokens = estimateTokens(response)
+	}
+	for _, sink := range t.sinks {
+		sink.OnRequestEnd(info, duration, err)
+	}
+}
+
+// registerTelemetryToolHook routes finished tool calls to the telemetry of the oldest
+// request in flight on the tool's session, which is the one generating unless several
+// calls were started on the session concurrently (generations run one at a time)
+func registerTelemetryToolHook() {
+	telemetryToolHook.Do(func() {
+		addToolHook(func(sess *Session, invocation ToolInvocation, done bool) {
+			if sess == nil || !done {
+				return
+			}
+			sess.mu.Lock()
+			var t *requestTrace
+			if len(sess.inflight) > 0 {
+				t = sess.inflight[0]
+			}
+			sess.mu.Unlock()
+			if t == nil {
+				return
+			}
+			for _, sink := range t.sinks {
+				sink.OnToolCall(t.info, invocation)
+			}
+		})
+	})
+}
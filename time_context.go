@@ -0,0 +1,38 @@
+package fm
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimeContextFormat is the time layout used for the automatic time context note
+const DefaultTimeContextFormat = "Monday, January 2, 2006 15:04 MST (-07:00)"
+
+// timeContextNow returns the time for the time context note, replaceable for
+// deterministic notes
+var timeContextNow = time.Now
+
+// SetAutoTimeContext enables or disables prepending a short note with the current local
+// date, time and timezone to each prompt, since the model has no notion of "now".
+// The note counts against the session's context.
+func (s *Session) SetAutoTimeContext(enabled bool) {
+	s.autoTimeContext = enabled
+}
+
+// SetTimeContextFormat sets the time layout (as used by time.Format) for the automatic
+// time context note. An empty layout restores DefaultTimeContextFormat.
+func (s *Session) SetTimeContextFormat(layout string) {
+	s.timeContextFormat = layout
+}
+
+// withTimeContext prepends the current time note to prompt if auto time context is enabled
+func (s *Session) withTimeContext(prompt string) string {
+	if !s.autoTimeContext {
+		return prompt
+	}
+	layout := s.timeContextFormat
+	if layout == "" {
+		layout = DefaultTimeContextFormat
+	}
+	return fmt.Sprintf("[Current date and time: %s]\n\n%s", timeContextNow().Format(layout), prompt)
+}
@@ -0,0 +1,49 @@
+package fm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTimeContext(t *testing.T) {
+	now := time.Date(2025, time.June, 9, 14, 30, 0, 0, time.FixedZone("PDT", -7*60*60))
+	saved := timeContextNow
+	timeContextNow = func() time.Time { return now }
+	t.Cleanup(func() { timeContextNow = saved })
+
+	tests := []struct {
+		name    string
+		enabled bool
+		layout  string
+		want    string
+	}{
+		{name: "disabled", enabled: false, want: "What should I do today?"},
+		{
+			name:    "default layout",
+			enabled: true,
+			want:    "[Current date and time: Monday, June 9, 2025 14:30 PDT (-07:00)]\n\nWhat should I do today?",
+		},
+		{
+			name:    "custom layout",
+			enabled: true,
+			layout:  time.DateOnly,
+			want:    "[Current date and time: 2025-06-09]\n\nWhat should I do today?",
+		},
+		{
+			name:    "layout ignored when disabled",
+			enabled: false,
+			layout:  time.DateOnly,
+			want:    "What should I do today?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := newTestSession()
+			sess.SetAutoTimeContext(tt.enabled)
+			sess.SetTimeContextFormat(tt.layout)
+			if got := sess.withTimeContext("What should I do today?"); got != tt.want {
+				t.Errorf("withTimeContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package fm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ebitengine/purego"
+)
+
+// tokenCountingUnavailable is set once the shim reports that the system can't count
+// tokens, so that later counts go straight to the length-based estimate
+var tokenCountingUnavailable atomic.Bool
+
+// Counts returned by the shim's CountTokens when it could not count
+const (
+	shimCountFailed      = -1 // This count failed
+	shimCountUnsupported = -2 // Counting requires a newer system
+)
+
+// CountTokens counts the tokens in text with the model's own tokenizer. It requires
+// macOS 26.4 or later; otherwise the error wraps ErrTokenCountingUnavailable.
+func CountTokens(text string) (int, error) {
+	return shimCountTokens(text)
+}
+
+// estimateTokens counts the tokens in text for context tracking and validation, using
+// the model's tokenizer where available and ~4 characters per token otherwise
+func estimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	if !tokenCountingUnavailable.Load() {
+		count, err := shimCountTokens(text)
+		if err == nil {
+			return count
+		}
+		if errors.Is(err, ErrTokenCountingUnavailable) {
+			tokenCountingUnavailable.Store(true)
+			logger().Debug("Token counting unavailable, estimating from text length", "error", err)
+		} else {
+			logger().Debug("Failed to count tokens, estimating from text length", "error", err)
+		}
+	}
+	return len(text) / 4
+}
+
+// shimCountTokens counts tokens in text using the model's tokenizer via the Swift shim
+func shimCountTokens(text string) (int, error) {
+	if Init() != nil {
+		return 0, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if err := shimSymbol(countTokens, "CountTokens"); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrTokenCountingUnavailable, err)
+	}
+
+	cText := cString(text)
+	defer freePtr(cText)
+
+	result, _, _ := purego.SyscallN(countTokens, uintptr(cText))
+	switch count := int(int32(uint32(result))); count {
+	case shimCountUnsupported:
+		return 0, ErrTokenCountingUnavailable
+	case shimCountFailed:
+		return 0, errors.New("token counting failed")
+	default:
+		return count, nil
+	}
+}
+
+// ContextDrift compares the session's tracked context size with the real token count of
+// its transcript as measured by the model's tokenizer. The tracked size counts each
+// prompt and response separately, and only estimates from text length where token
+// counting is unavailable. pct is the estimate's error relative
+// to the actual count (positive when the estimate is too high). If the actual count can't
+// be measured, actual and pct are 0.
+func (s *Session) ContextDrift() (estimated, actual int, pct float64) {
+	estimated = s.GetContextSize()
+
+	entries, err := s.Transcript()
+	if err != nil {
+		logger().Debug("Failed to measure context drift", "error", err)
+		return estimated, 0, 0
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		sb.WriteString(entry.Content)
+		sb.WriteString("\n")
+	}
+
+	actual, err = shimCountTokens(sb.String())
+	if err != nil {
+		logger().Debug("Failed to measure context drift", "error", err)
+		return estimated, 0, 0
+	}
+	pct = driftPercent(estimated, actual)
+
+	logger().Debug("Context drift",
+		"estimated", estimated,
+		"actual", actual,
+		"drift_percent", pct)
+
+	return estimated, actual, pct
+}
+
+// driftPercent returns the error of an estimated token count relative to the actual
+// count, in percent, or 0 if the actual count is 0
+func driftPercent(estimated, actual int) float64 {
+	if actual <= 0 {
+		return 0
+	}
+	return float64(estimated-actual) / float64(actual) * 100
+}
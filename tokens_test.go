@@ -0,0 +1,36 @@
+package fm
+
+import "testing"
+
+func TestDriftPercent(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimated int
+		actual    int
+		want      float64
+	}{
+		{name: "exact", estimated: 100, actual: 100, want: 0},
+		{name: "overestimate", estimated: 150, actual: 100, want: 50},
+		{name: "underestimate", estimated: 75, actual: 100, want: -25},
+		{name: "nothing estimated", estimated: 0, actual: 40, want: -100},
+		{name: "no actual count", estimated: 10, actual: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driftPercent(tt.estimated, tt.actual); got != tt.want {
+				t.Errorf("driftPercent(%d, %d) = %v, want %v", tt.estimated, tt.actual, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextDriftWithoutTranscript(t *testing.T) {
+	// A session without a shim-side session has no transcript to measure
+	sess := newTestSession()
+	sess.contextSize = 321
+
+	estimated, actual, pct := sess.ContextDrift()
+	if estimated != 321 || actual != 0 || pct != 0 {
+		t.Errorf("ContextDrift() = %d, %d, %v, want 321, 0, 0", estimated, actual, pct)
+	}
+}
@@ -0,0 +1,61 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+)
+
+// ToolCall is a tool call requested by the model, before it runs
+type ToolCall struct {
+	// Name is the name of the tool
+	Name string
+	// Arguments are the arguments the model passed to the tool
+	Arguments map[string]any
+}
+
+// ToolApprover decides whether a tool call may run, e.g. by asking the user. Returning
+// false denies the call; returning an error fails it. Either way the tool is not run and
+// the model is told why, so it can carry on without the result.
+type ToolApprover func(call ToolCall) (bool, error)
+
+// SetToolApprover sets a function consulted before every tool call on the session,
+// including calls routed to the fallback tool, so apps can require confirmation for
+// side-effectful tools (shell commands, file writes) while approving read-only ones
+// automatically. Calls reach it after argument validation, one at a time even when the
+// model calls several tools at once. It runs while the generation waits, so it may
+// block, e.g. on a prompt to the user. Pass nil to remove it.
+func (s *Session) SetToolApprover(approver ToolApprover) {
+	if s.lock(context.Background()) != nil {
+		return
+	}
+	defer s.unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolApprover = approver
+}
+
+// approveToolCall asks the session's approver whether a call may run. If it may not, it
+// returns false with the result to report to the model.
+func (s *Session) approveToolCall(name string, args map[string]any) (ToolResult, bool) {
+	s.mu.Lock()
+	approver := s.toolApprover
+	s.mu.Unlock()
+	if approver == nil {
+		return ToolResult{}, true
+	}
+
+	s.approveMu.Lock()
+	approved, err := approver(ToolCall{Name: name, Arguments: args})
+	s.approveMu.Unlock()
+	switch {
+	case err != nil:
+		logger().Warn("Tool approval failed", "tool_name", name, "error", err)
+		return ToolResult{Error: fmt.Sprintf("%v: %v", ErrToolCallDenied, err)}, false
+	case !approved:
+		logger().Debug("Tool call denied", "tool_name", name)
+		return ToolResult{Error: fmt.Sprintf("%v: the user did not approve calling '%s'", ErrToolCallDenied, name)}, false
+	default:
+		return ToolResult{}, true
+	}
+}
@@ -0,0 +1,186 @@
+package fm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ToolArgs wraps the arguments passed to Tool.Execute with typed getters, so tools don't
+// have to type-assert every value. Wrap the map with fm.ToolArgs(args).
+type ToolArgs map[string]any
+
+// Has reports whether the argument is present
+func (a ToolArgs) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+func (a ToolArgs) get(name string) (any, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return nil, fmt.Errorf("missing required argument: %s", name)
+	}
+	return value, nil
+}
+
+// String returns the argument as a string. Numbers and booleans are formatted as text.
+func (a ToolArgs) String(name string) (string, error) {
+	value, err := a.get(name)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64, float32, int, int32, int64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("argument %s must be a string, got %T", name, value)
+	}
+}
+
+// Int returns the argument as an int. Whole floats and numeric strings are accepted.
+func (a ToolArgs) Int(name string) (int, error) {
+	value, err := a.get(name)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case float32:
+		return floatToInt(name, float64(v))
+	case float64:
+		return floatToInt(name, v)
+	case json.Number:
+		return parseIntArg(name, v.String())
+	case string:
+		return parseIntArg(name, v)
+	default:
+		return 0, fmt.Errorf("argument %s must be an integer, got %T", name, value)
+	}
+}
+
+// Float returns the argument as a float64. Integers and numeric strings are accepted.
+func (a ToolArgs) Float(name string) (float64, error) {
+	value, err := a.get(name)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return parseFloatArg(name, v.String())
+	case string:
+		return parseFloatArg(name, v)
+	default:
+		return 0, fmt.Errorf("argument %s must be a number, got %T", name, value)
+	}
+}
+
+// Bool returns the argument as a bool. The strings "true"/"false" (and other forms
+// accepted by strconv.ParseBool) are accepted.
+func (a ToolArgs) Bool(name string) (bool, error) {
+	value, err := a.get(name)
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("argument %s must be a boolean, got %q", name, v)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("argument %s must be a boolean, got %T", name, value)
+	}
+}
+
+// StringSlice returns the argument as a []string. Arrays of strings are accepted, as is
+// a single string which is returned as a one-element slice.
+func (a ToolArgs) StringSlice(name string) ([]string, error) {
+	value, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument %s[%d] must be a string, got %T", name, i, item)
+			}
+			items[i] = s
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("argument %s must be an array of strings, got %T", name, value)
+	}
+}
+
+// StringOr returns the argument as a string, or def if it is missing
+func (a ToolArgs) StringOr(name, def string) (string, error) {
+	if !a.Has(name) {
+		return def, nil
+	}
+	return a.String(name)
+}
+
+func floatToInt(name string, v float64) (int, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("argument %s must be an integer, got %v", name, v)
+	}
+	// -math.MinInt is exactly representable, unlike math.MaxInt, and is just past the range
+	if v < float64(math.MinInt) || v >= -float64(math.MinInt) {
+		return 0, fmt.Errorf("argument %s is out of range for an integer: %v", name, v)
+	}
+	if v != math.Trunc(v) {
+		return 0, fmt.Errorf("argument %s must be an integer, got %v", name, v)
+	}
+	return int(v), nil
+}
+
+func parseIntArg(name, v string) (int, error) {
+	v = strings.TrimSpace(v)
+	if i, err := strconv.Atoi(v); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return floatToInt(name, f)
+	}
+	return 0, fmt.Errorf("argument %s must be an integer, got %q", name, v)
+}
+
+func parseFloatArg(name, v string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("argument %s must be a number, got %q", name, v)
+	}
+	return f, nil
+}
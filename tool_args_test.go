@@ -0,0 +1,198 @@
+package fm
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToolArgsInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    int
+		wantErr string
+	}{
+		{name: "int", value: 7, want: 7},
+		{name: "int32", value: int32(-3), want: -3},
+		{name: "int64", value: int64(1 << 40), want: 1 << 40},
+		{name: "whole float", value: 42.0, want: 42},
+		{name: "whole float32", value: float32(8), want: 8},
+		{name: "json number", value: json.Number("12"), want: 12},
+		{name: "numeric string", value: " 5 ", want: 5},
+		{name: "float string", value: "6.0", want: 6},
+		{name: "fractional float", value: 1.5, wantErr: "must be an integer"},
+		{name: "fractional string", value: "2.5", wantErr: "must be an integer"},
+		{name: "NaN", value: math.NaN(), wantErr: "must be an integer"},
+		{name: "NaN string", value: "NaN", wantErr: "must be an integer"},
+		{name: "+Inf", value: math.Inf(1), wantErr: "must be an integer"},
+		{name: "-Inf", value: math.Inf(-1), wantErr: "must be an integer"},
+		{name: "Inf string", value: "Inf", wantErr: "must be an integer"},
+		{name: "too large", value: 1e300, wantErr: "out of range"},
+		{name: "too small", value: -1e300, wantErr: "out of range"},
+		{name: "just past the range", value: -float64(math.MinInt), wantErr: "out of range"},
+		{name: "minimum", value: float64(math.MinInt), want: math.MinInt},
+		{name: "non-numeric string", value: "seven", wantErr: `must be an integer, got "seven"`},
+		{name: "bool", value: true, wantErr: "must be an integer, got bool"},
+		{name: "missing", value: nil, wantErr: "missing required argument: n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToolArgs{"n": tt.value}.Int("n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Int() = %d, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Int() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    float64
+		wantErr bool
+	}{
+		{name: "float64", value: 1.25, want: 1.25},
+		{name: "float32", value: float32(0.5), want: 0.5},
+		{name: "int", value: 3, want: 3},
+		{name: "int32", value: int32(4), want: 4},
+		{name: "int64", value: int64(5), want: 5},
+		{name: "json number", value: json.Number("2.5"), want: 2.5},
+		{name: "string", value: " -1.5 ", want: -1.5},
+		{name: "invalid string", value: "abc", wantErr: true},
+		{name: "bool", value: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToolArgs{"x": tt.value}.Float("x")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Float() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Float() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsString(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    ToolArgs
+		want    string
+		wantErr bool
+	}{
+		{name: "string", args: ToolArgs{"s": "Paris"}, want: "Paris"},
+		{name: "float", args: ToolArgs{"s": 2.5}, want: "2.5"},
+		{name: "int", args: ToolArgs{"s": 3}, want: "3"},
+		{name: "bool", args: ToolArgs{"s": true}, want: "true"},
+		{name: "json number", args: ToolArgs{"s": json.Number("10")}, want: "10"},
+		{name: "array", args: ToolArgs{"s": []any{"a"}}, wantErr: true},
+		{name: "null", args: ToolArgs{"s": nil}, wantErr: true},
+		{name: "missing", args: ToolArgs{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.String("s")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("String() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsBool(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    bool
+		wantErr bool
+	}{
+		{name: "bool", value: true, want: true},
+		{name: "string true", value: "true", want: true},
+		{name: "string 0", value: " 0 ", want: false},
+		{name: "invalid string", value: "yes please", wantErr: true},
+		{name: "number", value: 1.0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToolArgs{"b": tt.value}.Bool("b")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Bool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsStringSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    []string
+		wantErr string
+	}{
+		{name: "strings", value: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "json array", value: []any{"a", "b"}, want: []string{"a", "b"}},
+		{name: "single string", value: "a", want: []string{"a"}},
+		{name: "empty array", value: []any{}, want: []string{}},
+		{name: "mixed array", value: []any{"a", 1.0}, wantErr: "argument list[1] must be a string, got float64"},
+		{name: "number", value: 1.0, wantErr: "must be an array of strings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToolArgs{"list": tt.value}.StringSlice("list")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("StringSlice() = %v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("StringSlice() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsStringOr(t *testing.T) {
+	args := ToolArgs{"unit": "celsius", "count": []any{}}
+	tests := []struct {
+		name    string
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{name: "present", arg: "unit", want: "celsius"},
+		{name: "missing", arg: "city", want: "Paris"},
+		{name: "wrong type", arg: "count", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := args.StringOr(tt.arg, "Paris")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StringOr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("StringOr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if !args.Has("unit") || args.Has("city") {
+		t.Error("Has() does not match the arguments")
+	}
+}
@@ -0,0 +1,59 @@
+package fm
+
+import (
+	"errors"
+
+	"github.com/ebitengine/purego"
+)
+
+// WithMaxToolCalls limits each generation on the session to n tool calls, so a model
+// stuck calling tools in a loop can't run forever. The call that would exceed the limit
+// is not run; instead the generation fails with a *ToolBudgetError carrying the
+// transcript so far. 0 removes the limit.
+func WithMaxToolCalls(n int) SessionOption {
+	return func(s *Session) {
+		if err := s.SetMaxToolCalls(n); err != nil {
+			logger().Warn("Failed to set session tool call budget", "max_tool_calls", n, "error", err)
+		}
+	}
+}
+
+// SetMaxToolCalls changes the session's tool call budget (see WithMaxToolCalls). It
+// applies from the next generation.
+func (s *Session) SetMaxToolCalls(n int) error {
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := shimSymbol(setSessionMaxToolCalls, "SetSessionMaxToolCalls"); err != nil {
+		return err
+	}
+	n = max(n, 0)
+	purego.SyscallN(setSessionMaxToolCalls, uintptr(s.ptr), uintptr(n))
+	s.mu.Lock()
+	s.maxToolCalls = n
+	s.mu.Unlock()
+	return nil
+}
+
+// MaxToolCalls returns the session's tool call budget, or 0 if it has none
+func (s *Session) MaxToolCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxToolCalls
+}
+
+// withPartialTranscript adds the session's transcript to a tool budget error, so callers
+// can see what the model did before it was stopped. Other errors are returned as is.
+func (s *Session) withPartialTranscript(err error) error {
+	var budgetErr *ToolBudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Transcript != nil {
+		return err
+	}
+	entries, transcriptErr := s.Transcript()
+	if transcriptErr != nil {
+		logger().Warn("Failed to read transcript after tool budget was exceeded", "error", transcriptErr)
+		return err
+	}
+	budgetErr.Transcript = entries
+	return err
+}
@@ -0,0 +1,48 @@
+package fm
+
+// DefaultToolConcurrency is the default number of a session's tool calls that may run at
+// once
+const DefaultToolConcurrency = 4
+
+// WithToolConcurrency limits how many of the session's tool calls run at once (default:
+// DefaultToolConcurrency). When the model calls several tools in one turn, e.g. the
+// weather in three cities, the calls run concurrently and each result is returned to the
+// call that asked for it, so results keep the order of the calls. 1 runs calls one at a
+// time, for tools that aren't safe to run concurrently.
+func WithToolConcurrency(n int) SessionOption {
+	return func(s *Session) {
+		s.SetToolConcurrency(n)
+	}
+}
+
+// SetToolConcurrency changes how many of the session's tool calls run at once (see
+// WithToolConcurrency). Values below 1 are treated as 1.
+func (s *Session) SetToolConcurrency(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolSlots = make(chan struct{}, max(n, 1))
+}
+
+// ToolConcurrency returns how many of the session's tool calls run at once
+func (s *Session) ToolConcurrency() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.toolSlots == nil {
+		return DefaultToolConcurrency
+	}
+	return cap(s.toolSlots)
+}
+
+// acquireToolSlot waits until another tool call may run on the session and returns a
+// function releasing its slot
+func (s *Session) acquireToolSlot() func() {
+	s.mu.Lock()
+	if s.toolSlots == nil {
+		s.toolSlots = make(chan struct{}, DefaultToolConcurrency)
+	}
+	slots := s.toolSlots
+	s.mu.Unlock()
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
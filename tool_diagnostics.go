@@ -0,0 +1,71 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+)
+
+// ToolsResponse is a tool-enabled response along with which tools were offered to the
+// model and which it actually called
+type ToolsResponse struct {
+	// Text is the model's response
+	Text string
+	// ToolsOffered lists the tools registered with the session, in presentation order
+	ToolsOffered []string
+	// ToolsInvoked lists the tools the model called, in call order (names may repeat)
+	ToolsInvoked []string
+}
+
+// ToolsIgnored reports whether tools were offered but the model answered without
+// calling any of them, e.g. from its own knowledge
+func (r *ToolsResponse) ToolsIgnored() bool {
+	return len(r.ToolsOffered) > 0 && len(r.ToolsInvoked) == 0
+}
+
+// RespondWithToolsFull sends a prompt with tool calling enabled, like RespondWithTools,
+// and reports which tools were offered and invoked so the "model ignored the tool" case
+// can be detected programmatically. Invocations are read from the session transcript.
+// If generation fails the error is returned with a nil response; if only the
+// diagnostics could not be collected, the response is returned along with the error.
+func (s *Session) RespondWithToolsFull(prompt string) (*ToolsResponse, error) {
+	// Hold the lock across the transcript reads so concurrent calls can't interleave
+	if err := s.lock(context.Background()); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	before := 0
+	if entries, err := s.Transcript(); err == nil {
+		before = len(entries)
+	} else {
+		logger().Debug("Failed to read transcript before tool call", "error", err)
+	}
+
+	logger().Debug("RespondWithToolsFull called", "prompt_length", len(prompt))
+	text, err := s.generateLocked(context.Background(), generationTools, prompt, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ToolsResponse{
+		Text:         text,
+		ToolsOffered: s.GetRegisteredTools(),
+	}
+
+	entries, err := s.Transcript()
+	if err != nil {
+		return resp, fmt.Errorf("failed to collect tool diagnostics: %w", err)
+	}
+	for _, entry := range entries[min(before, len(entries)):] {
+		if entry.Role == TranscriptRoleToolCall {
+			resp.ToolsInvoked = append(resp.ToolsInvoked, entry.ToolName)
+		}
+	}
+
+	if resp.ToolsIgnored() {
+		logger().Warn("Model answered without calling any of the offered tools",
+			"tools_offered", resp.ToolsOffered)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,38 @@
+package fm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToolsResponseToolsIgnored(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered []string
+		invoked []string
+		want    bool
+	}{
+		{name: "no tools", want: false},
+		{name: "ignored", offered: []string{"weather"}, want: true},
+		{name: "called", offered: []string{"weather"}, invoked: []string{"weather"}, want: false},
+		{name: "called twice", offered: []string{"weather", "calc"}, invoked: []string{"calc", "calc"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ToolsResponse{ToolsOffered: tt.offered, ToolsInvoked: tt.invoked}
+			if got := r.ToolsIgnored(); got != tt.want {
+				t.Errorf("ToolsIgnored() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondWithToolsFullInvalidSession(t *testing.T) {
+	sess := newTestSession()
+	sess.busy = nil
+
+	resp, err := sess.RespondWithToolsFull("What's the weather?")
+	if !errors.Is(err, ErrInvalidSession) || resp != nil {
+		t.Errorf("RespondWithToolsFull() = %v, %v, want nil, ErrInvalidSession", resp, err)
+	}
+}
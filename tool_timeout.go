@@ -0,0 +1,104 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimedTool extends Tool with its own limit on how long a call may take, overriding the
+// session's default (see WithToolTimeout). A timeout of 0 means no limit.
+type TimedTool interface {
+	Tool
+	// Timeout returns how long a call may take
+	Timeout() time.Duration
+}
+
+// ContextTool extends Tool with execution that stops when its context is done. The
+// context is cancelled when the call times out, so tools making network requests should
+// implement it to stop them instead of leaving them running in the background.
+type ContextTool interface {
+	Tool
+	// ExecuteContext executes the tool with the given arguments until ctx is done
+	ExecuteContext(ctx context.Context, arguments map[string]any) (ToolResult, error)
+}
+
+// WithToolTimeout limits how long each tool call on the session may take, so a hung tool
+// can't stall the response. A call that runs out of time returns a result with TimedOut
+// set and an error the model can react to, e.g. by answering without it. Tools
+// implementing TimedTool set their own limit.
+func WithToolTimeout(timeout time.Duration) SessionOption {
+	return func(s *Session) {
+		s.SetToolTimeout(timeout)
+	}
+}
+
+// SetToolTimeout changes the session's default tool call timeout (see WithToolTimeout).
+// 0 removes it.
+func (s *Session) SetToolTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultToolTimeout = max(timeout, 0)
+}
+
+// ToolTimeout returns the session's default tool call timeout, or 0 if it has none
+func (s *Session) ToolTimeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultToolTimeout
+}
+
+// runTool executes tool, stopping waiting for it once its timeout elapses
+func (s *Session) runTool(tool Tool, args map[string]any) (ToolResult, error) {
+	timeout := s.ToolTimeout()
+	if timed, ok := tool.(TimedTool); ok {
+		timeout = timed.Timeout()
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	execute := func() (ToolResult, error) {
+		if contextTool, ok := tool.(ContextTool); ok {
+			return contextTool.ExecuteContext(ctx, args)
+		}
+		return tool.Execute(args)
+	}
+	if timeout <= 0 {
+		return execute()
+	}
+
+	type outcome struct {
+		result ToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := execute()
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		// A context tool may notice the deadline before we do and fail because of it
+		if ctx.Err() != nil && (o.err != nil || o.result.Error != "") {
+			return toolTimeoutResult(tool.Name(), timeout), nil
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		// The tool keeps running until it returns, but the model no longer waits for it
+		logger().Warn("Tool call timed out", "tool_name", tool.Name(), "timeout", timeout)
+		return toolTimeoutResult(tool.Name(), timeout), nil
+	}
+}
+
+// toolTimeoutResult returns the result reported to the model for a call that timed out
+func toolTimeoutResult(name string, timeout time.Duration) ToolResult {
+	return ToolResult{
+		Error:    fmt.Sprintf("%v: '%s' did not finish within %v", ErrToolTimeout, name, timeout),
+		TimedOut: true,
+	}
+}
@@ -0,0 +1,105 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ebitengine/purego"
+)
+
+// Transcript entry roles reported by the Swift shim
+const (
+	TranscriptRoleInstructions = "instructions"
+	TranscriptRolePrompt       = "prompt"
+	TranscriptRoleToolCall     = "toolCall"
+	TranscriptRoleToolOutput   = "toolOutput"
+	TranscriptRoleResponse     = "response"
+)
+
+// TranscriptEntry is one entry of the framework's transcript for a session
+type TranscriptEntry struct {
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	ToolName string `json:"toolName,omitempty"`
+}
+
+// Transcript returns the framework's actual transcript for the session, revealing how
+// instructions, prompts, tool calls and responses are structured for the model
+func (s *Session) Transcript() ([]TranscriptEntry, error) {
+	if Init() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if s.ptr == nil {
+		return nil, ErrInvalidSession
+	}
+	if err := shimSymbol(getTranscript, "GetTranscript"); err != nil {
+		return nil, err
+	}
+
+	respPtr, _, _ := purego.SyscallN(getTranscript, uintptr(s.ptr))
+	if respPtr == 0 {
+		return nil, fmt.Errorf("no transcript from FoundationModels")
+	}
+	response := takeString(respPtr)
+
+	if err := shimError(response); err != nil {
+		return nil, fmt.Errorf("failed to get transcript: %w", err)
+	}
+
+	var entries []TranscriptEntry
+	if err := json.Unmarshal([]byte(response), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %v", err)
+	}
+	return entries, nil
+}
+
+// SetTranscript replaces the session's conversation with entries, e.g. to continue a
+// chat whose history is kept by the caller. Instructions are replaced too: include an
+// instructions entry first to keep any. It waits for any in-flight generation to finish
+// first.
+func (s *Session) SetTranscript(entries []TranscriptEntry) error {
+	if Init() != nil {
+		return fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+	if err := s.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := s.setTranscript(entries); err != nil {
+		return err
+	}
+
+	s.systemInstructions = ""
+	for _, entry := range entries {
+		if entry.Role == TranscriptRoleInstructions {
+			s.systemInstructions = entry.Content
+			break
+		}
+	}
+	return nil
+}
+
+// FormatTranscript renders transcript entries as readable text with role labels
+func FormatTranscript(entries []TranscriptEntry) string {
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		label := strings.ToUpper(entry.Role)
+		switch entry.Role {
+		case TranscriptRoleToolCall:
+			label = fmt.Sprintf("TOOL CALL (%s)", entry.ToolName)
+		case TranscriptRoleToolOutput:
+			label = fmt.Sprintf("TOOL OUTPUT (%s)", entry.ToolName)
+		}
+		fmt.Fprintf(&sb, "[%s]\n%s\n", label, entry.Content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,152 @@
+//go:build darwin && arm64 && cgo
+// +build darwin,arm64,cgo
+
+package fm
+
+//go:generate bash -c "swiftc -sdk $(xcrun --show-sdk-path) -target arm64-apple-macos26 -emit-object -parse-as-library -whole-module-optimization -O -o libFMShim.o FoundationModelsShim.swift"
+//go:generate ar rcs libFMShim.a libFMShim.o
+//go:generate rm -f libFMShim.o
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework Foundation -framework FoundationModels -lc++ -lobjc
+
+// iOS can't load dynamic libraries, so every Apple target links its own static shim (see
+// the Makefile). gomobile builds Mac Catalyst with GOOS=darwin and the maccatalyst tag,
+// and the iOS simulator with GOOS=ios and the iossimulator tag.
+#cgo darwin,!ios,!maccatalyst LDFLAGS: ${SRCDIR}/libFMShim.a
+#cgo maccatalyst LDFLAGS: ${SRCDIR}/libFMShim_catalyst.a
+#cgo ios,!iossimulator LDFLAGS: ${SRCDIR}/libFMShim_ios.a
+#cgo iossimulator LDFLAGS: ${SRCDIR}/libFMShim_iossim.a
+
+#include <stdlib.h>
+#include <string.h>
+
+// The shim's exports, declared only to take their addresses. They are called through
+// purego exactly as when loaded from libFMShim.dylib. Keep this list in sync with the
+// @_cdecl functions in FoundationModelsShim.swift.
+extern void ShimVersion(void);
+extern void GetLogs(void);
+extern void CreateSession(void);
+extern void CreateSessionWithInstructions(void);
+extern void CreateSessionWithTranscript(void);
+extern void SetTranscript(void);
+extern void SetSessionUseCase(void);
+extern void SetSessionAdapter(void);
+extern void SetSessionGuardrails(void);
+extern void SetSessionMaxToolCalls(void);
+extern void CheckAdapterCompatibility(void);
+extern void ReleaseSession(void);
+extern void GetModelAvailability(void);
+extern void CheckModelAvailability(void);
+extern void RespondSync(void);
+extern void RespondWithStructuredOutput(void);
+extern void RespondWithSchema(void);
+extern void SetSessionToolCallback(void);
+extern void RegisterTool(void);
+extern void ClearTools(void);
+extern void RespondWithTools(void);
+extern void RespondWithStreaming(void);
+extern void RespondWithToolsStreaming(void);
+extern void RespondStreamingWithID(void);
+extern void RespondStreamingWithOptions(void);
+extern void RespondWithSchemaStreaming(void);
+extern void CancelResponse(void);
+extern void IsResponding(void);
+extern void PrewarmSession(void);
+extern void AddContext(void);
+extern void RespondWithGenerationOptions(void);
+extern void GetTranscript(void);
+extern void CountTokens(void);
+extern void GetModelDetails(void);
+extern void GetModelInfo(void);
+
+static const struct {
+	const char *name;
+	void *addr;
+} fm_symbols[] = {
+	{"ShimVersion", (void *)ShimVersion},
+	{"GetLogs", (void *)GetLogs},
+	{"CreateSession", (void *)CreateSession},
+	{"CreateSessionWithInstructions", (void *)CreateSessionWithInstructions},
+	{"CreateSessionWithTranscript", (void *)CreateSessionWithTranscript},
+	{"SetTranscript", (void *)SetTranscript},
+	{"SetSessionUseCase", (void *)SetSessionUseCase},
+	{"SetSessionAdapter", (void *)SetSessionAdapter},
+	{"SetSessionGuardrails", (void *)SetSessionGuardrails},
+	{"SetSessionMaxToolCalls", (void *)SetSessionMaxToolCalls},
+	{"CheckAdapterCompatibility", (void *)CheckAdapterCompatibility},
+	{"ReleaseSession", (void *)ReleaseSession},
+	{"GetModelAvailability", (void *)GetModelAvailability},
+	{"CheckModelAvailability", (void *)CheckModelAvailability},
+	{"RespondSync", (void *)RespondSync},
+	{"RespondWithStructuredOutput", (void *)RespondWithStructuredOutput},
+	{"RespondWithSchema", (void *)RespondWithSchema},
+	{"SetSessionToolCallback", (void *)SetSessionToolCallback},
+	{"RegisterTool", (void *)RegisterTool},
+	{"ClearTools", (void *)ClearTools},
+	{"RespondWithTools", (void *)RespondWithTools},
+	{"RespondWithStreaming", (void *)RespondWithStreaming},
+	{"RespondWithToolsStreaming", (void *)RespondWithToolsStreaming},
+	{"RespondStreamingWithID", (void *)RespondStreamingWithID},
+	{"RespondStreamingWithOptions", (void *)RespondStreamingWithOptions},
+	{"RespondWithSchemaStreaming", (void *)RespondWithSchemaStreaming},
+	{"CancelResponse", (void *)CancelResponse},
+	{"IsResponding", (void *)IsResponding},
+	{"PrewarmSession", (void *)PrewarmSession},
+	{"AddContext", (void *)AddContext},
+	{"RespondWithGenerationOptions", (void *)RespondWithGenerationOptions},
+	{"GetTranscript", (void *)GetTranscript},
+	{"CountTokens", (void *)CountTokens},
+	{"GetModelDetails", (void *)GetModelDetails},
+	{"GetModelInfo", (void *)GetModelInfo},
+	{"free", (void *)free},
+	{"malloc", (void *)malloc},
+};
+
+static void *fm_dlsym(const char *name) {
+	for (size_t i = 0; i < sizeof(fm_symbols) / sizeof(fm_symbols[0]); i++) {
+		if (strcmp(fm_symbols[i].name, name) == 0) {
+			return fm_symbols[i].addr;
+		}
+	}
+	return NULL;
+}
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// The CGO build links the shim statically instead of loading libFMShim.dylib, but
+// otherwise runs the same implementation, so both builds have the same features.
+
+// staticShimHandle stands in for a library handle; the shim and libc are both linked in
+const staticShimHandle uintptr = 1
+
+// openShim returns the handle of the linked shim. There is no library to find, so a
+// shim path passed to Init is ignored.
+func openShim(config initConfig) (uintptr, string, error) {
+	if config.shimPath != "" {
+		logger().Warn("Ignoring shim path, the shim is linked into the binary", "path", config.shimPath)
+	}
+	return staticShimHandle, "the linked shim", nil
+}
+
+// openLibc returns the handle of the linked libc
+func openLibc() (uintptr, error) {
+	return staticShimHandle, nil
+}
+
+// dlsym looks up the address of the linked symbol name
+func dlsym(lib uintptr, name string) (uintptr, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	addr := C.fm_dlsym(cName)
+	if addr == nil {
+		return 0, fmt.Errorf("symbol %s is not linked into the binary", name)
+	}
+	return uintptr(addr), nil
+}
@@ -0,0 +1,148 @@
+//go:build darwin && arm64 && !cgo && !ios
+// +build darwin,arm64,!cgo,!ios
+
+package fm
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ebitengine/purego"
+)
+
+// The default build loads libFMShim.dylib at runtime with purego: an on-disk library if
+// one is found, and otherwise the copy embedded in the binary.
+
+// Embed the Swift shim library
+//
+//go:embed libFMShim.dylib
+var embeddedShimLib []byte
+
+// openShim finds or extracts the shim library and loads it
+func openShim(config initConfig) (uintptr, string, error) {
+	shimPath, err := findOrExtractShimLibrary(config.shimPath)
+	if err != nil {
+		return 0, "", err
+	}
+	lib, err := purego.Dlopen(shimPath, purego.RTLD_NOW)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to load libFMShim.dylib from %s: %v", shimPath, err)
+	}
+	return lib, shimPath, nil
+}
+
+// openLibc loads the system libc, which allocates the strings exchanged with the shim
+func openLibc() (uintptr, error) {
+	return purego.Dlopen("/usr/lib/libc.dylib", purego.RTLD_NOW)
+}
+
+// dlsym looks up the address of the symbol name in lib
+func dlsym(lib uintptr, name string) (uintptr, error) {
+	return purego.Dlsym(lib, name)
+}
+
+// embeddedDisabled reports whether EnvDisableEmbedded is set to a true value
+func embeddedDisabled() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv(EnvDisableEmbedded))
+	return disabled
+}
+
+// findOrExtractShimLibrary finds existing shim library or extracts embedded one.
+// An explicit path (from WithShimPath or EnvShimPath) is used as is, with no fallback.
+// Otherwise on-disk libraries in the search paths always take precedence over the
+// embedded one, which is only used as a last resort unless EnvDisableEmbedded is set.
+func findOrExtractShimLibrary(shimPath string) (string, error) {
+	if shimPath == "" {
+		shimPath = os.Getenv(EnvShimPath)
+	}
+	if shimPath != "" {
+		if _, err := os.Stat(shimPath); err != nil {
+			return "", fmt.Errorf("shim library not found: %w", err)
+		}
+		return shimPath, nil
+	}
+
+	// Try to find existing library in various locations
+	searchPaths := []string{
+		"./libFMShim.dylib",       // Current directory
+		"libFMShim.dylib",         // Relative to executable
+		"./lib/libFMShim.dylib",   // lib subdirectory
+		"./build/libFMShim.dylib", // build subdirectory
+	}
+
+	for _, path := range searchPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	if embeddedDisabled() {
+		return "", fmt.Errorf("%s is set but no on-disk libFMShim.dylib was found (searched %s)",
+			EnvDisableEmbedded, strings.Join(searchPaths, ", "))
+	}
+
+	// No existing library found, extract embedded one
+	return extractEmbeddedShimLibrary()
+}
+
+// shimCacheDirName is the directory under the user cache directory (~/Library/Caches on
+// macOS) that extracted shim libraries are kept in
+const shimCacheDirName = "go-foundationmodels"
+
+// extractEmbeddedShimLibrary extracts the embedded shim library into a per-user cache
+// directory named after its SHA-256, so a different build never loads a stale copy. An
+// existing copy is used only if its checksum matches; otherwise it is replaced. The file
+// is written atomically so a concurrent process never loads a partial library.
+func extractEmbeddedShimLibrary() (string, error) {
+	sum := sha256.Sum256(embeddedShimLib)
+	checksum := hex.EncodeToString(sum[:])
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	dir := filepath.Join(cacheDir, shimCacheDirName, checksum)
+	shimPath := filepath.Join(dir, "libFMShim.dylib")
+
+	// Use a previous extraction if it hasn't been modified
+	if data, err := os.ReadFile(shimPath); err == nil {
+		if existing := sha256.Sum256(data); existing == sum {
+			logger().Debug("Using previously extracted shim library", "path", shimPath)
+			return shimPath, nil
+		}
+		logger().Warn("Extracted shim library has the wrong checksum, extracting it again", "path", shimPath)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create shim cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "libFMShim-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded shim library: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(embeddedShimLib); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to extract embedded shim library: %w", err)
+	}
+	if err := tmp.Chmod(0500); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to extract embedded shim library: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to extract embedded shim library: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), shimPath); err != nil {
+		return "", fmt.Errorf("failed to extract embedded shim library: %w", err)
+	}
+
+	logger().Debug("Extracted embedded shim library", "path", shimPath, "sha256", checksum)
+	return shimPath, nil
+}
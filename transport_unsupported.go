@@ -0,0 +1,20 @@
+//go:build !darwin || !arm64 || (ios && !cgo)
+// +build !darwin !arm64 ios,!cgo
+
+package fm
+
+// Foundation Models only runs on Apple silicon Macs. On every other platform the package
+// still compiles, so cross-platform programs can depend on it, but Init fails with
+// ErrUnsupportedPlatform and every call degrades accordingly.
+
+func openShim(config initConfig) (uintptr, string, error) {
+	return 0, "", ErrUnsupportedPlatform
+}
+
+func openLibc() (uintptr, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func dlsym(lib uintptr, name string) (uintptr, error) {
+	return 0, ErrUnsupportedPlatform
+}
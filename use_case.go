@@ -0,0 +1,82 @@
+package fm
+
+import (
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// UseCase selects a variant of the system language model specialized for a task
+type UseCase int
+
+const (
+	// UseCaseGeneral is the general-purpose model sessions use by default
+	UseCaseGeneral UseCase = iota
+	// UseCaseContentTagging is specialized for tagging content, such as extracting topics,
+	// entities and emotions. It works best with structured output.
+	UseCaseContentTagging
+)
+
+// String returns the name of the use case
+func (u UseCase) String() string {
+	switch u {
+	case UseCaseGeneral:
+		return "general"
+	case UseCaseContentTagging:
+		return "contentTagging"
+	default:
+		return fmt.Sprintf("unknown use case (%d)", int(u))
+	}
+}
+
+// WithUseCase runs the session on the model specialized for useCase
+func WithUseCase(useCase UseCase) SessionOption {
+	return func(s *Session) {
+		if err := s.setUseCase(useCase); err != nil {
+			logger().Warn("Failed to set session use case", "use_case", useCase, "error", err)
+		}
+	}
+}
+
+// NewSessionWithUseCase creates a new session running on the model specialized for
+// useCase, configured by opts. Combine WithUseCase with NewSessionWithInstructions to give
+// the session instructions as well.
+func NewSessionWithUseCase(useCase UseCase, opts ...SessionOption) (*Session, error) {
+	if useCase != UseCaseGeneral && useCase != UseCaseContentTagging {
+		return nil, fmt.Errorf("invalid use case: %s", useCase)
+	}
+	if Init() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrShimNotInitialized, shimInitError)
+	}
+
+	session := NewSession(append([]SessionOption{WithUseCase(useCase)}, opts...)...)
+	if session == nil {
+		return nil, fmt.Errorf("failed to create LanguageModelSession")
+	}
+	if session.useCase != useCase {
+		session.Release()
+		return nil, fmt.Errorf("failed to set session use case to %s", useCase)
+	}
+	return session, nil
+}
+
+// UseCase returns the use case the session runs on
+func (s *Session) UseCase() UseCase {
+	return s.useCase
+}
+
+// setUseCase switches the shim session to the model for useCase
+func (s *Session) setUseCase(useCase UseCase) error {
+	if s.ptr == nil {
+		return ErrInvalidSession
+	}
+	if err := shimSymbol(setSessionUseCase, "SetSessionUseCase"); err != nil {
+		return err
+	}
+	result, _, _ := purego.SyscallN(setSessionUseCase, uintptr(s.ptr), uintptr(useCase))
+	if result == 0 {
+		return fmt.Errorf("shim rejected use case %s", useCase)
+	}
+	s.useCase = useCase
+	return nil
+}